@@ -0,0 +1,10 @@
+// Package api holds the API contracts: the OpenAPI document of the HTTP
+// API and, under proto, the protobuf definitions of the gRPC API.
+package api
+
+import _ "embed"
+
+// OpenAPI is the OpenAPI 3 document of the HTTP API, in YAML.
+//
+//go:embed openapi.yaml
+var OpenAPI []byte
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/williamkoller/payment-system/config"
+	"github.com/williamkoller/payment-system/internal/apikey/application"
+	"github.com/williamkoller/payment-system/internal/apikey/domain"
+	apikeyRouter "github.com/williamkoller/payment-system/internal/apikey/router"
+	auditDomain "github.com/williamkoller/payment-system/internal/audit/domain"
+	auditRouter "github.com/williamkoller/payment-system/internal/audit/router"
+	merchantRouter "github.com/williamkoller/payment-system/internal/merchant/router"
+	"github.com/williamkoller/payment-system/pkg/auth"
+)
+
+const apiKeysUsage = "usage: api-keys create|list|rotate|revoke [flags]"
+
+// runAPIKeys manages API keys from the command line. It is how the first
+// platform key is issued, before the admin API can be called.
+func runAPIKeys(configuration *config.ResponseConfiguration, args []string) {
+	if len(args) == 0 {
+		log.Fatal(apiKeysUsage)
+	}
+
+	database := config.NewDatabaseConnection()
+	config.RunMigrations(database, "")
+	service := apikeyRouter.NewKeyService(database)
+	service.Merchants = merchantRouter.NewMerchantService(database, newSecretBox(configuration))
+	service.Audit = auditRouter.NewAuditService(database)
+	ctx := auditDomain.WithActor(context.Background(), "cli")
+
+	switch args[0] {
+	case "create":
+		fs := flag.NewFlagSet("api-keys create", flag.ExitOnError)
+		merchant := fs.String("merchant", "", "merchant the key acts for (required unless -scopes platform)")
+		name := fs.String("name", "", "label shown in listings")
+		env := fs.String("env", string(domain.EnvironmentTest), "test or live")
+		scopes := fs.String("scopes", string(auth.ScopePaymentsRead), "comma-separated scopes: payments:read, payments:write, refunds:write, reviews:write, admin, or platform alone")
+		_ = fs.Parse(args[1:])
+
+		key, secret, err := service.Create(ctx, application.CreateKeyInput{
+			MerchantID:  *merchant,
+			Name:        *name,
+			Environment: domain.Environment(*env),
+			Scopes:      parseScopes(*scopes),
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		printSecret(key, secret)
+	case "list":
+		fs := flag.NewFlagSet("api-keys list", flag.ExitOnError)
+		merchant := fs.String("merchant", "", "only list keys of this merchant")
+		_ = fs.Parse(args[1:])
+
+		keys, err := service.List(ctx, application.KeyFilter{MerchantID: *merchant})
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tMERCHANT\tNAME\tPREFIX\tSCOPES\tACTIVE\tLAST USED")
+		for _, k := range keys {
+			lastUsed := "never"
+			if k.LastUsedAt != nil {
+				lastUsed = k.LastUsedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%t\t%s\n",
+				k.ID, k.MerchantID, k.Name, k.Prefix, k.Scopes, k.IsActive(time.Now()), lastUsed)
+		}
+		_ = w.Flush()
+	case "rotate":
+		fs := flag.NewFlagSet("api-keys rotate", flag.ExitOnError)
+		id := fs.String("id", "", "key to rotate (required)")
+		grace := fs.Duration("grace", 24*time.Hour, "how long the old key keeps working; 0 revokes it now")
+		_ = fs.Parse(args[1:])
+
+		key, secret, err := service.Rotate(ctx, *id, *grace)
+		if err != nil {
+			log.Fatal(err)
+		}
+		printSecret(key, secret)
+	case "revoke":
+		fs := flag.NewFlagSet("api-keys revoke", flag.ExitOnError)
+		id := fs.String("id", "", "key to revoke (required)")
+		_ = fs.Parse(args[1:])
+
+		if _, err := service.Revoke(ctx, *id); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("revoked %s\n", *id)
+	default:
+		log.Fatal(apiKeysUsage)
+	}
+}
+
+func parseScopes(v string) []auth.Scope {
+	var scopes []auth.Scope
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			scopes = append(scopes, auth.Scope(s))
+		}
+	}
+	return scopes
+}
+
+func printSecret(key *domain.APIKey, secret string) {
+	fmt.Printf("id:     %s\nscopes: %s\nkey:    %s\n\nStore the key now; it cannot be shown again.\n", key.ID, key.Scopes, secret)
+}
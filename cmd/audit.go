@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/williamkoller/payment-system/config"
+	auditRouter "github.com/williamkoller/payment-system/internal/audit/router"
+)
+
+const auditUsage = "usage: audit verify"
+
+// runAudit checks the audit log. verify recomputes the hash chain and exits
+// non-zero at the first entry that does not match; the printed head hash
+// can be recorded elsewhere to detect the log being rewritten wholesale.
+func runAudit(args []string) {
+	if len(args) == 0 || args[0] != "verify" {
+		log.Fatal(auditUsage)
+	}
+
+	database := config.NewDatabaseConnection()
+	config.RunMigrations(database, "")
+
+	report, err := auditRouter.NewAuditService(database).Verify(context.Background())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if report.Break != nil {
+		fmt.Printf("chain broken at seq %d: %s (%d entries intact, head %s)\n",
+			report.Break.Seq, report.Break.Reason, report.Checked, report.Head)
+		os.Exit(1)
+	}
+	fmt.Printf("chain intact: %d entries, head %s\n", report.Checked, report.Head)
+}
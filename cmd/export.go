@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/williamkoller/payment-system/config"
+	paymentApplication "github.com/williamkoller/payment-system/internal/payment/application"
+	"github.com/williamkoller/payment-system/internal/payment/dtos"
+	paymentInterfaces "github.com/williamkoller/payment-system/internal/payment/interfaces"
+	paymentRouter "github.com/williamkoller/payment-system/internal/payment/router"
+	"github.com/williamkoller/payment-system/pkg/auth"
+)
+
+// runExport writes the same file as GET /payments/export, for exports too
+// large or too slow to pull over HTTP. Without -merchant it covers every
+// merchant.
+func runExport(configuration *config.ResponseConfiguration, args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	format := fs.String("format", paymentInterfaces.FormatCSV, "csv or ndjson")
+	columns := fs.String("columns", "", "comma-separated columns; all of them when empty")
+	timezone := fs.String("timezone", "", "IANA timezone dates are written in; UTC when empty")
+	expiringBefore := fs.String("expiring-before", "", "only authorized payments whose authorization lapses before this RFC 3339 time")
+	merchantID := fs.String("merchant", "", "only this merchant's payments")
+	output := fs.String("o", "", "output file; stdout when empty")
+	_ = fs.Parse(args)
+
+	if *format != paymentInterfaces.FormatCSV && *format != paymentInterfaces.FormatNDJSON {
+		log.Fatalf("invalid -format: %q", *format)
+	}
+	opts, err := paymentInterfaces.ParseExportOptions(*format, *columns, *timezone)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var filter dtos.ListPaymentsDto
+	if *expiringBefore != "" {
+		if filter.ExpiringBefore, err = time.Parse(time.RFC3339, *expiringBefore); err != nil {
+			log.Fatalf("invalid -expiring-before: %v", err)
+		}
+	}
+
+	ctx := context.Background()
+	if *merchantID != "" {
+		ctx = auth.ForMerchant(ctx, *merchantID)
+	}
+
+	var out io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		out = f
+	}
+	buffered := bufio.NewWriter(out)
+
+	database := config.NewDatabaseConnection()
+	config.RunMigrations(database, "")
+	repo, err := paymentRouter.NewPaymentRepository(database, configuration.PaymentStore)
+	if err != nil {
+		log.Fatal(err)
+	}
+	usecase := paymentApplication.NewPaymentUseCase(repo, nil)
+
+	w := paymentInterfaces.NewExportWriter(buffered, opts)
+	if err := w.WriteHeader(); err != nil {
+		log.Fatal(err)
+	}
+	if err := usecase.ExportPayments(ctx, filter, w.Write); err != nil {
+		log.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		log.Fatal(err)
+	}
+	if err := buffered.Flush(); err != nil {
+		log.Fatal(err)
+	}
+}
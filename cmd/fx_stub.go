@@ -0,0 +1,34 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/williamkoller/payment-system/config"
+	"github.com/williamkoller/payment-system/internal/fx/infra"
+)
+
+// runFxStub serves a local stand-in for the HTTP fx provider, backed by the
+// same rates file the static provider reads.
+func runFxStub(configuration *config.ResponseConfiguration, args []string) {
+	fs := flag.NewFlagSet("fx-stub", flag.ExitOnError)
+	addr := fs.String("addr", ":8090", "listen address")
+	rates := fs.String("rates", configuration.Fx.RatesFile, "path to the rates JSON file")
+	_ = fs.Parse(args)
+
+	provider, err := infra.LoadStaticRateProvider(*rates)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           infra.StubHandler(provider),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	log.Printf("fx stub listening on %s", *addr)
+	log.Fatal(srv.ListenAndServe())
+}
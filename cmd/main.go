@@ -4,27 +4,50 @@ import (
 	"context"
 	"errors"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
+	// Exports take any IANA timezone; the runtime image has no zoneinfo.
+	_ "time/tzdata"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	"github.com/williamkoller/payment-system/config"
+	apikeyRouter "github.com/williamkoller/payment-system/internal/apikey/router"
+	auditRouter "github.com/williamkoller/payment-system/internal/audit/router"
+	batchRouter "github.com/williamkoller/payment-system/internal/batch/router"
+	fxRouter "github.com/williamkoller/payment-system/internal/fx/router"
+	healthApplication "github.com/williamkoller/payment-system/internal/healthz/application"
+	healthInfra "github.com/williamkoller/payment-system/internal/healthz/infra"
 	healthRouter "github.com/williamkoller/payment-system/internal/healthz/router"
+	listsRouter "github.com/williamkoller/payment-system/internal/lists/router"
+	merchantRouter "github.com/williamkoller/payment-system/internal/merchant/router"
+	"github.com/williamkoller/payment-system/internal/metrics"
 	"github.com/williamkoller/payment-system/internal/middleware"
+	openapiRouter "github.com/williamkoller/payment-system/internal/openapi/router"
+	paymentApplication "github.com/williamkoller/payment-system/internal/payment/application"
+	paymentDomain "github.com/williamkoller/payment-system/internal/payment/domain"
+	paymentInfra "github.com/williamkoller/payment-system/internal/payment/infra"
+	paymentMiddleware "github.com/williamkoller/payment-system/internal/payment/middleware"
 	paymentRouter "github.com/williamkoller/payment-system/internal/payment/router"
+	ratelimitRouter "github.com/williamkoller/payment-system/internal/ratelimit/router"
+	reconciliationApplication "github.com/williamkoller/payment-system/internal/reconciliation/application"
+	reconciliationRouter "github.com/williamkoller/payment-system/internal/reconciliation/router"
+	riskRouter "github.com/williamkoller/payment-system/internal/risk/router"
 	webhookRouter "github.com/williamkoller/payment-system/internal/webhook/router"
 	"github.com/williamkoller/payment-system/pkg/logger"
+	"github.com/williamkoller/payment-system/pkg/pii"
+	"github.com/williamkoller/payment-system/pkg/secretbox"
+	"github.com/williamkoller/payment-system/pkg/tracing"
+	"google.golang.org/grpc"
 )
 
 func main() {
 	_ = godotenv.Load()
 
-	r := gin.Default()
-
 	err := logger.InitLogger("dev")
 	if err != nil {
 		log.Fatal(err)
@@ -37,19 +60,209 @@ func main() {
 		log.Fatal(err)
 	}
 
+	if len(os.Args) > 1 && os.Args[1] == "pii" {
+		runPII(configuration, os.Args[2:])
+		return
+	}
+
+	initPII(configuration)
+
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "serve":
+		case "reconcile":
+			runReconcile(configuration, os.Args[2:])
+			return
+		case "api-keys":
+			runAPIKeys(configuration, os.Args[2:])
+			return
+		case "merchants":
+			runMerchants(configuration, os.Args[2:])
+			return
+		case "audit":
+			runAudit(os.Args[2:])
+			return
+		case "export":
+			runExport(configuration, os.Args[2:])
+			return
+		case "fx-stub":
+			runFxStub(configuration, os.Args[2:])
+			return
+		default:
+			log.Fatalf("unknown command %q", os.Args[1])
+		}
+	}
+
+	serve(configuration)
+}
+
+func serve(configuration *config.ResponseConfiguration) {
+	shutdownTracing, err := tracing.Init(context.Background(), configuration.Tracing.Exporter, configuration.Tracing.ServiceName, configuration.Tracing.SampleRatio)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	r := gin.Default()
+	// The client IP feeds rate limits, risk rules and the audit log, so
+	// X-Forwarded-For is only believed from the configured proxies.
+	if err := r.SetTrustedProxies(configuration.App.TrustedProxies); err != nil {
+		log.Fatalf("invalid TRUSTED_PROXIES: %v", err)
+	}
+
 	database := config.NewDatabaseConnection()
 	config.RunMigrations(database, "")
 
+	sqlDB, err := database.DB()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := metrics.RegisterDB(sqlDB); err != nil {
+		log.Fatal(err)
+	}
+
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
+	health := healthApplication.NewService(configuration.Health.CheckTimeout, configuration.Health.CacheTTL)
+	health.Register(healthInfra.NewDBChecker(sqlDB))
+	latestMigration, err := healthInfra.LatestMigrationVersion(healthInfra.DefaultMigrationsDir())
+	if err != nil {
+		log.Fatal(err)
+	}
+	health.Register(healthInfra.NewMigrationChecker(sqlDB, latestMigration))
+
+	audit := auditRouter.NewAuditService(database)
+
+	merchants := merchantRouter.NewMerchantService(database, newSecretBox(configuration))
+	merchants.Audit = audit
+
+	events := paymentApplication.NewStatusBroker(configuration.Events.MaxStreams, configuration.Events.MaxStreamsPerMerchant)
+	events.HeartbeatInterval = configuration.Events.HeartbeatInterval
+
+	reconciler, err := reconciliationRouter.NewReconciler(database, configuration)
+	if err != nil {
+		log.Fatal(err)
+	}
+	reconciler.Accounts = reconciliationRouter.MerchantAccounts(merchants)
+	reconciler.Events = events
+	if configuration.Reconciliation.Enabled {
+		worker := reconciliationApplication.NewWorker(reconciler, configuration.Reconciliation.Interval, configuration.Reconciliation.Window)
+		go worker.Start(workerCtx)
+		health.Register(healthInfra.NewHeartbeatChecker("reconciliation_worker", &worker.Heartbeat, 2*configuration.Reconciliation.Interval))
+	}
+
+	quotes, err := fxRouter.NewQuoteService(database, configuration.Fx)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	paymentUseCase, err := paymentRouter.NewPaymentUseCase(database, configuration.PaymentStore)
+	if err != nil {
+		log.Fatal(err)
+	}
+	paymentUseCase.Settlement = quotes
+	paymentUseCase.Merchants = merchants
+	paymentUseCase.Audit = audit
+	paymentUseCase.Events = events
+	paymentUseCase.Gateways = paymentInfra.NewStripeClients(paymentUseCase.StripeClient, merchants)
+	batches := batchRouter.NewBatchService(database, paymentUseCase, configuration.Batch)
+	if breaker, ok := paymentUseCase.StripeClient.(healthInfra.BreakerStater); ok {
+		health.Register(healthInfra.NewBreakerChecker("stripe_circuit_breaker", breaker))
+		batches.Processor.Breaker = breaker
+	}
+
+	riskEngine, err := riskRouter.NewEngine(database, configuration.Risk.RulesFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if configuration.Risk.Enabled {
+		paymentUseCase.Risk = riskEngine
+	}
+
+	lists := listsRouter.NewListService(database)
+	if err := lists.Matcher.Refresh(workerCtx); err != nil {
+		log.Fatal(err)
+	}
+	go lists.Matcher.Start(workerCtx, configuration.Lists.RefreshInterval)
+	health.Register(healthInfra.NewHeartbeatChecker("lists_refresh", &lists.Matcher.Heartbeat, 2*configuration.Lists.RefreshInterval))
+	lists.AuditLog = audit
+	paymentUseCase.Lists = lists.Matcher
+	if expiry := configuration.AuthorizationExpiry; expiry.Enabled {
+		policies := paymentApplication.MerchantExpiryPolicy{
+			Merchants: merchants,
+			Default: paymentApplication.ExpiryPolicy{
+				AlertBefore: expiry.AlertBefore,
+				ActBefore:   expiry.ActBefore,
+				Action:      paymentDomain.ExpiryAction(expiry.Action),
+			},
+		}
+		scheduler := paymentApplication.NewAuthorizationExpiryScheduler(paymentUseCase, policies, expiry.Interval)
+		go scheduler.Start(workerCtx)
+		health.Register(healthInfra.NewHeartbeatChecker("authorization_expiry", &scheduler.Heartbeat, 2*expiry.Interval))
+	}
+
+	apiKeys := apikeyRouter.NewKeyService(database)
+	apiKeys.Merchants = merchants
+	apiKeys.Audit = audit
+	authn := middleware.Auth(apiKeys)
+	if !configuration.Auth.Enabled {
+		logger.Warn("API key authentication is disabled; every request is treated as admin")
+		authn = middleware.Anonymous()
+	}
+
+	var limits *middleware.RateLimits
+	if configuration.RateLimit.Enabled {
+		limiter, err := ratelimitRouter.NewLimiter(database, configuration.RateLimit)
+		if err != nil {
+			log.Fatal(err)
+		}
+		go limiter.Sweep(workerCtx, time.Hour)
+		limits = middleware.NewRateLimits(limiter)
+		batches.Processor.Limiter = limiter
+	}
+
+	go batches.Processor.Start(workerCtx)
+	health.Register(healthInfra.NewHeartbeatChecker("batch_processor", &batches.Processor.Heartbeat, 2*max(configuration.Batch.PollInterval, configuration.Batch.RetryDelay)))
+
 	middleware.Middlewares(r)
-	healthRouter.SetupRouter(r)
-	paymentRouter.SetupRouter(r, database)
-	webhookRouter.SetupWebhookRouter(r, database)
-	
+	r.Use(paymentMiddleware.Metrics())
+	r.GET("/metrics", gin.WrapH(metrics.Handler()))
+
+	spec, err := openapiRouter.LoadSpec()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := openapiRouter.SetupRouter(r, spec); err != nil {
+		log.Fatal(err)
+	}
+	if configuration.OpenAPI.Validate {
+		if gin.Mode() == gin.ReleaseMode {
+			logger.Warn("OPENAPI_VALIDATE is ignored in release mode")
+		} else if err := openapiRouter.Validate(r, spec); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	healthRouter.SetupRouter(r, health, configuration.Health.Details)
+	apikeyRouter.SetupRouter(r, apiKeys, authn)
+	merchantRouter.SetupRouter(r, merchants, authn)
+	paymentRouter.SetupRouter(r, paymentUseCase, authn, limits)
+	auditRouter.SetupRouter(r, audit, authn, limits)
+	batchRouter.SetupRouter(r, batches, authn, limits)
+	webhookRouter.SetupWebhookRouter(r, database, merchants, paymentUseCase.Events)
+	reconciliationRouter.SetupRouter(r, reconciler, authn)
+	fxRouter.SetupRouter(r, quotes, fxRouter.NewReportService(database), authn, limits)
+	riskRouter.SetupRouter(r, riskEngine, authn, limits)
+	listsRouter.SetupRouter(r, lists, authn)
+
 	srv := &http.Server{
 		Addr:              ":" + configuration.App.Port,
 		Handler:           r,
 		ReadHeaderTimeout: 5 * time.Second,
 	}
+	// Event streams never end on their own; closing them lets Shutdown
+	// finish.
+	srv.RegisterOnShutdown(paymentUseCase.Events.Close)
 
 	go func() {
 		logger.Info("Starting server", "AppName", configuration.App.AppName)
@@ -58,13 +271,82 @@ func main() {
 		}
 	}()
 
+	var grpcServer *grpc.Server
+	if configuration.GRPC.Port != "" {
+		var grpcAuth middleware.Authenticator = apiKeys
+		if !configuration.Auth.Enabled {
+			grpcAuth = nil
+		}
+		grpcServer = paymentRouter.NewGRPCServer(paymentUseCase, grpcAuth, configuration.GRPC)
+		listener, err := net.Listen("tcp", ":"+configuration.GRPC.Port)
+		if err != nil {
+			log.Fatal(err)
+		}
+		go func() {
+			logger.Info("Starting gRPC server", "port", configuration.GRPC.Port)
+			if err := grpcServer.Serve(listener); err != nil {
+				logger.Fatal("Error starting gRPC server", "err", err)
+			}
+		}()
+	}
+
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
+	stopWorkers()
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	_ = srv.Shutdown(ctx)
+	if grpcServer != nil {
+		stopGRPC(ctx, grpcServer)
+	}
+	if err := shutdownTracing(ctx); err != nil {
+		logger.Error("cannot flush traces", "err", err)
+	}
 	logger.Info("Server shutting down")
 }
+
+// stopGRPC lets in-flight calls finish, then cuts off whatever is still
+// running, e.g. WatchPayment streams, once ctx expires.
+func stopGRPC(ctx context.Context, server *grpc.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		server.Stop()
+	}
+}
+
+// initPII installs the process-wide PII cipher before any repository is
+// created.
+func initPII(configuration *config.ResponseConfiguration) {
+	if configuration.Encryption.PIIKeyFile == "" {
+		logger.Warn("PII_KEY_FILE is not set; customer emails are stored in plain text")
+		return
+	}
+	keys, err := pii.LoadKeyFile(configuration.Encryption.PIIKeyFile)
+	if err != nil {
+		log.Fatalf("invalid PII_KEY_FILE: %v", err)
+	}
+	pii.SetDefault(pii.New(keys))
+}
+
+// newSecretBox returns nil when ENCRYPTION_KEY is unset, which leaves
+// merchants on the platform Stripe account.
+func newSecretBox(configuration *config.ResponseConfiguration) *secretbox.Box {
+	if configuration.Encryption.Key == "" {
+		return nil
+	}
+	box, err := secretbox.NewFromBase64(configuration.Encryption.Key)
+	if err != nil {
+		log.Fatalf("invalid ENCRYPTION_KEY: %v", err)
+	}
+	return box
+}
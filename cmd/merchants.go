@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/williamkoller/payment-system/config"
+	auditDomain "github.com/williamkoller/payment-system/internal/audit/domain"
+	auditRouter "github.com/williamkoller/payment-system/internal/audit/router"
+	"github.com/williamkoller/payment-system/internal/merchant/application"
+	merchantRouter "github.com/williamkoller/payment-system/internal/merchant/router"
+)
+
+const merchantsUsage = "usage: merchants create|list [flags]"
+
+// runMerchants onboards merchants from the command line, so the first one
+// can be created before any API key exists.
+func runMerchants(configuration *config.ResponseConfiguration, args []string) {
+	if len(args) == 0 {
+		log.Fatal(merchantsUsage)
+	}
+
+	database := config.NewDatabaseConnection()
+	config.RunMigrations(database, "")
+	service := merchantRouter.NewMerchantService(database, newSecretBox(configuration))
+	service.Audit = auditRouter.NewAuditService(database)
+	ctx := auditDomain.WithActor(context.Background(), "cli")
+
+	switch args[0] {
+	case "create":
+		fs := flag.NewFlagSet("merchants create", flag.ExitOnError)
+		name := fs.String("name", "", "merchant name (required)")
+		stripeKey := fs.String("stripe-key", "", "the merchant's own Stripe secret key; empty uses the platform account")
+		webhookSecret := fs.String("webhook-secret", "", "signing secret of the merchant's Stripe webhook endpoint")
+		paymentMethod := fs.String("payment-method", "", "overrides STRIPE_METHOD for this merchant")
+		maxAmount := fs.Int64("max-amount", 0, "largest single payment in minor units; 0 for no cap")
+		_ = fs.Parse(args[1:])
+
+		merchant, err := service.Create(ctx, application.CreateMerchantInput{
+			Name:                *name,
+			StripeSecretKey:     *stripeKey,
+			StripeWebhookSecret: *webhookSecret,
+			StripePaymentMethod: *paymentMethod,
+			MaxPaymentAmount:    *maxAmount,
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("merchant %s created (own Stripe account: %t)\n", merchant.ID, merchant.HasOwnStripeAccount())
+	case "list":
+		merchants, err := service.List(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tSTATUS\tOWN STRIPE ACCOUNT\tMAX AMOUNT")
+		for _, m := range merchants {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%t\t%d\n", m.ID, m.Name, m.Status, m.HasOwnStripeAccount(), m.MaxPaymentAmount)
+		}
+		_ = w.Flush()
+	default:
+		log.Fatal(merchantsUsage)
+	}
+}
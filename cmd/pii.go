@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/williamkoller/payment-system/config"
+	"github.com/williamkoller/payment-system/pkg/pii"
+)
+
+const piiUsage = "usage: pii keygen|rotate [flags]"
+
+// piiColumns are the encrypted columns `pii rotate` re-encrypts.
+var piiColumns = []pii.Column{
+	{Table: "payments", Value: "email", Index: "email_index"},
+	{Table: "risk_assessments", Value: "email", Index: "email_index"},
+}
+
+// runPII manages the PII key file: keygen adds a key and makes it current,
+// rotate re-encrypts every stored value under the current key.
+func runPII(configuration *config.ResponseConfiguration, args []string) {
+	if len(args) == 0 {
+		log.Fatal(piiUsage)
+	}
+
+	switch args[0] {
+	case "keygen":
+		fs := flag.NewFlagSet("pii keygen", flag.ExitOnError)
+		file := fs.String("file", configuration.Encryption.PIIKeyFile, "key file to create or add the key to")
+		id := fs.String("id", "", "id of the new key, e.g. 2026-10 (required)")
+		_ = fs.Parse(args[1:])
+
+		if *file == "" || *id == "" {
+			log.Fatal("usage: pii keygen -id <key id> [-file <path>]")
+		}
+		if err := pii.AddKeyFile(*file, *id); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("key %s added to %s and made current; run `pii rotate` to re-encrypt existing data\n", *id, *file)
+	case "rotate":
+		fs := flag.NewFlagSet("pii rotate", flag.ExitOnError)
+		batch := fs.Int("batch", 500, "rows re-encrypted per query")
+		_ = fs.Parse(args[1:])
+
+		initPII(configuration)
+		cipher := pii.Default()
+		if cipher == nil {
+			log.Fatal("PII_KEY_FILE is required to rotate")
+		}
+
+		database := config.NewDatabaseConnection()
+		config.RunMigrations(database, "")
+		for _, column := range piiColumns {
+			n, err := cipher.Rotate(context.Background(), database, column, *batch)
+			if err != nil {
+				log.Fatalf("cannot rotate %s.%s: %v", column.Table, column.Value, err)
+			}
+			fmt.Printf("%s.%s: %d rows re-encrypted\n", column.Table, column.Value, n)
+		}
+	default:
+		log.Fatal(piiUsage)
+	}
+}
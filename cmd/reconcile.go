@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/williamkoller/payment-system/config"
+	merchantRouter "github.com/williamkoller/payment-system/internal/merchant/router"
+	reconciliationRouter "github.com/williamkoller/payment-system/internal/reconciliation/router"
+)
+
+const dateLayout = "2006-01-02"
+
+// runReconcile reconciles payments created in [-from, -to) against Stripe.
+// Both flags accept either a date (2006-01-02) or an RFC 3339 timestamp and
+// default to the previous UTC day.
+func runReconcile(configuration *config.ResponseConfiguration, args []string) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+
+	fs := flag.NewFlagSet("reconcile", flag.ExitOnError)
+	fromFlag := fs.String("from", today.Add(-24*time.Hour).Format(dateLayout), "start of the range (inclusive)")
+	toFlag := fs.String("to", today.Format(dateLayout), "end of the range (exclusive)")
+	_ = fs.Parse(args)
+
+	from, err := parseTime(*fromFlag)
+	if err != nil {
+		log.Fatalf("invalid -from: %v", err)
+	}
+	to, err := parseTime(*toFlag)
+	if err != nil {
+		log.Fatalf("invalid -to: %v", err)
+	}
+
+	database := config.NewDatabaseConnection()
+	config.RunMigrations(database, "")
+
+	reconciler, err := reconciliationRouter.NewReconciler(database, configuration)
+	if err != nil {
+		log.Fatal(err)
+	}
+	reconciler.Accounts = reconciliationRouter.MerchantAccounts(merchantRouter.NewMerchantService(database, newSecretBox(configuration)))
+	run, err := reconciler.Run(context.Background(), from, to)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("run %s: %s, checked=%d repaired=%d discrepancies=%d\n",
+		run.ID, run.Status, run.Checked, run.Repaired, run.Discrepancies)
+}
+
+func parseTime(v string) (time.Time, error) {
+	if t, err := time.Parse(dateLayout, v); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
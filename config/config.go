@@ -5,11 +5,17 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
+// AppConfiguration holds the HTTP server settings. TrustedProxies lists the
+// proxies (IPs or CIDRs) whose X-Forwarded-For is believed; by default none
+// are, and the client IP is the peer's address.
 type AppConfiguration struct {
-	Port    string
-	AppName string
+	Port           string
+	AppName        string
+	TrustedProxies []string
 }
 
 type StripeConfiguration struct {
@@ -26,10 +32,146 @@ type DatabaseConfiguration struct {
 	Database string
 }
 
+type ReconciliationConfiguration struct {
+	Enabled  bool
+	Interval time.Duration
+	Window   time.Duration
+}
+
+type AuthorizationExpiryConfiguration struct {
+	Enabled     bool
+	Interval    time.Duration
+	AlertBefore time.Duration
+	ActBefore   time.Duration
+	Action      string
+}
+
+type FxConfiguration struct {
+	Provider           string
+	RatesFile          string
+	HTTPURL            string
+	SettlementCurrency string
+	QuoteTTL           time.Duration
+}
+
+type RiskConfiguration struct {
+	Enabled   bool
+	RulesFile string
+}
+
+type ListsConfiguration struct {
+	RefreshInterval time.Duration
+}
+
+// RateLimitConfiguration holds the token bucket of each route class as
+// "<requests>/<s|m|h>" ("0" disables it). Store is "memory" or "postgres";
+// only the latter shares limits across replicas.
+type RateLimitConfiguration struct {
+	Enabled     bool
+	Store       string
+	IP          string
+	Read        string
+	Write       string
+	Concurrency int
+}
+
+// TracingConfiguration selects the span exporter: "otlp" (endpoint from the
+// standard OTEL_EXPORTER_OTLP_* variables), "stdout" or "none".
+type TracingConfiguration struct {
+	Exporter    string
+	ServiceName string
+	SampleRatio float64
+}
+
+// HealthConfiguration bounds readiness checks. Details exposes the
+// per-check report on /readyz; keep it off where probes are public.
+type HealthConfiguration struct {
+	CheckTimeout time.Duration
+	CacheTTL     time.Duration
+	Details      bool
+}
+
+// AuthConfiguration switches API key authentication. Disabling it lets every
+// request through as an admin and is meant for local development only.
+type AuthConfiguration struct {
+	Enabled bool
+}
+
+// EncryptionConfiguration holds the base64-encoded 32-byte key secrets are
+// sealed with at rest, e.g. merchants' Stripe credentials.
+type EncryptionConfiguration struct {
+	Key string
+	// PIIKeyFile is the key file customer PII is encrypted with. Without
+	// it PII is stored in plain text.
+	PIIKeyFile string
+}
+
+// PaymentStoreConfiguration selects how payments are persisted: "crud"
+// keeps only the payments table, "events" stores each payment as a stream
+// of events and projects it into the payments table.
+type PaymentStoreConfiguration struct {
+	Store string
+	// SnapshotEvery is how many events a payment accumulates between
+	// snapshots in the "events" store.
+	SnapshotEvery int
+}
+
+// GRPCConfiguration serves the payment API over gRPC on Port, next to the
+// HTTP server. An empty Port disables it.
+type GRPCConfiguration struct {
+	Port string
+	// WatchInterval is how often WatchPayment streams look for changes.
+	WatchInterval time.Duration
+}
+
+// OpenAPIConfiguration switches validating requests and responses against
+// the OpenAPI document. It is ignored in gin's release mode.
+type OpenAPIConfiguration struct {
+	Validate bool
+}
+
+// EventsConfiguration bounds the server-sent event streams of payment
+// status changes. The limits are per replica; 0 removes one.
+type EventsConfiguration struct {
+	// HeartbeatInterval is how often an idle stream gets a comment, so
+	// proxies keep it open and dead clients are noticed.
+	HeartbeatInterval     time.Duration
+	MaxStreams            int
+	MaxStreamsPerMerchant int
+}
+
+// BatchConfiguration sizes the worker pool that runs batch operations.
+type BatchConfiguration struct {
+	Workers int
+	// PollInterval is how often idle workers look for items queued by
+	// other replicas.
+	PollInterval time.Duration
+	// RetryDelay is how long an item waits after the gateway was
+	// unavailable, and how long workers pause while the circuit breaker is
+	// open.
+	RetryDelay  time.Duration
+	MaxAttempts int
+}
+
 type ResponseConfiguration struct {
-	App      AppConfiguration
-	Stripe   StripeConfiguration
-	Database DatabaseConfiguration
+	App                 AppConfiguration
+	Stripe              StripeConfiguration
+	Database            DatabaseConfiguration
+	Reconciliation      ReconciliationConfiguration
+	AuthorizationExpiry AuthorizationExpiryConfiguration
+	Fx                  FxConfiguration
+	Risk                RiskConfiguration
+	Lists               ListsConfiguration
+	Tracing             TracingConfiguration
+	Health              HealthConfiguration
+	Auth                AuthConfiguration
+	Encryption          EncryptionConfiguration
+	RateLimit           RateLimitConfiguration
+	PaymentStore        PaymentStoreConfiguration
+	GRPC                GRPCConfiguration
+	OpenAPI             OpenAPIConfiguration
+	Events              EventsConfiguration
+	Batch               BatchConfiguration
 }
 
 func loadStripeConfiguration() (*StripeConfiguration, error) {
@@ -79,10 +221,80 @@ func LoadConfiguration() (*ResponseConfiguration, error) {
 		return nil, fmt.Errorf("Error loading database configuration: %w", err)
 	}
 
+	reconciliation, err := loadReconciliationConfiguration()
+	if err != nil {
+		return nil, fmt.Errorf("Error loading reconciliation configuration: %w", err)
+	}
+
+	authorizationExpiry, err := loadAuthorizationExpiryConfiguration()
+	if err != nil {
+		return nil, fmt.Errorf("Error loading authorization expiry configuration: %w", err)
+	}
+
+	fx, err := loadFxConfiguration()
+	if err != nil {
+		return nil, fmt.Errorf("Error loading fx configuration: %w", err)
+	}
+
+	lists, err := loadListsConfiguration()
+	if err != nil {
+		return nil, fmt.Errorf("Error loading lists configuration: %w", err)
+	}
+
+	tracing, err := loadTracingConfiguration(app.AppName)
+	if err != nil {
+		return nil, fmt.Errorf("Error loading tracing configuration: %w", err)
+	}
+
+	health, err := loadHealthConfiguration()
+	if err != nil {
+		return nil, fmt.Errorf("Error loading health configuration: %w", err)
+	}
+
+	rateLimit, err := loadRateLimitConfiguration()
+	if err != nil {
+		return nil, fmt.Errorf("Error loading rate limit configuration: %w", err)
+	}
+
+	paymentStore, err := loadPaymentStoreConfiguration()
+	if err != nil {
+		return nil, fmt.Errorf("Error loading payment store configuration: %w", err)
+	}
+
+	grpc, err := loadGRPCConfiguration()
+	if err != nil {
+		return nil, fmt.Errorf("Error loading gRPC configuration: %w", err)
+	}
+
+	events, err := loadEventsConfiguration()
+	if err != nil {
+		return nil, fmt.Errorf("Error loading events configuration: %w", err)
+	}
+
+	batch, err := loadBatchConfiguration()
+	if err != nil {
+		return nil, fmt.Errorf("Error loading batch configuration: %w", err)
+	}
+
 	return &ResponseConfiguration{
-		App:      *app,
-		Stripe:   *stripe,
-		Database: *db,
+		App:                 *app,
+		Stripe:              *stripe,
+		Database:            *db,
+		Reconciliation:      *reconciliation,
+		AuthorizationExpiry: *authorizationExpiry,
+		Fx:                  *fx,
+		Risk:                *loadRiskConfiguration(),
+		Lists:               *lists,
+		Tracing:             *tracing,
+		Health:              *health,
+		Auth:                AuthConfiguration{Enabled: os.Getenv("AUTH_ENABLED") != "false"},
+		Encryption:          EncryptionConfiguration{Key: os.Getenv("ENCRYPTION_KEY"), PIIKeyFile: os.Getenv("PII_KEY_FILE")},
+		RateLimit:           *rateLimit,
+		PaymentStore:        *paymentStore,
+		GRPC:                *grpc,
+		OpenAPI:             OpenAPIConfiguration{Validate: os.Getenv("OPENAPI_VALIDATE") == "true"},
+		Events:              *events,
+		Batch:               *batch,
 	}, nil
 }
 
@@ -91,6 +303,11 @@ func loadAppConfiguration() (*AppConfiguration, error) {
 		Port:    os.Getenv("PORT"),
 		AppName: os.Getenv("APP_NAME"),
 	}
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			app.TrustedProxies = append(app.TrustedProxies, proxy)
+		}
+	}
 	if app.Port == "" {
 		return nil, errors.New("PORT is required")
 	}
@@ -99,3 +316,314 @@ func loadAppConfiguration() (*AppConfiguration, error) {
 	}
 	return app, nil
 }
+
+func loadReconciliationConfiguration() (*ReconciliationConfiguration, error) {
+	reconciliation := &ReconciliationConfiguration{
+		Enabled:  os.Getenv("RECONCILIATION_ENABLED") == "true",
+		Interval: 24 * time.Hour,
+		Window:   24 * time.Hour,
+	}
+
+	if v := os.Getenv("RECONCILIATION_INTERVAL"); v != "" {
+		interval, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RECONCILIATION_INTERVAL: %v", err)
+		}
+		reconciliation.Interval = interval
+	}
+
+	if v := os.Getenv("RECONCILIATION_WINDOW"); v != "" {
+		window, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RECONCILIATION_WINDOW: %v", err)
+		}
+		reconciliation.Window = window
+	}
+
+	return reconciliation, nil
+}
+
+func loadAuthorizationExpiryConfiguration() (*AuthorizationExpiryConfiguration, error) {
+	expiry := &AuthorizationExpiryConfiguration{
+		Enabled:     os.Getenv("AUTH_EXPIRY_ENABLED") == "true",
+		Interval:    15 * time.Minute,
+		AlertBefore: 24 * time.Hour,
+		ActBefore:   2 * time.Hour,
+		Action:      os.Getenv("AUTH_EXPIRY_ACTION"),
+	}
+
+	if expiry.Action == "" {
+		expiry.Action = "cancel"
+	}
+	if expiry.Action != "cancel" && expiry.Action != "capture" {
+		return nil, fmt.Errorf("invalid AUTH_EXPIRY_ACTION: %q", expiry.Action)
+	}
+
+	durations := map[string]*time.Duration{
+		"AUTH_EXPIRY_INTERVAL":     &expiry.Interval,
+		"AUTH_EXPIRY_ALERT_BEFORE": &expiry.AlertBefore,
+		"AUTH_EXPIRY_ACT_BEFORE":   &expiry.ActBefore,
+	}
+	for name, target := range durations {
+		v := os.Getenv(name)
+		if v == "" {
+			continue
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", name, err)
+		}
+		*target = d
+	}
+
+	return expiry, nil
+}
+
+func loadFxConfiguration() (*FxConfiguration, error) {
+	fx := &FxConfiguration{
+		Provider:           os.Getenv("FX_PROVIDER"),
+		RatesFile:          os.Getenv("FX_RATES_FILE"),
+		HTTPURL:            os.Getenv("FX_HTTP_URL"),
+		SettlementCurrency: os.Getenv("FX_SETTLEMENT_CURRENCY"),
+		QuoteTTL:           15 * time.Minute,
+	}
+
+	if fx.Provider == "" {
+		fx.Provider = "static"
+	}
+	if fx.SettlementCurrency == "" {
+		fx.SettlementCurrency = "BRL"
+	}
+
+	if v := os.Getenv("FX_QUOTE_TTL"); v != "" {
+		ttl, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid FX_QUOTE_TTL: %v", err)
+		}
+		fx.QuoteTTL = ttl
+	}
+
+	return fx, nil
+}
+
+func loadRiskConfiguration() *RiskConfiguration {
+	return &RiskConfiguration{
+		Enabled:   os.Getenv("RISK_ENABLED") != "false",
+		RulesFile: os.Getenv("RISK_RULES_FILE"),
+	}
+}
+
+func loadListsConfiguration() (*ListsConfiguration, error) {
+	lists := &ListsConfiguration{RefreshInterval: 30 * time.Second}
+
+	if v := os.Getenv("LISTS_REFRESH_INTERVAL"); v != "" {
+		interval, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid LISTS_REFRESH_INTERVAL: %v", err)
+		}
+		lists.RefreshInterval = interval
+	}
+
+	return lists, nil
+}
+
+func loadRateLimitConfiguration() (*RateLimitConfiguration, error) {
+	rateLimit := &RateLimitConfiguration{
+		Enabled:     os.Getenv("RATE_LIMIT_ENABLED") != "false",
+		Store:       "memory",
+		IP:          "50/s",
+		Read:        "100/s",
+		Write:       "10/s",
+		Concurrency: 20,
+	}
+
+	specs := map[string]*string{
+		"RATE_LIMIT_STORE": &rateLimit.Store,
+		"RATE_LIMIT_IP":    &rateLimit.IP,
+		"RATE_LIMIT_READ":  &rateLimit.Read,
+		"RATE_LIMIT_WRITE": &rateLimit.Write,
+	}
+	for env, target := range specs {
+		if v := os.Getenv(env); v != "" {
+			*target = v
+		}
+	}
+
+	switch rateLimit.Store {
+	case "memory", "postgres":
+	default:
+		return nil, fmt.Errorf("invalid RATE_LIMIT_STORE: %q", rateLimit.Store)
+	}
+
+	if v := os.Getenv("RATE_LIMIT_CONCURRENCY"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid RATE_LIMIT_CONCURRENCY: %q", v)
+		}
+		rateLimit.Concurrency = n
+	}
+
+	return rateLimit, nil
+}
+
+func loadTracingConfiguration(appName string) (*TracingConfiguration, error) {
+	tracing := &TracingConfiguration{
+		Exporter:    os.Getenv("TRACING_EXPORTER"),
+		ServiceName: os.Getenv("TRACING_SERVICE_NAME"),
+		SampleRatio: 1,
+	}
+
+	if tracing.Exporter == "" {
+		tracing.Exporter = "none"
+	}
+	switch tracing.Exporter {
+	case "otlp", "stdout", "none":
+	default:
+		return nil, fmt.Errorf("invalid TRACING_EXPORTER %q: must be otlp, stdout or none", tracing.Exporter)
+	}
+
+	if tracing.ServiceName == "" {
+		tracing.ServiceName = appName
+	}
+
+	if v := os.Getenv("TRACING_SAMPLE_RATIO"); v != "" {
+		ratio, err := strconv.ParseFloat(v, 64)
+		if err != nil || ratio < 0 || ratio > 1 {
+			return nil, fmt.Errorf("invalid TRACING_SAMPLE_RATIO: %q", v)
+		}
+		tracing.SampleRatio = ratio
+	}
+
+	return tracing, nil
+}
+
+func loadHealthConfiguration() (*HealthConfiguration, error) {
+	health := &HealthConfiguration{
+		CheckTimeout: 2 * time.Second,
+		CacheTTL:     5 * time.Second,
+		Details:      os.Getenv("HEALTH_DETAILS") == "true",
+	}
+
+	durations := map[string]*time.Duration{
+		"HEALTH_CHECK_TIMEOUT": &health.CheckTimeout,
+		"HEALTH_CACHE_TTL":     &health.CacheTTL,
+	}
+	for env, target := range durations {
+		v := os.Getenv(env)
+		if v == "" {
+			continue
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", env, err)
+		}
+		*target = d
+	}
+
+	return health, nil
+}
+
+func loadPaymentStoreConfiguration() (*PaymentStoreConfiguration, error) {
+	store := &PaymentStoreConfiguration{Store: "crud", SnapshotEvery: 100}
+
+	if v := os.Getenv("PAYMENT_STORE"); v != "" {
+		store.Store = v
+	}
+	switch store.Store {
+	case "crud", "events":
+	default:
+		return nil, fmt.Errorf("invalid PAYMENT_STORE: %q", store.Store)
+	}
+
+	if v := os.Getenv("PAYMENT_SNAPSHOT_EVERY"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid PAYMENT_SNAPSHOT_EVERY: %q", v)
+		}
+		store.SnapshotEvery = n
+	}
+
+	return store, nil
+}
+
+func loadGRPCConfiguration() (*GRPCConfiguration, error) {
+	grpc := &GRPCConfiguration{Port: os.Getenv("GRPC_PORT"), WatchInterval: time.Second}
+
+	if v := os.Getenv("GRPC_WATCH_INTERVAL"); v != "" {
+		interval, err := time.ParseDuration(v)
+		if err != nil || interval <= 0 {
+			return nil, fmt.Errorf("invalid GRPC_WATCH_INTERVAL: %q", v)
+		}
+		grpc.WatchInterval = interval
+	}
+
+	return grpc, nil
+}
+
+func loadEventsConfiguration() (*EventsConfiguration, error) {
+	events := &EventsConfiguration{HeartbeatInterval: 15 * time.Second, MaxStreams: 1000, MaxStreamsPerMerchant: 100}
+
+	if v := os.Getenv("EVENTS_HEARTBEAT_INTERVAL"); v != "" {
+		interval, err := time.ParseDuration(v)
+		if err != nil || interval <= 0 {
+			return nil, fmt.Errorf("invalid EVENTS_HEARTBEAT_INTERVAL: %q", v)
+		}
+		events.HeartbeatInterval = interval
+	}
+
+	limits := map[string]*int{
+		"EVENTS_MAX_STREAMS":              &events.MaxStreams,
+		"EVENTS_MAX_STREAMS_PER_MERCHANT": &events.MaxStreamsPerMerchant,
+	}
+	for env, target := range limits {
+		v := os.Getenv(env)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid %s: %q", env, v)
+		}
+		*target = n
+	}
+
+	return events, nil
+}
+
+func loadBatchConfiguration() (*BatchConfiguration, error) {
+	batch := &BatchConfiguration{Workers: 4, PollInterval: 5 * time.Second, RetryDelay: 30 * time.Second, MaxAttempts: 5}
+
+	durations := map[string]*time.Duration{
+		"BATCH_POLL_INTERVAL": &batch.PollInterval,
+		"BATCH_RETRY_DELAY":   &batch.RetryDelay,
+	}
+	for env, target := range durations {
+		v := os.Getenv(env)
+		if v == "" {
+			continue
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid %s: %q", env, v)
+		}
+		*target = d
+	}
+
+	counts := map[string]*int{
+		"BATCH_WORKERS":      &batch.Workers,
+		"BATCH_MAX_ATTEMPTS": &batch.MaxAttempts,
+	}
+	for env, target := range counts {
+		v := os.Getenv(env)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid %s: %q", env, v)
+		}
+		*target = n
+	}
+
+	return batch, nil
+}
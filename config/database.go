@@ -11,6 +11,7 @@ import (
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	gormLogger "gorm.io/gorm/logger"
+	"gorm.io/plugin/opentelemetry/tracing"
 
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	_ "github.com/lib/pq"
@@ -39,6 +40,15 @@ func NewDatabaseConnection() *gorm.DB {
 		Logger:         dbLogger,
 	})
 
+	if err != nil {
+		log.Fatal("failed to connect to database: ", err)
+	}
+
+	// Queries run with WithContext(ctx) become child spans of the caller.
+	if err := db.Use(tracing.NewPlugin(tracing.WithoutMetrics())); err != nil {
+		log.Fatal("failed to install gorm tracing: ", err)
+	}
+
 	log.Println("✅ Connected to DB")
 	return db
 }
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_payments_created_at;
+DROP INDEX IF EXISTS idx_payments_stripe_id;
+DROP TABLE reconciliation_discrepancies;
+DROP TABLE reconciliation_runs;
@@ -0,0 +1,35 @@
+CREATE TABLE IF NOT EXISTS reconciliation_runs (
+    id                  VARCHAR NOT NULL,
+    status              VARCHAR NOT NULL,
+    range_from          TIMESTAMP NOT NULL,
+    range_to            TIMESTAMP NOT NULL,
+    checked             INT NOT NULL DEFAULT 0,
+    repaired            INT NOT NULL DEFAULT 0,
+    discrepancies       INT NOT NULL DEFAULT 0,
+    error               VARCHAR NOT NULL DEFAULT '',
+    started_at          TIMESTAMP NOT NULL DEFAULT NOW(),
+    finished_at         TIMESTAMP,
+
+    CONSTRAINT pk_reconciliation_runs_id PRIMARY KEY (id)
+    );
+
+CREATE TABLE IF NOT EXISTS reconciliation_discrepancies (
+    id                  VARCHAR NOT NULL,
+    run_id              VARCHAR NOT NULL,
+    kind                VARCHAR NOT NULL,
+    payment_id          VARCHAR NOT NULL DEFAULT '',
+    stripe_id           VARCHAR NOT NULL DEFAULT '',
+    local_status        VARCHAR NOT NULL DEFAULT '',
+    stripe_status       VARCHAR NOT NULL DEFAULT '',
+    local_amount        BIGINT NOT NULL DEFAULT 0,
+    stripe_amount       BIGINT NOT NULL DEFAULT 0,
+    repaired            BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at          TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT pk_reconciliation_discrepancies_id PRIMARY KEY (id),
+    CONSTRAINT fk_reconciliation_discrepancies_run FOREIGN KEY (run_id) REFERENCES reconciliation_runs (id)
+    );
+
+CREATE INDEX IF NOT EXISTS idx_reconciliation_discrepancies_run_id ON reconciliation_discrepancies (run_id);
+CREATE INDEX IF NOT EXISTS idx_payments_stripe_id ON payments (stripe_id);
+CREATE INDEX IF NOT EXISTS idx_payments_created_at ON payments (created_at);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_payments_authorization_expires_at;
+
+ALTER TABLE payments
+    DROP COLUMN IF EXISTS expiry_alerted_at,
+    DROP COLUMN IF EXISTS authorization_expires_at;
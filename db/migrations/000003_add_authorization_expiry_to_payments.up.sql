@@ -0,0 +1,7 @@
+ALTER TABLE payments
+    ADD COLUMN IF NOT EXISTS authorization_expires_at TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS expiry_alerted_at        TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_payments_authorization_expires_at
+    ON payments (authorization_expires_at)
+    WHERE authorization_expires_at IS NOT NULL;
@@ -0,0 +1,7 @@
+ALTER TABLE payments
+    DROP COLUMN IF EXISTS fx_quote_id,
+    DROP COLUMN IF EXISTS fx_rate,
+    DROP COLUMN IF EXISTS settlement_currency,
+    DROP COLUMN IF EXISTS settlement_amount;
+
+DROP TABLE fx_quotes;
@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS fx_quotes (
+    id              VARCHAR NOT NULL,
+    "from"          VARCHAR NOT NULL,
+    "to"            VARCHAR NOT NULL,
+    rate            VARCHAR NOT NULL,
+    expires_at      TIMESTAMP NOT NULL,
+    created_at      TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT pk_fx_quotes_id PRIMARY KEY (id)
+    );
+
+ALTER TABLE payments
+    ADD COLUMN IF NOT EXISTS settlement_amount   BIGINT,
+    ADD COLUMN IF NOT EXISTS settlement_currency VARCHAR,
+    ADD COLUMN IF NOT EXISTS fx_rate             VARCHAR NOT NULL DEFAULT '1',
+    ADD COLUMN IF NOT EXISTS fx_quote_id         VARCHAR NOT NULL DEFAULT '';
+
+UPDATE payments
+SET settlement_amount = amount, settlement_currency = currency
+WHERE settlement_amount IS NULL;
+
+ALTER TABLE payments
+    ALTER COLUMN settlement_amount SET NOT NULL,
+    ALTER COLUMN settlement_amount SET DEFAULT 0,
+    ALTER COLUMN settlement_currency SET NOT NULL,
+    ALTER COLUMN settlement_currency SET DEFAULT '';
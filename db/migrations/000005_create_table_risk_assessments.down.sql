@@ -0,0 +1 @@
+DROP TABLE risk_assessments;
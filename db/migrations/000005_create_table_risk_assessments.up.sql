@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS risk_assessments (
+    id                  VARCHAR NOT NULL,
+    payment_id          VARCHAR NOT NULL,
+    email               VARCHAR NOT NULL DEFAULT '',
+    ip                  VARCHAR NOT NULL DEFAULT '',
+    card_fingerprint    VARCHAR NOT NULL DEFAULT '',
+    country             VARCHAR NOT NULL DEFAULT '',
+    score               INT NOT NULL DEFAULT 0,
+    decision            VARCHAR NOT NULL,
+    matched_rules       JSONB NOT NULL DEFAULT '[]',
+    reviewed_by         VARCHAR NOT NULL DEFAULT '',
+    reviewed_at         TIMESTAMP,
+    created_at          TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT pk_risk_assessments_id PRIMARY KEY (id)
+    );
+
+CREATE INDEX IF NOT EXISTS idx_risk_assessments_payment_id ON risk_assessments (payment_id);
+CREATE INDEX IF NOT EXISTS idx_risk_assessments_email_created_at ON risk_assessments (email, created_at);
+CREATE INDEX IF NOT EXISTS idx_risk_assessments_ip_created_at ON risk_assessments (ip, created_at);
+CREATE INDEX IF NOT EXISTS idx_risk_assessments_card_fingerprint_created_at ON risk_assessments (card_fingerprint, created_at);
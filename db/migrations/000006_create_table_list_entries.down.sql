@@ -0,0 +1,2 @@
+DROP TABLE list_entry_audits;
+DROP TABLE list_entries;
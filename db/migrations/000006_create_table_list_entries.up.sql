@@ -0,0 +1,33 @@
+CREATE TABLE IF NOT EXISTS list_entries (
+    id              VARCHAR NOT NULL,
+    kind            VARCHAR NOT NULL,
+    type            VARCHAR NOT NULL,
+    value           VARCHAR NOT NULL,
+    reason          VARCHAR NOT NULL,
+    created_by      VARCHAR NOT NULL DEFAULT '',
+    expires_at      TIMESTAMP,
+    created_at      TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at      TIMESTAMP NOT NULL DEFAULT NOW(),
+    deleted_at      TIMESTAMP,
+
+    CONSTRAINT pk_list_entries_id PRIMARY KEY (id)
+    );
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_list_entries_active_value
+    ON list_entries (kind, type, value)
+    WHERE deleted_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS list_entry_audits (
+    id              VARCHAR NOT NULL,
+    entry_id        VARCHAR NOT NULL,
+    action          VARCHAR NOT NULL,
+    actor           VARCHAR NOT NULL DEFAULT '',
+    before          JSONB,
+    after           JSONB,
+    created_at      TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT pk_list_entry_audits_id PRIMARY KEY (id),
+    CONSTRAINT fk_list_entry_audits_entry FOREIGN KEY (entry_id) REFERENCES list_entries (id)
+    );
+
+CREATE INDEX IF NOT EXISTS idx_list_entry_audits_entry_id ON list_entry_audits (entry_id);
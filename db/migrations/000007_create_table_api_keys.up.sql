@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS api_keys (
+    id              VARCHAR NOT NULL,
+    merchant_id     VARCHAR NOT NULL,
+    name            VARCHAR NOT NULL DEFAULT '',
+    environment     VARCHAR NOT NULL,
+    prefix          VARCHAR NOT NULL,
+    key_hash        VARCHAR NOT NULL,
+    scopes          VARCHAR NOT NULL,
+    rotated_from    VARCHAR NOT NULL DEFAULT '',
+    expires_at      TIMESTAMP,
+    last_used_at    TIMESTAMP,
+    revoked_at      TIMESTAMP,
+    created_at      TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at      TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT pk_api_keys_id PRIMARY KEY (id),
+    CONSTRAINT uq_api_keys_key_hash UNIQUE (key_hash)
+    );
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_merchant_id ON api_keys (merchant_id);
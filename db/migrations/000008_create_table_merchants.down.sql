@@ -0,0 +1,13 @@
+ALTER TABLE risk_assessments DROP COLUMN merchant_id;
+
+ALTER TABLE payments DROP CONSTRAINT uq_payments_merchant_email;
+ALTER TABLE payments ADD CONSTRAINT uq_payments_email UNIQUE (email);
+
+DROP INDEX IF EXISTS idx_payments_gateway_account_created_at;
+DROP INDEX IF EXISTS idx_payments_merchant_id_created_at;
+
+ALTER TABLE payments
+    DROP COLUMN gateway_account,
+    DROP COLUMN merchant_id;
+
+DROP TABLE merchants;
@@ -0,0 +1,31 @@
+CREATE TABLE IF NOT EXISTS merchants (
+    id                                VARCHAR NOT NULL,
+    name                              VARCHAR NOT NULL,
+    status                            VARCHAR NOT NULL DEFAULT 'ACTIVE',
+    stripe_secret_key_encrypted       VARCHAR NOT NULL DEFAULT '',
+    stripe_webhook_secret_encrypted   VARCHAR NOT NULL DEFAULT '',
+    stripe_payment_method             VARCHAR NOT NULL DEFAULT '',
+    max_payment_amount                BIGINT NOT NULL DEFAULT 0,
+    created_at                        TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at                        TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT pk_merchants_id PRIMARY KEY (id)
+    );
+
+-- Payments created before merchants existed keep an empty merchant_id and
+-- are only visible when authentication is disabled or to background jobs.
+ALTER TABLE payments
+    ADD COLUMN IF NOT EXISTS merchant_id     VARCHAR NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS gateway_account VARCHAR NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_payments_merchant_id_created_at ON payments (merchant_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_payments_gateway_account_created_at ON payments (gateway_account, created_at);
+
+-- A customer's email was unique across the whole table, so paying one
+-- merchant blocked paying any other. Uniqueness is now per merchant.
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS uq_payments_email;
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS payments_email_key;
+ALTER TABLE payments ADD CONSTRAINT uq_payments_merchant_email UNIQUE (merchant_id, email);
+
+ALTER TABLE risk_assessments
+    ADD COLUMN IF NOT EXISTS merchant_id VARCHAR NOT NULL DEFAULT '';
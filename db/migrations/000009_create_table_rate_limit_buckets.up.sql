@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS rate_limit_buckets (
+    key         VARCHAR NOT NULL,
+    tokens      DOUBLE PRECISION NOT NULL,
+    updated_at  TIMESTAMP NOT NULL,
+
+    CONSTRAINT pk_rate_limit_buckets_key PRIMARY KEY (key)
+    );
+
+CREATE INDEX IF NOT EXISTS idx_rate_limit_buckets_updated_at ON rate_limit_buckets (updated_at);
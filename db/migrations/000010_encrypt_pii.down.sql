@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_risk_assessments_email_index_created_at;
+CREATE INDEX IF NOT EXISTS idx_risk_assessments_email_created_at ON risk_assessments (email, created_at);
+
+ALTER TABLE risk_assessments DROP COLUMN IF EXISTS email_index;
+
+DROP INDEX IF EXISTS idx_payments_merchant_email_index;
+ALTER TABLE payments ADD CONSTRAINT uq_payments_merchant_email UNIQUE (merchant_id, email);
+
+ALTER TABLE payments DROP COLUMN IF EXISTS email_index;
@@ -0,0 +1,16 @@
+-- Emails are now encrypted with a random nonce, so equality lookups go
+-- through a blind index. Existing rows keep their plain text email and an
+-- empty index until `pii rotate` encrypts them. A customer may pay the same
+-- merchant any number of times, so the index is only for lookups.
+ALTER TABLE payments
+    ADD COLUMN IF NOT EXISTS email_index VARCHAR NOT NULL DEFAULT '';
+
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS uq_payments_merchant_email;
+CREATE INDEX IF NOT EXISTS idx_payments_merchant_email_index
+    ON payments (merchant_id, email_index) WHERE email_index <> '';
+
+ALTER TABLE risk_assessments
+    ADD COLUMN IF NOT EXISTS email_index VARCHAR NOT NULL DEFAULT '';
+
+DROP INDEX IF EXISTS idx_risk_assessments_email_created_at;
+CREATE INDEX IF NOT EXISTS idx_risk_assessments_email_index_created_at ON risk_assessments (email_index, created_at);
@@ -0,0 +1,41 @@
+-- Snapshots are TEXT rather than JSONB: JSONB normalizes documents, and the
+-- hash chain covers the exact bytes written.
+CREATE TABLE IF NOT EXISTS audit_log (
+    id              VARCHAR NOT NULL,
+    seq             BIGINT NOT NULL,
+    merchant_id     VARCHAR NOT NULL DEFAULT '',
+    actor           VARCHAR NOT NULL,
+    api_key_id      VARCHAR NOT NULL DEFAULT '',
+    ip              VARCHAR NOT NULL DEFAULT '',
+    request_id      VARCHAR NOT NULL DEFAULT '',
+    action          VARCHAR NOT NULL,
+    resource_type   VARCHAR NOT NULL,
+    resource_id     VARCHAR NOT NULL,
+    outcome         VARCHAR NOT NULL,
+    error_code      VARCHAR NOT NULL DEFAULT '',
+    before          TEXT NOT NULL DEFAULT '',
+    after           TEXT NOT NULL DEFAULT '',
+    details         TEXT NOT NULL DEFAULT '',
+    created_at      TIMESTAMP NOT NULL,
+    prev_hash       VARCHAR NOT NULL,
+    hash            VARCHAR NOT NULL,
+
+    CONSTRAINT pk_audit_log_id PRIMARY KEY (id),
+    CONSTRAINT uq_audit_log_seq UNIQUE (seq)
+    );
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_resource ON audit_log (resource_type, resource_id, seq);
+
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_no_update_or_delete
+    BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+
+CREATE TRIGGER audit_log_no_truncate
+    BEFORE TRUNCATE ON audit_log
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
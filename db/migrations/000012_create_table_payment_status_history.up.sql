@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS payment_status_history (
+    id              VARCHAR NOT NULL,
+    payment_id      VARCHAR NOT NULL,
+    merchant_id     VARCHAR NOT NULL DEFAULT '',
+    from_status     VARCHAR NOT NULL DEFAULT '',
+    to_status       VARCHAR NOT NULL,
+    source          VARCHAR NOT NULL,
+    reason          VARCHAR NOT NULL DEFAULT '',
+    error_code      VARCHAR NOT NULL DEFAULT '',
+    created_at      TIMESTAMP NOT NULL,
+
+    CONSTRAINT pk_payment_status_history_id PRIMARY KEY (id)
+    );
+
+CREATE INDEX IF NOT EXISTS idx_payment_status_history_payment ON payment_status_history (payment_id, created_at);
+
+-- Payments created before the timeline existed start it at their current
+-- status; how they got there is unknown.
+INSERT INTO payment_status_history (id, payment_id, merchant_id, from_status, to_status, source, reason, created_at)
+SELECT 'backfill_' || id, id, merchant_id, '', status, 'backfill', 'status when the timeline was introduced', updated_at
+FROM payments;
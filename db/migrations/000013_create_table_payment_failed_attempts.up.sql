@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS payment_failed_attempts (
+    id              VARCHAR NOT NULL,
+    payment_id      VARCHAR NOT NULL,
+    merchant_id     VARCHAR NOT NULL DEFAULT '',
+    operation       VARCHAR NOT NULL,
+    error_code      VARCHAR NOT NULL DEFAULT '',
+    message         VARCHAR NOT NULL DEFAULT '',
+    created_at      TIMESTAMP NOT NULL,
+
+    CONSTRAINT pk_payment_failed_attempts_id PRIMARY KEY (id)
+    );
+
+CREATE INDEX IF NOT EXISTS idx_payment_failed_attempts_payment ON payment_failed_attempts (payment_id, created_at);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS payment_snapshots;
+DROP TABLE IF EXISTS payment_events;
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS payment_events (
+    id              VARCHAR NOT NULL,
+    payment_id      VARCHAR NOT NULL,
+    merchant_id     VARCHAR NOT NULL DEFAULT '',
+    version         BIGINT NOT NULL,
+    type            VARCHAR NOT NULL,
+    data            TEXT NOT NULL,
+    created_at      TIMESTAMP NOT NULL,
+
+    CONSTRAINT pk_payment_events_id PRIMARY KEY (id),
+    CONSTRAINT uq_payment_events_payment_version UNIQUE (payment_id, version)
+    );
+
+-- Only the latest snapshot of each payment is kept.
+CREATE TABLE IF NOT EXISTS payment_snapshots (
+    payment_id      VARCHAR NOT NULL,
+    merchant_id     VARCHAR NOT NULL DEFAULT '',
+    version         BIGINT NOT NULL,
+    state           TEXT NOT NULL,
+    created_at      TIMESTAMP NOT NULL,
+
+    CONSTRAINT pk_payment_snapshots_payment_id PRIMARY KEY (payment_id)
+    );
@@ -0,0 +1,36 @@
+CREATE TABLE IF NOT EXISTS payment_batches (
+    id              VARCHAR NOT NULL,
+    merchant_id     VARCHAR NOT NULL DEFAULT '',
+    key_id          VARCHAR NOT NULL DEFAULT '',
+    created_at      TIMESTAMP NOT NULL,
+
+    CONSTRAINT pk_payment_batches_id PRIMARY KEY (id)
+    );
+
+-- Items are the queue the batch workers claim from; each keeps its own
+-- result.
+CREATE TABLE IF NOT EXISTS payment_batch_items (
+    id              VARCHAR NOT NULL,
+    batch_id        VARCHAR NOT NULL,
+    merchant_id     VARCHAR NOT NULL DEFAULT '',
+    key_id          VARCHAR NOT NULL DEFAULT '',
+    seq             INTEGER NOT NULL,
+    payment_id      VARCHAR NOT NULL,
+    operation       VARCHAR NOT NULL,
+    amount          BIGINT NOT NULL DEFAULT 0,
+    status          VARCHAR NOT NULL,
+    attempts        INTEGER NOT NULL DEFAULT 0,
+    payment_status  VARCHAR NOT NULL DEFAULT '',
+    error_code      VARCHAR NOT NULL DEFAULT '',
+    error_message   VARCHAR NOT NULL DEFAULT '',
+    created_at      TIMESTAMP NOT NULL,
+    available_at    TIMESTAMP NOT NULL,
+    claimed_at      TIMESTAMP,
+    processed_at    TIMESTAMP,
+
+    CONSTRAINT pk_payment_batch_items_id PRIMARY KEY (id),
+    CONSTRAINT fk_payment_batch_items_batch FOREIGN KEY (batch_id) REFERENCES payment_batches (id),
+    CONSTRAINT uq_payment_batch_items_batch_seq UNIQUE (batch_id, seq)
+    );
+
+CREATE INDEX IF NOT EXISTS idx_payment_batch_items_status_available_at ON payment_batch_items (status, available_at);
@@ -0,0 +1,4 @@
+ALTER TABLE merchants
+    DROP COLUMN IF EXISTS expiry_alert_before_seconds,
+    DROP COLUMN IF EXISTS expiry_act_before_seconds,
+    DROP COLUMN IF EXISTS expiry_action;
@@ -0,0 +1,6 @@
+-- A merchant may override the platform's authorization expiry policy.
+-- Zero and empty values keep the platform's.
+ALTER TABLE merchants
+    ADD COLUMN IF NOT EXISTS expiry_alert_before_seconds BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS expiry_act_before_seconds   BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS expiry_action               VARCHAR NOT NULL DEFAULT '';
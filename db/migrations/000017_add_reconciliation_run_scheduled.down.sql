@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_reconciliation_runs_scheduled_range_to;
+
+ALTER TABLE reconciliation_runs
+    DROP COLUMN IF EXISTS scheduled;
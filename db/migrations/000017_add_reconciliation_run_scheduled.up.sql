@@ -0,0 +1,8 @@
+-- The reconciliation worker resumes from the end of its last successful
+-- run instead of re-running a fixed window.
+ALTER TABLE reconciliation_runs
+    ADD COLUMN IF NOT EXISTS scheduled BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_reconciliation_runs_scheduled_range_to
+    ON reconciliation_runs (range_to)
+    WHERE scheduled AND status = 'SUCCEEDED';
@@ -0,0 +1,199 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/williamkoller/payment-system/internal/apikey/domain"
+	auditDomain "github.com/williamkoller/payment-system/internal/audit/domain"
+	merchantDomain "github.com/williamkoller/payment-system/internal/merchant/domain"
+	"github.com/williamkoller/payment-system/pkg/apperror"
+	"github.com/williamkoller/payment-system/pkg/auth"
+	"github.com/williamkoller/payment-system/pkg/logger"
+	"github.com/williamkoller/payment-system/pkg/ulid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrKeyNotFound   = apperror.New(apperror.KindNotFound, "api_key_not_found", "API key not found")
+	ErrInvalidAPIKey = apperror.New(apperror.KindUnauthenticated, "invalid_api_key", "invalid API key")
+)
+
+// lastUsedResolution bounds how often authenticating a key writes its
+// last_used_at, so busy keys do not cost a write per request.
+const lastUsedResolution = time.Minute
+
+type KeyRepository interface {
+	Save(ctx context.Context, key *domain.APIKey) error
+	// SaveRotation stores the replacement and the retired key atomically.
+	SaveRotation(ctx context.Context, retired, replacement *domain.APIKey) error
+	Update(ctx context.Context, key *domain.APIKey) error
+	TouchLastUsed(ctx context.Context, id string, at time.Time) error
+	FindByID(ctx context.Context, id string) (*domain.APIKey, error)
+	FindByHash(ctx context.Context, hash string) (*domain.APIKey, error)
+	FindAll(ctx context.Context, filter KeyFilter) ([]*domain.APIKey, error)
+}
+
+type KeyFilter struct {
+	MerchantID string
+}
+
+type CreateKeyInput struct {
+	MerchantID  string
+	Name        string
+	Environment domain.Environment
+	Scopes      []auth.Scope
+}
+
+// MerchantLookup confirms a key is issued for an existing merchant.
+type MerchantLookup interface {
+	Find(ctx context.Context, id string) (*merchantDomain.Merchant, error)
+}
+
+// Auditor appends entries to the audit log.
+type Auditor interface {
+	Record(ctx context.Context, entry *auditDomain.Entry)
+}
+
+type KeyService struct {
+	Repository KeyRepository
+	// Merchants is optional; without it keys are issued for any merchant id.
+	// Platform keys have no merchant to look up.
+	Merchants MerchantLookup
+	// Audit is optional; without it key changes are not audited.
+	Audit Auditor
+}
+
+func NewKeyService(repository KeyRepository) *KeyService {
+	return &KeyService{Repository: repository}
+}
+
+// Create returns the new key and its secret, which is not retrievable later.
+func (s *KeyService) Create(ctx context.Context, input CreateKeyInput) (*domain.APIKey, string, error) {
+	key, secret, err := domain.NewAPIKey(ulid.NewULID(), input.MerchantID, input.Name, input.Environment, input.Scopes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if s.Merchants != nil && key.MerchantID != "" {
+		if _, err := s.Merchants.Find(ctx, key.MerchantID); err != nil {
+			return nil, "", err
+		}
+	}
+
+	if err := s.Repository.Save(ctx, key); err != nil {
+		return nil, "", err
+	}
+	s.audit(ctx, "api_key.create", key, nil, nil)
+	return key, secret, nil
+}
+
+func (s *KeyService) Find(ctx context.Context, id string) (*domain.APIKey, error) {
+	key, err := s.Repository.FindByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrKeyNotFound.Wrap(err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func (s *KeyService) List(ctx context.Context, filter KeyFilter) ([]*domain.APIKey, error) {
+	return s.Repository.FindAll(ctx, filter)
+}
+
+// Rotate issues a replacement for key id and retires the old one after grace.
+func (s *KeyService) Rotate(ctx context.Context, id string, grace time.Duration) (*domain.APIKey, string, error) {
+	key, err := s.Find(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+
+	before := keySnapshot(key)
+	replacement, secret, err := key.Rotate(ulid.NewULID(), grace)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err := s.Repository.SaveRotation(ctx, key, replacement); err != nil {
+		return nil, "", err
+	}
+	s.audit(ctx, "api_key.rotate", key, before, map[string]any{"replacement_id": replacement.ID, "grace": grace.String()})
+	return replacement, secret, nil
+}
+
+func (s *KeyService) Revoke(ctx context.Context, id string) (*domain.APIKey, error) {
+	key, err := s.Find(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	before := keySnapshot(key)
+	if err := key.Revoke(); err != nil {
+		return nil, err
+	}
+
+	if err := s.Repository.Update(ctx, key); err != nil {
+		return nil, err
+	}
+	s.audit(ctx, "api_key.revoke", key, before, nil)
+	return key, nil
+}
+
+// Authenticate resolves a presented secret to its principal. Unknown,
+// revoked and expired keys are all reported as invalid_api_key.
+func (s *KeyService) Authenticate(ctx context.Context, secret string) (*auth.Principal, error) {
+	key, err := s.Repository.FindByHash(ctx, domain.HashKey(secret))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if !key.IsActive(now) {
+		return nil, ErrInvalidAPIKey.WithMessage("API key is revoked or expired")
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= lastUsedResolution {
+		if err := s.Repository.TouchLastUsed(ctx, key.ID, now); err != nil {
+			logger.Error("cannot record API key usage", "api_key_id", key.ID, "err", err)
+		}
+	}
+
+	return key.Principal(), nil
+}
+
+func (s *KeyService) audit(ctx context.Context, action string, key *domain.APIKey, before, details map[string]any) {
+	if s.Audit == nil {
+		return
+	}
+	entry := auditDomain.NewEntry(ctx, ulid.NewULID(), action, auditDomain.Resource{
+		Type:       auditDomain.ResourceAPIKey,
+		ID:         key.ID,
+		MerchantID: key.MerchantID,
+	})
+	entry.SetSnapshots(before, keySnapshot(key))
+	if details != nil {
+		entry.SetDetails(details)
+	}
+	s.Audit.Record(ctx, entry)
+}
+
+// keySnapshot is what the audit log keeps of a key: never its hash.
+func keySnapshot(k *domain.APIKey) map[string]any {
+	return map[string]any{
+		"id":           k.ID,
+		"merchant_id":  k.MerchantID,
+		"name":         k.Name,
+		"environment":  k.Environment,
+		"prefix":       k.Prefix,
+		"scopes":       k.ScopeList(),
+		"rotated_from": k.RotatedFrom,
+		"expires_at":   k.ExpiresAt,
+		"revoked_at":   k.RevokedAt,
+	}
+}
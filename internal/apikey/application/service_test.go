@@ -0,0 +1,194 @@
+package application_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/williamkoller/payment-system/internal/apikey/application"
+	"github.com/williamkoller/payment-system/internal/apikey/domain"
+	"github.com/williamkoller/payment-system/pkg/auth"
+	"gorm.io/gorm"
+)
+
+type fakeKeys struct {
+	keys    map[string]*domain.APIKey
+	touches int
+}
+
+func newFakeKeys() *fakeKeys {
+	return &fakeKeys{keys: map[string]*domain.APIKey{}}
+}
+
+func (f *fakeKeys) Save(_ context.Context, key *domain.APIKey) error {
+	f.keys[key.ID] = key
+	return nil
+}
+
+func (f *fakeKeys) SaveRotation(_ context.Context, retired, replacement *domain.APIKey) error {
+	f.keys[retired.ID] = retired
+	f.keys[replacement.ID] = replacement
+	return nil
+}
+
+func (f *fakeKeys) Update(_ context.Context, key *domain.APIKey) error {
+	f.keys[key.ID] = key
+	return nil
+}
+
+func (f *fakeKeys) TouchLastUsed(_ context.Context, id string, at time.Time) error {
+	f.touches++
+	f.keys[id].LastUsedAt = &at
+	return nil
+}
+
+func (f *fakeKeys) FindByID(_ context.Context, id string) (*domain.APIKey, error) {
+	if key, ok := f.keys[id]; ok {
+		return key, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f *fakeKeys) FindByHash(_ context.Context, hash string) (*domain.APIKey, error) {
+	for _, key := range f.keys {
+		if key.KeyHash == hash {
+			return key, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f *fakeKeys) FindAll(context.Context, application.KeyFilter) ([]*domain.APIKey, error) {
+	return nil, nil
+}
+
+func TestKeyService_CreateAndAuthenticate(t *testing.T) {
+	repo := newFakeKeys()
+	service := application.NewKeyService(repo)
+	ctx := context.Background()
+
+	key, secret, err := service.Create(ctx, application.CreateKeyInput{
+		MerchantID:  "merchant_1",
+		Environment: domain.EnvironmentLive,
+		Scopes:      []auth.Scope{auth.ScopePaymentsRead, auth.ScopePaymentsRead, auth.ScopeRefundsWrite},
+	})
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(secret, "sk_live_"))
+	assert.True(t, strings.HasPrefix(secret, key.Prefix))
+	assert.NotContains(t, key.KeyHash, secret)
+	assert.Equal(t, "payments:read refunds:write", key.Scopes)
+
+	principal, err := service.Authenticate(ctx, secret)
+	require.NoError(t, err)
+	assert.Equal(t, "merchant_1", principal.MerchantID)
+	assert.True(t, principal.Live)
+	assert.True(t, principal.HasScope(auth.ScopeRefundsWrite))
+	assert.False(t, principal.HasScope(auth.ScopePaymentsWrite))
+
+	_, err = service.Authenticate(ctx, secret)
+	require.NoError(t, err)
+	assert.Equal(t, 1, repo.touches, "last_used_at is written at most once per minute")
+	assert.NotNil(t, repo.keys[key.ID].LastUsedAt)
+
+	_, err = service.Authenticate(ctx, "sk_live_unknown")
+	assert.ErrorIs(t, err, application.ErrInvalidAPIKey)
+}
+
+func TestKeyService_Create_RejectsUnknownScope(t *testing.T) {
+	_, _, err := application.NewKeyService(newFakeKeys()).Create(context.Background(), application.CreateKeyInput{
+		MerchantID:  "merchant_1",
+		Environment: domain.EnvironmentTest,
+		Scopes:      []auth.Scope{"payments:delete"},
+	})
+	assert.ErrorIs(t, err, domain.ErrInvalidScope)
+}
+
+func TestKeyService_Create_PlatformKeys(t *testing.T) {
+	service := application.NewKeyService(newFakeKeys())
+	ctx := context.Background()
+
+	key, secret, err := service.Create(ctx, application.CreateKeyInput{
+		Environment: domain.EnvironmentLive,
+		Scopes:      []auth.Scope{auth.ScopePlatform},
+	})
+	require.NoError(t, err)
+	principal, err := service.Authenticate(ctx, secret)
+	require.NoError(t, err)
+	assert.Empty(t, principal.MerchantID)
+	assert.True(t, principal.HasScope(auth.ScopePlatform))
+	assert.False(t, principal.HasScope(auth.ScopePaymentsRead), "platform keys cannot act for merchants")
+	assert.Empty(t, key.MerchantID)
+
+	_, _, err = service.Create(ctx, application.CreateKeyInput{
+		MerchantID:  "merchant_1",
+		Environment: domain.EnvironmentLive,
+		Scopes:      []auth.Scope{auth.ScopePlatform},
+	})
+	assert.ErrorIs(t, err, domain.ErrPlatformMerchant)
+
+	_, _, err = service.Create(ctx, application.CreateKeyInput{
+		Environment: domain.EnvironmentLive,
+		Scopes:      []auth.Scope{auth.ScopePlatform, auth.ScopePaymentsRead},
+	})
+	assert.ErrorIs(t, err, domain.ErrInvalidScope)
+
+	_, _, err = service.Create(ctx, application.CreateKeyInput{
+		Environment: domain.EnvironmentLive,
+		Scopes:      []auth.Scope{auth.ScopeAdmin},
+	})
+	assert.ErrorIs(t, err, domain.ErrMerchantRequired)
+}
+
+func TestKeyService_Rotate(t *testing.T) {
+	service := application.NewKeyService(newFakeKeys())
+	ctx := context.Background()
+
+	old, oldSecret, err := service.Create(ctx, application.CreateKeyInput{
+		MerchantID:  "merchant_1",
+		Environment: domain.EnvironmentTest,
+		Scopes:      []auth.Scope{auth.ScopeAdmin},
+	})
+	require.NoError(t, err)
+
+	replacement, newSecret, err := service.Rotate(ctx, old.ID, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, old.ID, replacement.RotatedFrom)
+	assert.Equal(t, old.Scopes, replacement.Scopes)
+	assert.NotEqual(t, oldSecret, newSecret)
+
+	_, err = service.Authenticate(ctx, oldSecret)
+	assert.NoError(t, err, "old key keeps working during the grace period")
+
+	_, _, err = service.Rotate(ctx, replacement.ID, 0)
+	require.NoError(t, err)
+	_, err = service.Authenticate(ctx, newSecret)
+	assert.ErrorIs(t, err, application.ErrInvalidAPIKey, "zero grace revokes immediately")
+}
+
+func TestKeyService_Revoke(t *testing.T) {
+	service := application.NewKeyService(newFakeKeys())
+	ctx := context.Background()
+
+	key, secret, err := service.Create(ctx, application.CreateKeyInput{
+		MerchantID:  "merchant_1",
+		Environment: domain.EnvironmentTest,
+		Scopes:      []auth.Scope{auth.ScopePaymentsRead},
+	})
+	require.NoError(t, err)
+
+	_, err = service.Revoke(ctx, key.ID)
+	require.NoError(t, err)
+
+	_, err = service.Authenticate(ctx, secret)
+	assert.ErrorIs(t, err, application.ErrInvalidAPIKey)
+
+	_, err = service.Revoke(ctx, key.ID)
+	assert.ErrorIs(t, err, domain.ErrKeyRevoked)
+
+	_, err = service.Revoke(ctx, "missing")
+	assert.ErrorIs(t, err, application.ErrKeyNotFound)
+}
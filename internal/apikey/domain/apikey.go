@@ -0,0 +1,181 @@
+package domain
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/williamkoller/payment-system/pkg/apperror"
+	"github.com/williamkoller/payment-system/pkg/auth"
+)
+
+var (
+	ErrMerchantRequired   = apperror.New(apperror.KindValidation, "merchant_required", "merchant_id is required")
+	ErrPlatformMerchant   = apperror.New(apperror.KindValidation, "platform_key_merchant", "platform keys do not belong to a merchant")
+	ErrInvalidEnvironment = apperror.New(apperror.KindValidation, "invalid_environment", "environment must be test or live")
+	ErrInvalidScope       = apperror.New(apperror.KindValidation, "invalid_scope", "invalid scope")
+	ErrKeyRevoked         = apperror.New(apperror.KindInvalidTransition, "api_key_revoked", "API key is revoked")
+)
+
+type Environment string
+
+const (
+	EnvironmentTest Environment = "test"
+	EnvironmentLive Environment = "live"
+)
+
+const (
+	secretBytes = 24
+	// prefixLength keeps "sk_live_" plus four characters of the secret, enough
+	// for operators to tell keys apart in listings and logs.
+	prefixLength = 12
+)
+
+// APIKey is stored without its secret: only the SHA-256 of the key is kept,
+// and the plain key is handed out once, when it is created or rotated.
+type APIKey struct {
+	ID          string
+	MerchantID  string
+	Name        string
+	Environment Environment
+	Prefix      string
+	KeyHash     string
+	// Scopes is the space-separated list of granted auth.Scope values.
+	Scopes      string
+	RotatedFrom string
+	ExpiresAt   *time.Time
+	LastUsedAt  *time.Time
+	RevokedAt   *time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// NewAPIKey returns the key record and the plain secret to give the caller.
+// Platform keys have no merchant and no other scope; every other key acts
+// for merchantID.
+func NewAPIKey(id, merchantID, name string, env Environment, scopes []auth.Scope) (*APIKey, string, error) {
+	platform := slices.Contains(scopes, auth.ScopePlatform)
+	switch {
+	case platform && merchantID != "":
+		return nil, "", ErrPlatformMerchant
+	case !platform && strings.TrimSpace(merchantID) == "":
+		return nil, "", ErrMerchantRequired
+	}
+	if env != EnvironmentTest && env != EnvironmentLive {
+		return nil, "", ErrInvalidEnvironment
+	}
+	if len(scopes) == 0 {
+		return nil, "", ErrInvalidScope.WithMessage("at least one scope is required")
+	}
+
+	granted := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if !scope.Valid() {
+			return nil, "", ErrInvalidScope.WithMessage(fmt.Sprintf("invalid scope: %q", scope))
+		}
+		if !slices.Contains(granted, string(scope)) {
+			granted = append(granted, string(scope))
+		}
+	}
+	if platform && len(granted) > 1 {
+		return nil, "", ErrInvalidScope.WithMessage("the platform scope cannot be combined with other scopes")
+	}
+
+	secret, err := newSecret(env)
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	return &APIKey{
+		ID:          id,
+		MerchantID:  merchantID,
+		Name:        name,
+		Environment: env,
+		Prefix:      secret[:prefixLength],
+		KeyHash:     HashKey(secret),
+		Scopes:      strings.Join(granted, " "),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}, secret, nil
+}
+
+// HashKey is how keys are looked up. A plain SHA-256 is enough because the
+// secrets are random, not user-chosen passwords.
+func HashKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func newSecret(env Environment) (string, error) {
+	raw := make([]byte, secretBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("cannot generate API key: %w", err)
+	}
+	return "sk_" + string(env) + "_" + base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+func (k *APIKey) ScopeList() []auth.Scope {
+	fields := strings.Fields(k.Scopes)
+	scopes := make([]auth.Scope, 0, len(fields))
+	for _, f := range fields {
+		scopes = append(scopes, auth.Scope(f))
+	}
+	return scopes
+}
+
+func (k *APIKey) IsActive(now time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || now.Before(*k.ExpiresAt)
+}
+
+func (k *APIKey) Principal() *auth.Principal {
+	return &auth.Principal{
+		KeyID:      k.ID,
+		MerchantID: k.MerchantID,
+		Live:       k.Environment == EnvironmentLive,
+		Scopes:     k.ScopeList(),
+	}
+}
+
+// Rotate issues a replacement with the same merchant, name and scopes. The
+// old key keeps working for grace so clients can roll over; a zero grace
+// revokes it at once.
+func (k *APIKey) Rotate(newID string, grace time.Duration) (*APIKey, string, error) {
+	if k.RevokedAt != nil {
+		return nil, "", ErrKeyRevoked
+	}
+
+	replacement, secret, err := NewAPIKey(newID, k.MerchantID, k.Name, k.Environment, k.ScopeList())
+	if err != nil {
+		return nil, "", err
+	}
+	replacement.RotatedFrom = k.ID
+
+	now := time.Now()
+	if grace <= 0 {
+		k.RevokedAt = &now
+	} else if expiresAt := now.Add(grace); k.ExpiresAt == nil || expiresAt.Before(*k.ExpiresAt) {
+		k.ExpiresAt = &expiresAt
+	}
+	k.UpdatedAt = now
+
+	return replacement, secret, nil
+}
+
+func (k *APIKey) Revoke() error {
+	if k.RevokedAt != nil {
+		return ErrKeyRevoked
+	}
+	now := time.Now()
+	k.RevokedAt = &now
+	k.UpdatedAt = now
+	return nil
+}
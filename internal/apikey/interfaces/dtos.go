@@ -0,0 +1,28 @@
+package interfaces
+
+import (
+	"github.com/williamkoller/payment-system/internal/apikey/domain"
+	"github.com/williamkoller/payment-system/pkg/auth"
+)
+
+type CreateKeyDto struct {
+	// MerchantID is required unless the key is a platform key.
+	MerchantID  string             `json:"merchant_id"`
+	Name        string             `json:"name"`
+	Environment domain.Environment `json:"environment" binding:"required,oneof=test live"`
+	Scopes      []auth.Scope       `json:"scopes" binding:"required,min=1,dive,oneof=payments:read payments:write refunds:write reviews:write admin platform"`
+}
+
+type RotateKeyDto struct {
+	// GracePeriodSeconds keeps the old key valid while clients switch over;
+	// defaults to 24 hours, 0 revokes it immediately.
+	GracePeriodSeconds *int64 `json:"grace_period_seconds" binding:"omitempty,min=0"`
+}
+
+type ListKeysDto struct {
+	MerchantID string `form:"merchant_id"`
+}
+
+type IdentifyKeyDto struct {
+	KeyID string `uri:"key_id" binding:"required"`
+}
@@ -0,0 +1,119 @@
+package interfaces
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/williamkoller/payment-system/internal/apikey/application"
+	"github.com/williamkoller/payment-system/internal/middleware"
+	"github.com/williamkoller/payment-system/pkg/apperror"
+)
+
+const defaultGracePeriod = 24 * time.Hour
+
+type KeyHandler struct {
+	Service *application.KeyService
+}
+
+func NewKeyHandler(service *application.KeyService) *KeyHandler {
+	return &KeyHandler{Service: service}
+}
+
+func (h *KeyHandler) CreateKey(c *gin.Context) {
+	var dto CreateKeyDto
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		middleware.Problem(c, apperror.Validation(err))
+		return
+	}
+
+	key, secret, err := h.Service.Create(c.Request.Context(), application.CreateKeyInput{
+		MerchantID:  dto.MerchantID,
+		Name:        dto.Name,
+		Environment: dto.Environment,
+		Scopes:      dto.Scopes,
+	})
+	if err != nil {
+		middleware.Problem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, ToCreatedKeyResponse(key, secret))
+}
+
+func (h *KeyHandler) ListKeys(c *gin.Context) {
+	var query ListKeysDto
+	if err := c.ShouldBindQuery(&query); err != nil {
+		middleware.Problem(c, apperror.Validation(err))
+		return
+	}
+
+	keys, err := h.Service.List(c.Request.Context(), application.KeyFilter{MerchantID: query.MerchantID})
+	if err != nil {
+		middleware.Problem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ToKeyResponses(keys))
+}
+
+func (h *KeyHandler) GetKey(c *gin.Context) {
+	var uri IdentifyKeyDto
+	if err := c.ShouldBindUri(&uri); err != nil {
+		middleware.Problem(c, apperror.Validation(err))
+		return
+	}
+
+	key, err := h.Service.Find(c.Request.Context(), uri.KeyID)
+	if err != nil {
+		middleware.Problem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ToKeyResponse(key))
+}
+
+func (h *KeyHandler) RotateKey(c *gin.Context) {
+	var uri IdentifyKeyDto
+	if err := c.ShouldBindUri(&uri); err != nil {
+		middleware.Problem(c, apperror.Validation(err))
+		return
+	}
+
+	var dto RotateKeyDto
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&dto); err != nil {
+			middleware.Problem(c, apperror.Validation(err))
+			return
+		}
+	}
+
+	grace := defaultGracePeriod
+	if dto.GracePeriodSeconds != nil {
+		grace = time.Duration(*dto.GracePeriodSeconds) * time.Second
+	}
+
+	key, secret, err := h.Service.Rotate(c.Request.Context(), uri.KeyID, grace)
+	if err != nil {
+		middleware.Problem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, ToCreatedKeyResponse(key, secret))
+}
+
+func (h *KeyHandler) RevokeKey(c *gin.Context) {
+	var uri IdentifyKeyDto
+	if err := c.ShouldBindUri(&uri); err != nil {
+		middleware.Problem(c, apperror.Validation(err))
+		return
+	}
+
+	key, err := h.Service.Revoke(c.Request.Context(), uri.KeyID)
+	if err != nil {
+		middleware.Problem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ToKeyResponse(key))
+}
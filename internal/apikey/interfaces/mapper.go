@@ -0,0 +1,60 @@
+package interfaces
+
+import (
+	"time"
+
+	"github.com/williamkoller/payment-system/internal/apikey/domain"
+	"github.com/williamkoller/payment-system/pkg/auth"
+)
+
+type KeyResponse struct {
+	ID          string             `json:"id"`
+	MerchantID  string             `json:"merchant_id"`
+	Name        string             `json:"name"`
+	Environment domain.Environment `json:"environment"`
+	Prefix      string             `json:"prefix"`
+	Scopes      []auth.Scope       `json:"scopes"`
+	RotatedFrom string             `json:"rotated_from,omitempty"`
+	Active      bool               `json:"active"`
+	ExpiresAt   *time.Time         `json:"expires_at"`
+	LastUsedAt  *time.Time         `json:"last_used_at"`
+	RevokedAt   *time.Time         `json:"revoked_at"`
+	CreatedAt   time.Time          `json:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+}
+
+// CreatedKeyResponse is the only response that includes the secret.
+type CreatedKeyResponse struct {
+	KeyResponse
+	Key string `json:"key"`
+}
+
+func ToKeyResponse(k *domain.APIKey) KeyResponse {
+	return KeyResponse{
+		ID:          k.ID,
+		MerchantID:  k.MerchantID,
+		Name:        k.Name,
+		Environment: k.Environment,
+		Prefix:      k.Prefix,
+		Scopes:      k.ScopeList(),
+		RotatedFrom: k.RotatedFrom,
+		Active:      k.IsActive(time.Now()),
+		ExpiresAt:   k.ExpiresAt,
+		LastUsedAt:  k.LastUsedAt,
+		RevokedAt:   k.RevokedAt,
+		CreatedAt:   k.CreatedAt,
+		UpdatedAt:   k.UpdatedAt,
+	}
+}
+
+func ToKeyResponses(keys []*domain.APIKey) []KeyResponse {
+	responses := make([]KeyResponse, 0, len(keys))
+	for _, k := range keys {
+		responses = append(responses, ToKeyResponse(k))
+	}
+	return responses
+}
+
+func ToCreatedKeyResponse(k *domain.APIKey, secret string) CreatedKeyResponse {
+	return CreatedKeyResponse{KeyResponse: ToKeyResponse(k), Key: secret}
+}
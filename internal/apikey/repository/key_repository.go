@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/williamkoller/payment-system/internal/apikey/application"
+	"github.com/williamkoller/payment-system/internal/apikey/domain"
+	"gorm.io/gorm"
+)
+
+type KeyRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewKeyRepository(db *gorm.DB) *KeyRepositoryImpl {
+	return &KeyRepositoryImpl{db: db}
+}
+
+func (r *KeyRepositoryImpl) Save(ctx context.Context, key *domain.APIKey) error {
+	return r.db.WithContext(ctx).Create(key).Error
+}
+
+func (r *KeyRepositoryImpl) SaveRotation(ctx context.Context, retired, replacement *domain.APIKey) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(replacement).Error; err != nil {
+			return err
+		}
+		return update(tx, retired)
+	})
+}
+
+func (r *KeyRepositoryImpl) Update(ctx context.Context, key *domain.APIKey) error {
+	return update(r.db.WithContext(ctx), key)
+}
+
+func update(db *gorm.DB, key *domain.APIKey) error {
+	return db.Model(&domain.APIKey{}).
+		Select("ExpiresAt", "RevokedAt", "UpdatedAt").
+		Where("id = ?", key.ID).
+		Updates(key).Error
+}
+
+func (r *KeyRepositoryImpl) TouchLastUsed(ctx context.Context, id string, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&domain.APIKey{}).
+		Where("id = ?", id).
+		UpdateColumn("last_used_at", at).Error
+}
+
+func (r *KeyRepositoryImpl) FindByID(ctx context.Context, id string) (*domain.APIKey, error) {
+	var key domain.APIKey
+	if err := r.db.WithContext(ctx).First(&key, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *KeyRepositoryImpl) FindByHash(ctx context.Context, hash string) (*domain.APIKey, error) {
+	var key domain.APIKey
+	if err := r.db.WithContext(ctx).First(&key, "key_hash = ?", hash).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *KeyRepositoryImpl) FindAll(ctx context.Context, filter application.KeyFilter) ([]*domain.APIKey, error) {
+	query := r.db.WithContext(ctx)
+	if filter.MerchantID != "" {
+		query = query.Where("merchant_id = ?", filter.MerchantID)
+	}
+
+	var keys []*domain.APIKey
+	if err := query.Order("created_at DESC").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
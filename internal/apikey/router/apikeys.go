@@ -0,0 +1,27 @@
+package router
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/williamkoller/payment-system/internal/apikey/application"
+	"github.com/williamkoller/payment-system/internal/apikey/interfaces"
+	"github.com/williamkoller/payment-system/internal/apikey/repository"
+	"github.com/williamkoller/payment-system/internal/middleware"
+	"github.com/williamkoller/payment-system/pkg/auth"
+	"gorm.io/gorm"
+)
+
+func NewKeyService(db *gorm.DB) *application.KeyService {
+	return application.NewKeyService(repository.NewKeyRepository(db))
+}
+
+func SetupRouter(e *gin.Engine, service *application.KeyService, authn gin.HandlerFunc) {
+	handler := interfaces.NewKeyHandler(service)
+	keys := e.Group("/admin/api-keys", authn, middleware.RequireScope(auth.ScopePlatform))
+	{
+		keys.POST("/", handler.CreateKey)
+		keys.GET("/", handler.ListKeys)
+		keys.GET("/:key_id", handler.GetKey)
+		keys.POST("/:key_id/rotate", handler.RotateKey)
+		keys.DELETE("/:key_id", handler.RevokeKey)
+	}
+}
@@ -0,0 +1,73 @@
+package application
+
+import (
+	"context"
+
+	"github.com/williamkoller/payment-system/internal/audit/domain"
+	"github.com/williamkoller/payment-system/pkg/logger"
+)
+
+const verifyBatchSize = 1000
+
+type AuditRepository interface {
+	Append(ctx context.Context, entry *domain.Entry) error
+	FindByResource(ctx context.Context, resourceType, resourceID string) ([]*domain.Entry, error)
+	FindAfter(ctx context.Context, seq int64, limit int) ([]*domain.Entry, error)
+}
+
+type AuditService struct {
+	Repository AuditRepository
+}
+
+func NewAuditService(repository AuditRepository) *AuditService {
+	return &AuditService{Repository: repository}
+}
+
+// Record appends entry. The action it describes has already happened, so
+// a failure to record it is logged rather than failing the request.
+func (s *AuditService) Record(ctx context.Context, entry *domain.Entry) {
+	if err := s.Repository.Append(ctx, entry); err != nil {
+		logger.Error("cannot write audit log entry",
+			"action", entry.Action,
+			"resource_type", entry.ResourceType,
+			"resource_id", entry.ResourceID,
+			"err", err,
+		)
+	}
+}
+
+func (s *AuditService) ListForPayment(ctx context.Context, paymentID string) ([]*domain.Entry, error) {
+	return s.Repository.FindByResource(ctx, domain.ResourcePayment, paymentID)
+}
+
+// VerifyReport is the outcome of walking the whole chain.
+type VerifyReport struct {
+	Checked int64
+	// Head is the hash of the last intact entry.
+	Head string
+	// Break is the first entry where the chain does not hold, nil when it
+	// is intact.
+	Break *domain.Break
+}
+
+// Verify walks every entry in order, recomputing the hash chain.
+func (s *AuditService) Verify(ctx context.Context) (*VerifyReport, error) {
+	var verifier domain.ChainVerifier
+	var seq int64
+
+	for {
+		entries, err := s.Repository.FindAfter(ctx, seq, verifyBatchSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			if b := verifier.Next(e); b != nil {
+				return &VerifyReport{Checked: verifier.Checked, Head: verifier.Head(), Break: b}, nil
+			}
+			seq = e.Seq
+		}
+		if len(entries) < verifyBatchSize {
+			return &VerifyReport{Checked: verifier.Checked, Head: verifier.Head()}, nil
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package application_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/williamkoller/payment-system/internal/audit/application"
+	"github.com/williamkoller/payment-system/internal/audit/domain"
+)
+
+type fakeLog []*domain.Entry
+
+func (f *fakeLog) Append(_ context.Context, e *domain.Entry) error {
+	var prevSeq int64
+	var prevHash string
+	if n := len(*f); n > 0 {
+		prevSeq, prevHash = (*f)[n-1].Seq, (*f)[n-1].Hash
+	}
+	e.Chain(prevSeq, prevHash)
+	*f = append(*f, e)
+	return nil
+}
+
+func (f *fakeLog) FindByResource(_ context.Context, resourceType, resourceID string) ([]*domain.Entry, error) {
+	var entries []*domain.Entry
+	for _, e := range *f {
+		if e.ResourceType == resourceType && e.ResourceID == resourceID {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+func (f *fakeLog) FindAfter(_ context.Context, seq int64, limit int) ([]*domain.Entry, error) {
+	var entries []*domain.Entry
+	for _, e := range *f {
+		if e.Seq > seq && len(entries) < limit {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+func record(ctx context.Context, service *application.AuditService, id, action string) {
+	entry := domain.NewEntry(ctx, id, action, domain.Resource{Type: domain.ResourcePayment, ID: "pay_1", MerchantID: "m_1"})
+	entry.SetSnapshots(map[string]any{"status": "COMPLETED"}, map[string]any{"status": "CAPTURED"})
+	service.Record(ctx, entry)
+}
+
+func TestAuditService_VerifyDetectsTampering(t *testing.T) {
+	log := &fakeLog{}
+	service := application.NewAuditService(log)
+	ctx := context.Background()
+
+	record(ctx, service, "a1", "payment.create")
+	record(ctx, service, "a2", "payment.capture")
+	record(ctx, service, "a3", "payment.refund")
+
+	report, err := service.Verify(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, report.Break)
+	assert.EqualValues(t, 3, report.Checked)
+	assert.Equal(t, (*log)[2].Hash, report.Head)
+
+	(*log)[1].After = `{"status":"CANCELED"}`
+
+	report, err = service.Verify(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, report.Break)
+	assert.EqualValues(t, 2, report.Break.Seq)
+	assert.EqualValues(t, 1, report.Checked)
+}
+
+func TestAuditService_VerifyDetectsRemovedEntries(t *testing.T) {
+	log := &fakeLog{}
+	service := application.NewAuditService(log)
+	ctx := context.Background()
+
+	record(ctx, service, "a1", "payment.create")
+	record(ctx, service, "a2", "payment.capture")
+	record(ctx, service, "a3", "payment.refund")
+	*log = append((*log)[:1], (*log)[2:]...)
+
+	report, err := service.Verify(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, report.Break)
+	assert.EqualValues(t, 3, report.Break.Seq)
+}
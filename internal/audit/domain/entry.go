@@ -0,0 +1,165 @@
+package domain
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/williamkoller/payment-system/pkg/auth"
+	"github.com/williamkoller/payment-system/pkg/clientip"
+	"github.com/williamkoller/payment-system/pkg/requestid"
+)
+
+const (
+	ResourcePayment   = "payment"
+	ResourceAPIKey    = "api_key"
+	ResourceMerchant  = "merchant"
+	ResourceListEntry = "list_entry"
+
+	OutcomeSucceeded = "succeeded"
+	OutcomeFailed    = "failed"
+
+	ActorSystem    = "system"
+	ActorAnonymous = "anonymous"
+)
+
+// Entry is one line of the audit log. Entries are chained: Hash covers
+// every other field, PrevHash included, so editing, removing or reordering
+// any entry breaks the chain from that point on.
+type Entry struct {
+	ID           string
+	Seq          int64
+	MerchantID   string
+	Actor        string
+	APIKeyID     string
+	IP           string
+	RequestID    string
+	Action       string
+	ResourceType string
+	ResourceID   string
+	Outcome      string
+	ErrorCode    string
+	// Before, After and Details are JSON documents, "" when absent. They
+	// are stored as text so the bytes hashed are the bytes read back.
+	Before    string
+	After     string
+	Details   string
+	CreatedAt time.Time
+	PrevHash  string
+	Hash      string
+}
+
+// Resource identifies what an entry is about.
+type Resource struct {
+	Type       string
+	ID         string
+	MerchantID string
+}
+
+// NewEntry starts an entry for action on resource, taking the actor, API
+// key, IP and request id from ctx.
+func NewEntry(ctx context.Context, id, action string, resource Resource) *Entry {
+	e := &Entry{
+		ID:           id,
+		MerchantID:   resource.MerchantID,
+		Actor:        Actor(ctx),
+		IP:           clientip.FromContext(ctx),
+		RequestID:    requestid.FromContext(ctx),
+		Action:       action,
+		ResourceType: resource.Type,
+		ResourceID:   resource.ID,
+		Outcome:      OutcomeSucceeded,
+		// Postgres keeps microseconds; truncating up front keeps the hash
+		// stable across a round trip.
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+	}
+
+	if principal := auth.FromContext(ctx); principal != nil {
+		e.APIKeyID = principal.KeyID
+	}
+
+	return e
+}
+
+// SetSnapshots records the resource before and after the action; either
+// may be nil.
+func (e *Entry) SetSnapshots(before, after map[string]any) {
+	e.Before = marshal(before)
+	e.After = marshal(after)
+}
+
+func (e *Entry) SetDetails(details map[string]any) {
+	e.Details = marshal(details)
+}
+
+// Fail marks the entry as a failed attempt with the error's stable code.
+func (e *Entry) Fail(code string) {
+	e.Outcome = OutcomeFailed
+	e.ErrorCode = code
+}
+
+// Chain places the entry after the one with prevSeq and prevHash, and
+// seals it.
+func (e *Entry) Chain(prevSeq int64, prevHash string) {
+	e.Seq = prevSeq + 1
+	e.PrevHash = prevHash
+	e.Hash = e.ComputeHash()
+}
+
+// ComputeHash hashes every field but Hash itself.
+func (e *Entry) ComputeHash() string {
+	// The field order of the anonymous struct fixes the encoding.
+	raw, _ := json.Marshal(struct {
+		ID, MerchantID, Actor, APIKeyID, IP, RequestID, Action string
+		ResourceType, ResourceID, Outcome, ErrorCode           string
+		Before, After, Details                                 string
+		Seq                                                    int64
+		CreatedAt                                              string
+		PrevHash                                               string
+	}{
+		e.ID, e.MerchantID, e.Actor, e.APIKeyID, e.IP, e.RequestID, e.Action,
+		e.ResourceType, e.ResourceID, e.Outcome, e.ErrorCode,
+		e.Before, e.After, e.Details,
+		e.Seq,
+		e.CreatedAt.UTC().Format(time.RFC3339Nano),
+		e.PrevHash,
+	})
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:])
+}
+
+func marshal(v map[string]any) string {
+	if v == nil {
+		return ""
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(raw)
+}
+
+type actorKey struct{}
+
+// WithActor names the actor of work that is not done on behalf of an API
+// key, e.g. a background job.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// Actor names who ctx acts for: the actor set with WithActor, else the
+// API key of the request, else the system.
+func Actor(ctx context.Context) string {
+	if actor, _ := ctx.Value(actorKey{}).(string); actor != "" {
+		return actor
+	}
+	if principal := auth.FromContext(ctx); principal != nil {
+		if principal.KeyID != "" {
+			return "api_key:" + principal.KeyID
+		}
+		return ActorAnonymous
+	}
+	return ActorSystem
+}
@@ -0,0 +1,42 @@
+package domain
+
+import "fmt"
+
+// Break describes the first entry where the chain does not hold.
+type Break struct {
+	Seq    int64
+	Reason string
+}
+
+func (b *Break) Error() string {
+	return fmt.Sprintf("audit chain broken at seq %d: %s", b.Seq, b.Reason)
+}
+
+// ChainVerifier checks entries fed to it in sequence order.
+type ChainVerifier struct {
+	prevSeq  int64
+	prevHash string
+	Checked  int64
+}
+
+// Next checks e against the entry before it, returning the break if the
+// chain does not hold.
+func (v *ChainVerifier) Next(e *Entry) *Break {
+	switch {
+	case e.Seq != v.prevSeq+1:
+		return &Break{Seq: e.Seq, Reason: fmt.Sprintf("expected seq %d; entries are missing", v.prevSeq+1)}
+	case e.PrevHash != v.prevHash:
+		return &Break{Seq: e.Seq, Reason: "prev_hash does not match the previous entry"}
+	case e.ComputeHash() != e.Hash:
+		return &Break{Seq: e.Seq, Reason: "hash does not match the entry's contents"}
+	}
+	v.prevSeq, v.prevHash = e.Seq, e.Hash
+	v.Checked++
+	return nil
+}
+
+// Head is the hash of the last entry verified. Recording it elsewhere lets
+// a later run detect entries cut off the end of the log.
+func (v *ChainVerifier) Head() string {
+	return v.prevHash
+}
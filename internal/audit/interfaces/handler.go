@@ -0,0 +1,98 @@
+package interfaces
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/williamkoller/payment-system/internal/audit/application"
+	"github.com/williamkoller/payment-system/internal/audit/domain"
+	"github.com/williamkoller/payment-system/internal/middleware"
+	"github.com/williamkoller/payment-system/pkg/apperror"
+)
+
+type IdentifyPaymentDto struct {
+	PaymentID string `uri:"payment_id" binding:"required"`
+}
+
+type EntryResponse struct {
+	ID           string          `json:"id"`
+	Seq          int64           `json:"seq"`
+	Action       string          `json:"action"`
+	Outcome      string          `json:"outcome"`
+	ErrorCode    string          `json:"error_code,omitempty"`
+	Actor        string          `json:"actor"`
+	APIKeyID     string          `json:"api_key_id,omitempty"`
+	IP           string          `json:"ip,omitempty"`
+	RequestID    string          `json:"request_id,omitempty"`
+	ResourceType string          `json:"resource_type"`
+	ResourceID   string          `json:"resource_id"`
+	Before       json.RawMessage `json:"before,omitempty"`
+	After        json.RawMessage `json:"after,omitempty"`
+	Details      json.RawMessage `json:"details,omitempty"`
+	CreatedAt    time.Time       `json:"created_at"`
+	Hash         string          `json:"hash"`
+	PrevHash     string          `json:"prev_hash"`
+}
+
+func ToEntryResponse(e *domain.Entry) EntryResponse {
+	return EntryResponse{
+		ID:           e.ID,
+		Seq:          e.Seq,
+		Action:       e.Action,
+		Outcome:      e.Outcome,
+		ErrorCode:    e.ErrorCode,
+		Actor:        e.Actor,
+		APIKeyID:     e.APIKeyID,
+		IP:           e.IP,
+		RequestID:    e.RequestID,
+		ResourceType: e.ResourceType,
+		ResourceID:   e.ResourceID,
+		Before:       rawJSON(e.Before),
+		After:        rawJSON(e.After),
+		Details:      rawJSON(e.Details),
+		CreatedAt:    e.CreatedAt,
+		Hash:         e.Hash,
+		PrevHash:     e.PrevHash,
+	}
+}
+
+func ToEntryResponses(entries []*domain.Entry) []EntryResponse {
+	out := make([]EntryResponse, 0, len(entries))
+	for _, e := range entries {
+		out = append(out, ToEntryResponse(e))
+	}
+	return out
+}
+
+func rawJSON(s string) json.RawMessage {
+	if s == "" {
+		return nil
+	}
+	return json.RawMessage(s)
+}
+
+type AuditHandler struct {
+	Service *application.AuditService
+}
+
+func NewAuditHandler(service *application.AuditService) *AuditHandler {
+	return &AuditHandler{Service: service}
+}
+
+func (h *AuditHandler) GetPaymentAudit(c *gin.Context) {
+	var uri IdentifyPaymentDto
+	if err := c.ShouldBindUri(&uri); err != nil {
+		middleware.Problem(c, apperror.Validation(err))
+		return
+	}
+
+	entries, err := h.Service.ListForPayment(c.Request.Context(), uri.PaymentID)
+	if err != nil {
+		middleware.Problem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ToEntryResponses(entries))
+}
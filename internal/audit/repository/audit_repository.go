@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/williamkoller/payment-system/internal/audit/domain"
+	"github.com/williamkoller/payment-system/pkg/tenant"
+	"gorm.io/gorm"
+)
+
+// appendLock is the advisory lock serializing appends, so each entry
+// chains onto the latest one.
+const appendLock = 0x61756469740a // "audit"
+
+type AuditRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewAuditRepository(db *gorm.DB) *AuditRepositoryImpl {
+	return &AuditRepositoryImpl{db: db}
+}
+
+// Append chains entry onto the latest entry and stores it.
+func (r *AuditRepositoryImpl) Append(ctx context.Context, entry *domain.Entry) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", appendLock).Error; err != nil {
+			return err
+		}
+
+		var last domain.Entry
+		err := tx.Table("audit_log").Select("seq", "hash").Order("seq DESC").Take(&last).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		entry.Chain(last.Seq, last.Hash)
+		return tx.Table("audit_log").Create(entry).Error
+	})
+}
+
+func (r *AuditRepositoryImpl) FindByResource(ctx context.Context, resourceType, resourceID string) ([]*domain.Entry, error) {
+	var entries []*domain.Entry
+	err := r.db.WithContext(ctx).Table("audit_log").Scopes(tenant.Scope(ctx)).
+		Where("resource_type = ? AND resource_id = ?", resourceType, resourceID).
+		Order("seq").
+		Find(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// FindAfter returns up to limit entries following seq, in order.
+func (r *AuditRepositoryImpl) FindAfter(ctx context.Context, seq int64, limit int) ([]*domain.Entry, error) {
+	var entries []*domain.Entry
+	err := r.db.WithContext(ctx).Table("audit_log").
+		Where("seq > ?", seq).
+		Order("seq").
+		Limit(limit).
+		Find(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
@@ -0,0 +1,23 @@
+package router
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/williamkoller/payment-system/internal/audit/application"
+	"github.com/williamkoller/payment-system/internal/audit/interfaces"
+	"github.com/williamkoller/payment-system/internal/audit/repository"
+	"github.com/williamkoller/payment-system/internal/middleware"
+	ratelimit "github.com/williamkoller/payment-system/internal/ratelimit/domain"
+	"github.com/williamkoller/payment-system/pkg/auth"
+	"gorm.io/gorm"
+)
+
+func NewAuditService(db *gorm.DB) *application.AuditService {
+	return application.NewAuditService(repository.NewAuditRepository(db))
+}
+
+// SetupRouter mounts a payment's audit trail. Entries are scoped to the
+// caller's merchant like the payment itself.
+func SetupRouter(e *gin.Engine, service *application.AuditService, authn gin.HandlerFunc, limits *middleware.RateLimits) {
+	handler := interfaces.NewAuditHandler(service)
+	e.GET("/payments/:payment_id/audit", limits.PerIP(), authn, middleware.RequireScope(auth.ScopePaymentsRead), limits.PerClient(ratelimit.ClassRead), handler.GetPaymentAudit)
+}
@@ -0,0 +1,221 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sony/gobreaker"
+	"github.com/williamkoller/payment-system/internal/batch/domain"
+	paymentDomain "github.com/williamkoller/payment-system/internal/payment/domain"
+	"github.com/williamkoller/payment-system/internal/payment/dtos"
+	ratelimit "github.com/williamkoller/payment-system/internal/ratelimit/domain"
+	"github.com/williamkoller/payment-system/pkg/apperror"
+	"github.com/williamkoller/payment-system/pkg/auth"
+	"github.com/williamkoller/payment-system/pkg/heartbeat"
+	"github.com/williamkoller/payment-system/pkg/logger"
+	"github.com/williamkoller/payment-system/pkg/requestid"
+)
+
+// claimTimeout is how long an item may stay claimed before another worker
+// takes it over, presuming the one that claimed it died. It outlasts any
+// Stripe call.
+const claimTimeout = 5 * time.Minute
+
+// BreakerStater reports the state of the Stripe circuit breaker.
+type BreakerStater interface {
+	BreakerState() gobreaker.State
+}
+
+// RateLimiter charges batch items to their merchant's rate limit.
+type RateLimiter interface {
+	Allow(ctx context.Context, class ratelimit.Class, subject string) (ratelimit.Decision, bool, error)
+}
+
+// Processor runs queued batch items on a fixed number of workers. Items
+// are claimed from the database, so processors on every replica share the
+// queue and an item left behind by a crashed one is picked up again.
+type Processor struct {
+	Repository BatchRepository
+	Payments   Payments
+	// Breaker, when set, pauses the workers while it is open rather than
+	// failing item after item against a gateway known to be down.
+	Breaker BreakerStater
+	// Limiter, when set, counts items against their merchant's write
+	// limit, so a batch cannot go faster than the same calls made one by
+	// one.
+	Limiter RateLimiter
+
+	Workers int
+	// PollInterval is how often idle workers look for items queued by
+	// other replicas.
+	PollInterval time.Duration
+	// RetryDelay is how long an item waits after the gateway was
+	// unavailable, and how long workers pause while the breaker is open.
+	RetryDelay time.Duration
+	// MaxAttempts is how many times an item is tried while the gateway is
+	// unavailable before it fails.
+	MaxAttempts int
+
+	// Heartbeat beats every time a worker goes round, for readiness checks.
+	Heartbeat heartbeat.Heartbeat
+
+	wake chan struct{}
+}
+
+func NewProcessor(repository BatchRepository, payments Payments, workers int) *Processor {
+	return &Processor{
+		Repository:   repository,
+		Payments:     payments,
+		Workers:      workers,
+		PollInterval: 5 * time.Second,
+		RetryDelay:   30 * time.Second,
+		MaxAttempts:  5,
+		wake:         make(chan struct{}, 1),
+	}
+}
+
+// Notify wakes an idle worker. It does nothing on a nil processor.
+func (p *Processor) Notify() {
+	if p == nil {
+		return
+	}
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start runs the workers until ctx is done. Items being run when it is
+// are finished first.
+func (p *Processor) Start(ctx context.Context) {
+	p.Heartbeat.Beat()
+
+	var wg sync.WaitGroup
+	for range max(p.Workers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.work(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (p *Processor) work(ctx context.Context) {
+	for ctx.Err() == nil {
+		p.Heartbeat.Beat()
+
+		if p.Breaker != nil && p.Breaker.BreakerState() == gobreaker.StateOpen {
+			p.sleep(ctx, p.RetryDelay)
+			continue
+		}
+
+		if !p.RunNext(ctx) {
+			p.sleep(ctx, p.PollInterval)
+		}
+	}
+}
+
+// sleep waits for d, ctx or Notify, whichever comes first.
+func (p *Processor) sleep(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+	case <-p.wake:
+	case <-timer.C:
+	}
+}
+
+// RunNext claims one due item and runs it. It reports false when there was
+// none or the queue could not be read.
+func (p *Processor) RunNext(ctx context.Context) bool {
+	now := time.Now().UTC()
+	items, err := p.Repository.Claim(ctx, now, now.Add(-claimTimeout), 1)
+	if err != nil {
+		if ctx.Err() == nil {
+			logger.Error("cannot claim batch items", "err", err)
+		}
+		return false
+	}
+	if len(items) == 0 {
+		return false
+	}
+
+	// The item runs to completion even if ctx ends meanwhile: stopping
+	// halfway would leave it claimed until claimTimeout.
+	p.run(context.WithoutCancel(ctx), items[0])
+	return true
+}
+
+func (p *Processor) run(ctx context.Context, item *domain.Item) {
+	principal := &auth.Principal{MerchantID: item.MerchantID, KeyID: item.KeyID}
+	ctx = auth.NewContext(ctx, principal)
+	// The item id doubles as the request id, which keys Stripe's
+	// idempotency, so a retried item cannot charge twice.
+	ctx = requestid.NewContext(ctx, item.ID)
+
+	if wait, limited := p.rateLimited(ctx, principal); limited {
+		item.Defer(time.Now().Add(wait))
+		p.save(ctx, item)
+		return
+	}
+
+	payment, err := p.apply(ctx, item)
+	var paymentStatus string
+	if payment != nil {
+		paymentStatus = string(payment.Status)
+	}
+
+	switch appErr := apperror.As(err); {
+	case err == nil:
+		item.Succeed(paymentStatus)
+	case appErr.Kind == apperror.KindGatewayUnavailable && item.Attempts < p.MaxAttempts:
+		item.Retry(time.Now().Add(p.RetryDelay), appErr.Code, appErr.Message)
+	default:
+		item.Fail(paymentStatus, appErr.Code, appErr.Message)
+	}
+	p.save(ctx, item)
+}
+
+// rateLimited reports whether the item's merchant is over its write limit
+// and how long to wait. Like the middleware, it lets items through when
+// the limiter fails.
+func (p *Processor) rateLimited(ctx context.Context, principal *auth.Principal) (time.Duration, bool) {
+	subject := ratelimit.Subject(principal)
+	if p.Limiter == nil || subject == "" {
+		return 0, false
+	}
+	decision, ok, err := p.Limiter.Allow(ctx, ratelimit.ClassWrite, subject)
+	if err != nil {
+		logger.Warn("rate limiter unavailable, running batch item", "err", err)
+		return 0, false
+	}
+	if !ok || decision.Allowed {
+		return 0, false
+	}
+	return decision.RetryAfter, true
+}
+
+func (p *Processor) apply(ctx context.Context, item *domain.Item) (*paymentDomain.Payment, error) {
+	id := dtos.IdentifyPaymentDto{PaymentID: item.PaymentID}
+	switch item.Operation {
+	case paymentDomain.OperationCapture:
+		return p.Payments.Capture(ctx, id)
+	case paymentDomain.OperationCancel:
+		return p.Payments.Cancel(ctx, id)
+	case paymentDomain.OperationRefund:
+		return p.Payments.Refund(ctx, id, dtos.PaymentRefundDto{Amount: item.Amount})
+	default:
+		return nil, fmt.Errorf("unknown batch operation %q", item.Operation)
+	}
+}
+
+func (p *Processor) save(ctx context.Context, item *domain.Item) {
+	if err := p.Repository.Save(ctx, item); err != nil {
+		logger.Error("cannot save batch item", "batch_id", item.BatchID, "item_id", item.ID, "err", err)
+	}
+}
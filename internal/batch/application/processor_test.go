@@ -0,0 +1,195 @@
+package application_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/williamkoller/payment-system/internal/batch/application"
+	"github.com/williamkoller/payment-system/internal/batch/domain"
+	paymentApplication "github.com/williamkoller/payment-system/internal/payment/application"
+	paymentDomain "github.com/williamkoller/payment-system/internal/payment/domain"
+	"github.com/williamkoller/payment-system/internal/payment/dtos"
+	ratelimit "github.com/williamkoller/payment-system/internal/ratelimit/domain"
+	"github.com/williamkoller/payment-system/pkg/auth"
+	"github.com/williamkoller/payment-system/pkg/requestid"
+	"gorm.io/gorm"
+)
+
+type fakeBatches struct {
+	mu      sync.Mutex
+	batches map[string]*domain.Batch
+}
+
+func (f *fakeBatches) Create(_ context.Context, batch *domain.Batch) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.batches[batch.ID] = batch
+	return nil
+}
+
+func (f *fakeBatches) FindByID(ctx context.Context, id string) (*domain.Batch, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	batch, ok := f.batches[id]
+	if !ok || (auth.MerchantID(ctx) != "" && batch.MerchantID != auth.MerchantID(ctx)) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return batch, nil
+}
+
+func (f *fakeBatches) Claim(_ context.Context, now, _ time.Time, limit int) ([]*domain.Item, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var claimed []*domain.Item
+	for _, batch := range f.batches {
+		for _, item := range batch.Items {
+			if len(claimed) < limit && item.Status == domain.ItemPending && !item.AvailableAt.After(now) {
+				item.Status = domain.ItemProcessing
+				item.ClaimedAt = &now
+				item.Attempts++
+				claimed = append(claimed, item)
+			}
+		}
+	}
+	return claimed, nil
+}
+
+func (f *fakeBatches) Save(context.Context, *domain.Item) error {
+	return nil
+}
+
+// fakePayments answers each operation with the error queued for its
+// payment, or succeeds.
+type fakePayments struct {
+	errs  map[string][]error
+	calls []context.Context
+}
+
+func (f *fakePayments) do(ctx context.Context, paymentID string, status paymentDomain.PaymentStatus) (*paymentDomain.Payment, error) {
+	f.calls = append(f.calls, ctx)
+	if errs := f.errs[paymentID]; len(errs) > 0 {
+		f.errs[paymentID] = errs[1:]
+		return &paymentDomain.Payment{ID: paymentID, Status: paymentDomain.StatusCompleted}, errs[0]
+	}
+	return &paymentDomain.Payment{ID: paymentID, Status: status}, nil
+}
+
+func (f *fakePayments) Capture(ctx context.Context, i dtos.IdentifyPaymentDto) (*paymentDomain.Payment, error) {
+	return f.do(ctx, i.PaymentID, paymentDomain.StatusCaptured)
+}
+
+func (f *fakePayments) Cancel(ctx context.Context, i dtos.IdentifyPaymentDto) (*paymentDomain.Payment, error) {
+	return f.do(ctx, i.PaymentID, paymentDomain.StatusCanceled)
+}
+
+func (f *fakePayments) Refund(ctx context.Context, i dtos.IdentifyPaymentDto, _ dtos.PaymentRefundDto) (*paymentDomain.Payment, error) {
+	return f.do(ctx, i.PaymentID, paymentDomain.StatusRefund)
+}
+
+type fakeLimiter struct {
+	allowed bool
+}
+
+func (f *fakeLimiter) Allow(context.Context, ratelimit.Class, string) (ratelimit.Decision, bool, error) {
+	return ratelimit.Decision{Allowed: f.allowed, RetryAfter: time.Hour}, true, nil
+}
+
+func newService(payments *fakePayments) *application.BatchService {
+	repo := &fakeBatches{batches: make(map[string]*domain.Batch)}
+	service := application.NewBatchService(repo)
+	service.Processor = application.NewProcessor(repo, payments, 1)
+	service.Processor.RetryDelay = 0
+	service.Processor.MaxAttempts = 2
+	return service
+}
+
+func drain(ctx context.Context, processor *application.Processor) {
+	for processor.RunNext(ctx) {
+	}
+}
+
+func TestProcessor_RunsItemsAndKeepsPartialFailures(t *testing.T) {
+	payments := &fakePayments{errs: map[string][]error{
+		"pay_declined": {paymentApplication.ErrGatewayDeclined},
+		"pay_flaky":    {paymentApplication.ErrGatewayUnavailable},
+		"pay_down":     {paymentApplication.ErrGatewayUnavailable, paymentApplication.ErrGatewayUnavailable},
+	}}
+	service := newService(payments)
+	ctx := auth.NewContext(context.Background(), &auth.Principal{MerchantID: "m1", KeyID: "key_1"})
+
+	batch, err := service.Submit(ctx, []domain.Operation{
+		{PaymentID: "pay_ok", Operation: paymentDomain.OperationCapture},
+		{PaymentID: "pay_declined", Operation: paymentDomain.OperationRefund, Amount: 500},
+		{PaymentID: "pay_flaky", Operation: paymentDomain.OperationCancel},
+		{PaymentID: "pay_down", Operation: paymentDomain.OperationCapture},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, domain.StatusPending, batch.Status())
+
+	drain(context.Background(), service.Processor)
+
+	batch, err = service.Get(ctx, batch.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.StatusCompleted, batch.Status())
+	assert.Equal(t, domain.Counts{Total: 4, Succeeded: 2, Failed: 2}, batch.Counts())
+
+	ok, declined, flaky, down := batch.Items[0], batch.Items[1], batch.Items[2], batch.Items[3]
+	assert.Equal(t, domain.ItemSucceeded, ok.Status)
+	assert.Equal(t, string(paymentDomain.StatusCaptured), ok.PaymentStatus)
+
+	assert.Equal(t, domain.ItemFailed, declined.Status)
+	assert.Equal(t, "card_declined", declined.ErrorCode)
+	assert.Equal(t, 1, declined.Attempts, "declines are not retried")
+	assert.Equal(t, string(paymentDomain.StatusCompleted), declined.PaymentStatus)
+
+	assert.Equal(t, domain.ItemSucceeded, flaky.Status, "an unavailable gateway is retried")
+	assert.Equal(t, 2, flaky.Attempts)
+	assert.Empty(t, flaky.ErrorCode)
+
+	assert.Equal(t, domain.ItemFailed, down.Status, "until MaxAttempts")
+	assert.Equal(t, "gateway_unavailable", down.ErrorCode)
+
+	for _, call := range payments.calls {
+		assert.Equal(t, "m1", auth.MerchantID(call), "items run as the merchant that submitted them")
+		assert.NotEmpty(t, requestid.FromContext(call))
+	}
+}
+
+func TestProcessor_DefersItemsOverTheRateLimit(t *testing.T) {
+	payments := &fakePayments{}
+	service := newService(payments)
+	limiter := &fakeLimiter{}
+	service.Processor.Limiter = limiter
+	ctx := auth.NewContext(context.Background(), &auth.Principal{MerchantID: "m1"})
+
+	batch, err := service.Submit(ctx, []domain.Operation{{PaymentID: "pay_1", Operation: paymentDomain.OperationCapture}})
+	require.NoError(t, err)
+
+	drain(context.Background(), service.Processor)
+	item := batch.Items[0]
+	assert.Empty(t, payments.calls)
+	assert.Equal(t, domain.ItemPending, item.Status)
+	assert.Zero(t, item.Attempts, "waiting for the limit is not an attempt")
+	assert.True(t, item.AvailableAt.After(time.Now().Add(time.Minute)), "waits for the limit's retry-after")
+
+	limiter.allowed = true
+	item.AvailableAt = time.Now()
+	drain(context.Background(), service.Processor)
+	assert.Equal(t, domain.ItemSucceeded, item.Status)
+}
+
+func TestBatchService_Get(t *testing.T) {
+	service := newService(&fakePayments{})
+	batch, err := service.Submit(auth.ForMerchant(context.Background(), "m1"), []domain.Operation{{PaymentID: "pay_1", Operation: paymentDomain.OperationCancel}})
+	require.NoError(t, err)
+
+	_, err = service.Get(auth.ForMerchant(context.Background(), "m2"), batch.ID)
+	assert.ErrorIs(t, err, application.ErrBatchNotFound, "batches are scoped to their merchant")
+
+	_, err = service.Submit(context.Background(), nil)
+	assert.ErrorIs(t, err, domain.ErrEmptyBatch)
+}
@@ -0,0 +1,76 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/williamkoller/payment-system/internal/batch/domain"
+	paymentDomain "github.com/williamkoller/payment-system/internal/payment/domain"
+	"github.com/williamkoller/payment-system/internal/payment/dtos"
+	"github.com/williamkoller/payment-system/pkg/apperror"
+	"github.com/williamkoller/payment-system/pkg/auth"
+	"github.com/williamkoller/payment-system/pkg/ulid"
+	"gorm.io/gorm"
+)
+
+var ErrBatchNotFound = apperror.New(apperror.KindNotFound, "batch_not_found", "batch not found")
+
+type BatchRepository interface {
+	Create(ctx context.Context, batch *domain.Batch) error
+	FindByID(ctx context.Context, id string) (*domain.Batch, error)
+	Claim(ctx context.Context, now, staleBefore time.Time, limit int) ([]*domain.Item, error)
+	Save(ctx context.Context, item *domain.Item) error
+}
+
+// Payments runs the operations of batch items. The payment use case
+// implements it, so items go through the same checks, audit and failed
+// attempt records as single requests.
+type Payments interface {
+	Capture(ctx context.Context, i dtos.IdentifyPaymentDto) (*paymentDomain.Payment, error)
+	Cancel(ctx context.Context, i dtos.IdentifyPaymentDto) (*paymentDomain.Payment, error)
+	Refund(ctx context.Context, i dtos.IdentifyPaymentDto, pr dtos.PaymentRefundDto) (*paymentDomain.Payment, error)
+}
+
+type BatchService struct {
+	Repository BatchRepository
+	// Processor, when set, is woken up as soon as a batch is queued
+	// instead of at its next poll.
+	Processor *Processor
+}
+
+func NewBatchService(repository BatchRepository) *BatchService {
+	return &BatchService{Repository: repository}
+}
+
+// Submit queues operations on behalf of the caller and returns the batch
+// without waiting for any of them to run.
+func (s *BatchService) Submit(ctx context.Context, operations []domain.Operation) (*domain.Batch, error) {
+	var merchantID, keyID string
+	if principal := auth.FromContext(ctx); principal != nil {
+		merchantID, keyID = principal.MerchantID, principal.KeyID
+	}
+
+	batch, err := domain.NewBatch(ulid.NewULID(), merchantID, keyID, operations, ulid.NewULID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Repository.Create(ctx, batch); err != nil {
+		return nil, err
+	}
+
+	s.Processor.Notify()
+	return batch, nil
+}
+
+// Get returns the batch with the outcome of each item so far.
+func (s *BatchService) Get(ctx context.Context, id string) (*domain.Batch, error) {
+	batch, err := s.Repository.FindByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrBatchNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return batch, nil
+}
@@ -0,0 +1,180 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/williamkoller/payment-system/pkg/apperror"
+)
+
+// Item statuses. Items move from pending to processing when a worker
+// claims them, and back to pending when the gateway was unavailable.
+const (
+	ItemPending    = "PENDING"
+	ItemProcessing = "PROCESSING"
+	ItemSucceeded  = "SUCCEEDED"
+	ItemFailed     = "FAILED"
+)
+
+// Batch statuses, derived from the statuses of the items.
+const (
+	StatusPending    = "PENDING"
+	StatusProcessing = "PROCESSING"
+	StatusCompleted  = "COMPLETED"
+)
+
+var ErrEmptyBatch = apperror.New(apperror.KindValidation, "empty_batch", "a batch needs at least one operation")
+
+// Batch is a list of payment operations submitted together and run in the
+// background. It remembers who submitted it, so its items run with the
+// same merchant and key.
+type Batch struct {
+	ID         string
+	MerchantID string
+	KeyID      string
+	CreatedAt  time.Time
+	Items      []*Item `gorm:"-"`
+}
+
+// Item is one operation of a batch and its outcome.
+type Item struct {
+	ID         string
+	BatchID    string
+	MerchantID string
+	KeyID      string
+	Seq        int
+	PaymentID  string
+	Operation  string
+	// Amount is only set for refunds.
+	Amount   int64
+	Status   string
+	Attempts int
+	// PaymentStatus is the payment's status after the operation, when it
+	// got as far as loading the payment.
+	PaymentStatus string
+	ErrorCode     string
+	ErrorMessage  string
+	CreatedAt     time.Time
+	// AvailableAt is when a pending item may be claimed next.
+	AvailableAt time.Time
+	ClaimedAt   *time.Time
+	ProcessedAt *time.Time
+}
+
+// Operation is what a batch asks of one payment.
+type Operation struct {
+	PaymentID string
+	Operation string
+	Amount    int64
+}
+
+// NewBatch queues operations, in order, under id. newID names the items.
+func NewBatch(id, merchantID, keyID string, operations []Operation, newID func() string) (*Batch, error) {
+	if len(operations) == 0 {
+		return nil, ErrEmptyBatch
+	}
+
+	now := time.Now().UTC()
+	b := &Batch{ID: id, MerchantID: merchantID, KeyID: keyID, CreatedAt: now}
+	for i, op := range operations {
+		b.Items = append(b.Items, &Item{
+			ID:          newID(),
+			BatchID:     id,
+			MerchantID:  merchantID,
+			KeyID:       keyID,
+			Seq:         i + 1,
+			PaymentID:   op.PaymentID,
+			Operation:   op.Operation,
+			Amount:      op.Amount,
+			Status:      ItemPending,
+			CreatedAt:   now,
+			AvailableAt: now,
+		})
+	}
+	return b, nil
+}
+
+// Succeed records that the operation went through.
+func (i *Item) Succeed(paymentStatus string) {
+	now := time.Now().UTC()
+	i.Status = ItemSucceeded
+	i.PaymentStatus = paymentStatus
+	i.ErrorCode, i.ErrorMessage = "", ""
+	i.ProcessedAt = &now
+}
+
+// Fail records why the operation did not go through. paymentStatus is ""
+// when the payment could not be loaded.
+func (i *Item) Fail(paymentStatus, code, message string) {
+	now := time.Now().UTC()
+	i.Status = ItemFailed
+	i.PaymentStatus = paymentStatus
+	i.ErrorCode, i.ErrorMessage = code, message
+	i.ProcessedAt = &now
+}
+
+// Retry puts the item back in the queue until at. The last error is kept
+// so the batch shows why the item is waiting.
+func (i *Item) Retry(at time.Time, code, message string) {
+	i.Status = ItemPending
+	i.ErrorCode, i.ErrorMessage = code, message
+	i.AvailableAt = at.UTC()
+	i.ClaimedAt = nil
+}
+
+// Defer puts the item back in the queue until at without counting the
+// attempt, e.g. when its merchant is over its rate limit.
+func (i *Item) Defer(at time.Time) {
+	i.Status = ItemPending
+	i.Attempts--
+	i.AvailableAt = at.UTC()
+	i.ClaimedAt = nil
+}
+
+// Counts tallies a batch's items by status.
+type Counts struct {
+	Total      int
+	Pending    int
+	Processing int
+	Succeeded  int
+	Failed     int
+}
+
+func (b *Batch) Counts() Counts {
+	c := Counts{Total: len(b.Items)}
+	for _, item := range b.Items {
+		switch item.Status {
+		case ItemPending:
+			c.Pending++
+		case ItemProcessing:
+			c.Processing++
+		case ItemSucceeded:
+			c.Succeeded++
+		case ItemFailed:
+			c.Failed++
+		}
+	}
+	return c
+}
+
+// Status is COMPLETED once every item has its outcome, failed or not,
+// PENDING while none has been started and PROCESSING in between.
+func (b *Batch) Status() string {
+	c := b.Counts()
+	switch {
+	case c.Succeeded+c.Failed == c.Total:
+		return StatusCompleted
+	case c.Pending == c.Total && !b.started():
+		return StatusPending
+	default:
+		return StatusProcessing
+	}
+}
+
+func (b *Batch) started() bool {
+	for _, item := range b.Items {
+		if item.Attempts > 0 {
+			return true
+		}
+	}
+	return false
+}
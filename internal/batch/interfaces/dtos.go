@@ -0,0 +1,19 @@
+package interfaces
+
+// CreateBatchDto lists the operations of a batch. A payment may appear
+// only once: items run concurrently, so two operations on one payment
+// would race.
+type CreateBatchDto struct {
+	Operations []BatchOperationDto `json:"operations" binding:"required,min=1,max=1000,unique=PaymentID,dive"`
+}
+
+type BatchOperationDto struct {
+	PaymentID string `json:"payment_id" binding:"required"`
+	Operation string `json:"operation" binding:"required,oneof=capture cancel refund"`
+	// Amount is the amount to refund, in the currency's minor unit.
+	Amount int64 `json:"amount" binding:"required_if=Operation refund,excluded_unless=Operation refund"`
+}
+
+type IdentifyBatchDto struct {
+	BatchID string `uri:"batch_id" binding:"required"`
+}
@@ -0,0 +1,71 @@
+package interfaces
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/williamkoller/payment-system/internal/batch/application"
+	"github.com/williamkoller/payment-system/internal/batch/domain"
+	"github.com/williamkoller/payment-system/internal/middleware"
+	paymentDomain "github.com/williamkoller/payment-system/internal/payment/domain"
+	"github.com/williamkoller/payment-system/pkg/apperror"
+	"github.com/williamkoller/payment-system/pkg/auth"
+)
+
+type BatchHandler struct {
+	Service *application.BatchService
+}
+
+func NewBatchHandler(service *application.BatchService) *BatchHandler {
+	return &BatchHandler{Service: service}
+}
+
+// CreateBatch queues the operations and answers 202 with the batch, whose
+// Location is polled for the outcome of each item.
+func (h *BatchHandler) CreateBatch(c *gin.Context) {
+	var dto CreateBatchDto
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		middleware.Problem(c, apperror.Validation(err))
+		return
+	}
+
+	operations := make([]domain.Operation, 0, len(dto.Operations))
+	for _, op := range dto.Operations {
+		operations = append(operations, domain.Operation{PaymentID: op.PaymentID, Operation: op.Operation, Amount: op.Amount})
+	}
+
+	// The route only asks for payments:write; refunds need their own scope
+	// as they do on their own.
+	principal := auth.FromContext(c.Request.Context())
+	for _, op := range operations {
+		if op.Operation == paymentDomain.OperationRefund && !principal.HasScope(auth.ScopeRefundsWrite) {
+			middleware.Problem(c, middleware.ErrInsufficientScope.WithMessage("API key lacks the "+string(auth.ScopeRefundsWrite)+" scope"))
+			return
+		}
+	}
+
+	batch, err := h.Service.Submit(c.Request.Context(), operations)
+	if err != nil {
+		middleware.Problem(c, err)
+		return
+	}
+
+	c.Header("Location", "/batches/"+batch.ID)
+	c.JSON(http.StatusAccepted, ToBatchResponse(batch))
+}
+
+func (h *BatchHandler) GetBatch(c *gin.Context) {
+	var uri IdentifyBatchDto
+	if err := c.ShouldBindUri(&uri); err != nil {
+		middleware.Problem(c, apperror.Validation(err))
+		return
+	}
+
+	batch, err := h.Service.Get(c.Request.Context(), uri.BatchID)
+	if err != nil {
+		middleware.Problem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ToBatchResponse(batch))
+}
@@ -0,0 +1,72 @@
+package interfaces
+
+import (
+	"time"
+
+	"github.com/williamkoller/payment-system/internal/batch/domain"
+)
+
+type BatchResponse struct {
+	ID         string              `json:"id"`
+	Status     string              `json:"status"`
+	Total      int                 `json:"total"`
+	Pending    int                 `json:"pending"`
+	Processing int                 `json:"processing"`
+	Succeeded  int                 `json:"succeeded"`
+	Failed     int                 `json:"failed"`
+	CreatedAt  time.Time           `json:"created_at"`
+	Items      []BatchItemResponse `json:"items"`
+}
+
+type BatchItemResponse struct {
+	PaymentID     string     `json:"payment_id"`
+	Operation     string     `json:"operation"`
+	Amount        int64      `json:"amount,omitempty"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	PaymentStatus string     `json:"payment_status,omitempty"`
+	Error         *ItemError `json:"error,omitempty"`
+	ProcessedAt   *time.Time `json:"processed_at,omitempty"`
+}
+
+// ItemError is why an item failed or, while it is pending, why its last
+// attempt did not go through.
+type ItemError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func ToBatchResponse(b *domain.Batch) BatchResponse {
+	counts := b.Counts()
+	items := make([]BatchItemResponse, 0, len(b.Items))
+	for _, item := range b.Items {
+		items = append(items, ToBatchItemResponse(item))
+	}
+	return BatchResponse{
+		ID:         b.ID,
+		Status:     b.Status(),
+		Total:      counts.Total,
+		Pending:    counts.Pending,
+		Processing: counts.Processing,
+		Succeeded:  counts.Succeeded,
+		Failed:     counts.Failed,
+		CreatedAt:  b.CreatedAt,
+		Items:      items,
+	}
+}
+
+func ToBatchItemResponse(item *domain.Item) BatchItemResponse {
+	resp := BatchItemResponse{
+		PaymentID:     item.PaymentID,
+		Operation:     item.Operation,
+		Amount:        item.Amount,
+		Status:        item.Status,
+		Attempts:      item.Attempts,
+		PaymentStatus: item.PaymentStatus,
+		ProcessedAt:   item.ProcessedAt,
+	}
+	if item.ErrorCode != "" {
+		resp.Error = &ItemError{Code: item.ErrorCode, Message: item.ErrorMessage}
+	}
+	return resp
+}
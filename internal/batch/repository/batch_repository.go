@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/williamkoller/payment-system/internal/batch/domain"
+	"github.com/williamkoller/payment-system/pkg/tenant"
+	"gorm.io/gorm"
+)
+
+type BatchRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewBatchRepository(db *gorm.DB) *BatchRepositoryImpl {
+	return &BatchRepositoryImpl{db: db}
+}
+
+// Create stores the batch with its items, which queues them.
+func (r *BatchRepositoryImpl) Create(ctx context.Context, batch *domain.Batch) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table("payment_batches").Create(batch).Error; err != nil {
+			return err
+		}
+		return tx.Table("payment_batch_items").CreateInBatches(batch.Items, 500).Error
+	})
+}
+
+// FindByID returns the batch with its items in submission order.
+func (r *BatchRepositoryImpl) FindByID(ctx context.Context, id string) (*domain.Batch, error) {
+	var batch domain.Batch
+	err := r.db.WithContext(ctx).Table("payment_batches").Scopes(tenant.Scope(ctx)).
+		Where("id = ?", id).
+		Take(&batch).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.db.WithContext(ctx).Table("payment_batch_items").
+		Where("batch_id = ?", id).
+		Order("seq").
+		Find(&batch.Items).Error
+	if err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+// Claim marks up to limit items as processing and returns them, oldest
+// first. It takes pending items that are due and processing items claimed
+// before staleBefore, whose worker is presumed dead. SKIP LOCKED lets
+// replicas claim concurrently without handing out the same item twice.
+func (r *BatchRepositoryImpl) Claim(ctx context.Context, now, staleBefore time.Time, limit int) ([]*domain.Item, error) {
+	var items []*domain.Item
+	err := r.db.WithContext(ctx).Raw(`
+		UPDATE payment_batch_items
+		SET status = ?, claimed_at = ?, attempts = attempts + 1
+		WHERE id IN (
+			SELECT id FROM payment_batch_items
+			WHERE (status = ? AND available_at <= ?) OR (status = ? AND claimed_at < ?)
+			ORDER BY created_at, seq
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`,
+		domain.ItemProcessing, now,
+		domain.ItemPending, now, domain.ItemProcessing, staleBefore,
+		limit,
+	).Scan(&items).Error
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// Save stores the item's outcome, or puts it back in the queue.
+func (r *BatchRepositoryImpl) Save(ctx context.Context, item *domain.Item) error {
+	return r.db.WithContext(ctx).Table("payment_batch_items").
+		Select("Status", "Attempts", "PaymentStatus", "ErrorCode", "ErrorMessage", "AvailableAt", "ClaimedAt", "ProcessedAt").
+		Where("id = ?", item.ID).
+		Updates(item).Error
+}
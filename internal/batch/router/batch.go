@@ -0,0 +1,35 @@
+package router
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/williamkoller/payment-system/config"
+	"github.com/williamkoller/payment-system/internal/batch/application"
+	"github.com/williamkoller/payment-system/internal/batch/interfaces"
+	"github.com/williamkoller/payment-system/internal/batch/repository"
+	"github.com/williamkoller/payment-system/internal/middleware"
+	ratelimit "github.com/williamkoller/payment-system/internal/ratelimit/domain"
+	"github.com/williamkoller/payment-system/pkg/auth"
+	"gorm.io/gorm"
+)
+
+// NewBatchService returns the service together with the processor that
+// runs its items on payments.
+func NewBatchService(db *gorm.DB, payments application.Payments, cfg config.BatchConfiguration) *application.BatchService {
+	repo := repository.NewBatchRepository(db)
+	processor := application.NewProcessor(repo, payments, cfg.Workers)
+	processor.PollInterval = cfg.PollInterval
+	processor.RetryDelay = cfg.RetryDelay
+	processor.MaxAttempts = cfg.MaxAttempts
+
+	service := application.NewBatchService(repo)
+	service.Processor = processor
+	return service
+}
+
+// SetupRouter mounts the batch routes. Batches are scoped to the caller's
+// merchant like payments.
+func SetupRouter(e *gin.Engine, service *application.BatchService, authn gin.HandlerFunc, limits *middleware.RateLimits) {
+	handler := interfaces.NewBatchHandler(service)
+	e.POST("/payments/batch", limits.PerIP(), authn, middleware.RequireScope(auth.ScopePaymentsWrite), limits.PerClient(ratelimit.ClassWrite), handler.CreateBatch)
+	e.GET("/batches/:batch_id", limits.PerIP(), authn, middleware.RequireScope(auth.ScopePaymentsRead), limits.PerClient(ratelimit.ClassRead), handler.GetBatch)
+}
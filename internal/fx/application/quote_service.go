@@ -0,0 +1,120 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/williamkoller/payment-system/internal/fx/domain"
+	"github.com/williamkoller/payment-system/pkg/apperror"
+	"github.com/williamkoller/payment-system/pkg/money"
+	"github.com/williamkoller/payment-system/pkg/ulid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUnsupportedCurrency = apperror.New(apperror.KindValidation, "unsupported_currency", "currency is not supported")
+	ErrRateUnavailable     = apperror.New(apperror.KindGatewayUnavailable, "fx_rate_unavailable", "cannot fetch fx rate")
+	ErrQuoteNotFound       = apperror.New(apperror.KindNotFound, "fx_quote_not_found", "fx quote not found")
+)
+
+type RateProvider interface {
+	Rate(ctx context.Context, from, to string) (domain.Rate, error)
+}
+
+type QuoteRepository interface {
+	Save(ctx context.Context, quote *domain.FxQuote) error
+	FindByID(ctx context.Context, id string) (*domain.FxQuote, error)
+}
+
+type QuoteService struct {
+	Provider           RateProvider
+	Repository         QuoteRepository
+	SettlementCurrency string
+	TTL                time.Duration
+}
+
+func NewQuoteService(provider RateProvider, repository QuoteRepository, settlementCurrency string, ttl time.Duration) *QuoteService {
+	return &QuoteService{
+		Provider:           provider,
+		Repository:         repository,
+		SettlementCurrency: strings.ToUpper(settlementCurrency),
+		TTL:                ttl,
+	}
+}
+
+// CreateQuote locks the current rate from a presentment currency into the
+// settlement currency for TTL.
+func (s *QuoteService) CreateQuote(ctx context.Context, from string) (*domain.FxQuote, error) {
+	if _, err := money.LookupCurrency(from); err != nil {
+		return nil, ErrUnsupportedCurrency.Wrap(err)
+	}
+
+	rate, err := s.Provider.Rate(ctx, strings.ToUpper(from), s.SettlementCurrency)
+	if err != nil {
+		return nil, ErrRateUnavailable.Wrap(err)
+	}
+	if _, err := money.ParseRate(rate.Value); err != nil {
+		return nil, ErrRateUnavailable.Wrap(err)
+	}
+
+	quote := domain.NewFxQuote(ulid.NewULID(), rate, s.TTL)
+	if err := s.Repository.Save(ctx, quote); err != nil {
+		return nil, err
+	}
+	return quote, nil
+}
+
+func (s *QuoteService) FindQuote(ctx context.Context, id string) (*domain.FxQuote, error) {
+	quote, err := s.Repository.FindByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrQuoteNotFound.Wrap(err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return quote, nil
+}
+
+// Convert computes the settlement amount for a presentment amount. Payments
+// already in the settlement currency convert at 1, and quoteID is ignored;
+// otherwise quoteID, when given, must reference a live quote for the same
+// currency pair, and when empty a fresh quote is taken at the current rate.
+func (s *QuoteService) Convert(ctx context.Context, presentment money.Money, quoteID string) (domain.Conversion, error) {
+	settlement, err := money.LookupCurrency(s.SettlementCurrency)
+	if err != nil {
+		return domain.Conversion{}, err
+	}
+
+	if presentment.Currency.Code == settlement.Code {
+		return domain.Conversion{Amount: presentment.Amount, Currency: settlement.Code, Rate: "1"}, nil
+	}
+
+	var quote *domain.FxQuote
+	if quoteID != "" {
+		quote, err = s.FindQuote(ctx, quoteID)
+	} else {
+		quote, err = s.CreateQuote(ctx, presentment.Currency.Code)
+	}
+	if err != nil {
+		return domain.Conversion{}, err
+	}
+
+	if err := quote.Validate(presentment.Currency.Code, settlement.Code, time.Now()); err != nil {
+		return domain.Conversion{}, err
+	}
+
+	rate, err := money.ParseRate(quote.Rate)
+	if err != nil {
+		return domain.Conversion{}, err
+	}
+
+	converted := money.Convert(presentment, settlement, rate)
+	return domain.Conversion{
+		Amount:   converted.Amount,
+		Currency: settlement.Code,
+		Rate:     quote.Rate,
+		QuoteID:  quote.ID,
+	}, nil
+}
@@ -0,0 +1,63 @@
+package application_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/williamkoller/payment-system/internal/fx/application"
+	"github.com/williamkoller/payment-system/internal/fx/domain"
+	"github.com/williamkoller/payment-system/internal/fx/infra"
+	"github.com/williamkoller/payment-system/pkg/money"
+)
+
+type fakeQuotes map[string]*domain.FxQuote
+
+func (f fakeQuotes) Save(_ context.Context, q *domain.FxQuote) error {
+	f[q.ID] = q
+	return nil
+}
+
+func (f fakeQuotes) FindByID(_ context.Context, id string) (*domain.FxQuote, error) {
+	q, ok := f[id]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return q, nil
+}
+
+func TestQuoteService_Convert(t *testing.T) {
+	provider := infra.NewStaticRateProvider(map[string]string{"USD/BRL": "5.50"})
+	quotes := fakeQuotes{}
+	svc := application.NewQuoteService(provider, quotes, "brl", time.Minute)
+	ctx := context.Background()
+
+	usd, _ := money.New(1000, "USD")
+	brl, _ := money.New(1000, "BRL")
+
+	same, err := svc.Convert(ctx, brl, "")
+	assert.NoError(t, err)
+	assert.Equal(t, domain.Conversion{Amount: 1000, Currency: "BRL", Rate: "1"}, same)
+
+	quote, err := svc.CreateQuote(ctx, "usd")
+	assert.NoError(t, err)
+
+	same, err = svc.Convert(ctx, brl, quote.ID)
+	assert.NoError(t, err, "a quote is not needed in the settlement currency")
+	assert.Equal(t, domain.Conversion{Amount: 1000, Currency: "BRL", Rate: "1"}, same)
+
+	locked, err := svc.Convert(ctx, usd, quote.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5500), locked.Amount)
+	assert.Equal(t, quote.ID, locked.QuoteID)
+
+	eur, _ := money.New(1000, "EUR")
+	_, err = svc.Convert(ctx, eur, quote.ID)
+	assert.ErrorIs(t, err, domain.ErrQuoteMismatch)
+
+	quote.ExpiresAt = time.Now().Add(-time.Second)
+	_, err = svc.Convert(ctx, usd, quote.ID)
+	assert.ErrorIs(t, err, domain.ErrQuoteExpired)
+}
@@ -0,0 +1,30 @@
+package application
+
+import (
+	"context"
+	"time"
+
+	"github.com/williamkoller/payment-system/internal/fx/domain"
+	"github.com/williamkoller/payment-system/pkg/apperror"
+)
+
+var ErrInvalidRange = apperror.New(apperror.KindValidation, "invalid_range", "report range start must be before its end")
+
+type ReportRepository interface {
+	SettlementTotals(ctx context.Context, from, to time.Time) ([]domain.SettlementTotal, error)
+}
+
+type ReportService struct {
+	Repository ReportRepository
+}
+
+func NewReportService(repository ReportRepository) *ReportService {
+	return &ReportService{Repository: repository}
+}
+
+func (s *ReportService) SettlementReport(ctx context.Context, from, to time.Time) ([]domain.SettlementTotal, error) {
+	if !from.Before(to) {
+		return nil, ErrInvalidRange
+	}
+	return s.Repository.SettlementTotals(ctx, from, to)
+}
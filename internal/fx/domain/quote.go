@@ -0,0 +1,69 @@
+package domain
+
+import (
+	"strings"
+	"time"
+
+	"github.com/williamkoller/payment-system/pkg/apperror"
+)
+
+var (
+	ErrQuoteExpired  = apperror.New(apperror.KindUnprocessable, "fx_quote_expired", "fx quote expired")
+	ErrQuoteMismatch = apperror.New(apperror.KindUnprocessable, "fx_quote_mismatch", "fx quote does not match the payment currencies")
+)
+
+// Rate is a provider's price of one unit of From expressed in To.
+type Rate struct {
+	From  string
+	To    string
+	Value string
+	AsOf  time.Time
+}
+
+// FxQuote is a rate locked for a limited time so a payment created before
+// ExpiresAt settles at exactly that rate.
+type FxQuote struct {
+	ID        string
+	From      string
+	To        string
+	Rate      string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+func NewFxQuote(id string, rate Rate, ttl time.Duration) *FxQuote {
+	now := time.Now()
+	return &FxQuote{
+		ID:        id,
+		From:      strings.ToUpper(rate.From),
+		To:        strings.ToUpper(rate.To),
+		Rate:      rate.Value,
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}
+}
+
+func (q *FxQuote) Validate(from, to string, now time.Time) error {
+	if !strings.EqualFold(q.From, from) || !strings.EqualFold(q.To, to) {
+		return ErrQuoteMismatch
+	}
+	if !now.Before(q.ExpiresAt) {
+		return ErrQuoteExpired
+	}
+	return nil
+}
+
+// Conversion is the settlement side of a payment.
+type Conversion struct {
+	Amount   int64
+	Currency string
+	Rate     string
+	QuoteID  string
+}
+
+type SettlementTotal struct {
+	Status             string
+	SettlementCurrency string
+	Count              int64
+	Amount             int64
+}
@@ -0,0 +1,79 @@
+package infra
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/williamkoller/payment-system/internal/fx/domain"
+)
+
+// HTTPRateProvider fetches rates from GET {baseURL}/rates?from=USD&to=BRL,
+// which must answer with rateResponse. StubHandler implements the same
+// contract for local development.
+type HTTPRateProvider struct {
+	baseURL string
+	client  *http.Client
+}
+
+type rateResponse struct {
+	From string    `json:"from"`
+	To   string    `json:"to"`
+	Rate string    `json:"rate"`
+	AsOf time.Time `json:"as_of"`
+}
+
+func NewHTTPRateProvider(baseURL string) *HTTPRateProvider {
+	return &HTTPRateProvider{
+		baseURL: baseURL,
+		client:  &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+func (p *HTTPRateProvider) Rate(ctx context.Context, from, to string) (domain.Rate, error) {
+	query := url.Values{"from": {from}, "to": {to}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+"/rates?"+query.Encode(), nil)
+	if err != nil {
+		return domain.Rate{}, err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return domain.Rate{}, fmt.Errorf("fx provider request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return domain.Rate{}, fmt.Errorf("fx provider returned status %d", resp.StatusCode)
+	}
+
+	var body rateResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return domain.Rate{}, fmt.Errorf("invalid fx provider response: %w", err)
+	}
+
+	return domain.Rate{From: body.From, To: body.To, Value: body.Rate, AsOf: body.AsOf}, nil
+}
+
+// StubHandler serves the HTTPRateProvider contract from a static table so
+// the HTTP provider can be exercised without a real rates vendor.
+func StubHandler(rates *StaticRateProvider) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rates" {
+			http.NotFound(w, r)
+			return
+		}
+
+		rate, err := rates.Rate(r.Context(), r.URL.Query().Get("from"), r.URL.Query().Get("to"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(rateResponse{From: rate.From, To: rate.To, Rate: rate.Value, AsOf: rate.AsOf})
+	})
+}
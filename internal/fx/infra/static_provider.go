@@ -0,0 +1,70 @@
+package infra
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/williamkoller/payment-system/internal/fx/domain"
+	"github.com/williamkoller/payment-system/pkg/money"
+)
+
+// StaticRateProvider serves rates from a fixed table keyed by "FROM/TO",
+// e.g. {"rates": {"USD/BRL": "5.4231"}}. Missing pairs are answered with
+// the inverse of the opposite pair when that one is present.
+type StaticRateProvider struct {
+	rates map[string]string
+	asOf  time.Time
+}
+
+type staticRatesFile struct {
+	Rates map[string]string `json:"rates"`
+}
+
+func NewStaticRateProvider(rates map[string]string) *StaticRateProvider {
+	normalized := make(map[string]string, len(rates))
+	for pair, rate := range rates {
+		normalized[strings.ToUpper(pair)] = rate
+	}
+	return &StaticRateProvider{rates: normalized, asOf: time.Now()}
+}
+
+func LoadStaticRateProvider(path string) (*StaticRateProvider, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read fx rates file: %w", err)
+	}
+
+	var file staticRatesFile
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return nil, fmt.Errorf("invalid fx rates file: %w", err)
+	}
+
+	return NewStaticRateProvider(file.Rates), nil
+}
+
+func (p *StaticRateProvider) Rate(_ context.Context, from, to string) (domain.Rate, error) {
+	from, to = strings.ToUpper(from), strings.ToUpper(to)
+
+	if from == to {
+		return domain.Rate{From: from, To: to, Value: "1", AsOf: p.asOf}, nil
+	}
+
+	if rate, ok := p.rates[from+"/"+to]; ok {
+		return domain.Rate{From: from, To: to, Value: rate, AsOf: p.asOf}, nil
+	}
+
+	if inverse, ok := p.rates[to+"/"+from]; ok {
+		r, err := money.ParseRate(inverse)
+		if err != nil {
+			return domain.Rate{}, err
+		}
+		return domain.Rate{From: from, To: to, Value: new(big.Rat).Inv(r).FloatString(10), AsOf: p.asOf}, nil
+	}
+
+	return domain.Rate{}, fmt.Errorf("no fx rate for %s/%s", from, to)
+}
@@ -0,0 +1,16 @@
+package interfaces
+
+import "time"
+
+type CreateQuoteDto struct {
+	Currency string `json:"currency" binding:"required,currency"`
+}
+
+type IdentifyQuoteDto struct {
+	QuoteID string `uri:"quote_id" binding:"required"`
+}
+
+type SettlementReportDto struct {
+	From time.Time `form:"from" binding:"required" time_format:"2006-01-02"`
+	To   time.Time `form:"to" binding:"required" time_format:"2006-01-02"`
+}
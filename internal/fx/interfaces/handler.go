@@ -0,0 +1,67 @@
+package interfaces
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/williamkoller/payment-system/internal/fx/application"
+	"github.com/williamkoller/payment-system/internal/middleware"
+	"github.com/williamkoller/payment-system/pkg/apperror"
+)
+
+type FxHandler struct {
+	Quotes  *application.QuoteService
+	Reports *application.ReportService
+}
+
+func NewFxHandler(quotes *application.QuoteService, reports *application.ReportService) *FxHandler {
+	return &FxHandler{Quotes: quotes, Reports: reports}
+}
+
+func (h *FxHandler) CreateQuote(c *gin.Context) {
+	var dto CreateQuoteDto
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		middleware.Problem(c, apperror.Validation(err))
+		return
+	}
+
+	quote, err := h.Quotes.CreateQuote(c.Request.Context(), dto.Currency)
+	if err != nil {
+		middleware.Problem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, ToQuoteResponse(quote))
+}
+
+func (h *FxHandler) GetQuote(c *gin.Context) {
+	var uri IdentifyQuoteDto
+	if err := c.ShouldBindUri(&uri); err != nil {
+		middleware.Problem(c, apperror.Validation(err))
+		return
+	}
+
+	quote, err := h.Quotes.FindQuote(c.Request.Context(), uri.QuoteID)
+	if err != nil {
+		middleware.Problem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ToQuoteResponse(quote))
+}
+
+func (h *FxHandler) SettlementReport(c *gin.Context) {
+	var query SettlementReportDto
+	if err := c.ShouldBindQuery(&query); err != nil {
+		middleware.Problem(c, apperror.Validation(err))
+		return
+	}
+
+	totals, err := h.Reports.SettlementReport(c.Request.Context(), query.From, query.To)
+	if err != nil {
+		middleware.Problem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ToSettlementTotalResponses(totals))
+}
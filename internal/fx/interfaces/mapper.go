@@ -0,0 +1,54 @@
+package interfaces
+
+import (
+	"time"
+
+	"github.com/williamkoller/payment-system/internal/fx/domain"
+	"github.com/williamkoller/payment-system/pkg/money"
+)
+
+type QuoteResponse struct {
+	ID        string    `json:"id"`
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Rate      string    `json:"rate"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type SettlementTotalResponse struct {
+	Status             string `json:"status"`
+	SettlementCurrency string `json:"settlement_currency"`
+	Count              int64  `json:"count"`
+	Amount             int64  `json:"amount"`
+	DisplayAmount      string `json:"display_amount"`
+}
+
+func ToQuoteResponse(q *domain.FxQuote) QuoteResponse {
+	return QuoteResponse{
+		ID:        q.ID,
+		From:      q.From,
+		To:        q.To,
+		Rate:      q.Rate,
+		ExpiresAt: q.ExpiresAt,
+		CreatedAt: q.CreatedAt,
+	}
+}
+
+func ToSettlementTotalResponses(totals []domain.SettlementTotal) []SettlementTotalResponse {
+	responses := make([]SettlementTotalResponse, 0, len(totals))
+	for _, t := range totals {
+		display := ""
+		if m, err := money.New(t.Amount, t.SettlementCurrency); err == nil {
+			display = m.Format()
+		}
+		responses = append(responses, SettlementTotalResponse{
+			Status:             t.Status,
+			SettlementCurrency: t.SettlementCurrency,
+			Count:              t.Count,
+			Amount:             t.Amount,
+			DisplayAmount:      display,
+		})
+	}
+	return responses
+}
@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/williamkoller/payment-system/internal/fx/domain"
+	"github.com/williamkoller/payment-system/pkg/tenant"
+	"gorm.io/gorm"
+)
+
+type QuoteRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewQuoteRepository(db *gorm.DB) *QuoteRepositoryImpl {
+	return &QuoteRepositoryImpl{db: db}
+}
+
+func (r *QuoteRepositoryImpl) Save(ctx context.Context, quote *domain.FxQuote) error {
+	return r.db.WithContext(ctx).Create(quote).Error
+}
+
+func (r *QuoteRepositoryImpl) FindByID(ctx context.Context, id string) (*domain.FxQuote, error) {
+	var quote domain.FxQuote
+	if err := r.db.WithContext(ctx).First(&quote, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &quote, nil
+}
+
+// SettlementTotals sums the merchant's payments created in [from, to) by
+// status in their settlement currency.
+func (r *QuoteRepositoryImpl) SettlementTotals(ctx context.Context, from, to time.Time) ([]domain.SettlementTotal, error) {
+	var totals []domain.SettlementTotal
+	err := r.db.WithContext(ctx).
+		Table("payments").
+		Scopes(tenant.Scope(ctx)).
+		Select("status, settlement_currency, COUNT(*) AS count, COALESCE(SUM(settlement_amount), 0) AS amount").
+		Where("created_at >= ? AND created_at < ?", from, to).
+		Group("status, settlement_currency").
+		Order("status, settlement_currency").
+		Scan(&totals).Error
+	if err != nil {
+		return nil, err
+	}
+	return totals, nil
+}
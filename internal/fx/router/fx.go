@@ -0,0 +1,66 @@
+package router
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/williamkoller/payment-system/config"
+	"github.com/williamkoller/payment-system/internal/fx/application"
+	"github.com/williamkoller/payment-system/internal/fx/infra"
+	"github.com/williamkoller/payment-system/internal/fx/interfaces"
+	"github.com/williamkoller/payment-system/internal/fx/repository"
+	"github.com/williamkoller/payment-system/internal/middleware"
+	paymentDtos "github.com/williamkoller/payment-system/internal/payment/dtos"
+	ratelimit "github.com/williamkoller/payment-system/internal/ratelimit/domain"
+	"github.com/williamkoller/payment-system/pkg/auth"
+	"gorm.io/gorm"
+)
+
+func NewRateProvider(cfg config.FxConfiguration) (application.RateProvider, error) {
+	switch cfg.Provider {
+	case "http":
+		if cfg.HTTPURL == "" {
+			return nil, errors.New("FX_HTTP_URL is required for the http fx provider")
+		}
+		return infra.NewHTTPRateProvider(cfg.HTTPURL), nil
+	case "static":
+		if cfg.RatesFile == "" {
+			return infra.NewStaticRateProvider(nil), nil
+		}
+		return infra.LoadStaticRateProvider(cfg.RatesFile)
+	default:
+		return nil, errors.New("unknown FX_PROVIDER: " + cfg.Provider)
+	}
+}
+
+func NewQuoteService(db *gorm.DB, cfg config.FxConfiguration) (*application.QuoteService, error) {
+	provider, err := NewRateProvider(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return application.NewQuoteService(provider, repository.NewQuoteRepository(db), cfg.SettlementCurrency, cfg.QuoteTTL), nil
+}
+
+func NewReportService(db *gorm.DB) *application.ReportService {
+	return application.NewReportService(repository.NewQuoteRepository(db))
+}
+
+// SetupRouter mounts the quote and settlement report routes behind authn.
+// Quotes are taken to create payments, so they need the same scope.
+func SetupRouter(e *gin.Engine, quotes *application.QuoteService, reports *application.ReportService, authn gin.HandlerFunc, limits *middleware.RateLimits) {
+	if err := paymentDtos.RegisterValidations(); err != nil {
+		panic("cannot register payment validations: " + err.Error())
+	}
+
+	handler := interfaces.NewFxHandler(quotes, reports)
+
+	read := middleware.RequireScope(auth.ScopePaymentsRead)
+	write := middleware.RequireScope(auth.ScopePaymentsWrite)
+
+	fx := e.Group("/fx", limits.PerIP(), authn)
+	{
+		fx.POST("/quotes", write, limits.PerClient(ratelimit.ClassWrite), handler.CreateQuote)
+		fx.GET("/quotes/:quote_id", read, limits.PerClient(ratelimit.ClassRead), handler.GetQuote)
+	}
+	e.GET("/reports/settlement", limits.PerIP(), authn, read, limits.PerClient(ratelimit.ClassRead), handler.SettlementReport)
+}
@@ -0,0 +1,139 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusUp   Status = "up"
+	StatusDown Status = "down"
+)
+
+// Checker reports whether one dependency is usable. Check must honour ctx
+// cancellation; the service bounds every call with a timeout.
+type Checker interface {
+	Name() string
+	Check(ctx context.Context) error
+}
+
+type CheckResult struct {
+	Name      string    `json:"name"`
+	Status    Status    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	LatencyMs int64     `json:"latency_ms"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+type Report struct {
+	Status Status        `json:"status"`
+	Checks []CheckResult `json:"checks"`
+}
+
+type registration struct {
+	checker Checker
+	timeout time.Duration
+}
+
+// Service runs the registered checkers concurrently and caches each result
+// for cacheTTL, so frequent probes do not hammer the dependencies.
+type Service struct {
+	timeout  time.Duration
+	cacheTTL time.Duration
+
+	mu       sync.Mutex
+	checkers []registration
+	cache    map[string]CheckResult
+}
+
+func NewService(timeout, cacheTTL time.Duration) *Service {
+	return &Service{
+		timeout:  timeout,
+		cacheTTL: cacheTTL,
+		cache:    make(map[string]CheckResult),
+	}
+}
+
+// Register adds a readiness checker using the service's default timeout.
+func (s *Service) Register(checker Checker) {
+	s.RegisterWithTimeout(checker, s.timeout)
+}
+
+func (s *Service) RegisterWithTimeout(checker Checker, timeout time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checkers = append(s.checkers, registration{checker: checker, timeout: timeout})
+}
+
+// Ready runs every checker, or reuses its cached result, and is up only
+// when all of them are.
+func (s *Service) Ready(ctx context.Context) Report {
+	s.mu.Lock()
+	checkers := append([]registration(nil), s.checkers...)
+	s.mu.Unlock()
+
+	results := make([]CheckResult, len(checkers))
+	var wg sync.WaitGroup
+	for i, r := range checkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = s.check(ctx, r)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusUp, Checks: results}
+	for _, result := range results {
+		if result.Status != StatusUp {
+			report.Status = StatusDown
+		}
+	}
+	return report
+}
+
+func (s *Service) check(ctx context.Context, r registration) CheckResult {
+	name := r.checker.Name()
+	now := time.Now()
+
+	s.mu.Lock()
+	cached, ok := s.cache[name]
+	s.mu.Unlock()
+	if ok && now.Sub(cached.CheckedAt) < s.cacheTTL {
+		return cached
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	errc := make(chan error, 1)
+	go func() { errc <- r.checker.Check(ctx) }()
+
+	var err error
+	select {
+	case err = <-errc:
+	case <-ctx.Done():
+		err = fmt.Errorf("timed out after %s", r.timeout)
+	}
+
+	result := CheckResult{
+		Name:      name,
+		Status:    StatusUp,
+		LatencyMs: time.Since(now).Milliseconds(),
+		CheckedAt: now,
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+
+	s.mu.Lock()
+	s.cache[name] = result
+	s.mu.Unlock()
+
+	return result
+}
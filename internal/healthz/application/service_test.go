@@ -0,0 +1,68 @@
+package application_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/williamkoller/payment-system/internal/healthz/application"
+)
+
+type fakeChecker struct {
+	name  string
+	err   error
+	delay time.Duration
+	calls atomic.Int32
+}
+
+func (f *fakeChecker) Name() string { return f.name }
+
+func (f *fakeChecker) Check(ctx context.Context) error {
+	f.calls.Add(1)
+	select {
+	case <-time.After(f.delay):
+		return f.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestService_Ready(t *testing.T) {
+	db := &fakeChecker{name: "database"}
+	breaker := &fakeChecker{name: "stripe_circuit_breaker", err: errors.New("circuit breaker is open")}
+	slow := &fakeChecker{name: "slow", delay: time.Second}
+
+	service := application.NewService(50*time.Millisecond, time.Minute)
+	service.Register(db)
+	service.Register(breaker)
+	service.RegisterWithTimeout(slow, 10*time.Millisecond)
+
+	report := service.Ready(context.Background())
+
+	assert.Equal(t, application.StatusDown, report.Status)
+	byName := map[string]application.CheckResult{}
+	for _, check := range report.Checks {
+		byName[check.Name] = check
+	}
+	assert.Equal(t, application.StatusUp, byName["database"].Status)
+	assert.Equal(t, "circuit breaker is open", byName["stripe_circuit_breaker"].Error)
+	assert.Contains(t, byName["slow"].Error, "timed out")
+}
+
+func TestService_Ready_CachesResults(t *testing.T) {
+	db := &fakeChecker{name: "database"}
+	service := application.NewService(time.Second, time.Minute)
+	service.Register(db)
+
+	assert.Equal(t, application.StatusUp, service.Ready(context.Background()).Status)
+	assert.Equal(t, application.StatusUp, service.Ready(context.Background()).Status)
+	assert.Equal(t, int32(1), db.calls.Load())
+}
+
+func TestService_Ready_NoCheckers(t *testing.T) {
+	service := application.NewService(time.Second, 0)
+	assert.Equal(t, application.StatusUp, service.Ready(context.Background()).Status)
+}
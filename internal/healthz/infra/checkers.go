@@ -0,0 +1,144 @@
+package infra
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sony/gobreaker"
+	"github.com/williamkoller/payment-system/pkg/heartbeat"
+)
+
+type DBChecker struct {
+	db *sql.DB
+}
+
+func NewDBChecker(db *sql.DB) *DBChecker {
+	return &DBChecker{db: db}
+}
+
+func (c *DBChecker) Name() string { return "database" }
+
+func (c *DBChecker) Check(ctx context.Context) error {
+	return c.db.PingContext(ctx)
+}
+
+// MigrationChecker fails while the schema is behind the migrations shipped
+// with the binary or a migration was left dirty.
+type MigrationChecker struct {
+	db       *sql.DB
+	expected uint
+}
+
+func NewMigrationChecker(db *sql.DB, expected uint) *MigrationChecker {
+	return &MigrationChecker{db: db, expected: expected}
+}
+
+func (c *MigrationChecker) Name() string { return "migrations" }
+
+func (c *MigrationChecker) Check(ctx context.Context) error {
+	var version uint
+	var dirty bool
+	err := c.db.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	if err != nil {
+		return fmt.Errorf("cannot read schema version: %w", err)
+	}
+	if dirty {
+		return fmt.Errorf("migration %d is dirty", version)
+	}
+	if version < c.expected {
+		return fmt.Errorf("schema version %d is behind %d", version, c.expected)
+	}
+	return nil
+}
+
+// LatestMigrationVersion returns the highest version among the
+// NNNNNN_name.up.sql files in dir.
+func LatestMigrationVersion(dir string) (uint, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.up.sql"))
+	if err != nil {
+		return 0, err
+	}
+
+	var latest uint
+	for _, file := range files {
+		prefix, _, ok := strings.Cut(filepath.Base(file), "_")
+		if !ok {
+			continue
+		}
+		version, err := strconv.ParseUint(prefix, 10, 64)
+		if err != nil {
+			continue
+		}
+		if uint(version) > latest {
+			latest = uint(version)
+		}
+	}
+
+	if latest == 0 {
+		return 0, fmt.Errorf("no migrations found in %s", dir)
+	}
+	return latest, nil
+}
+
+// DefaultMigrationsDir mirrors the path config.RunMigrations applies.
+func DefaultMigrationsDir() string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return "db/migrations"
+	}
+	return filepath.Join(wd, "db/migrations")
+}
+
+type BreakerStater interface {
+	BreakerState() gobreaker.State
+}
+
+// BreakerChecker reports the service unready while the circuit breaker is
+// open; half-open still lets trial requests through.
+type BreakerChecker struct {
+	name    string
+	breaker BreakerStater
+}
+
+func NewBreakerChecker(name string, breaker BreakerStater) *BreakerChecker {
+	return &BreakerChecker{name: name, breaker: breaker}
+}
+
+func (c *BreakerChecker) Name() string { return c.name }
+
+func (c *BreakerChecker) Check(context.Context) error {
+	if state := c.breaker.BreakerState(); state == gobreaker.StateOpen {
+		return fmt.Errorf("circuit breaker is %s", state)
+	}
+	return nil
+}
+
+// HeartbeatChecker fails when a background loop has not beaten for maxAge.
+type HeartbeatChecker struct {
+	name      string
+	heartbeat *heartbeat.Heartbeat
+	maxAge    time.Duration
+}
+
+func NewHeartbeatChecker(name string, hb *heartbeat.Heartbeat, maxAge time.Duration) *HeartbeatChecker {
+	return &HeartbeatChecker{name: name, heartbeat: hb, maxAge: maxAge}
+}
+
+func (c *HeartbeatChecker) Name() string { return c.name }
+
+func (c *HeartbeatChecker) Check(context.Context) error {
+	last := c.heartbeat.Last()
+	if last.IsZero() {
+		return fmt.Errorf("worker has not started")
+	}
+	if age := time.Since(last); age > c.maxAge {
+		return fmt.Errorf("last heartbeat %s ago", age.Truncate(time.Second))
+	}
+	return nil
+}
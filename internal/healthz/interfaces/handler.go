@@ -0,0 +1,42 @@
+package interfaces
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/williamkoller/payment-system/internal/healthz/application"
+)
+
+type HealthHandler struct {
+	Service *application.Service
+	// Details adds the per-check report to /readyz responses.
+	Details bool
+}
+
+func NewHealthHandler(service *application.Service, details bool) *HealthHandler {
+	return &HealthHandler{Service: service, Details: details}
+}
+
+// Live only reports that the process is serving requests; it never touches
+// dependencies, so an outage cannot make the orchestrator restart us.
+func (h *HealthHandler) Live(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+func (h *HealthHandler) Ready(c *gin.Context) {
+	report := h.Service.Ready(c.Request.Context())
+
+	code := http.StatusOK
+	status := "ok"
+	if report.Status != application.StatusUp {
+		code = http.StatusServiceUnavailable
+		status = "unavailable"
+	}
+
+	if !h.Details {
+		c.JSON(code, gin.H{"status": status})
+		return
+	}
+
+	c.JSON(code, gin.H{"status": status, "checks": report.Checks})
+}
@@ -1,11 +1,17 @@
 package router
 
-import "github.com/gin-gonic/gin"
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/williamkoller/payment-system/internal/healthz/application"
+	"github.com/williamkoller/payment-system/internal/healthz/interfaces"
+)
 
-func SetupRouter(e *gin.Engine) *gin.Engine {
-	e.GET("/healthz", func(c *gin.Context) {
-		c.JSON(200, gin.H{"status": "ok"})
-	})
+func SetupRouter(e *gin.Engine, service *application.Service, details bool) *gin.Engine {
+	handler := interfaces.NewHealthHandler(service, details)
+
+	e.GET("/healthz", handler.Live)
+	e.GET("/livez", handler.Live)
+	e.GET("/readyz", handler.Ready)
 
 	return e
 }
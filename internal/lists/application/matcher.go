@@ -0,0 +1,167 @@
+package application
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/williamkoller/payment-system/internal/lists/domain"
+	"github.com/williamkoller/payment-system/pkg/heartbeat"
+	"github.com/williamkoller/payment-system/pkg/logger"
+)
+
+type ipRangeEntry struct {
+	network *net.IPNet
+	entry   *domain.ListEntry
+}
+
+type binRangeEntry struct {
+	low, high string
+	entry     *domain.ListEntry
+}
+
+// snapshot is an immutable, indexed copy of the active entries of one list.
+type snapshot struct {
+	emails       map[string]*domain.ListEntry
+	fingerprints map[string]*domain.ListEntry
+	ipRanges     []ipRangeEntry
+	binRanges    []binRangeEntry
+}
+
+func newSnapshot() *snapshot {
+	return &snapshot{
+		emails:       make(map[string]*domain.ListEntry),
+		fingerprints: make(map[string]*domain.ListEntry),
+	}
+}
+
+func (s *snapshot) add(e *domain.ListEntry) {
+	switch e.Type {
+	case domain.TypeEmail:
+		s.emails[e.Value] = e
+	case domain.TypeCardFingerprint:
+		s.fingerprints[e.Value] = e
+	case domain.TypeIPRange:
+		if _, network, err := net.ParseCIDR(e.Value); err == nil {
+			s.ipRanges = append(s.ipRanges, ipRangeEntry{network: network, entry: e})
+		}
+	case domain.TypeBinRange:
+		if low, high, ok := strings.Cut(e.Value, "-"); ok {
+			s.binRanges = append(s.binRanges, binRangeEntry{low: low, high: high, entry: e})
+		}
+	}
+}
+
+func (s *snapshot) find(subject domain.Subject, now time.Time) *domain.ListEntry {
+	candidates := make([]*domain.ListEntry, 0, 4)
+
+	if subject.Email != "" {
+		candidates = append(candidates, s.emails[strings.ToLower(subject.Email)])
+	}
+	if subject.CardFingerprint != "" {
+		candidates = append(candidates, s.fingerprints[subject.CardFingerprint])
+	}
+	if ip := net.ParseIP(subject.IP); ip != nil {
+		for _, r := range s.ipRanges {
+			if r.network.Contains(ip) {
+				candidates = append(candidates, r.entry)
+				break
+			}
+		}
+	}
+	if subject.CardBin != "" {
+		for _, r := range s.binRanges {
+			if len(subject.CardBin) < len(r.low) {
+				continue
+			}
+			prefix := subject.CardBin[:len(r.low)]
+			if prefix >= r.low && prefix <= r.high {
+				candidates = append(candidates, r.entry)
+				break
+			}
+		}
+	}
+
+	for _, e := range candidates {
+		if e != nil && e.IsActive(now) {
+			return e
+		}
+	}
+	return nil
+}
+
+// Matcher answers list lookups from memory. The snapshot is rebuilt from
+// the repository on Refresh, which runs on a timer and after every change
+// made through this instance.
+type Matcher struct {
+	repository ListRepository
+	mu         sync.RWMutex
+	block      *snapshot
+	allow      *snapshot
+
+	// Heartbeat beats every time the refresh loop wakes up, for readiness
+	// checks.
+	Heartbeat heartbeat.Heartbeat
+}
+
+func NewMatcher(repository ListRepository) *Matcher {
+	return &Matcher{repository: repository, block: newSnapshot(), allow: newSnapshot()}
+}
+
+func (m *Matcher) Refresh(ctx context.Context) error {
+	entries, err := m.repository.FindActive(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	block, allow := newSnapshot(), newSnapshot()
+	for _, e := range entries {
+		if e.Kind == domain.KindAllow {
+			allow.add(e)
+		} else {
+			block.add(e)
+		}
+	}
+
+	m.mu.Lock()
+	m.block, m.allow = block, allow
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *Matcher) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	m.Heartbeat.Beat()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.Heartbeat.Beat()
+			if err := m.Refresh(ctx); err != nil {
+				logger.Error("cannot refresh block/allow lists", "err", err)
+			}
+		}
+	}
+}
+
+// Check returns the entry the subject matches, if any. Blocklist entries
+// take precedence over allowlist entries.
+func (m *Matcher) Check(subject domain.Subject) *domain.Match {
+	m.mu.RLock()
+	block, allow := m.block, m.allow
+	m.mu.RUnlock()
+
+	now := time.Now()
+	if e := block.find(subject, now); e != nil {
+		return &domain.Match{EntryID: e.ID, Kind: e.Kind, Type: e.Type, Reason: e.Reason}
+	}
+	if e := allow.find(subject, now); e != nil {
+		return &domain.Match{EntryID: e.ID, Kind: e.Kind, Type: e.Type, Reason: e.Reason}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package application_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/williamkoller/payment-system/internal/lists/application"
+	"github.com/williamkoller/payment-system/internal/lists/domain"
+)
+
+type fakeLists []*domain.ListEntry
+
+func (f fakeLists) Save(context.Context, *domain.ListEntry, *domain.ListEntryAudit) error {
+	return nil
+}
+
+func (f fakeLists) Update(context.Context, *domain.ListEntry, *domain.ListEntryAudit) error {
+	return nil
+}
+
+func (f fakeLists) FindByID(context.Context, string) (*domain.ListEntry, error) { return nil, nil }
+
+func (f fakeLists) FindAll(context.Context, application.ListFilter) ([]*domain.ListEntry, error) {
+	return f, nil
+}
+
+func (f fakeLists) FindActive(context.Context, time.Time) ([]*domain.ListEntry, error) {
+	return f, nil
+}
+
+func (f fakeLists) FindAudit(context.Context, string) ([]*domain.ListEntryAudit, error) {
+	return nil, nil
+}
+
+func mustEntry(t *testing.T, kind domain.ListKind, entryType domain.EntryType, value string) *domain.ListEntry {
+	e, err := domain.NewListEntry(value, kind, entryType, value, "test", "ops", nil)
+	assert.NoError(t, err)
+	return e
+}
+
+func TestMatcher_Check(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+	expired := mustEntry(t, domain.KindBlock, domain.TypeEmail, "old@example.com")
+	expired.ExpiresAt = &past
+
+	matcher := application.NewMatcher(fakeLists{
+		mustEntry(t, domain.KindBlock, domain.TypeEmail, "Fraud@Example.com"),
+		mustEntry(t, domain.KindBlock, domain.TypeIPRange, "203.0.113.0/24"),
+		mustEntry(t, domain.KindBlock, domain.TypeBinRange, "411111-411199"),
+		mustEntry(t, domain.KindAllow, domain.TypeCardFingerprint, "fp_vip"),
+		expired,
+	})
+	assert.NoError(t, matcher.Refresh(context.Background()))
+
+	cases := []struct {
+		name    string
+		subject domain.Subject
+		code    string
+		kind    domain.ListKind
+	}{
+		{"email", domain.Subject{Email: "fraud@example.com"}, "blocklisted_email", domain.KindBlock},
+		{"ip range", domain.Subject{IP: "203.0.113.77"}, "blocklisted_ip", domain.KindBlock},
+		{"bin range", domain.Subject{CardBin: "41111234"}, "blocklisted_bin", domain.KindBlock},
+		{"block beats allow", domain.Subject{Email: "fraud@example.com", CardFingerprint: "fp_vip"}, "blocklisted_email", domain.KindBlock},
+		{"allow", domain.Subject{CardFingerprint: "fp_vip"}, "blocklisted_card", domain.KindAllow},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			match := matcher.Check(tc.subject)
+			if assert.NotNil(t, match) {
+				assert.Equal(t, tc.kind, match.Kind)
+				assert.Equal(t, tc.code, match.DeclineCode())
+			}
+		})
+	}
+
+	assert.Nil(t, matcher.Check(domain.Subject{Email: "old@example.com", IP: "198.51.100.1", CardBin: "411200"}))
+}
+
+func TestNormalizeValue(t *testing.T) {
+	v, err := domain.NormalizeValue(domain.TypeIPRange, "10.0.0.1")
+	assert.NoError(t, err)
+	assert.Equal(t, "10.0.0.1/32", v)
+
+	v, err = domain.NormalizeValue(domain.TypeBinRange, "424242")
+	assert.NoError(t, err)
+	assert.Equal(t, "424242-424242", v)
+
+	_, err = domain.NormalizeValue(domain.TypeBinRange, "411199-411111")
+	assert.Error(t, err)
+
+	_, err = domain.NormalizeValue(domain.TypeIPRange, "not-an-ip")
+	assert.Error(t, err)
+}
@@ -0,0 +1,192 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	auditDomain "github.com/williamkoller/payment-system/internal/audit/domain"
+	"github.com/williamkoller/payment-system/internal/lists/domain"
+	"github.com/williamkoller/payment-system/pkg/apperror"
+	"github.com/williamkoller/payment-system/pkg/logger"
+	"github.com/williamkoller/payment-system/pkg/ulid"
+	"gorm.io/gorm"
+)
+
+var ErrEntryNotFound = apperror.New(apperror.KindNotFound, "list_entry_not_found", "list entry not found")
+
+type ListRepository interface {
+	Save(ctx context.Context, entry *domain.ListEntry, audit *domain.ListEntryAudit) error
+	Update(ctx context.Context, entry *domain.ListEntry, audit *domain.ListEntryAudit) error
+	FindByID(ctx context.Context, id string) (*domain.ListEntry, error)
+	FindAll(ctx context.Context, filter ListFilter) ([]*domain.ListEntry, error)
+	FindActive(ctx context.Context, now time.Time) ([]*domain.ListEntry, error)
+	FindAudit(ctx context.Context, entryID string) ([]*domain.ListEntryAudit, error)
+}
+
+type ListFilter struct {
+	Kind domain.ListKind
+	Type domain.EntryType
+}
+
+type CreateEntryInput struct {
+	Kind      domain.ListKind
+	Type      domain.EntryType
+	Value     string
+	Reason    string
+	ExpiresAt *time.Time
+}
+
+type UpdateEntryInput struct {
+	Reason    string
+	ExpiresAt *time.Time
+}
+
+// Auditor records changes to the lists in the audit log.
+type Auditor interface {
+	Record(ctx context.Context, entry *auditDomain.Entry)
+}
+
+// ListService manages the block and allow lists. Changes are attributed to
+// the actor of the context they are made with.
+type ListService struct {
+	Repository ListRepository
+	Matcher    *Matcher
+	// AuditLog is optional; without it changes are only kept in the
+	// entry's own history.
+	AuditLog Auditor
+}
+
+func NewListService(repository ListRepository, matcher *Matcher) *ListService {
+	return &ListService{Repository: repository, Matcher: matcher}
+}
+
+func (s *ListService) Create(ctx context.Context, input CreateEntryInput) (*domain.ListEntry, error) {
+	actor := auditDomain.Actor(ctx)
+	entry, err := domain.NewListEntry(ulid.NewULID(), input.Kind, input.Type, input.Value, input.Reason, actor, input.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.Repository.Save(ctx, entry, newAudit(entry.ID, domain.AuditCreated, actor, nil, entry)); err != nil {
+		return nil, err
+	}
+	s.audit(ctx, "list_entry.create", nil, entry)
+
+	s.refresh(ctx)
+	return entry, nil
+}
+
+func (s *ListService) Find(ctx context.Context, id string) (*domain.ListEntry, error) {
+	entry, err := s.Repository.FindByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrEntryNotFound.Wrap(err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (s *ListService) List(ctx context.Context, filter ListFilter) ([]*domain.ListEntry, error) {
+	return s.Repository.FindAll(ctx, filter)
+}
+
+func (s *ListService) Update(ctx context.Context, id string, input UpdateEntryInput) (*domain.ListEntry, error) {
+	entry, err := s.Find(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	before := *entry
+	entry.Update(input.Reason, input.ExpiresAt)
+
+	if err := s.Repository.Update(ctx, entry, newAudit(entry.ID, domain.AuditUpdated, auditDomain.Actor(ctx), &before, entry)); err != nil {
+		return nil, err
+	}
+	s.audit(ctx, "list_entry.update", &before, entry)
+
+	s.refresh(ctx)
+	return entry, nil
+}
+
+func (s *ListService) Delete(ctx context.Context, id string) error {
+	entry, err := s.Find(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	before := *entry
+	entry.Delete()
+
+	if err := s.Repository.Update(ctx, entry, newAudit(entry.ID, domain.AuditDeleted, auditDomain.Actor(ctx), &before, entry)); err != nil {
+		return err
+	}
+	s.audit(ctx, "list_entry.delete", &before, entry)
+
+	s.refresh(ctx)
+	return nil
+}
+
+func (s *ListService) Audit(ctx context.Context, id string) ([]*domain.ListEntryAudit, error) {
+	if _, err := s.Find(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.Repository.FindAudit(ctx, id)
+}
+
+func (s *ListService) refresh(ctx context.Context) {
+	if err := s.Matcher.Refresh(ctx); err != nil {
+		logger.Error("cannot refresh block/allow lists", "err", err)
+	}
+}
+
+func (s *ListService) audit(ctx context.Context, action string, before, after *domain.ListEntry) {
+	if s.AuditLog == nil {
+		return
+	}
+	entry := auditDomain.NewEntry(ctx, ulid.NewULID(), action, auditDomain.Resource{
+		Type: auditDomain.ResourceListEntry,
+		ID:   after.ID,
+	})
+	entry.SetSnapshots(entrySnapshot(before), entrySnapshot(after))
+	s.AuditLog.Record(ctx, entry)
+}
+
+// entrySnapshot is what the audit log keeps of a list entry. The value is
+// left out, as it may be an email or an IP, so the log holds no PII.
+func entrySnapshot(e *domain.ListEntry) map[string]any {
+	if e == nil {
+		return nil
+	}
+	return map[string]any{
+		"id":         e.ID,
+		"kind":       e.Kind,
+		"type":       e.Type,
+		"reason":     e.Reason,
+		"created_by": e.CreatedBy,
+		"expires_at": e.ExpiresAt,
+		"deleted_at": e.DeletedAt,
+	}
+}
+
+func newAudit(entryID string, action domain.AuditAction, actor string, before, after *domain.ListEntry) *domain.ListEntryAudit {
+	return &domain.ListEntryAudit{
+		ID:        ulid.NewULID(),
+		EntryID:   entryID,
+		Action:    action,
+		Actor:     actor,
+		Before:    snapshotJSON(before),
+		After:     snapshotJSON(after),
+		CreatedAt: time.Now(),
+	}
+}
+
+func snapshotJSON(e *domain.ListEntry) string {
+	if e == nil {
+		return "null"
+	}
+	raw, _ := json.Marshal(e)
+	return string(raw)
+}
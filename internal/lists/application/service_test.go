@@ -0,0 +1,83 @@
+package application_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	auditDomain "github.com/williamkoller/payment-system/internal/audit/domain"
+	"github.com/williamkoller/payment-system/internal/lists/application"
+	"github.com/williamkoller/payment-system/internal/lists/domain"
+	"github.com/williamkoller/payment-system/pkg/auth"
+	"gorm.io/gorm"
+)
+
+// historyLists keeps entries by id and the history written with them.
+type historyLists struct {
+	fakeLists
+	entries map[string]*domain.ListEntry
+	history []*domain.ListEntryAudit
+}
+
+func (f *historyLists) Save(_ context.Context, entry *domain.ListEntry, audit *domain.ListEntryAudit) error {
+	f.entries[entry.ID] = entry
+	f.history = append(f.history, audit)
+	return nil
+}
+
+func (f *historyLists) Update(_ context.Context, entry *domain.ListEntry, audit *domain.ListEntryAudit) error {
+	f.entries[entry.ID] = entry
+	f.history = append(f.history, audit)
+	return nil
+}
+
+func (f *historyLists) FindByID(_ context.Context, id string) (*domain.ListEntry, error) {
+	entry, ok := f.entries[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return entry, nil
+}
+
+type fakeAuditLog struct {
+	entries []*auditDomain.Entry
+}
+
+func (f *fakeAuditLog) Record(_ context.Context, entry *auditDomain.Entry) {
+	f.entries = append(f.entries, entry)
+}
+
+func TestListService_AttributesChangesToThePrincipal(t *testing.T) {
+	repo := &historyLists{entries: map[string]*domain.ListEntry{}}
+	auditLog := &fakeAuditLog{}
+	service := application.NewListService(repo, application.NewMatcher(repo))
+	service.AuditLog = auditLog
+	ctx := auth.NewContext(context.Background(), &auth.Principal{KeyID: "key_ops"})
+
+	entry, err := service.Create(ctx, application.CreateEntryInput{
+		Kind: domain.KindBlock, Type: domain.TypeEmail, Value: "fraud@example.com", Reason: "chargebacks",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "api_key:key_ops", entry.CreatedBy)
+
+	_, err = service.Update(ctx, entry.ID, application.UpdateEntryInput{Reason: "confirmed fraud"})
+	require.NoError(t, err)
+	require.NoError(t, service.Delete(ctx, entry.ID))
+
+	require.Len(t, repo.history, 3)
+	for _, h := range repo.history {
+		assert.Equal(t, "api_key:key_ops", h.Actor)
+	}
+
+	require.Len(t, auditLog.entries, 3)
+	var actions []string
+	for _, e := range auditLog.entries {
+		actions = append(actions, e.Action)
+		assert.Equal(t, "api_key:key_ops", e.Actor)
+		assert.Equal(t, auditDomain.ResourceListEntry, e.ResourceType)
+		assert.Equal(t, entry.ID, e.ResourceID)
+		assert.NotContains(t, e.Before+e.After, "fraud@example.com", "the audit log holds no PII")
+	}
+	assert.Equal(t, []string{"list_entry.create", "list_entry.update", "list_entry.delete"}, actions)
+}
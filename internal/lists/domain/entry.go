@@ -0,0 +1,192 @@
+package domain
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/williamkoller/payment-system/pkg/apperror"
+)
+
+var ErrInvalidEntry = apperror.New(apperror.KindValidation, "invalid_list_entry", "invalid list entry")
+
+type ListKind string
+
+const (
+	KindBlock ListKind = "BLOCK"
+	KindAllow ListKind = "ALLOW"
+)
+
+type EntryType string
+
+const (
+	TypeEmail           EntryType = "EMAIL"
+	TypeCardFingerprint EntryType = "CARD_FINGERPRINT"
+	TypeIPRange         EntryType = "IP_RANGE"
+	TypeBinRange        EntryType = "BIN_RANGE"
+)
+
+// DeclineCode is returned to API clients when a payment hits a blocklist.
+func (t EntryType) DeclineCode() string {
+	switch t {
+	case TypeEmail:
+		return "blocklisted_email"
+	case TypeCardFingerprint:
+		return "blocklisted_card"
+	case TypeIPRange:
+		return "blocklisted_ip"
+	case TypeBinRange:
+		return "blocklisted_bin"
+	default:
+		return "blocklisted"
+	}
+}
+
+var binPattern = regexp.MustCompile(`^[0-9]{6,8}$`)
+
+type ListEntry struct {
+	ID        string
+	Kind      ListKind
+	Type      EntryType
+	Value     string
+	Reason    string
+	CreatedBy string
+	ExpiresAt *time.Time
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt *time.Time
+}
+
+func NewListEntry(id string, kind ListKind, entryType EntryType, value, reason, createdBy string, expiresAt *time.Time) (*ListEntry, error) {
+	if kind != KindBlock && kind != KindAllow {
+		return nil, ErrInvalidEntry.WithMessage(fmt.Sprintf("invalid list kind: %q", kind))
+	}
+
+	normalized, err := NormalizeValue(entryType, value)
+	if err != nil {
+		return nil, err
+	}
+
+	if reason == "" {
+		return nil, ErrInvalidEntry.WithMessage("reason is required")
+	}
+
+	now := time.Now()
+	return &ListEntry{
+		ID:        id,
+		Kind:      kind,
+		Type:      entryType,
+		Value:     normalized,
+		Reason:    reason,
+		CreatedBy: createdBy,
+		ExpiresAt: expiresAt,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}, nil
+}
+
+// NormalizeValue validates a value for its entry type and returns the
+// canonical form stored and matched against: lower-case emails, CIDR
+// notation for IPs and "LOW-HIGH" for BIN ranges.
+func NormalizeValue(entryType EntryType, value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", ErrInvalidEntry.WithMessage("value is required")
+	}
+
+	switch entryType {
+	case TypeEmail:
+		if !strings.Contains(value, "@") {
+			return "", ErrInvalidEntry.WithMessage(fmt.Sprintf("invalid email: %q", value))
+		}
+		return strings.ToLower(value), nil
+	case TypeCardFingerprint:
+		return value, nil
+	case TypeIPRange:
+		if ip := net.ParseIP(value); ip != nil {
+			bits := 32
+			if ip.To4() == nil {
+				bits = 128
+			}
+			return fmt.Sprintf("%s/%d", ip.String(), bits), nil
+		}
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return "", ErrInvalidEntry.WithMessage(fmt.Sprintf("invalid IP range: %q", value))
+		}
+		return network.String(), nil
+	case TypeBinRange:
+		low, high, found := strings.Cut(value, "-")
+		if !found {
+			high = low
+		}
+		if !binPattern.MatchString(low) || !binPattern.MatchString(high) || len(low) != len(high) || low > high {
+			return "", ErrInvalidEntry.WithMessage(fmt.Sprintf("invalid BIN range: %q", value))
+		}
+		return low + "-" + high, nil
+	default:
+		return "", ErrInvalidEntry.WithMessage(fmt.Sprintf("invalid entry type: %q", entryType))
+	}
+}
+
+func (e *ListEntry) IsActive(now time.Time) bool {
+	if e.DeletedAt != nil {
+		return false
+	}
+	return e.ExpiresAt == nil || now.Before(*e.ExpiresAt)
+}
+
+func (e *ListEntry) Update(reason string, expiresAt *time.Time) {
+	if reason != "" {
+		e.Reason = reason
+	}
+	e.ExpiresAt = expiresAt
+	e.UpdatedAt = time.Now()
+}
+
+func (e *ListEntry) Delete() {
+	now := time.Now()
+	e.DeletedAt = &now
+	e.UpdatedAt = now
+}
+
+type AuditAction string
+
+const (
+	AuditCreated AuditAction = "CREATED"
+	AuditUpdated AuditAction = "UPDATED"
+	AuditDeleted AuditAction = "DELETED"
+)
+
+// ListEntryAudit records every change to a list entry with JSON snapshots
+// of the entry before and after the change.
+type ListEntryAudit struct {
+	ID        string
+	EntryID   string
+	Action    AuditAction
+	Actor     string
+	Before    string
+	After     string
+	CreatedAt time.Time
+}
+
+// Subject is what a payment attempt is checked against.
+type Subject struct {
+	Email           string
+	IP              string
+	CardFingerprint string
+	CardBin         string
+}
+
+type Match struct {
+	EntryID string
+	Kind    ListKind
+	Type    EntryType
+	Reason  string
+}
+
+func (m *Match) DeclineCode() string {
+	return m.Type.DeclineCode()
+}
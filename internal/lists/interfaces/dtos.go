@@ -0,0 +1,29 @@
+package interfaces
+
+import (
+	"time"
+
+	"github.com/williamkoller/payment-system/internal/lists/domain"
+)
+
+type CreateEntryDto struct {
+	Kind      domain.ListKind  `json:"kind" binding:"required,oneof=BLOCK ALLOW"`
+	Type      domain.EntryType `json:"type" binding:"required,oneof=EMAIL CARD_FINGERPRINT IP_RANGE BIN_RANGE"`
+	Value     string           `json:"value" binding:"required"`
+	Reason    string           `json:"reason" binding:"required"`
+	ExpiresAt *time.Time       `json:"expires_at"`
+}
+
+type UpdateEntryDto struct {
+	Reason    string     `json:"reason"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type ListEntriesDto struct {
+	Kind domain.ListKind  `form:"kind" binding:"omitempty,oneof=BLOCK ALLOW"`
+	Type domain.EntryType `form:"type" binding:"omitempty,oneof=EMAIL CARD_FINGERPRINT IP_RANGE BIN_RANGE"`
+}
+
+type IdentifyEntryDto struct {
+	EntryID string `uri:"entry_id" binding:"required"`
+}
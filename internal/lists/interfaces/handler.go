@@ -0,0 +1,128 @@
+package interfaces
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/williamkoller/payment-system/internal/lists/application"
+	"github.com/williamkoller/payment-system/internal/middleware"
+	"github.com/williamkoller/payment-system/pkg/apperror"
+)
+
+type ListHandler struct {
+	Service *application.ListService
+}
+
+func NewListHandler(service *application.ListService) *ListHandler {
+	return &ListHandler{Service: service}
+}
+
+func (h *ListHandler) CreateEntry(c *gin.Context) {
+	var dto CreateEntryDto
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		middleware.Problem(c, apperror.Validation(err))
+		return
+	}
+
+	entry, err := h.Service.Create(c.Request.Context(), application.CreateEntryInput{
+		Kind:      dto.Kind,
+		Type:      dto.Type,
+		Value:     dto.Value,
+		Reason:    dto.Reason,
+		ExpiresAt: dto.ExpiresAt,
+	})
+	if err != nil {
+		middleware.Problem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, ToEntryResponse(entry))
+}
+
+func (h *ListHandler) ListEntries(c *gin.Context) {
+	var query ListEntriesDto
+	if err := c.ShouldBindQuery(&query); err != nil {
+		middleware.Problem(c, apperror.Validation(err))
+		return
+	}
+
+	entries, err := h.Service.List(c.Request.Context(), application.ListFilter{Kind: query.Kind, Type: query.Type})
+	if err != nil {
+		middleware.Problem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ToEntryResponses(entries))
+}
+
+func (h *ListHandler) GetEntry(c *gin.Context) {
+	var uri IdentifyEntryDto
+	if err := c.ShouldBindUri(&uri); err != nil {
+		middleware.Problem(c, apperror.Validation(err))
+		return
+	}
+
+	entry, err := h.Service.Find(c.Request.Context(), uri.EntryID)
+	if err != nil {
+		middleware.Problem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ToEntryResponse(entry))
+}
+
+func (h *ListHandler) UpdateEntry(c *gin.Context) {
+	var uri IdentifyEntryDto
+	if err := c.ShouldBindUri(&uri); err != nil {
+		middleware.Problem(c, apperror.Validation(err))
+		return
+	}
+
+	var dto UpdateEntryDto
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		middleware.Problem(c, apperror.Validation(err))
+		return
+	}
+
+	entry, err := h.Service.Update(c.Request.Context(), uri.EntryID, application.UpdateEntryInput{
+		Reason:    dto.Reason,
+		ExpiresAt: dto.ExpiresAt,
+	})
+	if err != nil {
+		middleware.Problem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ToEntryResponse(entry))
+}
+
+func (h *ListHandler) DeleteEntry(c *gin.Context) {
+	var uri IdentifyEntryDto
+	if err := c.ShouldBindUri(&uri); err != nil {
+		middleware.Problem(c, apperror.Validation(err))
+		return
+	}
+
+	if err := h.Service.Delete(c.Request.Context(), uri.EntryID); err != nil {
+		middleware.Problem(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *ListHandler) GetEntryAudit(c *gin.Context) {
+	var uri IdentifyEntryDto
+	if err := c.ShouldBindUri(&uri); err != nil {
+		middleware.Problem(c, apperror.Validation(err))
+		return
+	}
+
+	audits, err := h.Service.Audit(c.Request.Context(), uri.EntryID)
+	if err != nil {
+		middleware.Problem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ToAuditResponses(audits))
+}
@@ -0,0 +1,66 @@
+package interfaces
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/williamkoller/payment-system/internal/lists/domain"
+)
+
+type EntryResponse struct {
+	ID        string           `json:"id"`
+	Kind      domain.ListKind  `json:"kind"`
+	Type      domain.EntryType `json:"type"`
+	Value     string           `json:"value"`
+	Reason    string           `json:"reason"`
+	CreatedBy string           `json:"created_by"`
+	ExpiresAt *time.Time       `json:"expires_at"`
+	CreatedAt time.Time        `json:"created_at"`
+	UpdatedAt time.Time        `json:"updated_at"`
+}
+
+type AuditResponse struct {
+	ID        string             `json:"id"`
+	Action    domain.AuditAction `json:"action"`
+	Actor     string             `json:"actor"`
+	Before    json.RawMessage    `json:"before"`
+	After     json.RawMessage    `json:"after"`
+	CreatedAt time.Time          `json:"created_at"`
+}
+
+func ToEntryResponse(e *domain.ListEntry) EntryResponse {
+	return EntryResponse{
+		ID:        e.ID,
+		Kind:      e.Kind,
+		Type:      e.Type,
+		Value:     e.Value,
+		Reason:    e.Reason,
+		CreatedBy: e.CreatedBy,
+		ExpiresAt: e.ExpiresAt,
+		CreatedAt: e.CreatedAt,
+		UpdatedAt: e.UpdatedAt,
+	}
+}
+
+func ToEntryResponses(entries []*domain.ListEntry) []EntryResponse {
+	responses := make([]EntryResponse, 0, len(entries))
+	for _, e := range entries {
+		responses = append(responses, ToEntryResponse(e))
+	}
+	return responses
+}
+
+func ToAuditResponses(audits []*domain.ListEntryAudit) []AuditResponse {
+	responses := make([]AuditResponse, 0, len(audits))
+	for _, a := range audits {
+		responses = append(responses, AuditResponse{
+			ID:        a.ID,
+			Action:    a.Action,
+			Actor:     a.Actor,
+			Before:    json.RawMessage(a.Before),
+			After:     json.RawMessage(a.After),
+			CreatedAt: a.CreatedAt,
+		})
+	}
+	return responses
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/williamkoller/payment-system/internal/lists/application"
+	"github.com/williamkoller/payment-system/internal/lists/domain"
+	"gorm.io/gorm"
+)
+
+type ListRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewListRepository(db *gorm.DB) *ListRepositoryImpl {
+	return &ListRepositoryImpl{db: db}
+}
+
+func (r *ListRepositoryImpl) Save(ctx context.Context, entry *domain.ListEntry, audit *domain.ListEntryAudit) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(entry).Error; err != nil {
+			return err
+		}
+		return tx.Create(audit).Error
+	})
+}
+
+func (r *ListRepositoryImpl) Update(ctx context.Context, entry *domain.ListEntry, audit *domain.ListEntryAudit) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&domain.ListEntry{}).
+			Select("Reason", "ExpiresAt", "UpdatedAt", "DeletedAt").
+			Where("id = ?", entry.ID).
+			Updates(entry).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(audit).Error
+	})
+}
+
+func (r *ListRepositoryImpl) FindByID(ctx context.Context, id string) (*domain.ListEntry, error) {
+	var entry domain.ListEntry
+	if err := r.db.WithContext(ctx).First(&entry, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (r *ListRepositoryImpl) FindAll(ctx context.Context, filter application.ListFilter) ([]*domain.ListEntry, error) {
+	query := r.db.WithContext(ctx).Where("deleted_at IS NULL")
+	if filter.Kind != "" {
+		query = query.Where("kind = ?", filter.Kind)
+	}
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+
+	var entries []*domain.ListEntry
+	if err := query.Order("created_at DESC").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (r *ListRepositoryImpl) FindActive(ctx context.Context, now time.Time) ([]*domain.ListEntry, error) {
+	var entries []*domain.ListEntry
+	err := r.db.WithContext(ctx).
+		Where("deleted_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", now).
+		Find(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (r *ListRepositoryImpl) FindAudit(ctx context.Context, entryID string) ([]*domain.ListEntryAudit, error) {
+	var audits []*domain.ListEntryAudit
+	if err := r.db.WithContext(ctx).Where("entry_id = ?", entryID).Order("created_at").Find(&audits).Error; err != nil {
+		return nil, err
+	}
+	return audits, nil
+}
@@ -0,0 +1,29 @@
+package router
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/williamkoller/payment-system/internal/lists/application"
+	"github.com/williamkoller/payment-system/internal/lists/interfaces"
+	"github.com/williamkoller/payment-system/internal/lists/repository"
+	"github.com/williamkoller/payment-system/internal/middleware"
+	"github.com/williamkoller/payment-system/pkg/auth"
+	"gorm.io/gorm"
+)
+
+func NewListService(db *gorm.DB) *application.ListService {
+	repo := repository.NewListRepository(db)
+	return application.NewListService(repo, application.NewMatcher(repo))
+}
+
+func SetupRouter(e *gin.Engine, service *application.ListService, authn gin.HandlerFunc) {
+	handler := interfaces.NewListHandler(service)
+	lists := e.Group("/admin/lists", authn, middleware.RequireScope(auth.ScopePlatform))
+	{
+		lists.POST("/", handler.CreateEntry)
+		lists.GET("/", handler.ListEntries)
+		lists.GET("/:entry_id", handler.GetEntry)
+		lists.PATCH("/:entry_id", handler.UpdateEntry)
+		lists.DELETE("/:entry_id", handler.DeleteEntry)
+		lists.GET("/:entry_id/audit", handler.GetEntryAudit)
+	}
+}
@@ -0,0 +1,283 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	auditDomain "github.com/williamkoller/payment-system/internal/audit/domain"
+	"github.com/williamkoller/payment-system/internal/merchant/domain"
+	"github.com/williamkoller/payment-system/pkg/apperror"
+	"github.com/williamkoller/payment-system/pkg/secretbox"
+	"github.com/williamkoller/payment-system/pkg/ulid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrMerchantNotFound        = apperror.New(apperror.KindNotFound, "merchant_not_found", "merchant not found")
+	ErrEncryptionNotConfigured = apperror.New(apperror.KindUnprocessable, "encryption_not_configured", "ENCRYPTION_KEY must be set to store Stripe credentials")
+)
+
+type MerchantRepository interface {
+	Save(ctx context.Context, merchant *domain.Merchant) error
+	Update(ctx context.Context, merchant *domain.Merchant) error
+	FindByID(ctx context.Context, id string) (*domain.Merchant, error)
+	FindAll(ctx context.Context) ([]*domain.Merchant, error)
+	MaxExpiryWindow(ctx context.Context) (int64, error)
+}
+
+// ExpiryPolicyInput overrides the platform's authorization expiry policy.
+type ExpiryPolicyInput struct {
+	AlertBeforeSeconds int64
+	ActBeforeSeconds   int64
+	Action             string
+}
+
+type CreateMerchantInput struct {
+	Name                string
+	StripeSecretKey     string
+	StripeWebhookSecret string
+	StripePaymentMethod string
+	MaxPaymentAmount    int64
+	ExpiryPolicy        ExpiryPolicyInput
+}
+
+// UpdateMerchantInput changes only the fields that are set.
+type UpdateMerchantInput struct {
+	Name                *string
+	Status              *domain.Status
+	StripeSecretKey     *string
+	StripeWebhookSecret *string
+	StripePaymentMethod *string
+	MaxPaymentAmount    *int64
+	ExpiryPolicy        *ExpiryPolicyInput
+}
+
+// Auditor appends entries to the audit log.
+type Auditor interface {
+	Record(ctx context.Context, entry *auditDomain.Entry)
+}
+
+type MerchantService struct {
+	Repository MerchantRepository
+	// Box seals Stripe credentials; without it merchants can only use the
+	// platform account.
+	Box *secretbox.Box
+	// Audit is optional; without it merchant changes are not audited.
+	Audit Auditor
+}
+
+func NewMerchantService(repository MerchantRepository, box *secretbox.Box) *MerchantService {
+	return &MerchantService{Repository: repository, Box: box}
+}
+
+func (s *MerchantService) Create(ctx context.Context, input CreateMerchantInput) (*domain.Merchant, error) {
+	merchant, err := domain.NewMerchant(ulid.NewULID(), input.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.setCredentials(merchant, input.StripeSecretKey, input.StripeWebhookSecret); err != nil {
+		return nil, err
+	}
+	merchant.UpdateSettings(input.StripePaymentMethod)
+	if err := merchant.SetLimits(input.MaxPaymentAmount); err != nil {
+		return nil, err
+	}
+	expiry := input.ExpiryPolicy
+	if err := merchant.SetExpiryPolicy(expiry.AlertBeforeSeconds, expiry.ActBeforeSeconds, expiry.Action); err != nil {
+		return nil, err
+	}
+
+	if err := s.Repository.Save(ctx, merchant); err != nil {
+		return nil, err
+	}
+	s.audit(ctx, "merchant.create", merchant, nil, nil)
+	return merchant, nil
+}
+
+func (s *MerchantService) Find(ctx context.Context, id string) (*domain.Merchant, error) {
+	merchant, err := s.Repository.FindByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrMerchantNotFound.Wrap(err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return merchant, nil
+}
+
+func (s *MerchantService) List(ctx context.Context) ([]*domain.Merchant, error) {
+	return s.Repository.FindAll(ctx)
+}
+
+func (s *MerchantService) Update(ctx context.Context, id string, input UpdateMerchantInput) (*domain.Merchant, error) {
+	merchant, err := s.Find(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	before := merchantSnapshot(merchant)
+
+	if input.Name != nil {
+		if err := merchant.Rename(*input.Name); err != nil {
+			return nil, err
+		}
+	}
+	if input.Status != nil {
+		if err := merchant.SetStatus(*input.Status); err != nil {
+			return nil, err
+		}
+	}
+	if input.StripeSecretKey != nil || input.StripeWebhookSecret != nil {
+		secretKey, webhookSecret, err := s.credentials(merchant)
+		if err != nil {
+			return nil, err
+		}
+		if input.StripeSecretKey != nil {
+			secretKey = *input.StripeSecretKey
+		}
+		if input.StripeWebhookSecret != nil {
+			webhookSecret = *input.StripeWebhookSecret
+		}
+		if err := s.setCredentials(merchant, secretKey, webhookSecret); err != nil {
+			return nil, err
+		}
+	}
+	if input.StripePaymentMethod != nil {
+		merchant.UpdateSettings(*input.StripePaymentMethod)
+	}
+	if input.MaxPaymentAmount != nil {
+		if err := merchant.SetLimits(*input.MaxPaymentAmount); err != nil {
+			return nil, err
+		}
+	}
+	if expiry := input.ExpiryPolicy; expiry != nil {
+		if err := merchant.SetExpiryPolicy(expiry.AlertBeforeSeconds, expiry.ActBeforeSeconds, expiry.Action); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.Repository.Update(ctx, merchant); err != nil {
+		return nil, err
+	}
+	s.audit(ctx, "merchant.update", merchant, before, map[string]any{
+		"stripe_credentials_changed": input.StripeSecretKey != nil || input.StripeWebhookSecret != nil,
+	})
+	return merchant, nil
+}
+
+// StripeCredentials returns the merchant's Stripe secret key and payment
+// method. The key is empty when the merchant uses the platform account.
+func (s *MerchantService) StripeCredentials(ctx context.Context, merchantID string) (secretKey, paymentMethod string, err error) {
+	merchant, err := s.Find(ctx, merchantID)
+	if err != nil {
+		return "", "", err
+	}
+	secretKey, _, err = s.credentials(merchant)
+	return secretKey, merchant.StripePaymentMethod, err
+}
+
+// StripeWebhookSecret returns the secret the merchant's own Stripe account
+// signs webhooks with.
+func (s *MerchantService) StripeWebhookSecret(ctx context.Context, merchantID string) (string, error) {
+	merchant, err := s.Find(ctx, merchantID)
+	if err != nil {
+		return "", err
+	}
+	_, webhookSecret, err := s.credentials(merchant)
+	return webhookSecret, err
+}
+
+// MaxExpiryWindow returns the longest expiry window any merchant overrides
+// the platform's with, so the expiry scheduler looks far enough ahead.
+func (s *MerchantService) MaxExpiryWindow(ctx context.Context) (time.Duration, error) {
+	seconds, err := s.Repository.MaxExpiryWindow(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
+// GatewayAccounts lists the merchants charging through their own Stripe
+// account, whose payments reconcile against that account.
+func (s *MerchantService) GatewayAccounts(ctx context.Context) ([]string, error) {
+	merchants, err := s.Repository.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var accounts []string
+	for _, m := range merchants {
+		if m.HasOwnStripeAccount() {
+			accounts = append(accounts, m.ID)
+		}
+	}
+	return accounts, nil
+}
+
+func (s *MerchantService) setCredentials(merchant *domain.Merchant, secretKey, webhookSecret string) error {
+	if secretKey == "" && webhookSecret == "" {
+		return merchant.SetStripeCredentials("", "")
+	}
+	if s.Box == nil {
+		return ErrEncryptionNotConfigured
+	}
+
+	sealedKey, err := s.Box.Seal(secretKey)
+	if err != nil {
+		return err
+	}
+	sealedWebhook, err := s.Box.Seal(webhookSecret)
+	if err != nil {
+		return err
+	}
+	return merchant.SetStripeCredentials(sealedKey, sealedWebhook)
+}
+
+func (s *MerchantService) credentials(merchant *domain.Merchant) (secretKey, webhookSecret string, err error) {
+	if !merchant.HasOwnStripeAccount() {
+		return "", "", nil
+	}
+	if s.Box == nil {
+		return "", "", ErrEncryptionNotConfigured
+	}
+
+	if secretKey, err = s.Box.Open(merchant.StripeSecretKeyEncrypted); err != nil {
+		return "", "", err
+	}
+	if webhookSecret, err = s.Box.Open(merchant.StripeWebhookSecretEncrypted); err != nil {
+		return "", "", err
+	}
+	return secretKey, webhookSecret, nil
+}
+
+func (s *MerchantService) audit(ctx context.Context, action string, merchant *domain.Merchant, before, details map[string]any) {
+	if s.Audit == nil {
+		return
+	}
+	entry := auditDomain.NewEntry(ctx, ulid.NewULID(), action, auditDomain.Resource{
+		Type:       auditDomain.ResourceMerchant,
+		ID:         merchant.ID,
+		MerchantID: merchant.ID,
+	})
+	entry.SetSnapshots(before, merchantSnapshot(merchant))
+	if details != nil {
+		entry.SetDetails(details)
+	}
+	s.Audit.Record(ctx, entry)
+}
+
+// merchantSnapshot is what the audit log keeps of a merchant: never its
+// Stripe credentials, sealed or not.
+func merchantSnapshot(m *domain.Merchant) map[string]any {
+	return map[string]any{
+		"id":                          m.ID,
+		"name":                        m.Name,
+		"status":                      m.Status,
+		"own_stripe_account":          m.HasOwnStripeAccount(),
+		"stripe_payment_method":       m.StripePaymentMethod,
+		"max_payment_amount":          m.MaxPaymentAmount,
+		"expiry_alert_before_seconds": m.ExpiryAlertBeforeSeconds,
+		"expiry_act_before_seconds":   m.ExpiryActBeforeSeconds,
+		"expiry_action":               m.ExpiryAction,
+	}
+}
@@ -0,0 +1,131 @@
+package application_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/williamkoller/payment-system/internal/merchant/application"
+	"github.com/williamkoller/payment-system/internal/merchant/domain"
+	"github.com/williamkoller/payment-system/pkg/secretbox"
+	"gorm.io/gorm"
+)
+
+type fakeMerchants map[string]*domain.Merchant
+
+func (f fakeMerchants) Save(_ context.Context, m *domain.Merchant) error {
+	f[m.ID] = m
+	return nil
+}
+
+func (f fakeMerchants) Update(_ context.Context, m *domain.Merchant) error {
+	f[m.ID] = m
+	return nil
+}
+
+func (f fakeMerchants) FindByID(_ context.Context, id string) (*domain.Merchant, error) {
+	if m, ok := f[id]; ok {
+		return m, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f fakeMerchants) FindAll(context.Context) ([]*domain.Merchant, error) {
+	merchants := make([]*domain.Merchant, 0, len(f))
+	for _, m := range f {
+		merchants = append(merchants, m)
+	}
+	return merchants, nil
+}
+
+func (f fakeMerchants) MaxExpiryWindow(context.Context) (int64, error) {
+	var longest int64
+	for _, m := range f {
+		longest = max(longest, m.ExpiryAlertBeforeSeconds, m.ExpiryActBeforeSeconds)
+	}
+	return longest, nil
+}
+
+func newBox(t *testing.T) *secretbox.Box {
+	box, err := secretbox.New(bytes.Repeat([]byte{1}, 32))
+	require.NoError(t, err)
+	return box
+}
+
+func TestMerchantService_CredentialsAreSealed(t *testing.T) {
+	repo := fakeMerchants{}
+	service := application.NewMerchantService(repo, newBox(t))
+	ctx := context.Background()
+
+	merchant, err := service.Create(ctx, application.CreateMerchantInput{
+		Name:                "Acme",
+		StripeSecretKey:     "sk_live_acme",
+		StripeWebhookSecret: "whsec_acme",
+		StripePaymentMethod: "pm_card_visa",
+	})
+	require.NoError(t, err)
+	assert.True(t, merchant.HasOwnStripeAccount())
+	assert.NotContains(t, merchant.StripeSecretKeyEncrypted, "sk_live_acme")
+	assert.NotContains(t, merchant.StripeWebhookSecretEncrypted, "whsec_acme")
+
+	secretKey, paymentMethod, err := service.StripeCredentials(ctx, merchant.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "sk_live_acme", secretKey)
+	assert.Equal(t, "pm_card_visa", paymentMethod)
+
+	webhookSecret, err := service.StripeWebhookSecret(ctx, merchant.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "whsec_acme", webhookSecret)
+
+	accounts, err := service.GatewayAccounts(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{merchant.ID}, accounts)
+}
+
+func TestMerchantService_Update(t *testing.T) {
+	service := application.NewMerchantService(fakeMerchants{}, newBox(t))
+	ctx := context.Background()
+
+	merchant, err := service.Create(ctx, application.CreateMerchantInput{Name: "Acme", StripeSecretKey: "sk_test_old"})
+	require.NoError(t, err)
+
+	webhookSecret := "whsec_new"
+	disabled := domain.StatusDisabled
+	limit := int64(50_000)
+	merchant, err = service.Update(ctx, merchant.ID, application.UpdateMerchantInput{
+		StripeWebhookSecret: &webhookSecret,
+		Status:              &disabled,
+		MaxPaymentAmount:    &limit,
+	})
+	require.NoError(t, err)
+
+	secretKey, _, err := service.StripeCredentials(ctx, merchant.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "sk_test_old", secretKey, "updating one secret keeps the other")
+	assert.ErrorIs(t, merchant.CheckPayment(100), domain.ErrDisabled)
+
+	_, err = service.Update(ctx, "missing", application.UpdateMerchantInput{})
+	assert.ErrorIs(t, err, application.ErrMerchantNotFound)
+}
+
+func TestMerchantService_CredentialsNeedEncryptionKey(t *testing.T) {
+	service := application.NewMerchantService(fakeMerchants{}, nil)
+
+	_, err := service.Create(context.Background(), application.CreateMerchantInput{Name: "Acme", StripeSecretKey: "sk_test_acme"})
+	assert.ErrorIs(t, err, application.ErrEncryptionNotConfigured)
+
+	merchant, err := service.Create(context.Background(), application.CreateMerchantInput{Name: "Platform only"})
+	require.NoError(t, err)
+	assert.False(t, merchant.HasOwnStripeAccount())
+}
+
+func TestMerchant_CheckPayment(t *testing.T) {
+	merchant, err := domain.NewMerchant("m1", "Acme")
+	require.NoError(t, err)
+	require.NoError(t, merchant.SetLimits(10_000))
+
+	assert.NoError(t, merchant.CheckPayment(10_000))
+	assert.ErrorIs(t, merchant.CheckPayment(10_001), domain.ErrLimitExceeded)
+}
@@ -0,0 +1,142 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/williamkoller/payment-system/pkg/apperror"
+)
+
+var (
+	ErrNameRequired       = apperror.New(apperror.KindValidation, "merchant_name_required", "merchant name is required")
+	ErrInvalidLimit       = apperror.New(apperror.KindValidation, "invalid_merchant_limit", "merchant limits cannot be negative")
+	ErrDisabled           = apperror.New(apperror.KindForbidden, "merchant_disabled", "merchant is disabled")
+	ErrLimitExceeded      = apperror.New(apperror.KindUnprocessable, "merchant_limit_exceeded", "amount exceeds the merchant's payment limit")
+	ErrInvalidCredentials = apperror.New(apperror.KindValidation, "invalid_stripe_credentials", "stripe_webhook_secret requires stripe_secret_key")
+	ErrInvalidExpiry      = apperror.New(apperror.KindValidation, "invalid_expiry_policy", "expiry windows cannot be negative and the expiry action must be capture or cancel")
+)
+
+type Status string
+
+const (
+	StatusActive   Status = "ACTIVE"
+	StatusDisabled Status = "DISABLED"
+)
+
+// Merchant is a tenant of the platform. Its Stripe credentials are stored
+// sealed (see pkg/secretbox) and only opened when a client is built; a
+// merchant without them charges through the platform's own account.
+type Merchant struct {
+	ID                           string
+	Name                         string
+	Status                       Status
+	StripeSecretKeyEncrypted     string
+	StripeWebhookSecretEncrypted string
+	// StripePaymentMethod overrides STRIPE_METHOD for this merchant.
+	StripePaymentMethod string
+	// MaxPaymentAmount caps a single payment, in minor units of the
+	// settlement currency; zero means no cap.
+	MaxPaymentAmount int64
+	// The expiry fields override the platform's authorization expiry
+	// policy for the merchant's payments; zero values keep the platform's.
+	ExpiryAlertBeforeSeconds int64
+	ExpiryActBeforeSeconds   int64
+	ExpiryAction             string
+	CreatedAt                time.Time
+	UpdatedAt                time.Time
+}
+
+func NewMerchant(id, name string) (*Merchant, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, ErrNameRequired
+	}
+
+	now := time.Now()
+	return &Merchant{
+		ID:        id,
+		Name:      name,
+		Status:    StatusActive,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}, nil
+}
+
+// HasOwnStripeAccount reports whether payments go through the merchant's
+// Stripe account rather than the platform's.
+func (m *Merchant) HasOwnStripeAccount() bool {
+	return m.StripeSecretKeyEncrypted != ""
+}
+
+func (m *Merchant) Rename(name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return ErrNameRequired
+	}
+	m.Name = name
+	m.UpdatedAt = time.Now()
+	return nil
+}
+
+// SetStripeCredentials takes already sealed secrets. A webhook secret
+// without a secret key would leave the merchant's own account half set up.
+func (m *Merchant) SetStripeCredentials(secretKey, webhookSecret string) error {
+	if secretKey == "" && webhookSecret != "" {
+		return ErrInvalidCredentials
+	}
+	m.StripeSecretKeyEncrypted = secretKey
+	m.StripeWebhookSecretEncrypted = webhookSecret
+	m.UpdatedAt = time.Now()
+	return nil
+}
+
+func (m *Merchant) UpdateSettings(paymentMethod string) {
+	m.StripePaymentMethod = paymentMethod
+	m.UpdatedAt = time.Now()
+}
+
+func (m *Merchant) SetLimits(maxPaymentAmount int64) error {
+	if maxPaymentAmount < 0 {
+		return ErrInvalidLimit
+	}
+	m.MaxPaymentAmount = maxPaymentAmount
+	m.UpdatedAt = time.Now()
+	return nil
+}
+
+// SetExpiryPolicy overrides when the merchant's uncaptured authorizations
+// are alerted on and resolved, and whether they are captured or canceled.
+func (m *Merchant) SetExpiryPolicy(alertBeforeSeconds, actBeforeSeconds int64, action string) error {
+	if alertBeforeSeconds < 0 || actBeforeSeconds < 0 {
+		return ErrInvalidExpiry
+	}
+	if action != "" && action != "capture" && action != "cancel" {
+		return ErrInvalidExpiry
+	}
+	m.ExpiryAlertBeforeSeconds = alertBeforeSeconds
+	m.ExpiryActBeforeSeconds = actBeforeSeconds
+	m.ExpiryAction = action
+	m.UpdatedAt = time.Now()
+	return nil
+}
+
+func (m *Merchant) SetStatus(status Status) error {
+	if status != StatusActive && status != StatusDisabled {
+		return apperror.New(apperror.KindValidation, "invalid_merchant_status", fmt.Sprintf("invalid merchant status: %q", status))
+	}
+	m.Status = status
+	m.UpdatedAt = time.Now()
+	return nil
+}
+
+// CheckPayment rejects payments the merchant may not take.
+func (m *Merchant) CheckPayment(amount int64) error {
+	if m.Status != StatusActive {
+		return ErrDisabled
+	}
+	if m.MaxPaymentAmount > 0 && amount > m.MaxPaymentAmount {
+		return ErrLimitExceeded.WithMessage(fmt.Sprintf("amount %d exceeds the merchant's payment limit of %d", amount, m.MaxPaymentAmount))
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package interfaces
+
+import "github.com/williamkoller/payment-system/internal/merchant/domain"
+
+type CreateMerchantDto struct {
+	Name                string          `json:"name" binding:"required"`
+	StripeSecretKey     string          `json:"stripe_secret_key"`
+	StripeWebhookSecret string          `json:"stripe_webhook_secret"`
+	StripePaymentMethod string          `json:"stripe_payment_method"`
+	MaxPaymentAmount    int64           `json:"max_payment_amount" binding:"min=0"`
+	ExpiryPolicy        ExpiryPolicyDto `json:"expiry_policy"`
+}
+
+// ExpiryPolicyDto overrides the platform's authorization expiry policy;
+// zero and empty fields keep the platform's.
+type ExpiryPolicyDto struct {
+	AlertBeforeSeconds int64  `json:"alert_before_seconds" binding:"min=0"`
+	ActBeforeSeconds   int64  `json:"act_before_seconds" binding:"min=0"`
+	Action             string `json:"action" binding:"omitempty,oneof=capture cancel"`
+}
+
+type UpdateMerchantDto struct {
+	Name                *string        `json:"name"`
+	Status              *domain.Status `json:"status" binding:"omitempty,oneof=ACTIVE DISABLED"`
+	StripeSecretKey     *string        `json:"stripe_secret_key"`
+	StripeWebhookSecret *string        `json:"stripe_webhook_secret"`
+	StripePaymentMethod *string        `json:"stripe_payment_method"`
+	MaxPaymentAmount    *int64         `json:"max_payment_amount" binding:"omitempty,min=0"`
+	// ExpiryPolicy replaces the merchant's whole expiry policy.
+	ExpiryPolicy *ExpiryPolicyDto `json:"expiry_policy"`
+}
+
+type IdentifyMerchantDto struct {
+	MerchantID string `uri:"merchant_id" binding:"required"`
+}
@@ -0,0 +1,97 @@
+package interfaces
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/williamkoller/payment-system/internal/merchant/application"
+	"github.com/williamkoller/payment-system/internal/middleware"
+	"github.com/williamkoller/payment-system/pkg/apperror"
+)
+
+type MerchantHandler struct {
+	Service *application.MerchantService
+}
+
+func NewMerchantHandler(service *application.MerchantService) *MerchantHandler {
+	return &MerchantHandler{Service: service}
+}
+
+func (h *MerchantHandler) CreateMerchant(c *gin.Context) {
+	var dto CreateMerchantDto
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		middleware.Problem(c, apperror.Validation(err))
+		return
+	}
+
+	merchant, err := h.Service.Create(c.Request.Context(), application.CreateMerchantInput{
+		Name:                dto.Name,
+		StripeSecretKey:     dto.StripeSecretKey,
+		StripeWebhookSecret: dto.StripeWebhookSecret,
+		StripePaymentMethod: dto.StripePaymentMethod,
+		MaxPaymentAmount:    dto.MaxPaymentAmount,
+		ExpiryPolicy:        application.ExpiryPolicyInput(dto.ExpiryPolicy),
+	})
+	if err != nil {
+		middleware.Problem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, ToMerchantResponse(merchant))
+}
+
+func (h *MerchantHandler) ListMerchants(c *gin.Context) {
+	merchants, err := h.Service.List(c.Request.Context())
+	if err != nil {
+		middleware.Problem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ToMerchantResponses(merchants))
+}
+
+func (h *MerchantHandler) GetMerchant(c *gin.Context) {
+	var uri IdentifyMerchantDto
+	if err := c.ShouldBindUri(&uri); err != nil {
+		middleware.Problem(c, apperror.Validation(err))
+		return
+	}
+
+	merchant, err := h.Service.Find(c.Request.Context(), uri.MerchantID)
+	if err != nil {
+		middleware.Problem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ToMerchantResponse(merchant))
+}
+
+func (h *MerchantHandler) UpdateMerchant(c *gin.Context) {
+	var uri IdentifyMerchantDto
+	if err := c.ShouldBindUri(&uri); err != nil {
+		middleware.Problem(c, apperror.Validation(err))
+		return
+	}
+
+	var dto UpdateMerchantDto
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		middleware.Problem(c, apperror.Validation(err))
+		return
+	}
+
+	merchant, err := h.Service.Update(c.Request.Context(), uri.MerchantID, application.UpdateMerchantInput{
+		Name:                dto.Name,
+		Status:              dto.Status,
+		StripeSecretKey:     dto.StripeSecretKey,
+		StripeWebhookSecret: dto.StripeWebhookSecret,
+		StripePaymentMethod: dto.StripePaymentMethod,
+		MaxPaymentAmount:    dto.MaxPaymentAmount,
+		ExpiryPolicy:        (*application.ExpiryPolicyInput)(dto.ExpiryPolicy),
+	})
+	if err != nil {
+		middleware.Problem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ToMerchantResponse(merchant))
+}
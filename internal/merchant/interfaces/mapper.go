@@ -0,0 +1,49 @@
+package interfaces
+
+import (
+	"time"
+
+	"github.com/williamkoller/payment-system/internal/merchant/domain"
+)
+
+// MerchantResponse never includes the Stripe credentials, only whether the
+// merchant has them.
+type MerchantResponse struct {
+	ID                  string          `json:"id"`
+	Name                string          `json:"name"`
+	Status              domain.Status   `json:"status"`
+	OwnStripeAccount    bool            `json:"own_stripe_account"`
+	HasWebhookSecret    bool            `json:"has_webhook_secret"`
+	StripePaymentMethod string          `json:"stripe_payment_method"`
+	MaxPaymentAmount    int64           `json:"max_payment_amount"`
+	ExpiryPolicy        ExpiryPolicyDto `json:"expiry_policy"`
+	CreatedAt           time.Time       `json:"created_at"`
+	UpdatedAt           time.Time       `json:"updated_at"`
+}
+
+func ToMerchantResponse(m *domain.Merchant) MerchantResponse {
+	return MerchantResponse{
+		ID:                  m.ID,
+		Name:                m.Name,
+		Status:              m.Status,
+		OwnStripeAccount:    m.HasOwnStripeAccount(),
+		HasWebhookSecret:    m.StripeWebhookSecretEncrypted != "",
+		StripePaymentMethod: m.StripePaymentMethod,
+		MaxPaymentAmount:    m.MaxPaymentAmount,
+		ExpiryPolicy: ExpiryPolicyDto{
+			AlertBeforeSeconds: m.ExpiryAlertBeforeSeconds,
+			ActBeforeSeconds:   m.ExpiryActBeforeSeconds,
+			Action:             m.ExpiryAction,
+		},
+		CreatedAt: m.CreatedAt,
+		UpdatedAt: m.UpdatedAt,
+	}
+}
+
+func ToMerchantResponses(merchants []*domain.Merchant) []MerchantResponse {
+	responses := make([]MerchantResponse, 0, len(merchants))
+	for _, m := range merchants {
+		responses = append(responses, ToMerchantResponse(m))
+	}
+	return responses
+}
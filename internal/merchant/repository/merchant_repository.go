@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/williamkoller/payment-system/internal/merchant/domain"
+	"gorm.io/gorm"
+)
+
+type MerchantRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewMerchantRepository(db *gorm.DB) *MerchantRepositoryImpl {
+	return &MerchantRepositoryImpl{db: db}
+}
+
+func (r *MerchantRepositoryImpl) Save(ctx context.Context, merchant *domain.Merchant) error {
+	return r.db.WithContext(ctx).Create(merchant).Error
+}
+
+func (r *MerchantRepositoryImpl) Update(ctx context.Context, merchant *domain.Merchant) error {
+	return r.db.WithContext(ctx).Model(&domain.Merchant{}).
+		Select("Name", "Status", "StripeSecretKeyEncrypted", "StripeWebhookSecretEncrypted",
+			"StripePaymentMethod", "MaxPaymentAmount",
+			"ExpiryAlertBeforeSeconds", "ExpiryActBeforeSeconds", "ExpiryAction", "UpdatedAt").
+		Where("id = ?", merchant.ID).
+		Updates(merchant).Error
+}
+
+func (r *MerchantRepositoryImpl) FindByID(ctx context.Context, id string) (*domain.Merchant, error) {
+	var merchant domain.Merchant
+	if err := r.db.WithContext(ctx).First(&merchant, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &merchant, nil
+}
+
+// MaxExpiryWindow returns the longest expiry window any merchant sets, in
+// seconds.
+func (r *MerchantRepositoryImpl) MaxExpiryWindow(ctx context.Context) (int64, error) {
+	var seconds int64
+	err := r.db.WithContext(ctx).Model(&domain.Merchant{}).
+		Select("COALESCE(MAX(GREATEST(expiry_alert_before_seconds, expiry_act_before_seconds)), 0)").
+		Scan(&seconds).Error
+	return seconds, err
+}
+
+func (r *MerchantRepositoryImpl) FindAll(ctx context.Context) ([]*domain.Merchant, error) {
+	var merchants []*domain.Merchant
+	if err := r.db.WithContext(ctx).Order("created_at").Find(&merchants).Error; err != nil {
+		return nil, err
+	}
+	return merchants, nil
+}
@@ -0,0 +1,27 @@
+package router
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/williamkoller/payment-system/internal/merchant/application"
+	"github.com/williamkoller/payment-system/internal/merchant/interfaces"
+	"github.com/williamkoller/payment-system/internal/merchant/repository"
+	"github.com/williamkoller/payment-system/internal/middleware"
+	"github.com/williamkoller/payment-system/pkg/auth"
+	"github.com/williamkoller/payment-system/pkg/secretbox"
+	"gorm.io/gorm"
+)
+
+func NewMerchantService(db *gorm.DB, box *secretbox.Box) *application.MerchantService {
+	return application.NewMerchantService(repository.NewMerchantRepository(db), box)
+}
+
+func SetupRouter(e *gin.Engine, service *application.MerchantService, authn gin.HandlerFunc) {
+	handler := interfaces.NewMerchantHandler(service)
+	merchants := e.Group("/admin/merchants", authn, middleware.RequireScope(auth.ScopePlatform))
+	{
+		merchants.POST("/", handler.CreateMerchant)
+		merchants.GET("/", handler.ListMerchants)
+		merchants.GET("/:merchant_id", handler.GetMerchant)
+		merchants.PATCH("/:merchant_id", handler.UpdateMerchant)
+	}
+}
@@ -0,0 +1,207 @@
+// Package metrics owns the Prometheus registry exposed on /metrics.
+//
+// Exposed metrics (see also the "Metrics" section of the README):
+//
+//	http_requests_total{route,method,status}                 counter
+//	http_request_duration_seconds{route,method}              histogram
+//	payments_status_transitions_total{from,to,currency}      counter
+//	payments_amount{currency}                                histogram, major units
+//	stripe_request_duration_seconds{operation}               histogram
+//	stripe_request_errors_total{operation,code}              counter
+//	stripe_circuit_breaker_state{name}                       gauge, 0 closed / 1 half-open / 2 open
+//	webhook_events_total{type,outcome}                       counter
+//	go_sql_*{db_name="payments"}                             database/sql pool stats
+//
+// plus the standard go_* and process_* collectors.
+package metrics
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sony/gobreaker"
+	"github.com/stripe/stripe-go"
+	"github.com/williamkoller/payment-system/internal/payment/domain"
+)
+
+const (
+	NameHTTPRequestsTotal          = "http_requests_total"
+	NameHTTPRequestDuration        = "http_request_duration_seconds"
+	NamePaymentTransitionsTotal    = "payments_status_transitions_total"
+	NamePaymentAmount              = "payments_amount"
+	NameStripeRequestDuration      = "stripe_request_duration_seconds"
+	NameStripeRequestErrorsTotal   = "stripe_request_errors_total"
+	NameStripeCircuitBreakerState  = "stripe_circuit_breaker_state"
+	NameWebhookEventsTotal         = "webhook_events_total"
+	DBName                         = "payments"
+	unmatchedRoute                 = "unmatched"
+	webhookOutcomeProcessed        = "processed"
+	webhookOutcomeIgnored          = "ignored"
+	webhookOutcomeFailed           = "failed"
+	webhookOutcomeInvalidSignature = "invalid_signature"
+)
+
+// Names lists every application metric this package defines, in the order
+// they are documented.
+var Names = []string{
+	NameHTTPRequestsTotal,
+	NameHTTPRequestDuration,
+	NamePaymentTransitionsTotal,
+	NamePaymentAmount,
+	NameStripeRequestDuration,
+	NameStripeRequestErrorsTotal,
+	NameStripeCircuitBreakerState,
+	NameWebhookEventsTotal,
+}
+
+var (
+	Registry = prometheus.NewRegistry()
+
+	httpRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: NameHTTPRequestsTotal,
+		Help: "HTTP requests handled, by route template, method and status code.",
+	}, []string{"route", "method", "status"})
+
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    NameHTTPRequestDuration,
+		Help:    "HTTP request latency in seconds, by route template and method.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method"})
+
+	paymentTransitionsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: NamePaymentTransitionsTotal,
+		Help: "Payment status transitions; from is empty for newly created payments.",
+	}, []string{"from", "to", "currency"})
+
+	paymentAmount = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    NamePaymentAmount,
+		Help:    "Amount of created payments in major currency units.",
+		Buckets: prometheus.ExponentialBuckets(1, 4, 10),
+	}, []string{"currency"})
+
+	stripeRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    NameStripeRequestDuration,
+		Help:    "Latency of Stripe operations in seconds, including retries and circuit breaker rejections.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"operation"})
+
+	stripeRequestErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: NameStripeRequestErrorsTotal,
+		Help: "Failed Stripe operations by operation and error code.",
+	}, []string{"operation", "code"})
+
+	stripeCircuitBreakerState = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: NameStripeCircuitBreakerState,
+		Help: "State of the Stripe circuit breaker: 0 closed, 1 half-open, 2 open.",
+	}, []string{"name"})
+
+	webhookEventsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: NameWebhookEventsTotal,
+		Help: "Inbound webhook events by event type and outcome (processed, ignored, failed, invalid_signature).",
+	}, []string{"type", "outcome"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequestsTotal,
+		httpRequestDuration,
+		paymentTransitionsTotal,
+		paymentAmount,
+		stripeRequestDuration,
+		stripeRequestErrorsTotal,
+		stripeCircuitBreakerState,
+		webhookEventsTotal,
+	)
+}
+
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// RegisterDB exposes the connection pool statistics of db.
+func RegisterDB(db *sql.DB) error {
+	err := Registry.Register(collectors.NewDBStatsCollector(db, DBName))
+	var already prometheus.AlreadyRegisteredError
+	if errors.As(err, &already) {
+		return nil
+	}
+	return err
+}
+
+func ObserveHTTPRequest(route, method string, status int, elapsed time.Duration) {
+	if route == "" {
+		route = unmatchedRoute
+	}
+	httpRequestsTotal.WithLabelValues(route, method, strconv.Itoa(status)).Inc()
+	httpRequestDuration.WithLabelValues(route, method).Observe(elapsed.Seconds())
+}
+
+// ObservePaymentTransitions counts the status changes of p once they are
+// persisted, and the amount of p when one of them created it.
+func ObservePaymentTransitions(p *domain.Payment, changes []*domain.StatusChange) {
+	for _, c := range changes {
+		paymentTransitionsTotal.WithLabelValues(string(c.FromStatus), string(c.ToStatus), p.Currency).Inc()
+
+		if c.FromStatus != "" {
+			continue
+		}
+		if m, err := p.Money(); err == nil {
+			paymentAmount.WithLabelValues(p.Currency).Observe(float64(m.Amount) / float64(m.Currency.Factor()))
+		}
+	}
+}
+
+// ObserveStripeCall records the latency and, on failure, the error code of
+// a Stripe operation. Use it as
+//
+//	defer metrics.ObserveStripeCall("capture", time.Now(), &err)
+func ObserveStripeCall(operation string, start time.Time, err *error) {
+	stripeRequestDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+
+	if err == nil || *err == nil {
+		return
+	}
+	stripeRequestErrorsTotal.WithLabelValues(operation, stripeErrorCode(*err)).Inc()
+}
+
+func stripeErrorCode(err error) string {
+	var stripeErr *stripe.Error
+	switch {
+	case errors.Is(err, gobreaker.ErrOpenState), errors.Is(err, gobreaker.ErrTooManyRequests):
+		return "circuit_open"
+	case errors.As(err, &stripeErr) && stripeErr.Code != "":
+		return string(stripeErr.Code)
+	case errors.As(err, &stripeErr):
+		return string(stripeErr.Type)
+	default:
+		return "error"
+	}
+}
+
+func SetCircuitBreakerState(name string, state gobreaker.State) {
+	stripeCircuitBreakerState.WithLabelValues(name).Set(float64(state))
+}
+
+func WebhookProcessed(eventType string) {
+	webhookEventsTotal.WithLabelValues(eventType, webhookOutcomeProcessed).Inc()
+}
+
+func WebhookIgnored(eventType string) {
+	webhookEventsTotal.WithLabelValues(eventType, webhookOutcomeIgnored).Inc()
+}
+
+func WebhookFailed(eventType string) {
+	webhookEventsTotal.WithLabelValues(eventType, webhookOutcomeFailed).Inc()
+}
+
+func WebhookInvalidSignature() {
+	webhookEventsTotal.WithLabelValues("", webhookOutcomeInvalidSignature).Inc()
+}
@@ -0,0 +1,68 @@
+package metrics_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/williamkoller/payment-system/internal/metrics"
+	"github.com/williamkoller/payment-system/internal/payment/domain"
+	"github.com/williamkoller/payment-system/internal/payment/middleware"
+)
+
+func TestNames_AreDocumented(t *testing.T) {
+	readme, err := os.ReadFile("../../README.md")
+	require.NoError(t, err)
+
+	for _, name := range metrics.Names {
+		assert.Contains(t, string(readme), "`"+name+"`", "metric %s is not documented in README.md", name)
+	}
+}
+
+func TestMetrics_Exposed(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.Metrics())
+	r.GET("/payments/:payment_id", func(c *gin.Context) { c.Status(http.StatusNotFound) })
+	r.GET("/metrics", gin.WrapH(metrics.Handler()))
+
+	for _, id := range []string{"a", "b"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/payments/"+id, nil))
+	}
+
+	payment, err := domain.NewPayment("p1", 1500, "usd", "a@example.com", "card")
+	require.NoError(t, err)
+	payment.Complete()
+	metrics.ObservePaymentTransitions(payment, payment.PendingStatusChanges())
+	metrics.WebhookIgnored("charge.refunded")
+	metrics.SetCircuitBreakerState("Stripe", 0)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	body := rec.Body.String()
+
+	assert.Contains(t, body, `http_requests_total{method="GET",route="/payments/:payment_id",status="404"} 2`)
+	assert.Contains(t, body, `payments_status_transitions_total{currency="USD",from="PENDING",to="COMPLETED"} 1`)
+	assert.Contains(t, body, `payments_amount_count{currency="USD"} 1`)
+	assert.Contains(t, body, `webhook_events_total{outcome="ignored",type="charge.refunded"} 1`)
+
+	for _, name := range metrics.Names {
+		if name == metrics.NameStripeRequestDuration || name == metrics.NameStripeRequestErrorsTotal {
+			continue
+		}
+		assert.True(t, strings.Contains(body, "# TYPE "+name+" "), "metric %s is not exposed", name)
+	}
+}
+
+func TestMetrics_CollectorsLint(t *testing.T) {
+	problems, err := testutil.GatherAndLint(metrics.Registry)
+	require.NoError(t, err)
+	assert.Empty(t, problems)
+}
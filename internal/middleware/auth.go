@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/williamkoller/payment-system/pkg/apperror"
+	"github.com/williamkoller/payment-system/pkg/auth"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	ErrMissingAPIKey     = apperror.New(apperror.KindUnauthenticated, "missing_api_key", "missing API key: send it as \"Authorization: Bearer <key>\"")
+	ErrInsufficientScope = apperror.New(apperror.KindForbidden, "insufficient_scope", "API key lacks the required scope")
+)
+
+// Authenticator resolves a raw API key to the caller it belongs to.
+type Authenticator interface {
+	Authenticate(ctx context.Context, key string) (*auth.Principal, error)
+}
+
+// Auth authenticates the bearer API key and stores the resolved principal
+// in the request context. Scopes are checked per route by RequireScope.
+func Auth(authenticator Authenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key, ok := bearerToken(c.GetHeader("Authorization"))
+		if !ok {
+			Problem(c, ErrMissingAPIKey)
+			return
+		}
+
+		principal, err := authenticator.Authenticate(c.Request.Context(), key)
+		if err != nil {
+			Problem(c, err)
+			return
+		}
+
+		c.Request = c.Request.WithContext(auth.NewContext(c.Request.Context(), principal))
+		c.Set(loggerKey, FromContext(c).With("merchant_id", principal.MerchantID, "api_key_id", principal.KeyID))
+		trace.SpanFromContext(c.Request.Context()).SetAttributes(
+			attribute.String("merchant.id", principal.MerchantID),
+			attribute.String("api_key.id", principal.KeyID),
+		)
+
+		c.Next()
+	}
+}
+
+// RequireScope rejects callers whose key was not granted scope. It must run
+// after Auth.
+func RequireScope(scope auth.Scope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal := auth.FromContext(c.Request.Context())
+		if principal == nil {
+			Problem(c, ErrMissingAPIKey)
+			return
+		}
+		if !principal.HasScope(scope) {
+			Problem(c, ErrInsufficientScope.WithMessage("API key lacks the "+string(scope)+" scope"))
+			return
+		}
+		c.Next()
+	}
+}
+
+// Anonymous treats every request as an admin and platform operator without
+// asking for a key. It backs AUTH_ENABLED=false and is meant for local
+// development only.
+func Anonymous() gin.HandlerFunc {
+	principal := &auth.Principal{Scopes: []auth.Scope{auth.ScopeAdmin, auth.ScopePlatform}}
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(auth.NewContext(c.Request.Context(), principal))
+		c.Next()
+	}
+}
+
+func bearerToken(header string) (string, bool) {
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
@@ -0,0 +1,88 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/williamkoller/payment-system/internal/middleware"
+	"github.com/williamkoller/payment-system/pkg/apperror"
+	"github.com/williamkoller/payment-system/pkg/auth"
+	"github.com/williamkoller/payment-system/pkg/logger"
+)
+
+type fakeAuthenticator map[string]*auth.Principal
+
+func (f fakeAuthenticator) Authenticate(_ context.Context, key string) (*auth.Principal, error) {
+	if p, ok := f[key]; ok {
+		return p, nil
+	}
+	return nil, apperror.New(apperror.KindUnauthenticated, "invalid_api_key", "invalid API key")
+}
+
+func TestAuth_RequireScope(t *testing.T) {
+	require.NoError(t, logger.InitLogger("dev"))
+	gin.SetMode(gin.TestMode)
+
+	authenticator := fakeAuthenticator{
+		"sk_test_reader": {KeyID: "k1", MerchantID: "m1", Scopes: []auth.Scope{auth.ScopePaymentsRead}},
+		"sk_test_admin":  {KeyID: "k2", MerchantID: "m1", Scopes: []auth.Scope{auth.ScopeAdmin}},
+		"sk_test_ops":    {KeyID: "k3", Scopes: []auth.Scope{auth.ScopePlatform}},
+		"sk_test_review": {KeyID: "k4", MerchantID: "m1", Scopes: []auth.Scope{auth.ScopeReviewsWrite}},
+	}
+
+	r := gin.New()
+	middleware.Middlewares(r)
+	r.POST("/payments/", middleware.Auth(authenticator), middleware.RequireScope(auth.ScopePaymentsWrite), func(c *gin.Context) {
+		c.String(http.StatusCreated, auth.FromContext(c.Request.Context()).MerchantID)
+	})
+	r.POST("/payments/pay_1/review/approve", middleware.Auth(authenticator), middleware.RequireScope(auth.ScopeReviewsWrite), func(c *gin.Context) {
+		c.String(http.StatusCreated, auth.FromContext(c.Request.Context()).MerchantID)
+	})
+	r.POST("/admin/merchants/", middleware.Auth(authenticator), middleware.RequireScope(auth.ScopePlatform), func(c *gin.Context) {
+		c.String(http.StatusCreated, auth.FromContext(c.Request.Context()).MerchantID)
+	})
+
+	tests := []struct {
+		name          string
+		path          string
+		authorization string
+		status        int
+		merchant      string
+	}{
+		{"missing key", "/payments/", "", http.StatusUnauthorized, ""},
+		{"wrong scheme", "/payments/", "Basic sk_test_admin", http.StatusUnauthorized, ""},
+		{"unknown key", "/payments/", "Bearer sk_test_nope", http.StatusUnauthorized, ""},
+		{"missing scope", "/payments/", "Bearer sk_test_reader", http.StatusForbidden, ""},
+		{"admin implies every merchant scope", "/payments/", "Bearer sk_test_admin", http.StatusCreated, "m1"},
+		{"reviews", "/payments/pay_1/review/approve", "Bearer sk_test_review", http.StatusCreated, "m1"},
+		{"reviews need their own scope", "/payments/pay_1/review/approve", "Bearer sk_test_reader", http.StatusForbidden, ""},
+		{"reviews do not imply writes", "/payments/", "Bearer sk_test_review", http.StatusForbidden, ""},
+		{"admin does not imply platform", "/admin/merchants/", "Bearer sk_test_admin", http.StatusForbidden, ""},
+		{"platform", "/admin/merchants/", "Bearer sk_test_ops", http.StatusCreated, ""},
+		{"platform does not imply merchant scopes", "/payments/", "Bearer sk_test_ops", http.StatusForbidden, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.status, rec.Code)
+			if tt.status == http.StatusUnauthorized {
+				assert.Equal(t, "Bearer", rec.Header().Get("WWW-Authenticate"))
+			}
+			if tt.status == http.StatusCreated {
+				assert.Equal(t, tt.merchant, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/williamkoller/payment-system/pkg/apperror"
+	"github.com/williamkoller/payment-system/pkg/auth"
+	"github.com/williamkoller/payment-system/pkg/clientip"
+	"github.com/williamkoller/payment-system/pkg/logger"
+	"github.com/williamkoller/payment-system/pkg/requestid"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// errorDomain identifies this service in the ErrorInfo detail of gRPC
+// errors.
+const errorDomain = "payment-system"
+
+var codeByKind = map[apperror.Kind]codes.Code{
+	apperror.KindValidation:          codes.InvalidArgument,
+	apperror.KindUnauthenticated:     codes.Unauthenticated,
+	apperror.KindForbidden:           codes.PermissionDenied,
+	apperror.KindNotFound:            codes.NotFound,
+	apperror.KindNotAcceptable:       codes.InvalidArgument,
+	apperror.KindInvalidTransition:   codes.FailedPrecondition,
+	apperror.KindIdempotencyConflict: codes.AlreadyExists,
+	apperror.KindUnprocessable:       codes.FailedPrecondition,
+	apperror.KindDeclined:            codes.FailedPrecondition,
+	apperror.KindRateLimited:         codes.ResourceExhausted,
+	apperror.KindGateway:             codes.Unavailable,
+	apperror.KindGatewayUnavailable:  codes.Unavailable,
+	apperror.KindInternal:            codes.Internal,
+}
+
+// GRPCError is the gRPC counterpart of Problem: it maps err onto a status
+// whose ErrorInfo detail carries the same stable code, and the decline
+// code if any, as problem details do. Internal errors never leak their
+// cause to the client.
+func GRPCError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	appErr := apperror.As(err)
+	code, ok := codeByKind[appErr.Kind]
+	if !ok {
+		code = codes.Internal
+	}
+	message := appErr.Error()
+	if appErr.Kind == apperror.KindInternal {
+		message = appErr.Message
+	}
+
+	info := &errdetails.ErrorInfo{Reason: appErr.Code, Domain: errorDomain}
+	if appErr.DeclineCode != "" {
+		info.Metadata = map[string]string{"decline_code": appErr.DeclineCode}
+	}
+	st, detailErr := status.New(code, message).WithDetails(info)
+	if detailErr != nil {
+		return status.Error(code, message)
+	}
+	return st.Err()
+}
+
+// GRPCLogger is the gRPC counterpart of ZapLoggerMiddleware: it accepts the
+// caller's x-request-id metadata or generates one, echoes it in the
+// response header, stores it and the peer's IP in the call's context and
+// logs every completed call.
+func GRPCLogger() (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor) {
+	unary := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, log := startCall(ctx, info.FullMethod)
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		logCall(log, start, err)
+		return resp, err
+	}
+	stream := func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, log := startCall(ss.Context(), info.FullMethod)
+		start := time.Now()
+		err := handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+		logCall(log, start, err)
+		return err
+	}
+	return unary, stream
+}
+
+func startCall(ctx context.Context, method string) (context.Context, *zap.SugaredLogger) {
+	var inbound string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(requestid.Header); len(values) > 0 {
+			inbound = values[0]
+		}
+	}
+	requestID := requestid.Resolve(inbound)
+	_ = grpc.SetHeader(ctx, metadata.Pairs(requestid.Header, requestID))
+
+	ctx = requestid.NewContext(ctx, requestID)
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		ip := p.Addr.String()
+		if host, _, err := net.SplitHostPort(ip); err == nil {
+			ip = host
+		}
+		ctx = clientip.NewContext(ctx, ip)
+	}
+
+	return ctx, logger.WithFields(map[string]interface{}{
+		"request_id": requestID,
+		"method":     method,
+	})
+}
+
+func logCall(log *zap.SugaredLogger, start time.Time, err error) {
+	code := status.Code(err)
+	fields := []interface{}{"code", code.String(), "duration", time.Since(start).String()}
+	switch code {
+	case codes.OK:
+		log.Infow("call completed", fields...)
+	case codes.Internal, codes.Unknown, codes.Unavailable:
+		log.Errorw("call failed", append(fields, "err", err.Error())...)
+	default:
+		log.Infow("call rejected", append(fields, "err", err.Error())...)
+	}
+}
+
+// GRPCAuth is the gRPC counterpart of Auth and RequireScope: it
+// authenticates the bearer API key in the call's authorization metadata
+// and requires the scope scopes maps the full method name to. Methods
+// missing from scopes are denied.
+func GRPCAuth(authenticator Authenticator, scopes map[string]auth.Scope) (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor) {
+	authenticate := func(ctx context.Context, method string) (context.Context, error) {
+		var header string
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get("authorization"); len(values) > 0 {
+				header = values[0]
+			}
+		}
+		key, ok := bearerToken(header)
+		if !ok {
+			return nil, GRPCError(ErrMissingAPIKey.WithMessage("missing API key: send it as \"authorization: Bearer <key>\" metadata"))
+		}
+
+		principal, err := authenticator.Authenticate(ctx, key)
+		if err != nil {
+			return nil, GRPCError(err)
+		}
+		return authorize(auth.NewContext(ctx, principal), principal, scopes[method])
+	}
+	return grpcAuthInterceptors(authenticate)
+}
+
+// GRPCAnonymous is the gRPC counterpart of Anonymous: every call runs as
+// an admin without a key. It backs AUTH_ENABLED=false and is meant for
+// local development only.
+func GRPCAnonymous() (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor) {
+	principal := &auth.Principal{Scopes: []auth.Scope{auth.ScopeAdmin}}
+	return grpcAuthInterceptors(func(ctx context.Context, _ string) (context.Context, error) {
+		return auth.NewContext(ctx, principal), nil
+	})
+}
+
+func authorize(ctx context.Context, principal *auth.Principal, scope auth.Scope) (context.Context, error) {
+	if scope == "" {
+		return nil, GRPCError(ErrInsufficientScope.WithMessage("method is not open to API keys"))
+	}
+	if !principal.HasScope(scope) {
+		return nil, GRPCError(ErrInsufficientScope.WithMessage("API key lacks the " + string(scope) + " scope"))
+	}
+	return ctx, nil
+}
+
+func grpcAuthInterceptors(authenticate func(ctx context.Context, method string) (context.Context, error)) (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor) {
+	unary := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := authenticate(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+	stream := func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticate(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+	return unary, stream
+}
+
+// contextStream replaces the context of a server stream, which interceptors
+// cannot otherwise pass on to the handler.
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,154 @@
+package middleware_test
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/williamkoller/payment-system/internal/middleware"
+	"github.com/williamkoller/payment-system/pkg/api/paymentv1"
+	"github.com/williamkoller/payment-system/pkg/apperror"
+	"github.com/williamkoller/payment-system/pkg/auth"
+	"github.com/williamkoller/payment-system/pkg/logger"
+	"github.com/williamkoller/payment-system/pkg/requestid"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// stubPaymentServer answers GetPayment and WatchPayment with the caller's
+// merchant and request id, and declines every capture.
+type stubPaymentServer struct {
+	paymentv1.UnimplementedPaymentServiceServer
+}
+
+func (stubPaymentServer) GetPayment(ctx context.Context, req *paymentv1.GetPaymentRequest) (*paymentv1.Payment, error) {
+	return &paymentv1.Payment{Id: req.GetPaymentId(), MerchantId: auth.MerchantID(ctx), IdempotencyKey: requestid.FromContext(ctx)}, nil
+}
+
+func (stubPaymentServer) Capture(context.Context, *paymentv1.CaptureRequest) (*paymentv1.Payment, error) {
+	declined := apperror.New(apperror.KindDeclined, "card_declined", "card declined").WithDecline("insufficient_funds")
+	return nil, middleware.GRPCError(declined)
+}
+
+func (stubPaymentServer) WatchPayment(req *paymentv1.WatchPaymentRequest, stream grpc.ServerStreamingServer[paymentv1.Payment]) error {
+	return stream.Send(&paymentv1.Payment{Id: req.GetPaymentId(), MerchantId: auth.MerchantID(stream.Context())})
+}
+
+func dialStub(t *testing.T, authenticator middleware.Authenticator) paymentv1.PaymentServiceClient {
+	t.Helper()
+	require.NoError(t, logger.InitLogger("dev"))
+
+	logUnary, logStream := middleware.GRPCLogger()
+	authUnary, authStream := middleware.GRPCAuth(authenticator, map[string]auth.Scope{
+		paymentv1.PaymentService_GetPayment_FullMethodName:   auth.ScopePaymentsRead,
+		paymentv1.PaymentService_Capture_FullMethodName:      auth.ScopePaymentsWrite,
+		paymentv1.PaymentService_WatchPayment_FullMethodName: auth.ScopePaymentsRead,
+	})
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(logUnary, authUnary),
+		grpc.ChainStreamInterceptor(logStream, authStream),
+	)
+	paymentv1.RegisterPaymentServiceServer(server, stubPaymentServer{})
+
+	listener := bufconn.Listen(1 << 20)
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return paymentv1.NewPaymentServiceClient(conn)
+}
+
+func TestGRPCAuth(t *testing.T) {
+	client := dialStub(t, fakeAuthenticator{
+		"sk_test_reader": {KeyID: "k1", MerchantID: "m1", Scopes: []auth.Scope{auth.ScopePaymentsRead}},
+		"sk_test_admin":  {KeyID: "k2", MerchantID: "m1", Scopes: []auth.Scope{auth.ScopeAdmin}},
+	})
+
+	tests := []struct {
+		name          string
+		authorization string
+		call          func(ctx context.Context) error
+		code          codes.Code
+	}{
+		{"missing key", "", getPayment(client), codes.Unauthenticated},
+		{"unknown key", "Bearer sk_test_nope", getPayment(client), codes.Unauthenticated},
+		{"read scope reads", "Bearer sk_test_reader", getPayment(client), codes.OK},
+		{"read scope cannot capture", "Bearer sk_test_reader", capture(client), codes.PermissionDenied},
+		{"admin reaches the handler", "Bearer sk_test_admin", capture(client), codes.FailedPrecondition},
+		{"streams are authenticated too", "", watchPayment(client), codes.Unauthenticated},
+		{"read scope watches", "Bearer sk_test_reader", watchPayment(client), codes.OK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.authorization != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, "authorization", tt.authorization)
+			}
+			err := tt.call(ctx)
+			assert.Equal(t, tt.code, status.Code(err), "%v", err)
+		})
+	}
+}
+
+func TestGRPCLogger_RequestIDAndErrors(t *testing.T) {
+	client := dialStub(t, fakeAuthenticator{
+		"sk_test_admin": {KeyID: "k2", MerchantID: "m1", Scopes: []auth.Scope{auth.ScopeAdmin}},
+	})
+	ctx := metadata.AppendToOutgoingContext(context.Background(),
+		"authorization", "Bearer sk_test_admin",
+		"x-request-id", "req-123",
+	)
+
+	var header metadata.MD
+	payment, err := client.GetPayment(ctx, &paymentv1.GetPaymentRequest{PaymentId: "pay_1"}, grpc.Header(&header))
+	require.NoError(t, err)
+	assert.Equal(t, "m1", payment.GetMerchantId())
+	assert.Equal(t, "req-123", payment.GetIdempotencyKey(), "the request id reaches the handler")
+	assert.Equal(t, []string{"req-123"}, header.Get(requestid.Header))
+
+	_, err = client.Capture(ctx, &paymentv1.CaptureRequest{PaymentId: "pay_1"})
+	st := status.Convert(err)
+	require.Len(t, st.Details(), 1)
+	info, ok := st.Details()[0].(*errdetails.ErrorInfo)
+	require.True(t, ok)
+	assert.Equal(t, "card_declined", info.GetReason())
+	assert.Equal(t, "insufficient_funds", info.GetMetadata()["decline_code"])
+}
+
+func getPayment(client paymentv1.PaymentServiceClient) func(context.Context) error {
+	return func(ctx context.Context) error {
+		_, err := client.GetPayment(ctx, &paymentv1.GetPaymentRequest{PaymentId: "pay_1"})
+		return err
+	}
+}
+
+func capture(client paymentv1.PaymentServiceClient) func(context.Context) error {
+	return func(ctx context.Context) error {
+		_, err := client.Capture(ctx, &paymentv1.CaptureRequest{PaymentId: "pay_1"})
+		return err
+	}
+}
+
+func watchPayment(client paymentv1.PaymentServiceClient) func(context.Context) error {
+	return func(ctx context.Context) error {
+		stream, err := client.WatchPayment(ctx, &paymentv1.WatchPaymentRequest{PaymentId: "pay_1"})
+		if err != nil {
+			return err
+		}
+		_, err = stream.Recv()
+		return err
+	}
+}
@@ -4,22 +4,39 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/williamkoller/payment-system/pkg/clientip"
 	"github.com/williamkoller/payment-system/pkg/logger"
-	"github.com/williamkoller/payment-system/pkg/ulid"
+	"github.com/williamkoller/payment-system/pkg/requestid"
+	"github.com/williamkoller/payment-system/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
 const loggerKey = "logger"
 
+// ZapLoggerMiddleware accepts the caller's X-Request-ID or generates one,
+// echoes it on the response and stores it, with the client IP, in the
+// request context so it follows the request into use cases, Stripe calls,
+// logs and the audit log.
 func ZapLoggerMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
-		requestID := ulid.NewULID()
-		ctxLogger := logger.WithFields(map[string]interface{}{
+		requestID := requestid.Resolve(c.GetHeader(requestid.Header))
+		c.Header(requestid.Header, requestID)
+		ctx := requestid.NewContext(c.Request.Context(), requestID)
+		c.Request = c.Request.WithContext(clientip.NewContext(ctx, c.ClientIP()))
+		trace.SpanFromContext(c.Request.Context()).SetAttributes(attribute.String("http.request_id", requestID))
+
+		fields := map[string]interface{}{
 			"request_id": requestID,
 			"method":     c.Request.Method,
 			"path":       c.Request.URL.Path,
-		})
+		}
+		if traceID := tracing.TraceID(c.Request.Context()); traceID != "" {
+			fields["trace_id"] = traceID
+		}
+		ctxLogger := logger.WithFields(fields)
 
 		c.Set(loggerKey, ctxLogger)
 
@@ -0,0 +1,64 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/williamkoller/payment-system/internal/middleware"
+	"github.com/williamkoller/payment-system/pkg/logger"
+	"github.com/williamkoller/payment-system/pkg/requestid"
+)
+
+func newEngine(t *testing.T, seen *string) *gin.Engine {
+	require.NoError(t, logger.InitLogger("dev"))
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.Use(middleware.ZapLoggerMiddleware())
+	r.GET("/", func(c *gin.Context) {
+		*seen = requestid.FromContext(c.Request.Context())
+		c.Status(http.StatusNoContent)
+	})
+	return r
+}
+
+func TestZapLoggerMiddleware_RequestID(t *testing.T) {
+	tests := []struct {
+		name    string
+		inbound string
+		keep    bool
+	}{
+		{name: "accepts inbound id", inbound: "req-123", keep: true},
+		{name: "generates when missing", inbound: ""},
+		{name: "replaces ids with spaces", inbound: "bad id"},
+		{name: "replaces overlong ids", inbound: strings.Repeat("a", 129)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen string
+			r := newEngine(t, &seen)
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.inbound != "" {
+				req.Header.Set(requestid.Header, tt.inbound)
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			returned := rec.Header().Get(requestid.Header)
+			assert.NotEmpty(t, returned)
+			assert.Equal(t, returned, seen, "handler context must carry the returned id")
+			if tt.keep {
+				assert.Equal(t, tt.inbound, returned)
+			} else {
+				assert.NotEqual(t, tt.inbound, returned)
+			}
+		})
+	}
+}
@@ -1,8 +1,24 @@
 package middleware
 
-import "github.com/gin-gonic/gin"
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/williamkoller/payment-system/pkg/apperror"
+	"github.com/williamkoller/payment-system/pkg/tracing"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+)
 
 func Middlewares(e *gin.Engine) {
-	e.Use(gin.Recovery())
+	useJSONFieldNames()
+
+	e.Use(gin.CustomRecovery(func(c *gin.Context, recovered any) {
+		Problem(c, apperror.As(fmt.Errorf("panic: %v", recovered)))
+	}))
+	e.Use(otelgin.Middleware(tracing.ServiceName()))
 	e.Use(ZapLoggerMiddleware())
+
+	e.NoRoute(func(c *gin.Context) {
+		Problem(c, apperror.New(apperror.KindNotFound, "route_not_found", "route not found"))
+	})
 }
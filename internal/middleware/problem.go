@@ -0,0 +1,167 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/williamkoller/payment-system/pkg/apperror"
+	"github.com/williamkoller/payment-system/pkg/requestid"
+)
+
+const (
+	ProblemContentType = "application/problem+json"
+	problemTypeBase    = "https://github.com/williamkoller/payment-system/blob/main/docs/errors.md#"
+)
+
+var statusByKind = map[apperror.Kind]int{
+	apperror.KindValidation:          http.StatusBadRequest,
+	apperror.KindUnauthenticated:     http.StatusUnauthorized,
+	apperror.KindForbidden:           http.StatusForbidden,
+	apperror.KindNotFound:            http.StatusNotFound,
+	apperror.KindNotAcceptable:       http.StatusNotAcceptable,
+	apperror.KindInvalidTransition:   http.StatusConflict,
+	apperror.KindIdempotencyConflict: http.StatusConflict,
+	apperror.KindUnprocessable:       http.StatusUnprocessableEntity,
+	apperror.KindDeclined:            http.StatusPaymentRequired,
+	apperror.KindRateLimited:         http.StatusTooManyRequests,
+	apperror.KindGateway:             http.StatusBadGateway,
+	apperror.KindGatewayUnavailable:  http.StatusServiceUnavailable,
+	apperror.KindInternal:            http.StatusInternalServerError,
+}
+
+// ProblemDetails is an RFC 7807 body extended with the stable error code,
+// the request id and, where they apply, the decline code and field errors.
+type ProblemDetails struct {
+	Type        string       `json:"type"`
+	Title       string       `json:"title"`
+	Status      int          `json:"status"`
+	Detail      string       `json:"detail,omitempty"`
+	Instance    string       `json:"instance,omitempty"`
+	Code        string       `json:"code"`
+	RequestID   string       `json:"request_id,omitempty"`
+	DeclineCode string       `json:"decline_code,omitempty"`
+	Errors      []FieldError `json:"errors,omitempty"`
+}
+
+type FieldError struct {
+	Field string `json:"field"`
+	Rule  string `json:"rule"`
+}
+
+// StatusFor returns the HTTP status err is reported with.
+func StatusFor(err error) int {
+	if status, ok := statusByKind[apperror.As(err).Kind]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// NewProblem maps err onto problem details. Internal errors never leak
+// their cause to the client.
+func NewProblem(c *gin.Context, err error) ProblemDetails {
+	appErr := apperror.As(err)
+	status := StatusFor(err)
+
+	problem := ProblemDetails{
+		Type:        problemTypeBase + appErr.Code,
+		Title:       http.StatusText(status),
+		Status:      status,
+		Detail:      appErr.Error(),
+		Instance:    c.Request.URL.Path,
+		Code:        appErr.Code,
+		RequestID:   requestid.FromContext(c.Request.Context()),
+		DeclineCode: appErr.DeclineCode,
+	}
+
+	if appErr.Kind == apperror.KindInternal {
+		problem.Detail = appErr.Message
+	}
+
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		problem.Detail = appErr.Message
+		for _, fe := range validationErrors {
+			problem.Errors = append(problem.Errors, FieldError{Field: fieldPath(fe), Rule: fe.Tag()})
+		}
+	}
+
+	return problem
+}
+
+// Problem writes err as application/problem+json and aborts the request.
+// extensions are merged into the body as additional members.
+func Problem(c *gin.Context, err error, extensions ...gin.H) {
+	problem := NewProblem(c, err)
+
+	log := FromContext(c)
+	if problem.Status >= http.StatusInternalServerError {
+		log.Errorw("Request failed", "code", problem.Code, "status", problem.Status, "err", err.Error())
+	} else {
+		log.Infow("Request rejected", "code", problem.Code, "status", problem.Status, "err", err.Error())
+	}
+
+	body := gin.H{
+		"type":   problem.Type,
+		"title":  problem.Title,
+		"status": problem.Status,
+		"code":   problem.Code,
+	}
+	for _, ext := range extensions {
+		for k, v := range ext {
+			body[k] = v
+		}
+	}
+	if problem.Detail != "" {
+		body["detail"] = problem.Detail
+	}
+	if problem.Instance != "" {
+		body["instance"] = problem.Instance
+	}
+	if problem.RequestID != "" {
+		body["request_id"] = problem.RequestID
+	}
+	if problem.DeclineCode != "" {
+		body["decline_code"] = problem.DeclineCode
+	}
+	if len(problem.Errors) > 0 {
+		body["errors"] = problem.Errors
+	}
+
+	if problem.Status == http.StatusUnauthorized {
+		c.Header("WWW-Authenticate", "Bearer")
+	}
+	c.Header("Content-Type", ProblemContentType)
+	c.AbortWithStatusJSON(problem.Status, body)
+}
+
+// fieldPath turns "AddPaymentDto.fx_quote_id" into "fx_quote_id".
+func fieldPath(fe validator.FieldError) string {
+	ns := fe.Namespace()
+	if _, rest, ok := strings.Cut(ns, "."); ok {
+		return rest
+	}
+	return ns
+}
+
+// useJSONFieldNames makes validation errors name fields the way clients
+// send them: by their json, uri or form key rather than the Go field name.
+func useJSONFieldNames() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		for _, tag := range []string{"json", "uri", "form"} {
+			name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+			if name != "" && name != "-" {
+				return name
+			}
+		}
+		return field.Name
+	})
+}
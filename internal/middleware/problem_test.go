@@ -0,0 +1,109 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/williamkoller/payment-system/internal/middleware"
+	"github.com/williamkoller/payment-system/pkg/apperror"
+	"github.com/williamkoller/payment-system/pkg/logger"
+)
+
+type createDto struct {
+	Amount   int64  `json:"amount" binding:"required,gt=0"`
+	Currency string `json:"currency" binding:"required"`
+}
+
+func serveProblem(t *testing.T, handler gin.HandlerFunc, body string) (*httptest.ResponseRecorder, map[string]any) {
+	require.NoError(t, logger.InitLogger("dev"))
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	middleware.Middlewares(r)
+	r.POST("/payments/", handler)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/payments/", stringsReader(body)))
+
+	var problem map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+	return rec, problem
+}
+
+func TestProblem(t *testing.T) {
+	declined := apperror.New(apperror.KindDeclined, "card_declined", "payment declined")
+
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{"not found", apperror.New(apperror.KindNotFound, "payment_not_found", "payment not found"), http.StatusNotFound, "payment_not_found"},
+		{"invalid transition", apperror.New(apperror.KindInvalidTransition, "payment_not_captured", "not captured"), http.StatusConflict, "payment_not_captured"},
+		{"gateway unavailable", apperror.New(apperror.KindGatewayUnavailable, "gateway_unavailable", "down"), http.StatusServiceUnavailable, "gateway_unavailable"},
+		{"wrapped", declined.Wrap(errors.New("insufficient funds")), http.StatusPaymentRequired, "card_declined"},
+		{"untyped", errors.New("pq: connection refused"), http.StatusInternalServerError, "internal_error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, problem := serveProblem(t, func(c *gin.Context) { middleware.Problem(c, tt.err) }, "")
+
+			assert.Equal(t, tt.status, rec.Code)
+			assert.Equal(t, middleware.ProblemContentType, rec.Header().Get("Content-Type"))
+			assert.Equal(t, tt.code, problem["code"])
+			assert.Equal(t, float64(tt.status), problem["status"])
+			assert.Equal(t, "/payments/", problem["instance"])
+			assert.NotEmpty(t, problem["request_id"])
+		})
+	}
+}
+
+func TestProblem_InternalErrorsHideCause(t *testing.T) {
+	_, problem := serveProblem(t, func(c *gin.Context) {
+		middleware.Problem(c, errors.New("pq: password authentication failed"))
+	}, "")
+
+	assert.Equal(t, "internal server error", problem["detail"])
+}
+
+func TestProblem_DeclineCodeAndExtensions(t *testing.T) {
+	err := apperror.New(apperror.KindDeclined, "blocklisted", "payment declined by block list").WithDecline("blocklisted_email")
+
+	_, problem := serveProblem(t, func(c *gin.Context) {
+		middleware.Problem(c, err, gin.H{"payment_id": "p1"})
+	}, "")
+
+	assert.Equal(t, "blocklisted_email", problem["decline_code"])
+	assert.Equal(t, "p1", problem["payment_id"])
+}
+
+func TestProblem_ValidationErrors(t *testing.T) {
+	rec, problem := serveProblem(t, func(c *gin.Context) {
+		var dto createDto
+		if err := c.ShouldBindJSON(&dto); err != nil {
+			middleware.Problem(c, apperror.Validation(err))
+			return
+		}
+		c.Status(http.StatusCreated)
+	}, `{"amount": -1}`)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, "validation_failed", problem["code"])
+	assert.ElementsMatch(t, []any{
+		map[string]any{"field": "amount", "rule": "gt"},
+		map[string]any{"field": "currency", "rule": "required"},
+	}, problem["errors"])
+}
+
+func stringsReader(s string) *strings.Reader {
+	return strings.NewReader(s)
+}
@@ -0,0 +1,140 @@
+package middleware
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/williamkoller/payment-system/internal/ratelimit/domain"
+	"github.com/williamkoller/payment-system/pkg/apperror"
+	"github.com/williamkoller/payment-system/pkg/auth"
+)
+
+const rateLimitRemainingKey = "rate_limit_remaining"
+
+var (
+	ErrRateLimited       = apperror.New(apperror.KindRateLimited, "rate_limited", "rate limit exceeded")
+	ErrTooManyConcurrent = apperror.New(apperror.KindRateLimited, "too_many_concurrent_requests", "too many concurrent requests")
+)
+
+// RateLimiter decides whether a subject may make one more request of a
+// route class, and caps the requests it has in flight.
+type RateLimiter interface {
+	Allow(ctx context.Context, class domain.Class, subject string) (domain.Decision, bool, error)
+	Acquire(subject string) (release func(), ok bool)
+}
+
+// RateLimits builds the rate limiting middleware of each route. A nil
+// *RateLimits limits nothing, which is how rate limiting is turned off.
+type RateLimits struct {
+	Limiter RateLimiter
+}
+
+func NewRateLimits(limiter RateLimiter) *RateLimits {
+	return &RateLimits{Limiter: limiter}
+}
+
+// PerIP limits requests by client IP. Mount it before Auth, so floods of
+// bad keys are turned away before they reach the key store.
+func (l *RateLimits) PerIP() gin.HandlerFunc {
+	if l == nil {
+		return passThrough
+	}
+	return func(c *gin.Context) {
+		if l.take(c, domain.ClassIP, c.ClientIP()) {
+			c.Next()
+		}
+	}
+}
+
+// PerClient limits requests of class by the merchant behind the API key,
+// so rotating or adding keys does not raise a merchant's budget, and caps
+// its concurrent requests. It must run after Auth.
+func (l *RateLimits) PerClient(class domain.Class) gin.HandlerFunc {
+	return l.perClient(class, true)
+}
+
+// PerClientStream is PerClient for long-lived streams: it limits how often
+// they are opened but does not count them as concurrent requests, which
+// they would hold for as long as they are open. Streams are capped by
+// their own limits.
+func (l *RateLimits) PerClientStream(class domain.Class) gin.HandlerFunc {
+	return l.perClient(class, false)
+}
+
+func (l *RateLimits) perClient(class domain.Class, concurrency bool) gin.HandlerFunc {
+	if l == nil {
+		return passThrough
+	}
+	return func(c *gin.Context) {
+		subject := domain.Subject(auth.FromContext(c.Request.Context()))
+		if subject == "" {
+			c.Next()
+			return
+		}
+		if !l.take(c, class, subject) {
+			return
+		}
+		if !concurrency {
+			c.Next()
+			return
+		}
+
+		release, ok := l.Limiter.Acquire(subject)
+		if !ok {
+			c.Header("Retry-After", "1")
+			Problem(c, ErrTooManyConcurrent)
+			return
+		}
+		defer release()
+
+		c.Next()
+	}
+}
+
+// take reports whether the request may proceed, writing the RateLimit
+// headers either way and the 429 when it may not. A failing store lets
+// requests through: losing the limiter must not take payments down.
+func (l *RateLimits) take(c *gin.Context, class domain.Class, subject string) bool {
+	decision, ok, err := l.Limiter.Allow(c.Request.Context(), class, subject)
+	if err != nil {
+		FromContext(c).Warnw("rate limiter unavailable, allowing request", "class", class, "err", err)
+		return true
+	}
+	if !ok {
+		return true
+	}
+
+	setRateLimitHeaders(c, decision)
+	if decision.Allowed {
+		return true
+	}
+
+	c.Header("Retry-After", strconv.Itoa(ceilSeconds(decision.RetryAfter)))
+	Problem(c, ErrRateLimited.WithMessage("rate limit of "+decision.Limit.String()+" exceeded"))
+	return false
+}
+
+// setRateLimitHeaders reports the most restrictive of the limits a request
+// went through.
+func setRateLimitHeaders(c *gin.Context, d domain.Decision) {
+	if prev, ok := c.Get(rateLimitRemainingKey); ok && prev.(int) <= d.Remaining {
+		return
+	}
+	c.Set(rateLimitRemainingKey, d.Remaining)
+
+	c.Header("RateLimit-Limit", strconv.Itoa(d.Limit.Requests))
+	c.Header("RateLimit-Remaining", strconv.Itoa(d.Remaining))
+	c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(d.Reset)))
+	c.Header("RateLimit-Policy", d.Limit.Policy())
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+func passThrough(c *gin.Context) {
+	c.Next()
+}
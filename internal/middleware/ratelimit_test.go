@@ -0,0 +1,128 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/williamkoller/payment-system/internal/middleware"
+	"github.com/williamkoller/payment-system/internal/ratelimit/application"
+	"github.com/williamkoller/payment-system/internal/ratelimit/domain"
+	"github.com/williamkoller/payment-system/internal/ratelimit/infra"
+	"github.com/williamkoller/payment-system/pkg/auth"
+	"github.com/williamkoller/payment-system/pkg/logger"
+)
+
+func TestRateLimits_PerClient(t *testing.T) {
+	require.NoError(t, logger.InitLogger("dev"))
+	gin.SetMode(gin.TestMode)
+
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	limiter := application.NewLimiter(infra.NewMemoryStore(), map[domain.Class]domain.Limit{
+		domain.ClassIP:    {Requests: 100, Per: time.Second},
+		domain.ClassWrite: {Requests: 2, Per: time.Second},
+	}, 0)
+	limiter.Now = func() time.Time { return now }
+	limits := middleware.NewRateLimits(limiter)
+
+	authenticator := fakeAuthenticator{
+		"sk_test_a": {KeyID: "k1", MerchantID: "m1", Scopes: []auth.Scope{auth.ScopeAdmin}},
+		"sk_test_b": {KeyID: "k2", MerchantID: "m1", Scopes: []auth.Scope{auth.ScopeAdmin}},
+		"sk_test_c": {KeyID: "k3", MerchantID: "m2", Scopes: []auth.Scope{auth.ScopeAdmin}},
+	}
+
+	r := gin.New()
+	middleware.Middlewares(r)
+	r.POST("/payments/", limits.PerIP(), middleware.Auth(authenticator), limits.PerClient(domain.ClassWrite), func(c *gin.Context) {
+		c.Status(http.StatusCreated)
+	})
+
+	post := func(key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/payments/", nil)
+		req.Header.Set("Authorization", "Bearer "+key)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := post("sk_test_a")
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "2", rec.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", rec.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "2;w=1", rec.Header().Get("RateLimit-Policy"))
+
+	// A second key of the same merchant draws from the same bucket.
+	assert.Equal(t, http.StatusCreated, post("sk_test_b").Code)
+
+	rec = post("sk_test_a")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+	assert.Equal(t, "0", rec.Header().Get("RateLimit-Remaining"))
+
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, "rate_limited", body["code"])
+
+	assert.Equal(t, http.StatusCreated, post("sk_test_c").Code, "other merchants are unaffected")
+
+	now = now.Add(500 * time.Millisecond)
+	assert.Equal(t, http.StatusCreated, post("sk_test_a").Code, "one token refilled")
+}
+
+func TestRateLimits_NilLimitsNothing(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var limits *middleware.RateLimits
+	r := gin.New()
+	r.GET("/payments/", limits.PerIP(), limits.PerClient(domain.ClassRead), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/payments/", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get("RateLimit-Limit"))
+}
+
+func TestRateLimits_PerIPTrustsOnlyConfiguredProxies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name    string
+		proxies []string
+		status  int
+	}{
+		// httptest requests come from 192.0.2.1.
+		{"no trusted proxies", nil, http.StatusTooManyRequests},
+		{"trusted proxy", []string{"192.0.2.0/24"}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := application.NewLimiter(infra.NewMemoryStore(), map[domain.Class]domain.Limit{
+				domain.ClassIP: {Requests: 1, Per: time.Hour},
+			}, 0)
+			limits := middleware.NewRateLimits(limiter)
+
+			r := gin.New()
+			require.NoError(t, r.SetTrustedProxies(tt.proxies))
+			r.GET("/payments/", limits.PerIP(), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			var rec *httptest.ResponseRecorder
+			for _, forwarded := range []string{"203.0.113.1", "203.0.113.2"} {
+				req := httptest.NewRequest(http.MethodGet, "/payments/", nil)
+				req.Header.Set("X-Forwarded-For", forwarded)
+				rec = httptest.NewRecorder()
+				r.ServeHTTP(rec, req)
+			}
+			assert.Equal(t, tt.status, rec.Code, "X-Forwarded-For only counts from a trusted proxy")
+		})
+	}
+}
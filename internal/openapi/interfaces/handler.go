@@ -0,0 +1,45 @@
+package interfaces
+
+import (
+	"net/http"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gin-gonic/gin"
+)
+
+// docsPage renders /openapi.json with Redoc.
+const docsPage = `<!DOCTYPE html>
+<html>
+<head>
+  <title>Payment System API</title>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+</head>
+<body>
+  <redoc spec-url="/openapi.json"></redoc>
+  <script src="https://cdn.redoc.ly/redoc/latest/bundles/redoc.standalone.js"></script>
+</body>
+</html>
+`
+
+type SpecHandler struct {
+	spec []byte
+}
+
+// NewSpecHandler serializes spec once; the document never changes while
+// the process runs.
+func NewSpecHandler(spec *openapi3.T) (*SpecHandler, error) {
+	encoded, err := spec.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	return &SpecHandler{spec: encoded}, nil
+}
+
+func (h *SpecHandler) Spec(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json", h.spec)
+}
+
+func (h *SpecHandler) Docs(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(docsPage))
+}
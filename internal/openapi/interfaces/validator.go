@@ -0,0 +1,129 @@
+package interfaces
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/gin-gonic/gin"
+	"github.com/williamkoller/payment-system/internal/middleware"
+	"github.com/williamkoller/payment-system/pkg/apperror"
+)
+
+// streamedTypes are the media types of responses written as they are
+// produced: event streams, which last as long as the connection, and
+// exports, which can be larger than memory.
+var streamedTypes = []string{"text/event-stream", "text/csv", "application/x-ndjson"}
+
+var ErrRequestMismatch = apperror.New(apperror.KindValidation, "openapi_mismatch", "request does not match the OpenAPI document")
+
+type Validator struct {
+	router  routers.Router
+	options *openapi3filter.Options
+}
+
+// NewValidator checks traffic against spec. API keys are left to the auth
+// middleware; the validator only checks the shape of requests and
+// responses.
+func NewValidator(spec *openapi3.T) (*Validator, error) {
+	router, err := gorillamux.NewRouter(spec)
+	if err != nil {
+		return nil, err
+	}
+	// Without this, every schema error quotes the whole schema, which ends
+	// up in problem details and logs.
+	openapi3.SchemaErrorDetailsDisabled = true
+	// Streamed bodies are documented as plain strings.
+	openapi3filter.RegisterBodyDecoder("application/x-ndjson", openapi3filter.PlainBodyDecoder)
+	openapi3filter.RegisterBodyDecoder("text/event-stream", openapi3filter.PlainBodyDecoder)
+	return &Validator{
+		router:  router,
+		options: &openapi3filter.Options{AuthenticationFunc: openapi3filter.NoopAuthenticationFunc},
+	}, nil
+}
+
+// Middleware rejects requests that do not match the document and logs
+// responses that do not, which are a bug in the handler or the document.
+// Routes the document does not describe pass through. It keeps a copy of
+// every response body, so it is meant for development and staging only;
+// streamed responses are the exception, see streams.
+func (v *Validator) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route, params, err := v.router.FindRoute(c.Request)
+		if err != nil {
+			c.Next()
+			return
+		}
+
+		input := &openapi3filter.RequestValidationInput{
+			Request:    c.Request,
+			PathParams: params,
+			Route:      route,
+			Options:    v.options,
+		}
+		if err := openapi3filter.ValidateRequest(c.Request.Context(), input); err != nil {
+			middleware.Problem(c, ErrRequestMismatch.Wrap(err))
+			return
+		}
+
+		if streams(route) {
+			c.Next()
+			return
+		}
+
+		recorder := &bodyRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		err = openapi3filter.ValidateResponse(c.Request.Context(), &openapi3filter.ResponseValidationInput{
+			RequestValidationInput: input,
+			Status:                 recorder.Status(),
+			Header:                 recorder.Header(),
+			Body:                   io.NopCloser(bytes.NewReader(recorder.body.Bytes())),
+			Options:                v.options,
+		})
+		if err != nil {
+			middleware.FromContext(c).Errorw("Response does not match the OpenAPI document",
+				"route", route.Path, "method", route.Method, "status", recorder.Status(), "err", err.Error())
+		}
+	}
+}
+
+// streams reports whether route answers with a streamed response, which
+// is left unrecorded and unchecked: a copy of it would grow for as long as
+// the stream runs.
+func streams(route *routers.Route) bool {
+	if route.Operation == nil || route.Operation.Responses == nil {
+		return false
+	}
+	ok := route.Operation.Responses.Status(http.StatusOK)
+	if ok == nil || ok.Value == nil {
+		return false
+	}
+	for _, t := range streamedTypes {
+		if ok.Value.Content.Get(t) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// bodyRecorder keeps a copy of the response body as it is written.
+type bodyRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *bodyRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *bodyRecorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,49 @@
+package router
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gin-gonic/gin"
+	"github.com/williamkoller/payment-system/api"
+	"github.com/williamkoller/payment-system/internal/openapi/interfaces"
+)
+
+// LoadSpec parses the OpenAPI document embedded in the binary and checks
+// that it is a valid OpenAPI 3 document.
+func LoadSpec() (*openapi3.T, error) {
+	spec, err := openapi3.NewLoader().LoadFromData(api.OpenAPI)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse OpenAPI document: %w", err)
+	}
+	if err := spec.Validate(context.Background()); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: %w", err)
+	}
+	return spec, nil
+}
+
+// SetupRouter serves the document at /openapi.json and renders it at
+// /docs. Both are public.
+func SetupRouter(e *gin.Engine, spec *openapi3.T) error {
+	handler, err := interfaces.NewSpecHandler(spec)
+	if err != nil {
+		return err
+	}
+
+	e.GET("/openapi.json", handler.Spec)
+	e.GET("/docs", handler.Docs)
+	return nil
+}
+
+// Validate checks every later-registered route the document describes
+// against it; see interfaces.Validator. It must be installed before the
+// routes it should cover are mounted.
+func Validate(e *gin.Engine, spec *openapi3.T) error {
+	validator, err := interfaces.NewValidator(spec)
+	if err != nil {
+		return err
+	}
+	e.Use(validator.Middleware())
+	return nil
+}
@@ -0,0 +1,153 @@
+package router_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	auditApplication "github.com/williamkoller/payment-system/internal/audit/application"
+	auditRouter "github.com/williamkoller/payment-system/internal/audit/router"
+	batchApplication "github.com/williamkoller/payment-system/internal/batch/application"
+	batchRouter "github.com/williamkoller/payment-system/internal/batch/router"
+	openapiRouter "github.com/williamkoller/payment-system/internal/openapi/router"
+	"github.com/williamkoller/payment-system/internal/payment/application"
+	"github.com/williamkoller/payment-system/internal/payment/domain"
+	paymentInterfaces "github.com/williamkoller/payment-system/internal/payment/interfaces"
+	paymentRouter "github.com/williamkoller/payment-system/internal/payment/router"
+	riskApplication "github.com/williamkoller/payment-system/internal/risk/application"
+	riskRouter "github.com/williamkoller/payment-system/internal/risk/router"
+	webhookRouter "github.com/williamkoller/payment-system/internal/webhook/router"
+	"github.com/williamkoller/payment-system/pkg/logger"
+)
+
+var ginParam = regexp.MustCompile(`:([a-z_]+)`)
+
+func TestSpec_DescribesEveryPaymentAndWebhookRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("PORT", "8080")
+	t.Setenv("APP_NAME", "payment-system")
+	t.Setenv("STRIPE_API_KEY", "sk_test")
+	t.Setenv("DB_PORT", "5432")
+
+	spec, err := openapiRouter.LoadSpec()
+	require.NoError(t, err)
+
+	e := gin.New()
+	noAuth := func(c *gin.Context) { c.Next() }
+	paymentRouter.SetupRouter(e, &application.PaymentUseCase{}, noAuth, nil)
+	batchRouter.SetupRouter(e, &batchApplication.BatchService{}, noAuth, nil)
+	auditRouter.SetupRouter(e, &auditApplication.AuditService{}, noAuth, nil)
+	riskRouter.SetupRouter(e, &riskApplication.Engine{}, noAuth, nil)
+	webhookRouter.SetupWebhookRouter(e, nil, nil, nil)
+
+	registered := make(map[string]bool)
+	for _, route := range e.Routes() {
+		path := ginParam.ReplaceAllString(route.Path, "{$1}")
+		registered[route.Method+" "+path] = true
+
+		item := spec.Paths.Value(path)
+		if !assert.NotNil(t, item, "%s is not in api/openapi.yaml", path) {
+			continue
+		}
+		assert.NotNil(t, item.GetOperation(route.Method), "%s %s is not in api/openapi.yaml", route.Method, path)
+	}
+
+	for path, item := range spec.Paths.Map() {
+		for method := range item.Operations() {
+			assert.True(t, registered[method+" "+path], "api/openapi.yaml describes %s %s, which is not registered", method, path)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	require.NoError(t, logger.InitLogger("dev"))
+	gin.SetMode(gin.TestMode)
+
+	spec, err := openapiRouter.LoadSpec()
+	require.NoError(t, err)
+
+	e := gin.New()
+	require.NoError(t, openapiRouter.Validate(e, spec))
+	e.POST("/payments/", func(c *gin.Context) {
+		payment := &domain.Payment{ID: "pay_1", Amount: 1000, Currency: "USD", Status: domain.StatusCompleted}
+		c.JSON(http.StatusCreated, paymentInterfaces.ToPaymentResponse(payment))
+	})
+	e.GET("/undocumented", func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{"valid request", http.MethodPost, "/payments/", `{"amount":1000,"currency":"USD","email":"user@example.com","payment_method":"pm_card_visa"}`, http.StatusCreated},
+		{"missing field", http.MethodPost, "/payments/", `{"amount":1000,"currency":"USD","email":"user@example.com"}`, http.StatusBadRequest},
+		{"wrong type", http.MethodPost, "/payments/", `{"amount":"1000","currency":"USD","email":"user@example.com","payment_method":"pm_card_visa"}`, http.StatusBadRequest},
+		{"undocumented route", http.MethodGet, "/undocumented", "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.status, rec.Code, rec.Body.String())
+			if tt.status == http.StatusBadRequest {
+				assert.Contains(t, rec.Body.String(), `"code":"openapi_mismatch"`)
+			}
+		})
+	}
+}
+
+func TestValidate_LeavesStreamsUnrecorded(t *testing.T) {
+	require.NoError(t, logger.InitLogger("dev"))
+	gin.SetMode(gin.TestMode)
+
+	spec, err := openapiRouter.LoadSpec()
+	require.NoError(t, err)
+
+	e := gin.New()
+	e.Use(func(c *gin.Context) {
+		c.Set("writer", c.Writer)
+		c.Next()
+	})
+	require.NoError(t, openapiRouter.Validate(e, spec))
+	unwrapped := func(c *gin.Context) {
+		c.String(http.StatusOK, "%t", c.Writer == c.MustGet("writer"))
+	}
+	e.GET("/payments/export", unwrapped)
+	e.GET("/payments/:payment_id", unwrapped)
+	e.GET("/payments/:payment_id/events", unwrapped)
+
+	tests := []struct {
+		name      string
+		path      string
+		unwrapped bool
+	}{
+		{"json response", "/payments/pay_1", false},
+		{"event stream", "/payments/pay_1/events", true},
+		{"export", "/payments/export", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, fmt.Sprint(tt.unwrapped), rec.Body.String(), "streamed responses go straight to the client")
+		})
+	}
+}
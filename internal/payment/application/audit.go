@@ -0,0 +1,58 @@
+package application
+
+import (
+	"context"
+
+	auditDomain "github.com/williamkoller/payment-system/internal/audit/domain"
+	"github.com/williamkoller/payment-system/internal/payment/domain"
+	"github.com/williamkoller/payment-system/pkg/apperror"
+	"github.com/williamkoller/payment-system/pkg/ulid"
+)
+
+// Auditor appends entries to the audit log.
+type Auditor interface {
+	Record(ctx context.Context, entry *auditDomain.Entry)
+}
+
+// audit starts the audit entry of action on payment, given its state
+// before the action. The returned func completes the entry with the
+// payment's final state and the action's error, and records it.
+func (u *PaymentUseCase) audit(ctx context.Context, action string, payment *domain.Payment, before map[string]any, details map[string]any) func(err error) {
+	if u.Audit == nil {
+		return func(error) {}
+	}
+
+	return func(err error) {
+		entry := auditDomain.NewEntry(ctx, ulid.NewULID(), action, auditDomain.Resource{
+			Type:       auditDomain.ResourcePayment,
+			ID:         payment.ID,
+			MerchantID: payment.MerchantID,
+		})
+		entry.SetSnapshots(before, paymentSnapshot(payment))
+		if details != nil {
+			entry.SetDetails(details)
+		}
+		if err != nil {
+			entry.Fail(apperror.As(err).Code)
+		}
+		u.Audit.Record(ctx, entry)
+	}
+}
+
+// paymentSnapshot is what the audit log keeps of a payment. The email is
+// left out so the log holds no PII.
+func paymentSnapshot(p *domain.Payment) map[string]any {
+	return map[string]any{
+		"id":                       p.ID,
+		"merchant_id":              p.MerchantID,
+		"status":                   p.Status,
+		"amount":                   p.Amount,
+		"currency":                 p.Currency,
+		"settlement_amount":        p.SettlementAmount,
+		"settlement_currency":      p.SettlementCurrency,
+		"fx_rate":                  p.FxRate,
+		"stripe_id":                p.StripeID,
+		"gateway_account":          p.GatewayAccount,
+		"authorization_expires_at": p.AuthorizationExpiresAt,
+	}
+}
@@ -0,0 +1,193 @@
+package application
+
+import (
+	"context"
+	"time"
+
+	auditDomain "github.com/williamkoller/payment-system/internal/audit/domain"
+	"github.com/williamkoller/payment-system/internal/payment/domain"
+	"github.com/williamkoller/payment-system/internal/payment/dtos"
+	"github.com/williamkoller/payment-system/pkg/heartbeat"
+	"github.com/williamkoller/payment-system/pkg/logger"
+)
+
+// ExpiryPolicy decides what happens to an authorization as it approaches
+// AuthorizationExpiresAt: operators are alerted AlertBefore the deadline and
+// Action is applied ActBefore the deadline.
+type ExpiryPolicy struct {
+	AlertBefore time.Duration
+	ActBefore   time.Duration
+	Action      domain.ExpiryAction
+}
+
+// window is how long before the deadline the policy first has something
+// to do.
+func (p ExpiryPolicy) window() time.Duration {
+	return max(p.AlertBefore, p.ActBefore)
+}
+
+type ExpiryPolicyProvider interface {
+	PolicyFor(ctx context.Context, payment *domain.Payment) (ExpiryPolicy, error)
+	// Horizon is the longest AlertBefore or ActBefore PolicyFor can
+	// return, so a sweep loads every payment some policy applies to.
+	Horizon(ctx context.Context) (time.Duration, error)
+}
+
+// StaticExpiryPolicy applies the same policy to every payment.
+type StaticExpiryPolicy ExpiryPolicy
+
+func (s StaticExpiryPolicy) PolicyFor(context.Context, *domain.Payment) (ExpiryPolicy, error) {
+	return ExpiryPolicy(s), nil
+}
+
+func (s StaticExpiryPolicy) Horizon(context.Context) (time.Duration, error) {
+	return ExpiryPolicy(s).window(), nil
+}
+
+// MerchantExpirySettings is the part of the merchant service a
+// MerchantExpiryPolicy reads.
+type MerchantExpirySettings interface {
+	MerchantDirectory
+	// MaxExpiryWindow is the longest expiry window any merchant sets.
+	MaxExpiryWindow(ctx context.Context) (time.Duration, error)
+}
+
+// MerchantExpiryPolicy applies each merchant's own expiry policy from its
+// settings. Default fills in whatever the merchant leaves unset and covers
+// payments without a merchant.
+type MerchantExpiryPolicy struct {
+	Merchants MerchantExpirySettings
+	Default   ExpiryPolicy
+}
+
+func (m MerchantExpiryPolicy) PolicyFor(ctx context.Context, payment *domain.Payment) (ExpiryPolicy, error) {
+	policy := m.Default
+	if payment.MerchantID == "" {
+		return policy, nil
+	}
+
+	merchant, err := m.Merchants.Find(ctx, payment.MerchantID)
+	if err != nil {
+		return ExpiryPolicy{}, err
+	}
+	if merchant.ExpiryAlertBeforeSeconds > 0 {
+		policy.AlertBefore = time.Duration(merchant.ExpiryAlertBeforeSeconds) * time.Second
+	}
+	if merchant.ExpiryActBeforeSeconds > 0 {
+		policy.ActBefore = time.Duration(merchant.ExpiryActBeforeSeconds) * time.Second
+	}
+	if merchant.ExpiryAction != "" {
+		policy.Action = domain.ExpiryAction(merchant.ExpiryAction)
+	}
+	return policy, nil
+}
+
+func (m MerchantExpiryPolicy) Horizon(ctx context.Context) (time.Duration, error) {
+	longest, err := m.Merchants.MaxExpiryWindow(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return max(m.Default.window(), longest), nil
+}
+
+type AuthorizationExpiryScheduler struct {
+	usecase  *PaymentUseCase
+	policies ExpiryPolicyProvider
+	interval time.Duration
+
+	// Heartbeat beats every time the loop wakes up, for readiness checks.
+	Heartbeat heartbeat.Heartbeat
+}
+
+// NewAuthorizationExpiryScheduler sweeps every interval for authorizations
+// within the horizon of policies.
+func NewAuthorizationExpiryScheduler(usecase *PaymentUseCase, policies ExpiryPolicyProvider, interval time.Duration) *AuthorizationExpiryScheduler {
+	return &AuthorizationExpiryScheduler{
+		usecase:  usecase,
+		policies: policies,
+		interval: interval,
+	}
+}
+
+func (s *AuthorizationExpiryScheduler) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	s.Heartbeat.Beat()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.Heartbeat.Beat()
+			if err := s.Sweep(ctx, now); err != nil {
+				logger.Error("authorization expiry sweep failed", "err", err)
+			}
+		}
+	}
+}
+
+// Sweep alerts on the authorizations entering their policy's alert window
+// and resolves those entering its act window. A payment whose policy
+// cannot be read is left for the next sweep.
+func (s *AuthorizationExpiryScheduler) Sweep(ctx context.Context, now time.Time) error {
+	horizon, err := s.policies.Horizon(ctx)
+	if err != nil {
+		return err
+	}
+
+	before := now.Add(horizon)
+	payments, err := s.usecase.Repository.List(ctx, domain.PaymentFilter{ExpiringBefore: &before})
+	if err != nil {
+		return err
+	}
+
+	for _, payment := range payments {
+		policy, err := s.policies.PolicyFor(ctx, payment)
+		if err != nil {
+			logger.Error("cannot read authorization expiry policy", "payment_id", payment.ID, "merchant_id", payment.MerchantID, "err", err)
+			continue
+		}
+
+		if payment.IsAuthorizationExpiringBefore(now.Add(policy.ActBefore)) {
+			s.act(ctx, payment, policy.Action)
+			continue
+		}
+
+		if payment.ExpiryAlertedAt == nil && payment.IsAuthorizationExpiringBefore(now.Add(policy.AlertBefore)) {
+			logger.Warn("authorization expiring soon",
+				"payment_id", payment.ID,
+				"stripe_id", payment.StripeID,
+				"expires_at", payment.AuthorizationExpiresAt,
+				"action", policy.Action,
+			)
+			payment.MarkExpiryAlerted()
+			if err := s.usecase.update(ctx, payment); err != nil {
+				logger.Error("cannot record expiry alert", "payment_id", payment.ID, "err", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *AuthorizationExpiryScheduler) act(ctx context.Context, payment *domain.Payment, action domain.ExpiryAction) {
+	id := dtos.IdentifyPaymentDto{PaymentID: payment.ID}
+	ctx = auditDomain.WithActor(ctx, "authorization_expiry")
+	ctx = domain.WithSource(ctx, domain.SourceScheduler)
+
+	var err error
+	switch action {
+	case domain.ExpiryActionCapture:
+		_, err = s.usecase.Capture(ctx, id)
+	default:
+		_, err = s.usecase.Cancel(ctx, id)
+	}
+
+	if err != nil {
+		logger.Error("cannot resolve expiring authorization", "payment_id", payment.ID, "action", action, "err", err)
+		return
+	}
+
+	logger.Info("resolved expiring authorization", "payment_id", payment.ID, "action", action)
+}
@@ -0,0 +1,136 @@
+package application_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	merchantDomain "github.com/williamkoller/payment-system/internal/merchant/domain"
+	"github.com/williamkoller/payment-system/internal/payment/application"
+	"github.com/williamkoller/payment-system/internal/payment/domain"
+	"gorm.io/gorm"
+)
+
+type fakeMerchants map[string]*merchantDomain.Merchant
+
+func (f fakeMerchants) Find(_ context.Context, id string) (*merchantDomain.Merchant, error) {
+	if m, ok := f[id]; ok {
+		return m, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f fakeMerchants) MaxExpiryWindow(context.Context) (time.Duration, error) {
+	var longest int64
+	for _, m := range f {
+		longest = max(longest, m.ExpiryAlertBeforeSeconds, m.ExpiryActBeforeSeconds)
+	}
+	return time.Duration(longest) * time.Second, nil
+}
+
+func authorized(id, merchantID string, expiresAt time.Time) *domain.Payment {
+	return &domain.Payment{
+		ID:                     id,
+		MerchantID:             merchantID,
+		Status:                 domain.StatusCompleted,
+		StripeID:               "pi_" + id,
+		AuthorizationExpiresAt: &expiresAt,
+	}
+}
+
+func TestAuthorizationExpiryScheduler_Sweep(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		policies application.ExpiryPolicyProvider
+		expires  time.Duration
+		status   domain.PaymentStatus
+		alerted  bool
+		gateway  int
+	}{
+		{
+			name:     "outside both windows",
+			policies: application.StaticExpiryPolicy{AlertBefore: 24 * time.Hour, ActBefore: 2 * time.Hour, Action: domain.ExpiryActionCancel},
+			expires:  48 * time.Hour,
+			status:   domain.StatusCompleted,
+		},
+		{
+			name:     "alert",
+			policies: application.StaticExpiryPolicy{AlertBefore: 24 * time.Hour, ActBefore: 2 * time.Hour, Action: domain.ExpiryActionCancel},
+			expires:  10 * time.Hour,
+			status:   domain.StatusCompleted,
+			alerted:  true,
+		},
+		{
+			name:     "act by canceling",
+			policies: application.StaticExpiryPolicy{AlertBefore: 24 * time.Hour, ActBefore: 2 * time.Hour, Action: domain.ExpiryActionCancel},
+			expires:  time.Hour,
+			status:   domain.StatusCanceled,
+			gateway:  1,
+		},
+		{
+			name:     "act by capturing",
+			policies: application.StaticExpiryPolicy{AlertBefore: 24 * time.Hour, ActBefore: 2 * time.Hour, Action: domain.ExpiryActionCapture},
+			expires:  time.Hour,
+			status:   domain.StatusCaptured,
+			gateway:  1,
+		},
+		{
+			name:     "act window beyond the alert window",
+			policies: application.StaticExpiryPolicy{AlertBefore: 24 * time.Hour, ActBefore: 48 * time.Hour, Action: domain.ExpiryActionCancel},
+			expires:  30 * time.Hour,
+			status:   domain.StatusCanceled,
+			gateway:  1,
+		},
+		{
+			name: "merchant policy",
+			policies: application.MerchantExpiryPolicy{
+				Merchants: fakeMerchants{"m1": {ID: "m1", ExpiryActBeforeSeconds: int64((72 * time.Hour).Seconds()), ExpiryAction: "capture"}},
+				Default:   application.ExpiryPolicy{AlertBefore: 24 * time.Hour, ActBefore: 2 * time.Hour, Action: domain.ExpiryActionCancel},
+			},
+			expires: 60 * time.Hour,
+			status:  domain.StatusCaptured,
+			gateway: 1,
+		},
+		{
+			name: "unknown merchant is left for the next sweep",
+			policies: application.MerchantExpiryPolicy{
+				Merchants: fakeMerchants{},
+				Default:   application.ExpiryPolicy{AlertBefore: 24 * time.Hour, ActBefore: 2 * time.Hour, Action: domain.ExpiryActionCancel},
+			},
+			expires: time.Hour,
+			status:  domain.StatusCompleted,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payment := authorized("pay_1", "m1", now.Add(tt.expires))
+			repo := &fakePayments{payments: map[string]*domain.Payment{payment.ID: payment}}
+			stripe := &fakeStripe{}
+			usecase := application.NewPaymentUseCase(repo, stripe)
+
+			scheduler := application.NewAuthorizationExpiryScheduler(usecase, tt.policies, time.Minute)
+			require.NoError(t, scheduler.Sweep(context.Background(), now))
+
+			assert.Equal(t, tt.status, payment.Status)
+			assert.Equal(t, tt.alerted, payment.ExpiryAlertedAt != nil)
+			assert.Equal(t, tt.gateway, stripe.calls)
+		})
+	}
+}
+
+func TestAuthorizationExpiryScheduler_Sweep_AlertsOnce(t *testing.T) {
+	payment := authorized("pay_1", "", time.Now().Add(10*time.Hour))
+	repo := &fakePayments{payments: map[string]*domain.Payment{payment.ID: payment}}
+	usecase := application.NewPaymentUseCase(repo, &fakeStripe{})
+	policy := application.StaticExpiryPolicy{AlertBefore: 24 * time.Hour, ActBefore: 2 * time.Hour, Action: domain.ExpiryActionCancel}
+	scheduler := application.NewAuthorizationExpiryScheduler(usecase, policy, time.Minute)
+
+	require.NoError(t, scheduler.Sweep(context.Background(), time.Now()))
+	require.NoError(t, scheduler.Sweep(context.Background(), time.Now()))
+	assert.Equal(t, 1, repo.updates)
+}
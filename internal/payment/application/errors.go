@@ -0,0 +1,87 @@
+package application
+
+import (
+	"context"
+	"errors"
+
+	"github.com/sony/gobreaker"
+	"github.com/stripe/stripe-go"
+	"github.com/williamkoller/payment-system/pkg/apperror"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrPaymentNotFound     = apperror.New(apperror.KindNotFound, "payment_not_found", "payment not found")
+	ErrPaymentNotAtGateway = apperror.New(apperror.KindInvalidTransition, "payment_not_at_gateway", "missing Stripe payment intent ID")
+
+	ErrAlreadyProcessed     = apperror.New(apperror.KindIdempotencyConflict, "payment_already_processed", "transaction already processed successfully")
+	ErrAlreadyFailed        = apperror.New(apperror.KindIdempotencyConflict, "payment_already_failed", "transaction already attempted and failed")
+	ErrIdempotencyConflict  = apperror.New(apperror.KindIdempotencyConflict, "idempotency_conflict", "transaction already exists")
+	ErrIdempotencyKeyReused = apperror.New(apperror.KindIdempotencyConflict, "idempotency_key_reused", "Idempotency-Key already used for a different payment")
+
+	ErrBlockedByRisk   = apperror.New(apperror.KindDeclined, "blocked_by_risk", "payment blocked by risk rules")
+	ErrBlocklisted     = apperror.New(apperror.KindDeclined, "blocklisted", "payment declined by block list")
+	ErrFxQuoteRejected = apperror.New(apperror.KindUnprocessable, "fx_quote_rejected", "fx quote rejected")
+
+	ErrGatewayDeclined    = apperror.New(apperror.KindDeclined, "card_declined", "payment declined by the gateway")
+	ErrGatewayUnavailable = apperror.New(apperror.KindGatewayUnavailable, "gateway_unavailable", "payment gateway unavailable")
+	ErrGatewayRejected    = apperror.New(apperror.KindGateway, "gateway_error", "payment gateway rejected the request")
+)
+
+// notFound maps a repository miss onto ErrPaymentNotFound and leaves other
+// failures as they are.
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrPaymentNotFound.Wrap(err)
+	}
+	return err
+}
+
+// failureCode is the error code a status change records for err: the
+// gateway's decline or error code when Stripe refused the call, otherwise
+// the API error code.
+func failureCode(err error) string {
+	var stripeErr *stripe.Error
+	if errors.As(err, &stripeErr) {
+		if stripeErr.DeclineCode != "" {
+			return string(stripeErr.DeclineCode)
+		}
+		if stripeErr.Code != "" {
+			return string(stripeErr.Code)
+		}
+	}
+	return apperror.As(err).Code
+}
+
+// failureMessage is the message a failed attempt records for err: Stripe's
+// own message when it refused the call, otherwise the API error message.
+func failureMessage(err error) string {
+	var stripeErr *stripe.Error
+	if errors.As(err, &stripeErr) && stripeErr.Msg != "" {
+		return stripeErr.Msg
+	}
+	return apperror.As(err).Message
+}
+
+// gatewayError classifies a Stripe failure: card errors are declines
+// carrying the issuer's decline code, an open breaker, timeouts and 5xx
+// mean the gateway is unavailable, and anything else is a rejected request.
+func gatewayError(message string, err error) error {
+	var stripeErr *stripe.Error
+	switch {
+	case errors.As(err, &stripeErr) && stripeErr.Type == stripe.ErrorTypeCard:
+		declineCode := string(stripeErr.DeclineCode)
+		if declineCode == "" {
+			declineCode = string(stripeErr.Code)
+		}
+		return ErrGatewayDeclined.WithMessage(message).WithDecline(declineCode).Wrap(err)
+	case errors.Is(err, gobreaker.ErrOpenState),
+		errors.Is(err, gobreaker.ErrTooManyRequests),
+		errors.Is(err, context.DeadlineExceeded),
+		errors.As(err, &stripeErr) && stripeErr.HTTPStatusCode >= 500,
+		!errors.As(err, &stripeErr):
+		return ErrGatewayUnavailable.WithMessage(message).Wrap(err)
+	default:
+		return ErrGatewayRejected.WithMessage(message).Wrap(err)
+	}
+}
@@ -0,0 +1,77 @@
+package application
+
+import (
+	"context"
+
+	auditDomain "github.com/williamkoller/payment-system/internal/audit/domain"
+	"github.com/williamkoller/payment-system/internal/payment/domain"
+	"github.com/williamkoller/payment-system/internal/payment/dtos"
+	"github.com/williamkoller/payment-system/pkg/tracing"
+)
+
+// ApproveReview releases a payment held by the risk engine and sends it to
+// the gateway. The review is attributed to the actor of ctx.
+func (u *PaymentUseCase) ApproveReview(ctx context.Context, i dtos.IdentifyPaymentDto) (_ *domain.Payment, err error) {
+	ctx, span := tracing.Start(ctx, "PaymentUseCase.ApproveReview", paymentAttributes(i.PaymentID))
+	defer func() { tracing.End(span, err) }()
+
+	payment, err := u.findForReview(ctx, i)
+	if payment != nil {
+		record := u.audit(ctx, "payment.review.approve", payment, paymentSnapshot(payment), nil)
+		defer func() { record(err) }()
+	}
+	if err != nil {
+		return payment, err
+	}
+
+	payment.Pending()
+	payment.Explain("review approved by "+auditDomain.Actor(ctx), "")
+	if err := u.update(ctx, payment); err != nil {
+		return payment, err
+	}
+
+	return u.authorize(ctx, payment, false)
+}
+
+// RejectReview cancels a payment held by the risk engine without ever
+// contacting the gateway.
+func (u *PaymentUseCase) RejectReview(ctx context.Context, i dtos.IdentifyPaymentDto) (_ *domain.Payment, err error) {
+	ctx, span := tracing.Start(ctx, "PaymentUseCase.RejectReview", paymentAttributes(i.PaymentID))
+	defer func() { tracing.End(span, err) }()
+
+	payment, err := u.findForReview(ctx, i)
+	if payment != nil {
+		record := u.audit(ctx, "payment.review.reject", payment, paymentSnapshot(payment), nil)
+		defer func() { record(err) }()
+	}
+	if err != nil {
+		return payment, err
+	}
+
+	payment.Cancel()
+	payment.Explain("review rejected by "+auditDomain.Actor(ctx), "")
+	if err := u.update(ctx, payment); err != nil {
+		return payment, err
+	}
+
+	return payment, nil
+}
+
+func (u *PaymentUseCase) findForReview(ctx context.Context, i dtos.IdentifyPaymentDto) (*domain.Payment, error) {
+	payment, err := u.Repository.FindByID(ctx, i.PaymentID)
+	if err != nil {
+		return nil, notFound(err)
+	}
+
+	if err := payment.CanReview(); err != nil {
+		return payment, err
+	}
+
+	if u.Risk != nil {
+		if err := u.Risk.RecordReview(ctx, payment.ID, auditDomain.Actor(ctx)); err != nil {
+			return payment, err
+		}
+	}
+
+	return payment, nil
+}
@@ -0,0 +1,239 @@
+package application
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/williamkoller/payment-system/internal/metrics"
+	"github.com/williamkoller/payment-system/internal/payment/domain"
+	"github.com/williamkoller/payment-system/internal/payment/dtos"
+	"github.com/williamkoller/payment-system/pkg/apperror"
+	"github.com/williamkoller/payment-system/pkg/auth"
+	"github.com/williamkoller/payment-system/pkg/tracing"
+)
+
+var ErrTooManyStreams = apperror.New(apperror.KindRateLimited, "too_many_streams", "too many event streams open")
+
+// DefaultHeartbeatInterval is how often streams send a heartbeat when the
+// broker does not say otherwise.
+const DefaultHeartbeatInterval = 15 * time.Second
+
+// subscriptionBuffer is how many changes a stream may fall behind by. A
+// stream that falls further behind is closed; its client reconnects and
+// resumes from the timeline.
+const subscriptionBuffer = 16
+
+// StatusBroker fans persisted status changes out to the event streams
+// watching each payment. It only sees the changes made by this process, so
+// streams also re-read the timeline at every heartbeat to pick up changes
+// made by other replicas.
+type StatusBroker struct {
+	// HeartbeatInterval is how often idle streams send a heartbeat and
+	// re-read the timeline.
+	HeartbeatInterval time.Duration
+
+	maxStreams            int
+	maxStreamsPerMerchant int
+
+	mu          sync.Mutex
+	subscribers map[string]map[*StatusSubscription]struct{}
+	streams     int
+	perMerchant map[string]int
+	closed      bool
+}
+
+// NewStatusBroker caps the streams open at once, overall and per merchant;
+// 0 removes a cap.
+func NewStatusBroker(maxStreams, maxStreamsPerMerchant int) *StatusBroker {
+	return &StatusBroker{
+		HeartbeatInterval:     DefaultHeartbeatInterval,
+		maxStreams:            maxStreams,
+		maxStreamsPerMerchant: maxStreamsPerMerchant,
+		subscribers:           make(map[string]map[*StatusSubscription]struct{}),
+		perMerchant:           make(map[string]int),
+	}
+}
+
+// Heartbeat returns HeartbeatInterval, or the default on a nil broker.
+func (b *StatusBroker) Heartbeat() time.Duration {
+	if b == nil || b.HeartbeatInterval <= 0 {
+		return DefaultHeartbeatInterval
+	}
+	return b.HeartbeatInterval
+}
+
+// StatusSubscription receives the status changes of one payment on C. C is
+// closed when the subscription is closed, falls behind or the broker shuts
+// down.
+type StatusSubscription struct {
+	C <-chan *domain.StatusChange
+
+	c          chan *domain.StatusChange
+	broker     *StatusBroker
+	paymentID  string
+	merchantID string
+}
+
+// Subscribe opens a subscription to the changes of paymentID on behalf of
+// merchantID.
+func (b *StatusBroker) Subscribe(paymentID, merchantID string) (*StatusSubscription, error) {
+	c := make(chan *domain.StatusChange, subscriptionBuffer)
+	sub := &StatusSubscription{C: c, c: c, broker: b, paymentID: paymentID, merchantID: merchantID}
+	if b == nil {
+		return sub, nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed ||
+		(b.maxStreams > 0 && b.streams >= b.maxStreams) ||
+		(b.maxStreamsPerMerchant > 0 && merchantID != "" && b.perMerchant[merchantID] >= b.maxStreamsPerMerchant) {
+		return nil, ErrTooManyStreams
+	}
+
+	if b.subscribers[paymentID] == nil {
+		b.subscribers[paymentID] = make(map[*StatusSubscription]struct{})
+	}
+	b.subscribers[paymentID][sub] = struct{}{}
+	b.streams++
+	if merchantID != "" {
+		b.perMerchant[merchantID]++
+	}
+	return sub, nil
+}
+
+// Publish hands changes to the subscribers of their payments without
+// waiting for them. It does nothing on a nil broker.
+func (b *StatusBroker) Publish(changes ...*domain.StatusChange) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, change := range changes {
+		for sub := range b.subscribers[change.PaymentID] {
+			select {
+			case sub.c <- change:
+			default:
+				b.remove(sub)
+			}
+		}
+	}
+}
+
+// Close ends every subscription and refuses new ones, so open streams let
+// the server shut down.
+func (b *StatusBroker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for _, subs := range b.subscribers {
+		for sub := range subs {
+			b.remove(sub)
+		}
+	}
+}
+
+// remove must be called with b.mu held.
+func (b *StatusBroker) remove(sub *StatusSubscription) {
+	subs := b.subscribers[sub.paymentID]
+	if _, ok := subs[sub]; !ok {
+		return
+	}
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(b.subscribers, sub.paymentID)
+	}
+	b.streams--
+	if sub.merchantID != "" {
+		if b.perMerchant[sub.merchantID]--; b.perMerchant[sub.merchantID] == 0 {
+			delete(b.perMerchant, sub.merchantID)
+		}
+	}
+	close(sub.c)
+}
+
+func (s *StatusSubscription) Close() {
+	if s.broker == nil {
+		return
+	}
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	s.broker.remove(s)
+}
+
+// WatchStatus subscribes to the payment's status changes and returns its
+// timeline after lastEventID, the id of the last change the client saw, or
+// the whole timeline when lastEventID is empty or unknown. Changes
+// persisted while the timeline is read may arrive both ways.
+func (u *PaymentUseCase) WatchStatus(ctx context.Context, i dtos.IdentifyPaymentDto, lastEventID string) (_ []*domain.StatusChange, _ *StatusSubscription, err error) {
+	ctx, span := tracing.Start(ctx, "PaymentUseCase.WatchStatus", paymentAttributes(i.PaymentID))
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := u.Repository.FindByID(ctx, i.PaymentID); err != nil {
+		return nil, nil, notFound(err)
+	}
+
+	sub, err := u.Events.Subscribe(i.PaymentID, auth.MerchantID(ctx))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	changes, err := u.Repository.FindStatusChanges(ctx, i.PaymentID)
+	if err != nil {
+		sub.Close()
+		return nil, nil, err
+	}
+	return ChangesAfter(changes, lastEventID), sub, nil
+}
+
+// ChangesAfter returns the changes that follow the one with id lastID, or
+// all of them when there is no such change.
+func ChangesAfter(changes []*domain.StatusChange, lastID string) []*domain.StatusChange {
+	for i, change := range changes {
+		if change.ID == lastID {
+			return changes[i+1:]
+		}
+	}
+	return changes
+}
+
+// save and update persist the payment, then publish the status changes
+// persisted with it, which only get their ids on the way.
+func (u *PaymentUseCase) save(ctx context.Context, payment *domain.Payment) error {
+	changes := payment.PendingStatusChanges()
+	if _, err := u.Repository.Save(ctx, payment); err != nil {
+		return err
+	}
+	metrics.ObservePaymentTransitions(payment, changes)
+	u.Events.Publish(changes...)
+	return nil
+}
+
+func (u *PaymentUseCase) update(ctx context.Context, payment *domain.Payment) error {
+	return UpdateAndPublish(ctx, u.Repository, u.Events, payment)
+}
+
+// PaymentUpdater is the part of a payment repository UpdateAndPublish
+// writes through.
+type PaymentUpdater interface {
+	Update(ctx context.Context, p *domain.Payment) error
+}
+
+// UpdateAndPublish updates payment in repo, counts its status changes and
+// publishes them to events, which may be nil. Writers of payments outside the use
+// case call it so streams see their changes too.
+func UpdateAndPublish(ctx context.Context, repo PaymentUpdater, events *StatusBroker, payment *domain.Payment) error {
+	changes := payment.PendingStatusChanges()
+	if err := repo.Update(ctx, payment); err != nil {
+		return err
+	}
+	metrics.ObservePaymentTransitions(payment, changes)
+	events.Publish(changes...)
+	return nil
+}
@@ -0,0 +1,92 @@
+package application_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/williamkoller/payment-system/internal/payment/application"
+	"github.com/williamkoller/payment-system/internal/payment/domain"
+	"github.com/williamkoller/payment-system/internal/payment/dtos"
+)
+
+func TestStatusBroker_Caps(t *testing.T) {
+	broker := application.NewStatusBroker(3, 2)
+
+	_, err := broker.Subscribe("pay_1", "m1")
+	require.NoError(t, err)
+	second, err := broker.Subscribe("pay_2", "m1")
+	require.NoError(t, err)
+
+	_, err = broker.Subscribe("pay_3", "m1")
+	assert.ErrorIs(t, err, application.ErrTooManyStreams, "per merchant")
+
+	_, err = broker.Subscribe("pay_3", "m2")
+	require.NoError(t, err)
+	_, err = broker.Subscribe("pay_4", "m3")
+	assert.ErrorIs(t, err, application.ErrTooManyStreams, "overall")
+
+	second.Close()
+	_, err = broker.Subscribe("pay_3", "m1")
+	assert.NoError(t, err, "closing a stream frees its slot")
+}
+
+func TestStatusBroker_ClosesSlowAndShutdownStreams(t *testing.T) {
+	broker := application.NewStatusBroker(0, 0)
+	slow, err := broker.Subscribe("pay_1", "")
+	require.NoError(t, err)
+	other, err := broker.Subscribe("pay_2", "")
+	require.NoError(t, err)
+
+	for i := 0; i < 100; i++ {
+		broker.Publish(&domain.StatusChange{PaymentID: "pay_1"})
+	}
+
+	received := 0
+	for range slow.C {
+		received++
+	}
+	assert.Less(t, received, 100, "a stream that falls behind is closed")
+	assert.Empty(t, other.C, "other payments' streams get nothing")
+
+	broker.Close()
+	_, open := <-other.C
+	assert.False(t, open)
+	_, err = broker.Subscribe("pay_2", "")
+	assert.ErrorIs(t, err, application.ErrTooManyStreams)
+}
+
+func TestPaymentUseCase_WatchStatus(t *testing.T) {
+	payment := &domain.Payment{ID: "pay_1", StripeID: "pi_1", Amount: 1000, Currency: "USD", Status: domain.StatusReview}
+	repo := &fakePayments{payments: map[string]*domain.Payment{payment.ID: payment}}
+	usecase := application.NewPaymentUseCase(repo, &fakeStripe{})
+	usecase.Events = application.NewStatusBroker(0, 0)
+	ctx := context.Background()
+	id := dtos.IdentifyPaymentDto{PaymentID: payment.ID}
+
+	_, err := usecase.RejectReview(ctx, id)
+	require.NoError(t, err)
+
+	backlog, sub, err := usecase.WatchStatus(ctx, id, "")
+	require.NoError(t, err)
+	defer sub.Close()
+	require.Len(t, backlog, 1)
+	assert.Equal(t, domain.StatusCanceled, backlog[0].ToStatus)
+
+	resumed, resumedSub, err := usecase.WatchStatus(ctx, id, backlog[0].ID)
+	require.NoError(t, err)
+	resumedSub.Close()
+	assert.Empty(t, resumed, "nothing happened after the last event")
+
+	payment.Status = domain.StatusCaptured
+	_, err = usecase.Refund(ctx, id, dtos.PaymentRefundDto{Amount: 1000})
+	require.NoError(t, err)
+
+	change := <-sub.C
+	assert.Equal(t, domain.StatusRefund, change.ToStatus)
+	assert.NotEmpty(t, change.ID, "changes are published once persisted")
+
+	_, _, err = usecase.WatchStatus(ctx, dtos.IdentifyPaymentDto{PaymentID: "missing"}, "")
+	assert.ErrorIs(t, err, application.ErrPaymentNotFound)
+}
@@ -5,28 +5,95 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/stripe/stripe-go"
+	fxDomain "github.com/williamkoller/payment-system/internal/fx/domain"
+	listsDomain "github.com/williamkoller/payment-system/internal/lists/domain"
+	merchantDomain "github.com/williamkoller/payment-system/internal/merchant/domain"
 	"github.com/williamkoller/payment-system/internal/payment/domain"
 	"github.com/williamkoller/payment-system/internal/payment/dtos"
 	"github.com/williamkoller/payment-system/internal/payment/infra"
+	riskDomain "github.com/williamkoller/payment-system/internal/risk/domain"
+	"github.com/williamkoller/payment-system/pkg/auth"
+	"github.com/williamkoller/payment-system/pkg/logger"
+	"github.com/williamkoller/payment-system/pkg/money"
+	"github.com/williamkoller/payment-system/pkg/pii"
+	"github.com/williamkoller/payment-system/pkg/tracing"
 	"github.com/williamkoller/payment-system/pkg/ulid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"gorm.io/gorm"
 )
 
 type PaymentRepository interface {
-	Save(payment *domain.Payment) (*domain.Payment, error)
-	FindByID(id string) (*domain.Payment, error)
-	FindAll() ([]*domain.Payment, error)
-	Remove(id string) error
-	Update(payment *domain.Payment) error
-	FindByStripeID(stripeID string) (*domain.Payment, error)
-	FindByIdempotencyKey(idempotencyKey string) (*domain.Payment, error)
+	Save(ctx context.Context, payment *domain.Payment) (*domain.Payment, error)
+	FindByID(ctx context.Context, id string) (*domain.Payment, error)
+	FindAll(ctx context.Context) ([]*domain.Payment, error)
+	Remove(ctx context.Context, id string) error
+	Update(ctx context.Context, payment *domain.Payment) error
+	FindByStripeID(ctx context.Context, stripeID string) (*domain.Payment, error)
+	FindByIdempotencyKey(ctx context.Context, idempotencyKey string) (*domain.Payment, error)
+	List(ctx context.Context, filter domain.PaymentFilter) ([]*domain.Payment, error)
+	// Each is List for result sets too large to hold in memory.
+	Each(ctx context.Context, filter domain.PaymentFilter, fn func(*domain.Payment) error) error
+	FindStatusChanges(ctx context.Context, paymentID string) ([]*domain.StatusChange, error)
+	SaveFailedAttempt(ctx context.Context, attempt *domain.FailedAttempt) error
+	FindFailedAttempts(ctx context.Context, paymentID string) ([]*domain.FailedAttempt, error)
+}
+
+// SettlementConverter converts a presentment amount into the merchant's
+// settlement currency, optionally at the rate locked by an FX quote.
+type SettlementConverter interface {
+	Convert(ctx context.Context, presentment money.Money, quoteID string) (fxDomain.Conversion, error)
+}
+
+// RiskAssessor scores a payment attempt before it is sent to the gateway
+// and stores the outcome against the payment.
+type RiskAssessor interface {
+	Assess(ctx context.Context, paymentID string, subject riskDomain.Subject) (*riskDomain.RiskAssessment, error)
+	RecordReview(ctx context.Context, paymentID, reviewer string) error
+}
+
+// ListChecker looks a payment attempt up in the block and allow lists.
+type ListChecker interface {
+	Check(subject listsDomain.Subject) *listsDomain.Match
+}
+
+// MerchantDirectory looks up the merchant a payment is taken for.
+type MerchantDirectory interface {
+	Find(ctx context.Context, id string) (*merchantDomain.Merchant, error)
+}
+
+// GatewayResolver returns the Stripe client of a gateway account: "" for
+// the platform account, otherwise the merchant's own.
+type GatewayResolver interface {
+	ForAccount(ctx context.Context, account string) (infra.StripeClient, error)
 }
 
 type PaymentUseCase struct {
-	Repository   PaymentRepository
+	Repository PaymentRepository
+	// StripeClient is the platform account's client, used when Gateways is
+	// not set.
 	StripeClient infra.StripeClient
+	// Gateways is optional; without it every payment goes through
+	// StripeClient.
+	Gateways GatewayResolver
+	// Merchants is optional; without it merchant limits and own Stripe
+	// accounts do not apply.
+	Merchants MerchantDirectory
+	// Settlement is optional; without it payments settle in their own
+	// currency.
+	Settlement SettlementConverter
+	// Risk is optional; without it every payment is allowed.
+	Risk RiskAssessor
+	// Lists is optional; without it no block or allow lists apply.
+	Lists ListChecker
+	// Audit is optional; without it operations are not audited.
+	Audit Auditor
+	// Events is optional; without it event streams only see changes when
+	// they re-read the timeline.
+	Events *StatusBroker
 }
 
 type PaymentInput struct {
@@ -34,33 +101,49 @@ type PaymentInput struct {
 	Currency      string
 	Email         string
 	PaymentMethod string
+	FxQuoteID     string
+	IP            string
+	// IdempotencyKey is the client's Idempotency-Key. Without one, the key
+	// is derived from the email, payment method, currency and amount.
+	IdempotencyKey string
 }
 
 func NewPaymentUseCase(Repository PaymentRepository, StripeClient infra.StripeClient) *PaymentUseCase {
 	return &PaymentUseCase{Repository: Repository, StripeClient: StripeClient}
 }
 
-func (u *PaymentUseCase) CreatePayment(input PaymentInput) (*domain.Payment, error) {
-	ctx := context.Background()
-	idempotencyKeyReq := fmt.Sprintf("%s_%s_%s_%v", input.Email, input.PaymentMethod, input.Currency, input.Amount)
+func (u *PaymentUseCase) CreatePayment(ctx context.Context, input PaymentInput) (_ *domain.Payment, err error) {
+	ctx, span := tracing.Start(ctx, "PaymentUseCase.CreatePayment")
+	defer func() { tracing.End(span, err) }()
+
+	// The email goes in as its blind index, so the key, which is stored and
+	// logged, never carries it in plain text.
+	idempotencyKeyReq := input.IdempotencyKey
+	if idempotencyKeyReq == "" {
+		idempotencyKeyReq = fmt.Sprintf("%s_%s_%s_%v", pii.Default().BlindIndex(input.Email), input.PaymentMethod, input.Currency, input.Amount)
+	}
 
-	existingPayment, err := u.Repository.FindByIdempotencyKey(idempotencyKeyReq)
+	existingPayment, err := u.Repository.FindByIdempotencyKey(ctx, idempotencyKeyReq)
 	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, err
 	}
 
 	if existingPayment != nil {
+		if !existingPayment.SameCharge(input.Amount, input.Currency, input.Email, input.PaymentMethod) {
+			return nil, ErrIdempotencyKeyReused
+		}
 		switch existingPayment.Status {
 		case domain.StatusCompleted:
-			return existingPayment, fmt.Errorf("transaction already processed successfully")
+			return existingPayment, ErrAlreadyProcessed
 		case domain.StatusFailed:
-			return existingPayment, fmt.Errorf("transaction already attempted and failed")
+			return existingPayment, ErrAlreadyFailed
 		default:
-			return existingPayment, fmt.Errorf("transaction already exists with status: %s", existingPayment.Status)
+			return existingPayment, ErrIdempotencyConflict.WithMessage(fmt.Sprintf("transaction already exists with status: %s", existingPayment.Status))
 		}
 	}
 
 	id := ulid.NewULID()
+	span.SetAttributes(attribute.String("payment.id", id))
 	payment, err := domain.NewPayment(id, input.Amount, strings.ToUpper(input.Currency), input.Email, input.PaymentMethod)
 	if err != nil {
 		return nil, err
@@ -68,137 +151,421 @@ func (u *PaymentUseCase) CreatePayment(input PaymentInput) (*domain.Payment, err
 
 	payment.SetIdempotencyKey(idempotencyKeyReq)
 
-	if _, err := u.Repository.Save(payment); err != nil {
+	record := u.audit(ctx, "payment.create", payment, nil, nil)
+	defer func() { record(err) }()
+
+	if err := u.applySettlement(ctx, payment, input.FxQuoteID); err != nil {
 		return nil, err
 	}
 
-	intent, err := u.StripeClient.CreatePaymentIntent(ctx, input.Amount, input.Currency, input.Email, input.PaymentMethod)
+	if err := u.assignMerchant(ctx, payment); err != nil {
+		return nil, err
+	}
+
+	card, err := u.card(ctx, payment)
 	if err != nil {
+		return nil, err
+	}
+
+	match := u.checkLists(payment, input.IP, card)
+	if match != nil && match.Kind == listsDomain.KindBlock {
+		payment.Fail()
+		payment.Explain("matched a block list entry", match.DeclineCode())
+		if err := u.save(ctx, payment); err != nil {
+			return nil, err
+		}
+		return payment, ErrBlocklisted.WithDecline(match.DeclineCode())
+	}
+
+	decision := riskDomain.DecisionAllow
+	if match == nil {
+		decision, err = u.assessRisk(ctx, payment, input.IP, card)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch decision {
+	case riskDomain.DecisionBlock:
 		payment.Fail()
-		_ = u.Repository.Update(payment)
-		return payment, fmt.Errorf("stripe payment failed: %w", err)
+		payment.Explain("blocked by risk rules", ErrBlockedByRisk.Code)
+	case riskDomain.DecisionReview:
+		payment.Hold()
+		payment.Explain("held for review by risk rules", "")
+	}
+
+	if err := u.save(ctx, payment); err != nil {
+		return nil, err
+	}
+
+	switch decision {
+	case riskDomain.DecisionBlock:
+		return payment, ErrBlockedByRisk
+	case riskDomain.DecisionReview:
+		return payment, nil
+	}
+
+	return u.authorize(ctx, payment, decision == riskDomain.DecisionRequire3DS)
+}
+
+// authorize creates and confirms the gateway payment intent for a payment
+// that has already been persisted.
+func (u *PaymentUseCase) authorize(ctx context.Context, payment *domain.Payment, requireThreeDS bool) (_ *domain.Payment, err error) {
+	ctx, span := tracing.Start(ctx, "PaymentUseCase.authorize", paymentAttributes(payment.ID))
+	defer func() { tracing.End(span, err) }()
+
+	gateway, err := u.gateway(ctx, payment)
+	if err != nil {
+		return payment, err
+	}
+
+	intent, err := gateway.CreatePaymentIntent(ctx, infra.PaymentIntentInput{
+		PaymentID:           payment.ID,
+		Amount:              payment.Amount,
+		Currency:            strings.ToLower(payment.Currency),
+		Email:               payment.Email,
+		PaymentMethod:       payment.PaymentMethod,
+		RequestThreeDSecure: requireThreeDS,
+	})
+	if err != nil {
+		payment.Fail()
+		payment.Explain("stripe payment failed", failureCode(err))
+		_ = u.update(ctx, payment)
+		return payment, gatewayError("stripe payment failed", err)
 	}
 
-	payment.Complete()
 	payment.SetStripeID(intent.ID)
 
-	if err := u.Repository.Update(payment); err != nil {
+	// A 3D Secure challenge leaves the intent waiting for the customer; the
+	// payment stays PENDING until the gateway reports the authorization.
+	if intent.Status == stripe.PaymentIntentStatusRequiresAction {
+		payment.Pending()
+		payment.Explain("awaiting 3D Secure authentication", "")
+	} else {
+		payment.Complete()
+		payment.SetAuthorizationExpiresAt(authorizedAt(intent))
+	}
+
+	if err := u.update(ctx, payment); err != nil {
 		return payment, err
 	}
 
 	return payment, nil
 }
 
-func (u *PaymentUseCase) FindPaymentByID(i dtos.IdentifyPaymentDto) (*domain.Payment, error) {
-	paymentFound, err := u.Repository.FindByID(i.PaymentID)
+// assignMerchant ties the payment to the merchant the request acts for,
+// enforcing its status and limits and choosing the Stripe account to use.
+func (u *PaymentUseCase) assignMerchant(ctx context.Context, payment *domain.Payment) error {
+	merchantID := auth.MerchantID(ctx)
+	if merchantID == "" || u.Merchants == nil {
+		payment.AssignMerchant(merchantID, "")
+		return nil
+	}
+
+	merchant, err := u.Merchants.Find(ctx, merchantID)
+	if err != nil {
+		return err
+	}
+	if err := merchant.CheckPayment(payment.SettlementAmount); err != nil {
+		return err
+	}
+
+	account := ""
+	if merchant.HasOwnStripeAccount() {
+		account = merchant.ID
+	}
+	payment.AssignMerchant(merchant.ID, account)
+	return nil
+}
+
+// gateway returns the client of the account the payment was authorized on.
+func (u *PaymentUseCase) gateway(ctx context.Context, payment *domain.Payment) (infra.StripeClient, error) {
+	if u.Gateways == nil {
+		return u.StripeClient, nil
+	}
+
+	client, err := u.Gateways.ForAccount(ctx, payment.GatewayAccount)
+	if err != nil {
+		return nil, ErrGatewayUnavailable.WithMessage("cannot resolve the payment's Stripe account").Wrap(err)
+	}
+	return client, nil
+}
+
+// card reads the card being charged from the gateway, so the lists and risk
+// rules never act on card details the client could make up. It is only
+// looked up when something checks it.
+func (u *PaymentUseCase) card(ctx context.Context, payment *domain.Payment) (infra.Card, error) {
+	if u.Lists == nil && u.Risk == nil {
+		return infra.Card{}, nil
+	}
+
+	gateway, err := u.gateway(ctx, payment)
+	if err != nil {
+		return infra.Card{}, err
+	}
+
+	card, err := gateway.Card(ctx)
+	if err != nil {
+		return infra.Card{}, gatewayError("cannot read the card from stripe", err)
+	}
+	return card, nil
+}
+
+func (u *PaymentUseCase) checkLists(payment *domain.Payment, ip string, card infra.Card) *listsDomain.Match {
+	if u.Lists == nil {
+		return nil
+	}
+
+	return u.Lists.Check(listsDomain.Subject{
+		Email:           payment.Email,
+		IP:              ip,
+		CardFingerprint: card.Fingerprint,
+		CardBin:         card.BIN,
+	})
+}
+
+func (u *PaymentUseCase) assessRisk(ctx context.Context, payment *domain.Payment, ip string, card infra.Card) (riskDomain.Decision, error) {
+	if u.Risk == nil {
+		return riskDomain.DecisionAllow, nil
+	}
+
+	assessment, err := u.Risk.Assess(ctx, payment.ID, riskDomain.Subject{
+		Email:           payment.Email,
+		IP:              ip,
+		CardFingerprint: card.Fingerprint,
+		Country:         card.Country,
+		Amount:          payment.Amount,
+		Currency:        payment.Currency,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return assessment.Decision, nil
+}
+
+func (u *PaymentUseCase) FindPaymentByID(ctx context.Context, i dtos.IdentifyPaymentDto) (_ *domain.Payment, err error) {
+	ctx, span := tracing.Start(ctx, "PaymentUseCase.FindPaymentByID", paymentAttributes(i.PaymentID))
+	defer func() { tracing.End(span, err) }()
+
+	paymentFound, err := u.Repository.FindByID(ctx, i.PaymentID)
 	if err != nil {
-		return nil, fmt.Errorf("payment not found: %w", err)
+		return nil, notFound(err)
 	}
 	if paymentFound == nil {
-		return nil, errors.New("payment not found")
+		return nil, ErrPaymentNotFound
 	}
 	return paymentFound, nil
 }
 
-func (u *PaymentUseCase) Capture(ctx context.Context, i dtos.IdentifyPaymentDto) (*domain.Payment, error) {
-	payment, err := u.Repository.FindByID(i.PaymentID)
+func (u *PaymentUseCase) ListPayments(ctx context.Context, l dtos.ListPaymentsDto) (_ []*domain.Payment, err error) {
+	ctx, span := tracing.Start(ctx, "PaymentUseCase.ListPayments")
+	defer func() { tracing.End(span, err) }()
+
+	return u.Repository.List(ctx, listFilter(l))
+}
+
+// ExportPayments calls fn with each payment ListPayments would return, in
+// the same order, without holding them all in memory.
+func (u *PaymentUseCase) ExportPayments(ctx context.Context, l dtos.ListPaymentsDto, fn func(*domain.Payment) error) (err error) {
+	ctx, span := tracing.Start(ctx, "PaymentUseCase.ExportPayments")
+	defer func() { tracing.End(span, err) }()
+
+	return u.Repository.Each(ctx, listFilter(l), fn)
+}
+
+func listFilter(l dtos.ListPaymentsDto) domain.PaymentFilter {
+	filter := domain.PaymentFilter{Limit: l.Limit, StartingAfter: l.StartingAfter}
+	if !l.ExpiringBefore.IsZero() {
+		filter.ExpiringBefore = &l.ExpiringBefore
+	}
+	return filter
+}
+
+// Timeline returns every status change of a payment, oldest first.
+func (u *PaymentUseCase) Timeline(ctx context.Context, i dtos.IdentifyPaymentDto) (_ []*domain.StatusChange, err error) {
+	ctx, span := tracing.Start(ctx, "PaymentUseCase.Timeline", paymentAttributes(i.PaymentID))
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := u.Repository.FindByID(ctx, i.PaymentID); err != nil {
+		return nil, notFound(err)
+	}
+	return u.Repository.FindStatusChanges(ctx, i.PaymentID)
+}
+
+func (u *PaymentUseCase) Capture(ctx context.Context, i dtos.IdentifyPaymentDto) (_ *domain.Payment, err error) {
+	ctx, span := tracing.Start(ctx, "PaymentUseCase.Capture", paymentAttributes(i.PaymentID))
+	defer func() { tracing.End(span, err) }()
+
+	payment, err := u.Repository.FindByID(ctx, i.PaymentID)
 	if err != nil {
-		return nil, fmt.Errorf("payment not found: %w", err)
+		return nil, notFound(err)
 	}
 
+	record := u.audit(ctx, "payment.capture", payment, paymentSnapshot(payment), nil)
+	defer func() { record(err) }()
+	defer func() { u.recordFailedAttempt(ctx, payment, domain.OperationCapture, err) }()
+
 	if payment.StripeID == "" {
-		return nil, errors.New("missing Stripe payment intent ID")
+		return nil, ErrPaymentNotAtGateway
 	}
 
-	err = u.StripeClient.Capture(ctx, payment.StripeID)
+	gateway, err := u.gateway(ctx, payment)
 	if err != nil {
-		payment.Fail()
-		_ = u.Repository.Update(payment)
-		return payment, fmt.Errorf("stripe capture failed: %w", err)
+		return payment, err
+	}
+
+	err = gateway.Capture(ctx, payment.StripeID)
+	if err != nil {
+		return payment, gatewayError("stripe capture failed", err)
 	}
 
 	payment.Capture()
-	if err := u.Repository.Update(payment); err != nil {
+	if err := u.update(ctx, payment); err != nil {
 		return payment, err
 	}
 
 	return payment, nil
 }
 
-func (u *PaymentUseCase) Cancel(ctx context.Context, i dtos.IdentifyPaymentDto) (*domain.Payment, error) {
-	payment, err := u.Repository.FindByID(i.PaymentID)
+func (u *PaymentUseCase) Cancel(ctx context.Context, i dtos.IdentifyPaymentDto) (_ *domain.Payment, err error) {
+	ctx, span := tracing.Start(ctx, "PaymentUseCase.Cancel", paymentAttributes(i.PaymentID))
+	defer func() { tracing.End(span, err) }()
+
+	payment, err := u.Repository.FindByID(ctx, i.PaymentID)
 	if err != nil {
-		return nil, fmt.Errorf("payment not found: %w", err)
+		return nil, notFound(err)
 	}
 
+	record := u.audit(ctx, "payment.cancel", payment, paymentSnapshot(payment), nil)
+	defer func() { record(err) }()
+	defer func() { u.recordFailedAttempt(ctx, payment, domain.OperationCancel, err) }()
+
 	if payment.StripeID == "" {
-		return nil, errors.New("missing Stripe payment intent ID")
+		return nil, ErrPaymentNotAtGateway
 	}
 
 	if err := payment.CanCancel(); err != nil {
-		payment.Fail()
-		_ = u.Repository.Update(payment)
-		return payment, fmt.Errorf("stripe cancel failed: %w", err)
+		return payment, err
 	}
 
-	err = u.StripeClient.Cancel(ctx, payment.StripeID)
+	gateway, err := u.gateway(ctx, payment)
 	if err != nil {
+		return payment, err
+	}
+
+	err = gateway.Cancel(ctx, payment.StripeID)
+	if err != nil {
+		// Stripe refuses to cancel an intent that was captured behind our
+		// back; the payment catches up with what the gateway says.
 		var stripeErr *stripe.Error
-		if errors.As(err, &stripeErr) {
-			if stripeErr.Code == stripe.ErrorCodePaymentIntentUnexpectedState {
-				payment.Capture()
-				_ = u.Repository.Update(payment)
-				return payment, fmt.Errorf("cannot cancel payment: already captured on Stripe")
-			}
-
-			if stripeErr.HTTPStatusCode >= 400 && stripeErr.HTTPStatusCode < 500 {
-				return payment, fmt.Errorf("stripe client error: %s (%s)", stripeErr.Msg, stripeErr.Code)
-			}
-
-			if stripeErr.HTTPStatusCode >= 500 {
-				return payment, fmt.Errorf("stripe server error: %s", stripeErr.Msg)
-			}
+		if errors.As(err, &stripeErr) && stripeErr.Code == stripe.ErrorCodePaymentIntentUnexpectedState {
+			payment.Capture()
+			payment.Explain("cancel found the payment already captured on Stripe", failureCode(err))
+			_ = u.update(ctx, payment)
+			return payment, domain.ErrAlreadyCaptured.WithMessage("cannot cancel payment: already captured on Stripe").Wrap(err)
 		}
 
-		payment.Fail()
-		_ = u.Repository.Update(payment)
-		return payment, fmt.Errorf("stripe cancel failed: %w", err)
+		return payment, gatewayError("stripe cancel failed", err)
 	}
 
 	payment.Cancel()
-	if err := u.Repository.Update(payment); err != nil {
+	if err := u.update(ctx, payment); err != nil {
 		return payment, err
 	}
 	return payment, nil
 }
 
-func (u *PaymentUseCase) Refund(ctx context.Context, uri dtos.IdentifyPaymentDto, pr dtos.PaymentRefundDto) (*domain.Payment, error) {
-	payment, err := u.Repository.FindByID(uri.PaymentID)
+func (u *PaymentUseCase) Refund(ctx context.Context, uri dtos.IdentifyPaymentDto, pr dtos.PaymentRefundDto) (_ *domain.Payment, err error) {
+	ctx, span := tracing.Start(ctx, "PaymentUseCase.Refund", paymentAttributes(uri.PaymentID))
+	defer func() { tracing.End(span, err) }()
+
+	payment, err := u.Repository.FindByID(ctx, uri.PaymentID)
 	if err != nil {
-		return nil, fmt.Errorf("payment not found: %w", err)
+		return nil, notFound(err)
 	}
 
+	record := u.audit(ctx, "payment.refund", payment, paymentSnapshot(payment), map[string]any{"amount": pr.Amount})
+	defer func() { record(err) }()
+	defer func() { u.recordFailedAttempt(ctx, payment, domain.OperationRefund, err) }()
+
 	if payment.StripeID == "" {
-		return nil, errors.New("missing Stripe payment intent ID")
+		return nil, ErrPaymentNotAtGateway
 	}
 
 	if err := payment.CanRefund(); err != nil {
-		payment.Fail()
-		_ = u.Repository.Update(payment)
-		return payment, fmt.Errorf("stripe refund failed: %w", err)
+		return payment, err
 	}
 
-	err = u.StripeClient.Refund(ctx, payment.StripeID, pr.Amount)
+	gateway, err := u.gateway(ctx, payment)
 	if err != nil {
-		payment.Fail()
-		_ = u.Repository.Update(payment)
-		return payment, fmt.Errorf("stripe refund failed: %w", err)
+		return payment, err
+	}
+
+	err = gateway.Refund(ctx, payment.StripeID, pr.Amount)
+	if err != nil {
+		return payment, gatewayError("stripe refund failed", err)
 	}
 
 	payment.Refund()
-	if err := u.Repository.Update(payment); err != nil {
+	if err := u.update(ctx, payment); err != nil {
 		return payment, err
 	}
 
 	return payment, nil
 }
+
+// FailedAttempts returns the operations on a payment that did not go
+// through, oldest first.
+func (u *PaymentUseCase) FailedAttempts(ctx context.Context, i dtos.IdentifyPaymentDto) (_ []*domain.FailedAttempt, err error) {
+	ctx, span := tracing.Start(ctx, "PaymentUseCase.FailedAttempts", paymentAttributes(i.PaymentID))
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := u.Repository.FindByID(ctx, i.PaymentID); err != nil {
+		return nil, notFound(err)
+	}
+	return u.Repository.FindFailedAttempts(ctx, i.PaymentID)
+}
+
+// recordFailedAttempt records that operation on payment failed with err,
+// if it did. Failing to record it is logged: the caller already has an
+// error to return.
+func (u *PaymentUseCase) recordFailedAttempt(ctx context.Context, payment *domain.Payment, operation string, err error) {
+	if err == nil {
+		return
+	}
+	attempt := domain.NewFailedAttempt(ulid.NewULID(), payment, operation, failureCode(err), failureMessage(err))
+	if saveErr := u.Repository.SaveFailedAttempt(ctx, attempt); saveErr != nil {
+		logger.Error("cannot record failed payment operation", "payment_id", payment.ID, "operation", operation, "err", saveErr)
+	}
+}
+
+func (u *PaymentUseCase) applySettlement(ctx context.Context, payment *domain.Payment, quoteID string) error {
+	if u.Settlement == nil {
+		return nil
+	}
+
+	presentment, err := payment.Money()
+	if err != nil {
+		return err
+	}
+
+	conversion, err := u.Settlement.Convert(ctx, presentment, quoteID)
+	if err != nil {
+		return ErrFxQuoteRejected.Wrap(err)
+	}
+
+	payment.SetSettlement(conversion.Amount, conversion.Currency, conversion.Rate, conversion.QuoteID)
+	return nil
+}
+
+func paymentAttributes(paymentID string) trace.SpanStartOption {
+	return trace.WithAttributes(attribute.String("payment.id", paymentID))
+}
+
+func authorizedAt(intent *stripe.PaymentIntent) time.Time {
+	if intent.Created > 0 {
+		return time.Unix(intent.Created, 0)
+	}
+	return time.Now()
+}
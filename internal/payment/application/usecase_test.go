@@ -0,0 +1,347 @@
+package application_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stripe/stripe-go"
+	listsDomain "github.com/williamkoller/payment-system/internal/lists/domain"
+	"github.com/williamkoller/payment-system/internal/payment/application"
+	"github.com/williamkoller/payment-system/internal/payment/domain"
+	"github.com/williamkoller/payment-system/internal/payment/dtos"
+	"github.com/williamkoller/payment-system/internal/payment/infra"
+	riskDomain "github.com/williamkoller/payment-system/internal/risk/domain"
+	"github.com/williamkoller/payment-system/pkg/auth"
+	"gorm.io/gorm"
+)
+
+type fakePayments struct {
+	payments map[string]*domain.Payment
+	attempts []*domain.FailedAttempt
+	changes  []*domain.StatusChange
+	updates  int
+}
+
+func (f *fakePayments) Save(_ context.Context, p *domain.Payment) (*domain.Payment, error) {
+	f.payments[p.ID] = p
+	f.saveChanges(p)
+	return p, nil
+}
+
+func (f *fakePayments) FindByID(_ context.Context, id string) (*domain.Payment, error) {
+	p, ok := f.payments[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return p, nil
+}
+
+func (f *fakePayments) FindAll(context.Context) ([]*domain.Payment, error) { return nil, nil }
+func (f *fakePayments) Remove(context.Context, string) error               { return nil }
+
+func (f *fakePayments) Update(_ context.Context, p *domain.Payment) error {
+	f.updates++
+	f.payments[p.ID] = p
+	f.saveChanges(p)
+	return nil
+}
+
+// saveChanges numbers and keeps the payment's pending status changes, as
+// the repository does.
+func (f *fakePayments) saveChanges(p *domain.Payment) {
+	for _, c := range p.PendingStatusChanges() {
+		c.ID = fmt.Sprintf("chg_%d", len(f.changes)+1)
+		f.changes = append(f.changes, c)
+	}
+	p.StatusChangesSaved()
+}
+
+func (f *fakePayments) FindByStripeID(context.Context, string) (*domain.Payment, error) {
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f *fakePayments) FindByIdempotencyKey(context.Context, string) (*domain.Payment, error) {
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f *fakePayments) List(_ context.Context, filter domain.PaymentFilter) ([]*domain.Payment, error) {
+	var payments []*domain.Payment
+	for _, p := range f.payments {
+		if filter.ExpiringBefore == nil || p.IsAuthorizationExpiringBefore(*filter.ExpiringBefore) {
+			payments = append(payments, p)
+		}
+	}
+	return payments, nil
+}
+
+func (f *fakePayments) Each(_ context.Context, _ domain.PaymentFilter, fn func(*domain.Payment) error) error {
+	for _, p := range f.payments {
+		if err := fn(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *fakePayments) FindStatusChanges(_ context.Context, paymentID string) ([]*domain.StatusChange, error) {
+	var changes []*domain.StatusChange
+	for _, c := range f.changes {
+		if c.PaymentID == paymentID {
+			changes = append(changes, c)
+		}
+	}
+	return changes, nil
+}
+
+func (f *fakePayments) SaveFailedAttempt(_ context.Context, a *domain.FailedAttempt) error {
+	f.attempts = append(f.attempts, a)
+	return nil
+}
+
+func (f *fakePayments) FindFailedAttempts(context.Context, string) ([]*domain.FailedAttempt, error) {
+	return f.attempts, nil
+}
+
+// fakeStripe fails every call with err and counts the calls made. Card
+// describes card.
+type fakeStripe struct {
+	err   error
+	calls int
+	card  infra.Card
+}
+
+func (f *fakeStripe) CreatePaymentIntent(context.Context, infra.PaymentIntentInput) (*stripe.PaymentIntent, error) {
+	f.calls++
+	return nil, f.err
+}
+
+func (f *fakeStripe) Card(context.Context) (infra.Card, error) {
+	return f.card, nil
+}
+
+func (f *fakeStripe) Capture(context.Context, string) error {
+	f.calls++
+	return f.err
+}
+
+func (f *fakeStripe) Cancel(context.Context, string) error {
+	f.calls++
+	return f.err
+}
+
+func (f *fakeStripe) Refund(context.Context, string, int64) error {
+	f.calls++
+	return f.err
+}
+
+// recordingLists remembers what it was asked to check and matches nothing.
+type recordingLists struct {
+	subjects []listsDomain.Subject
+}
+
+func (f *recordingLists) Check(subject listsDomain.Subject) *listsDomain.Match {
+	f.subjects = append(f.subjects, subject)
+	return nil
+}
+
+func TestPaymentUseCase_CreatePayment_ChecksTheGatewaysCard(t *testing.T) {
+	lists := &recordingLists{}
+	usecase := application.NewPaymentUseCase(&fakePayments{payments: map[string]*domain.Payment{}}, &fakeStripe{
+		err:  errors.New("stripe down"),
+		card: infra.Card{Fingerprint: "fp_1", BIN: "424242", Country: "US"},
+	})
+	usecase.Lists = lists
+
+	_, _ = usecase.CreatePayment(context.Background(), application.PaymentInput{
+		Amount: 1000, Currency: "USD", Email: "user@example.com", PaymentMethod: "card", IP: "198.51.100.1",
+	})
+
+	require.Len(t, lists.subjects, 1)
+	assert.Equal(t, listsDomain.Subject{Email: "user@example.com", IP: "198.51.100.1", CardFingerprint: "fp_1", CardBin: "424242"}, lists.subjects[0])
+}
+
+// fakeRisk allows everything and remembers who reviewed what.
+type fakeRisk struct {
+	reviewers map[string]string
+}
+
+func (f *fakeRisk) Assess(context.Context, string, riskDomain.Subject) (*riskDomain.RiskAssessment, error) {
+	return &riskDomain.RiskAssessment{Decision: riskDomain.DecisionAllow}, nil
+}
+
+func (f *fakeRisk) RecordReview(_ context.Context, paymentID, reviewer string) error {
+	f.reviewers[paymentID] = reviewer
+	return nil
+}
+
+func TestPaymentUseCase_RejectReview_RecordsTheCaller(t *testing.T) {
+	payment := &domain.Payment{ID: "pay_1", Amount: 1000, Currency: "USD", Status: domain.StatusReview}
+	repo := &fakePayments{payments: map[string]*domain.Payment{payment.ID: payment}}
+	risk := &fakeRisk{reviewers: map[string]string{}}
+	usecase := application.NewPaymentUseCase(repo, &fakeStripe{})
+	usecase.Risk = risk
+	ctx := auth.NewContext(context.Background(), &auth.Principal{KeyID: "key_ops", MerchantID: "m1"})
+
+	_, err := usecase.RejectReview(ctx, dtos.IdentifyPaymentDto{PaymentID: payment.ID})
+	require.NoError(t, err)
+
+	assert.Equal(t, "api_key:key_ops", risk.reviewers[payment.ID])
+	require.Len(t, repo.changes, 1)
+	assert.Equal(t, "review rejected by api_key:key_ops", repo.changes[0].Reason)
+}
+
+func TestPaymentUseCase_FailedOperationsKeepStatus(t *testing.T) {
+	declined := &stripe.Error{Type: stripe.ErrorTypeCard, Code: stripe.ErrorCodeCardDeclined, DeclineCode: stripe.DeclineCodeInsufficientFunds, HTTPStatusCode: 402, Msg: "Your card has insufficient funds."}
+	alreadyRefunded := &stripe.Error{Type: stripe.ErrorTypeInvalidRequest, Code: stripe.ErrorCodeChargeAlreadyRefunded, HTTPStatusCode: 400, Msg: "Charge has already been refunded."}
+	expired := &stripe.Error{Type: stripe.ErrorTypeInvalidRequest, Code: stripe.ErrorCode("payment_intent_expired_for_capture"), HTTPStatusCode: 400, Msg: "The authorization has expired."}
+	unavailable := errors.New("connection reset by peer")
+
+	tests := []struct {
+		name        string
+		status      domain.PaymentStatus
+		stripeID    string
+		stripeErr   error
+		operation   string
+		wantErr     error
+		wantCode    string
+		wantGateway bool
+	}{
+		{name: "capture declined by stripe", status: domain.StatusCompleted, stripeID: "pi_1", stripeErr: expired, operation: domain.OperationCapture,
+			wantErr: application.ErrGatewayRejected, wantCode: "payment_intent_expired_for_capture", wantGateway: true},
+		{name: "capture with stripe unavailable", status: domain.StatusCompleted, stripeID: "pi_1", stripeErr: unavailable, operation: domain.OperationCapture,
+			wantErr: application.ErrGatewayUnavailable, wantCode: "gateway_unavailable", wantGateway: true},
+		{name: "capture before reaching the gateway", status: domain.StatusPending, operation: domain.OperationCapture,
+			wantErr: application.ErrPaymentNotAtGateway, wantCode: "payment_not_at_gateway"},
+		{name: "cancel of a captured payment", status: domain.StatusCaptured, stripeID: "pi_1", operation: domain.OperationCancel,
+			wantErr: domain.ErrAlreadyCaptured, wantCode: domain.ErrAlreadyCaptured.Code},
+		{name: "cancel of a canceled payment", status: domain.StatusCanceled, stripeID: "pi_1", operation: domain.OperationCancel,
+			wantErr: domain.ErrAlreadyCanceled, wantCode: domain.ErrAlreadyCanceled.Code},
+		{name: "cancel rejected by stripe", status: domain.StatusCompleted, stripeID: "pi_1", stripeErr: alreadyRefunded, operation: domain.OperationCancel,
+			wantErr: application.ErrGatewayRejected, wantCode: string(stripe.ErrorCodeChargeAlreadyRefunded), wantGateway: true},
+		{name: "cancel with stripe unavailable", status: domain.StatusCompleted, stripeID: "pi_1", stripeErr: unavailable, operation: domain.OperationCancel,
+			wantErr: application.ErrGatewayUnavailable, wantCode: "gateway_unavailable", wantGateway: true},
+		{name: "refund of an uncaptured payment", status: domain.StatusCompleted, stripeID: "pi_1", operation: domain.OperationRefund,
+			wantErr: domain.ErrNotCaptured, wantCode: domain.ErrNotCaptured.Code},
+		{name: "refund declined by stripe", status: domain.StatusCaptured, stripeID: "pi_1", stripeErr: declined, operation: domain.OperationRefund,
+			wantErr: application.ErrGatewayDeclined, wantCode: string(stripe.DeclineCodeInsufficientFunds), wantGateway: true},
+		{name: "refund rejected by stripe", status: domain.StatusCaptured, stripeID: "pi_1", stripeErr: alreadyRefunded, operation: domain.OperationRefund,
+			wantErr: application.ErrGatewayRejected, wantCode: string(stripe.ErrorCodeChargeAlreadyRefunded), wantGateway: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payment := &domain.Payment{ID: "pay_1", StripeID: tt.stripeID, Amount: 1000, Currency: "USD", Status: tt.status}
+			repo := &fakePayments{payments: map[string]*domain.Payment{payment.ID: payment}}
+			gateway := &fakeStripe{err: tt.stripeErr}
+			usecase := application.NewPaymentUseCase(repo, gateway)
+
+			id := dtos.IdentifyPaymentDto{PaymentID: payment.ID}
+			ctx := context.Background()
+			var err error
+			switch tt.operation {
+			case domain.OperationCapture:
+				_, err = usecase.Capture(ctx, id)
+			case domain.OperationCancel:
+				_, err = usecase.Cancel(ctx, id)
+			case domain.OperationRefund:
+				_, err = usecase.Refund(ctx, id, dtos.PaymentRefundDto{Amount: 500})
+			}
+
+			require.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.status, repo.payments[payment.ID].Status, "a failed operation must not change the payment's status")
+			assert.Zero(t, repo.updates)
+			if tt.wantGateway {
+				assert.Equal(t, 1, gateway.calls)
+			} else {
+				assert.Zero(t, gateway.calls, "local checks fail before calling Stripe")
+			}
+
+			require.Len(t, repo.attempts, 1)
+			attempt := repo.attempts[0]
+			assert.Equal(t, payment.ID, attempt.PaymentID)
+			assert.Equal(t, tt.operation, attempt.Operation)
+			assert.Equal(t, tt.wantCode, attempt.ErrorCode)
+			assert.NotEmpty(t, attempt.Message)
+		})
+	}
+}
+
+func TestPaymentUseCase_CancelCapturedOnStripe(t *testing.T) {
+	payment := &domain.Payment{ID: "pay_1", StripeID: "pi_1", Amount: 1000, Currency: "USD", Status: domain.StatusCompleted}
+	repo := &fakePayments{payments: map[string]*domain.Payment{payment.ID: payment}}
+	gateway := &fakeStripe{err: &stripe.Error{Type: stripe.ErrorTypeInvalidRequest, Code: stripe.ErrorCodePaymentIntentUnexpectedState, HTTPStatusCode: 400}}
+	usecase := application.NewPaymentUseCase(repo, gateway)
+
+	_, err := usecase.Cancel(context.Background(), dtos.IdentifyPaymentDto{PaymentID: payment.ID})
+
+	require.ErrorIs(t, err, domain.ErrAlreadyCaptured)
+	assert.Equal(t, domain.StatusCaptured, payment.Status, "the payment catches up with Stripe")
+	assert.Equal(t, 1, repo.updates)
+	assert.Len(t, repo.attempts, 1)
+}
+
+func TestPaymentUseCase_Timeline(t *testing.T) {
+	declined := &stripe.Error{Type: stripe.ErrorTypeCard, Code: stripe.ErrorCodeCardDeclined, HTTPStatusCode: 402}
+
+	tests := []struct {
+		name       string
+		operations []string
+		stripeErr  error
+		want       [][2]domain.PaymentStatus
+	}{
+		{name: "no changes yet", want: nil},
+		{name: "capture", operations: []string{domain.OperationCapture},
+			want: [][2]domain.PaymentStatus{{domain.StatusCompleted, domain.StatusCaptured}}},
+		{name: "capture then refund", operations: []string{domain.OperationCapture, domain.OperationRefund},
+			want: [][2]domain.PaymentStatus{{domain.StatusCompleted, domain.StatusCaptured}, {domain.StatusCaptured, domain.StatusRefund}}},
+		{name: "cancel", operations: []string{domain.OperationCancel},
+			want: [][2]domain.PaymentStatus{{domain.StatusCompleted, domain.StatusCanceled}}},
+		{name: "failed operations leave no change", operations: []string{domain.OperationCapture, domain.OperationCancel}, stripeErr: declined,
+			want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payment := &domain.Payment{ID: "pay_1", StripeID: "pi_1", Amount: 1000, Currency: "USD", Status: domain.StatusCompleted}
+			other := &domain.Payment{ID: "pay_2", StripeID: "pi_2", Amount: 1000, Currency: "USD", Status: domain.StatusCompleted}
+			repo := &fakePayments{payments: map[string]*domain.Payment{payment.ID: payment, other.ID: other}}
+			usecase := application.NewPaymentUseCase(repo, &fakeStripe{err: tt.stripeErr})
+
+			ctx := context.Background()
+			id := dtos.IdentifyPaymentDto{PaymentID: payment.ID}
+			_, err := usecase.Cancel(ctx, dtos.IdentifyPaymentDto{PaymentID: other.ID})
+			require.Equal(t, tt.stripeErr == nil, err == nil)
+			for _, operation := range tt.operations {
+				switch operation {
+				case domain.OperationCapture:
+					_, err = usecase.Capture(ctx, id)
+				case domain.OperationCancel:
+					_, err = usecase.Cancel(ctx, id)
+				case domain.OperationRefund:
+					_, err = usecase.Refund(ctx, id, dtos.PaymentRefundDto{Amount: 1000})
+				}
+				require.Equal(t, tt.stripeErr == nil, err == nil)
+			}
+
+			changes, err := usecase.Timeline(ctx, id)
+			require.NoError(t, err)
+			var got [][2]domain.PaymentStatus
+			for _, c := range changes {
+				assert.Equal(t, payment.ID, c.PaymentID, "the timeline holds only the payment's own changes")
+				got = append(got, [2]domain.PaymentStatus{c.FromStatus, c.ToStatus})
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPaymentUseCase_Timeline_UnknownPayment(t *testing.T) {
+	usecase := application.NewPaymentUseCase(&fakePayments{payments: map[string]*domain.Payment{}}, &fakeStripe{})
+
+	_, err := usecase.Timeline(context.Background(), dtos.IdentifyPaymentDto{PaymentID: "pay_missing"})
+	assert.ErrorIs(t, err, application.ErrPaymentNotFound)
+}
@@ -0,0 +1,35 @@
+package domain
+
+import "time"
+
+const (
+	OperationCapture = "capture"
+	OperationCancel  = "cancel"
+	OperationRefund  = "refund"
+)
+
+// FailedAttempt is an operation on a payment that did not go through, such
+// as a refund Stripe declined. The payment's own status is left as it was.
+type FailedAttempt struct {
+	ID         string
+	PaymentID  string
+	MerchantID string
+	Operation  string
+	// ErrorCode is the gateway's error or decline code, or the API error
+	// code for attempts refused locally.
+	ErrorCode string
+	Message   string
+	CreatedAt time.Time
+}
+
+func NewFailedAttempt(id string, p *Payment, operation, errorCode, message string) *FailedAttempt {
+	return &FailedAttempt{
+		ID:         id,
+		PaymentID:  p.ID,
+		MerchantID: p.MerchantID,
+		Operation:  operation,
+		ErrorCode:  errorCode,
+		Message:    message,
+		CreatedAt:  time.Now(),
+	}
+}
@@ -0,0 +1,16 @@
+package domain
+
+import "github.com/williamkoller/payment-system/pkg/apperror"
+
+var (
+	ErrInvalidAmount         = apperror.New(apperror.KindValidation, "invalid_amount", "amount must be greater than zero")
+	ErrAmountOutOfRange      = apperror.New(apperror.KindValidation, "amount_out_of_range", "amount is outside the charge limits of the currency")
+	ErrInvalidCurrency       = apperror.New(apperror.KindValidation, "invalid_currency", "currency is missing or not supported")
+	ErrEmailRequired         = apperror.New(apperror.KindValidation, "email_required", "email must not be empty")
+	ErrPaymentMethodRequired = apperror.New(apperror.KindValidation, "payment_method_required", "payment method must not be empty")
+
+	ErrAlreadyCaptured = apperror.New(apperror.KindInvalidTransition, "payment_already_captured", "cannot cancel: payment already captured")
+	ErrAlreadyCanceled = apperror.New(apperror.KindInvalidTransition, "payment_already_canceled", "payment already canceled")
+	ErrNotInReview     = apperror.New(apperror.KindInvalidTransition, "payment_not_in_review", "payment is not awaiting review")
+	ErrNotCaptured     = apperror.New(apperror.KindInvalidTransition, "payment_not_captured", "payment must be captured before refund is allowed")
+)
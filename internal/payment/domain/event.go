@@ -0,0 +1,275 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type EventType string
+
+const (
+	// EventCreated carries the whole payment as it was first stored.
+	EventCreated EventType = "payment.created"
+	// EventStatusChanged carries one status change.
+	EventStatusChanged EventType = "payment.status_changed"
+	// EventUpdated carries the fields other than the status that changed,
+	// with their new values.
+	EventUpdated EventType = "payment.updated"
+)
+
+var ErrNoEvents = errors.New("payment has no events")
+
+// PaymentEvent is one change of a payment in the event-sourced store.
+// Version numbers a payment's events from 1 without gaps.
+type PaymentEvent struct {
+	ID         string
+	PaymentID  string
+	MerchantID string
+	Version    int64
+	Type       EventType
+	Data       string
+	CreatedAt  time.Time
+}
+
+type statusChangedData struct {
+	From      PaymentStatus `json:"from"`
+	To        PaymentStatus `json:"to"`
+	Reason    string        `json:"reason,omitempty"`
+	ErrorCode string        `json:"error_code,omitempty"`
+}
+
+// paymentState is the persisted form of a payment in events and snapshots.
+// Its json names are what updated events list, so they must not change.
+type paymentState struct {
+	ID                     string        `json:"id"`
+	MerchantID             string        `json:"merchant_id"`
+	GatewayAccount         string        `json:"gateway_account"`
+	StripeID               string        `json:"stripe_id"`
+	Amount                 int64         `json:"amount"`
+	Currency               string        `json:"currency"`
+	Status                 PaymentStatus `json:"status"`
+	Email                  string        `json:"email"`
+	EmailIndex             string        `json:"email_index"`
+	PaymentMethod          string        `json:"payment_method"`
+	IdempotencyKey         string        `json:"idempotency_key"`
+	SettlementAmount       int64         `json:"settlement_amount"`
+	SettlementCurrency     string        `json:"settlement_currency"`
+	FxRate                 string        `json:"fx_rate"`
+	FxQuoteID              string        `json:"fx_quote_id"`
+	AuthorizationExpiresAt *time.Time    `json:"authorization_expires_at"`
+	ExpiryAlertedAt        *time.Time    `json:"expiry_alerted_at"`
+	CreatedAt              time.Time     `json:"created_at"`
+	UpdatedAt              time.Time     `json:"updated_at"`
+}
+
+// immutableFields never change after creation, so updated events never
+// carry them. The email in particular stays out of every event but the
+// first.
+var immutableFields = map[string]bool{"id": true, "email": true, "email_index": true}
+
+func stateOf(p *Payment) paymentState {
+	return paymentState{
+		ID:                     p.ID,
+		MerchantID:             p.MerchantID,
+		GatewayAccount:         p.GatewayAccount,
+		StripeID:               p.StripeID,
+		Amount:                 p.Amount,
+		Currency:               p.Currency,
+		Status:                 p.Status,
+		Email:                  p.Email,
+		EmailIndex:             p.EmailIndex,
+		PaymentMethod:          p.PaymentMethod,
+		IdempotencyKey:         p.IdempotencyKey,
+		SettlementAmount:       p.SettlementAmount,
+		SettlementCurrency:     p.SettlementCurrency,
+		FxRate:                 p.FxRate,
+		FxQuoteID:              p.FxQuoteID,
+		AuthorizationExpiresAt: p.AuthorizationExpiresAt,
+		ExpiryAlertedAt:        p.ExpiryAlertedAt,
+		CreatedAt:              p.CreatedAt,
+		UpdatedAt:              p.UpdatedAt,
+	}
+}
+
+func (s paymentState) payment() *Payment {
+	return &Payment{
+		ID:                     s.ID,
+		MerchantID:             s.MerchantID,
+		GatewayAccount:         s.GatewayAccount,
+		StripeID:               s.StripeID,
+		Amount:                 s.Amount,
+		Currency:               s.Currency,
+		Status:                 s.Status,
+		Email:                  s.Email,
+		EmailIndex:             s.EmailIndex,
+		PaymentMethod:          s.PaymentMethod,
+		IdempotencyKey:         s.IdempotencyKey,
+		SettlementAmount:       s.SettlementAmount,
+		SettlementCurrency:     s.SettlementCurrency,
+		FxRate:                 s.FxRate,
+		FxQuoteID:              s.FxQuoteID,
+		AuthorizationExpiresAt: s.AuthorizationExpiresAt,
+		ExpiryAlertedAt:        s.ExpiryAlertedAt,
+		CreatedAt:              s.CreatedAt,
+		UpdatedAt:              s.UpdatedAt,
+	}
+}
+
+// EncodeSnapshot serializes p as a snapshot of its state.
+func EncodeSnapshot(p *Payment) (string, error) {
+	data, err := json.Marshal(stateOf(p))
+	return string(data), err
+}
+
+func DecodeSnapshot(data string) (*Payment, error) {
+	var s paymentState
+	if err := json.Unmarshal([]byte(data), &s); err != nil {
+		return nil, fmt.Errorf("invalid payment snapshot: %w", err)
+	}
+	return s.payment(), nil
+}
+
+// EventsBetween returns the events that take a payment from before to
+// after: one status change per change pending on after, then an updated
+// event for any other field that differs. before is nil for a payment not
+// stored yet, whose events start with EventCreated.
+func EventsBetween(before, after *Payment) ([]*PaymentEvent, error) {
+	var events []*PaymentEvent
+	changes := after.PendingStatusChanges()
+
+	var current paymentState
+	if before == nil {
+		current = stateOf(after)
+		if len(changes) > 0 {
+			current.Status = changes[0].FromStatus
+		}
+		event, err := newEvent(after, EventCreated, current)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	} else {
+		current = stateOf(before)
+	}
+
+	for _, c := range changes {
+		event, err := newEvent(after, EventStatusChanged, statusChangedData{
+			From:      c.FromStatus,
+			To:        c.ToStatus,
+			Reason:    c.Reason,
+			ErrorCode: c.ErrorCode,
+		})
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+		current.Status = c.ToStatus
+	}
+
+	changed, err := changedFields(current, stateOf(after))
+	if err != nil {
+		return nil, err
+	}
+	if len(changed) > 0 {
+		event, err := newEvent(after, EventUpdated, changed)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+func newEvent(p *Payment, eventType EventType, data any) (*PaymentEvent, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	return &PaymentEvent{
+		PaymentID:  p.ID,
+		MerchantID: p.MerchantID,
+		Type:       eventType,
+		Data:       string(encoded),
+		CreatedAt:  time.Now(),
+	}, nil
+}
+
+func changedFields(before, after paymentState) (map[string]json.RawMessage, error) {
+	from, err := fields(before)
+	if err != nil {
+		return nil, err
+	}
+	to, err := fields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := make(map[string]json.RawMessage)
+	for name, value := range to {
+		if !immutableFields[name] && !bytes.Equal(from[name], value) {
+			changed[name] = value
+		}
+	}
+	return changed, nil
+}
+
+func fields(s paymentState) (map[string]json.RawMessage, error) {
+	encoded, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]json.RawMessage
+	return m, json.Unmarshal(encoded, &m)
+}
+
+// Replay rebuilds a payment by applying events, in version order, to
+// snapshot, or from scratch when snapshot is nil.
+func Replay(snapshot *Payment, events []*PaymentEvent) (*Payment, error) {
+	if snapshot == nil && len(events) == 0 {
+		return nil, ErrNoEvents
+	}
+
+	p := snapshot
+	for _, e := range events {
+		var err error
+		if p, err = apply(p, e); err != nil {
+			return nil, fmt.Errorf("cannot apply event %d of payment %s: %w", e.Version, e.PaymentID, err)
+		}
+	}
+	return p, nil
+}
+
+func apply(p *Payment, e *PaymentEvent) (*Payment, error) {
+	if p == nil && e.Type != EventCreated {
+		return nil, fmt.Errorf("stream starts with %s", e.Type)
+	}
+
+	switch e.Type {
+	case EventCreated:
+		if p != nil {
+			return nil, errors.New("payment created twice")
+		}
+		return DecodeSnapshot(e.Data)
+	case EventStatusChanged:
+		var data statusChangedData
+		if err := json.Unmarshal([]byte(e.Data), &data); err != nil {
+			return nil, err
+		}
+		p.Status = data.To
+		return p, nil
+	case EventUpdated:
+		// Decoding over the current state only overwrites the fields the
+		// event carries.
+		s := stateOf(p)
+		if err := json.Unmarshal([]byte(e.Data), &s); err != nil {
+			return nil, err
+		}
+		return s.payment(), nil
+	default:
+		return nil, fmt.Errorf("unknown event type %q", e.Type)
+	}
+}
@@ -0,0 +1,73 @@
+package domain_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/williamkoller/payment-system/internal/payment/domain"
+)
+
+// stored returns what the event store keeps of p: its events and the
+// payment they rebuild.
+func stored(t *testing.T, before, after *domain.Payment) []*domain.PaymentEvent {
+	t.Helper()
+	events, err := domain.EventsBetween(before, after)
+	require.NoError(t, err)
+	after.StatusChangesSaved()
+	return events
+}
+
+func TestReplay_RebuildsPaymentFromEvents(t *testing.T) {
+	p, err := domain.NewPayment("pay_1", 1000, "USD", "user@example.com", "pm_card_visa")
+	require.NoError(t, err)
+	p.AssignMerchant("m_1", "")
+	p.SetIdempotencyKey("idem_1")
+	created := stored(t, nil, p)
+	require.Len(t, created, 2)
+	assert.Equal(t, domain.EventCreated, created[0].Type)
+	assert.Equal(t, domain.EventStatusChanged, created[1].Type)
+
+	before, err := domain.Replay(nil, created)
+	require.NoError(t, err)
+	p.SetStripeID("pi_1")
+	p.Complete()
+	p.SetAuthorizationExpiresAt(time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC))
+	authorized := stored(t, before, p)
+	require.Len(t, authorized, 2)
+	assert.Equal(t, domain.EventStatusChanged, authorized[0].Type)
+	assert.Equal(t, domain.EventUpdated, authorized[1].Type)
+	assert.NotContains(t, authorized[1].Data, "user@example.com", "only the created event carries the email")
+
+	rebuilt, err := domain.Replay(nil, append(created, authorized...))
+	require.NoError(t, err)
+	assert.Equal(t, p.ID, rebuilt.ID)
+	assert.Equal(t, "m_1", rebuilt.MerchantID)
+	assert.Equal(t, "user@example.com", rebuilt.Email)
+	assert.Equal(t, "pi_1", rebuilt.StripeID)
+	assert.Equal(t, domain.StatusCompleted, rebuilt.Status)
+	require.NotNil(t, rebuilt.AuthorizationExpiresAt)
+	assert.True(t, p.AuthorizationExpiresAt.Equal(*rebuilt.AuthorizationExpiresAt))
+	assert.True(t, p.UpdatedAt.Equal(rebuilt.UpdatedAt))
+
+	snapshot, err := domain.EncodeSnapshot(rebuilt)
+	require.NoError(t, err)
+	fromSnapshot, err := domain.DecodeSnapshot(snapshot)
+	require.NoError(t, err)
+	p.Capture()
+	captured := stored(t, fromSnapshot, p)
+
+	rebuilt, err = domain.Replay(fromSnapshot, captured)
+	require.NoError(t, err)
+	assert.Equal(t, domain.StatusCaptured, rebuilt.Status)
+	assert.Nil(t, rebuilt.AuthorizationExpiresAt, "clearing a field is an event too")
+}
+
+func TestReplay_RejectsStreamWithoutCreatedEvent(t *testing.T) {
+	_, err := domain.Replay(nil, nil)
+	assert.ErrorIs(t, err, domain.ErrNoEvents)
+
+	_, err = domain.Replay(nil, []*domain.PaymentEvent{{Type: domain.EventStatusChanged, Data: `{"to":"FAILED"}`}})
+	assert.Error(t, err)
+}
@@ -2,7 +2,10 @@ package domain
 
 import (
 	"errors"
+	"strings"
 	"time"
+
+	"github.com/williamkoller/payment-system/pkg/money"
 )
 
 type PaymentStatus string
@@ -14,84 +17,161 @@ const (
 	StatusCanceled  PaymentStatus = "CANCELED"
 	StatusCaptured  PaymentStatus = "CAPTURED"
 	StatusRefund    PaymentStatus = "REFUND"
+	// StatusReview holds a payment flagged by the risk engine until an
+	// operator approves or rejects it.
+	StatusReview PaymentStatus = "REVIEW"
+)
+
+// AuthorizationValidity is how long the gateway keeps a manually captured
+// authorization alive before releasing the funds.
+const AuthorizationValidity = 7 * 24 * time.Hour
+
+type ExpiryAction string
+
+const (
+	ExpiryActionCapture ExpiryAction = "capture"
+	ExpiryActionCancel  ExpiryAction = "cancel"
 )
 
+type PaymentFilter struct {
+	ExpiringBefore *time.Time
+	// Limit caps the page size; 0 returns every match.
+	Limit int
+	// StartingAfter is the id of the last payment of the previous page.
+	StartingAfter string
+}
+
 type Payment struct {
-	ID             string
+	ID         string
+	MerchantID string
+	// GatewayAccount is the Stripe account the payment was authorized on:
+	// the merchant's id when it has its own credentials, "" for the
+	// platform account. Later calls must go to the same account.
+	GatewayAccount string
 	StripeID       string
 	Amount         int64
 	Currency       string
 	Status         PaymentStatus
 	Email          string
+	// EmailIndex is the blind index of Email, which is stored encrypted.
+	// It is set by the repository.
+	EmailIndex     string
 	PaymentMethod  string
 	IdempotencyKey string
-	CreatedAt      time.Time
-	UpdatedAt      time.Time
+	// Amount and Currency are what the customer is charged (presentment);
+	// the settlement fields are what the merchant receives after FX.
+	SettlementAmount   int64
+	SettlementCurrency string
+	FxRate             string
+	FxQuoteID          string
+	// AuthorizationExpiresAt is set once the gateway authorizes the payment
+	// and cleared when the authorization is captured or released.
+	AuthorizationExpiresAt *time.Time
+	ExpiryAlertedAt        *time.Time
+	CreatedAt              time.Time
+	UpdatedAt              time.Time
+
+	// changes are the status changes not yet persisted.
+	changes []*StatusChange
 }
 
 func NewPayment(id string, amount int64, currency, email string, paymentMethod string) (*Payment, error) {
 	if amount <= 0 {
-		return nil, errors.New("amount must be greater than zero")
+		return nil, ErrInvalidAmount
 	}
 
 	if currency == "" {
-		return nil, errors.New("currency must be empty")
+		return nil, ErrInvalidCurrency
+	}
+
+	charge, err := money.NewCharge(amount, currency)
+	if errors.Is(err, money.ErrAmountTooSmall) || errors.Is(err, money.ErrAmountTooLarge) {
+		return nil, ErrAmountOutOfRange.Wrap(err)
+	}
+	if err != nil {
+		return nil, ErrInvalidCurrency.Wrap(err)
 	}
 
 	if email == "" {
-		return nil, errors.New("email must be empty")
+		return nil, ErrEmailRequired
 	}
 
 	if paymentMethod == "" {
-		return nil, errors.New("payment method must be empty")
+		return nil, ErrPaymentMethodRequired
 	}
 
 	now := time.Now()
 
-	return &Payment{
-		ID:            id,
-		Amount:        amount,
-		Currency:      currency,
-		Status:        StatusPending,
-		Email:         email,
-		PaymentMethod: paymentMethod,
-		CreatedAt:     now,
-		UpdatedAt:     now,
-	}, nil
+	payment := &Payment{
+		ID:                 id,
+		Amount:             charge.Amount,
+		Currency:           charge.Currency.Code,
+		Status:             StatusPending,
+		Email:              email,
+		PaymentMethod:      paymentMethod,
+		SettlementAmount:   charge.Amount,
+		SettlementCurrency: charge.Currency.Code,
+		FxRate:             "1",
+		CreatedAt:          now,
+		UpdatedAt:          now,
+	}
+	payment.recordChange("", StatusPending)
+
+	return payment, nil
 }
 
-func (p *Payment) Complete() {
-	p.Status = StatusCompleted
+func (p *Payment) transition(to PaymentStatus) {
+	from := p.Status
+	p.Status = to
 	p.UpdatedAt = time.Now()
+	p.recordChange(from, to)
+}
+
+func (p *Payment) Pending() {
+	p.transition(StatusPending)
+}
+
+func (p *Payment) Hold() {
+	p.transition(StatusReview)
+}
+
+func (p *Payment) Complete() {
+	p.transition(StatusCompleted)
 }
 
 func (p *Payment) Cancel() {
-	p.Status = StatusCanceled
-	p.UpdatedAt = time.Now()
+	p.AuthorizationExpiresAt = nil
+	p.transition(StatusCanceled)
 }
 
 func (p *Payment) Fail() {
-	p.Status = StatusFailed
-	p.UpdatedAt = time.Now()
+	p.transition(StatusFailed)
 }
 
 func (p *Payment) Capture() {
-	p.Status = StatusCaptured
-	p.UpdatedAt = time.Now()
+	p.AuthorizationExpiresAt = nil
+	p.transition(StatusCaptured)
 }
 
 func (p *Payment) Refund() {
-	p.Status = StatusRefund
-	p.UpdatedAt = time.Now()
+	p.transition(StatusRefund)
 }
 
 func (p *Payment) CanCancel() error {
-	if p.Status == StatusCanceled {
-		return errors.New("cannot cancel: payment already captured")
+	if p.Status == StatusCaptured {
+		return ErrAlreadyCaptured
 	}
 
 	if p.Status == StatusCanceled {
-		return errors.New("payment already canceled")
+		return ErrAlreadyCanceled
+	}
+
+	return nil
+}
+
+func (p *Payment) CanReview() error {
+	if p.Status != StatusReview {
+		return ErrNotInReview
 	}
 
 	return nil
@@ -99,12 +179,47 @@ func (p *Payment) CanCancel() error {
 
 func (p *Payment) CanRefund() error {
 	if p.Status != StatusCaptured {
-		return errors.New("payment must be captured before refund is allowed")
+		return ErrNotCaptured
 	}
 
 	return nil
 }
 
+func (p *Payment) SetSettlement(amount int64, currency, rate, quoteID string) {
+	p.SettlementAmount = amount
+	p.SettlementCurrency = currency
+	p.FxRate = rate
+	p.FxQuoteID = quoteID
+}
+
+func (p *Payment) SetAuthorizationExpiresAt(authorizedAt time.Time) {
+	expiresAt := authorizedAt.Add(AuthorizationValidity)
+	p.AuthorizationExpiresAt = &expiresAt
+	p.ExpiryAlertedAt = nil
+}
+
+func (p *Payment) MarkExpiryAlerted() {
+	now := time.Now()
+	p.ExpiryAlertedAt = &now
+}
+
+// IsAuthorizationExpiringBefore reports whether the payment holds an
+// uncaptured authorization that lapses before t.
+func (p *Payment) IsAuthorizationExpiringBefore(t time.Time) bool {
+	return p.Status == StatusCompleted &&
+		p.AuthorizationExpiresAt != nil &&
+		p.AuthorizationExpiresAt.Before(t)
+}
+
+// SameCharge reports whether a create request with these fields asks for
+// the charge p already records, so a retry of it can return p.
+func (p *Payment) SameCharge(amount int64, currency, email, paymentMethod string) bool {
+	return p.Amount == amount &&
+		strings.EqualFold(p.Currency, currency) &&
+		strings.EqualFold(p.Email, email) &&
+		p.PaymentMethod == paymentMethod
+}
+
 func (p *Payment) GetID() string {
 	return p.ID
 }
@@ -121,6 +236,13 @@ func (p *Payment) GetCurrency() string {
 	return p.Currency
 }
 
+// Money returns the payment amount as a value object. Payments persisted
+// before currency validation existed may carry an unknown code, so the
+// error must not be ignored.
+func (p *Payment) Money() (money.Money, error) {
+	return money.New(p.Amount, p.Currency)
+}
+
 func (p *Payment) GetStatus() PaymentStatus {
 	return p.Status
 }
@@ -149,6 +271,11 @@ func (p *Payment) GetIdempotencyKey() string {
 	return p.IdempotencyKey
 }
 
+func (p *Payment) AssignMerchant(merchantID, gatewayAccount string) {
+	p.MerchantID = merchantID
+	p.GatewayAccount = gatewayAccount
+}
+
 func (p *Payment) SetIdempotencyKey(idempotencyKey string) {
 	p.IdempotencyKey = idempotencyKey
 }
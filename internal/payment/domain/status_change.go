@@ -0,0 +1,83 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// ChangeSource is what drove a status change.
+type ChangeSource string
+
+const (
+	SourceAPI            ChangeSource = "api"
+	SourceWebhook        ChangeSource = "webhook"
+	SourceScheduler      ChangeSource = "scheduler"
+	SourceReconciliation ChangeSource = "reconciliation"
+	// SourceBackfill marks the entry that starts the timeline of a payment
+	// created before timelines were recorded.
+	SourceBackfill ChangeSource = "backfill"
+)
+
+// StatusChange is one entry of a payment's timeline.
+type StatusChange struct {
+	ID         string
+	PaymentID  string
+	MerchantID string
+	FromStatus PaymentStatus
+	ToStatus   PaymentStatus
+	Source     ChangeSource
+	Reason     string
+	// ErrorCode is the gateway's error or decline code, or the API error
+	// code for failures decided locally.
+	ErrorCode string
+	CreatedAt time.Time
+}
+
+type sourceKey struct{}
+
+// WithSource marks status changes persisted with ctx as driven by source.
+func WithSource(ctx context.Context, source ChangeSource) context.Context {
+	return context.WithValue(ctx, sourceKey{}, source)
+}
+
+// SourceFromContext defaults to SourceAPI: changes are driven by API
+// requests unless a webhook, worker or job says otherwise.
+func SourceFromContext(ctx context.Context) ChangeSource {
+	if source, ok := ctx.Value(sourceKey{}).(ChangeSource); ok {
+		return source
+	}
+	return SourceAPI
+}
+
+func (p *Payment) recordChange(from, to PaymentStatus) {
+	p.changes = append(p.changes, &StatusChange{
+		PaymentID:  p.ID,
+		FromStatus: from,
+		ToStatus:   to,
+		CreatedAt:  p.UpdatedAt,
+	})
+}
+
+// Explain sets why the latest status change happened, and the error code
+// behind it if any. It does nothing when the status has not changed since
+// the payment was last persisted.
+func (p *Payment) Explain(reason, errorCode string) {
+	if len(p.changes) == 0 {
+		return
+	}
+	last := p.changes[len(p.changes)-1]
+	last.Reason = reason
+	last.ErrorCode = errorCode
+}
+
+// PendingStatusChanges returns the status changes made since the payment
+// was last persisted.
+func (p *Payment) PendingStatusChanges() []*StatusChange {
+	return p.changes
+}
+
+// StatusChangesSaved is called by the repository once the pending changes
+// are persisted.
+func (p *Payment) StatusChangesSaved() {
+	p.changes = nil
+}
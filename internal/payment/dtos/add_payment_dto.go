@@ -2,7 +2,8 @@ package dtos
 
 type AddPaymentDto struct {
 	Amount        int64  `json:"amount" binding:"required,gt=0"`
-	Currency      string `json:"currency" binding:"required"`
+	Currency      string `json:"currency" binding:"required,currency"`
 	Email         string `json:"email" binding:"required,email"`
 	PaymentMethod string `json:"payment_method" binding:"required"`
+	FxQuoteID     string `json:"fx_quote_id"`
 }
@@ -0,0 +1,10 @@
+package dtos
+
+// ExportPaymentsDto takes the listing filters plus how to render the
+// export. Columns is comma-separated; every column is exported without it.
+// Timezone is an IANA name dates are written in, UTC by default.
+type ExportPaymentsDto struct {
+	ListPaymentsDto
+	Columns  string `form:"columns"`
+	Timezone string `form:"timezone"`
+}
@@ -0,0 +1,10 @@
+package dtos
+
+import "time"
+
+type ListPaymentsDto struct {
+	ExpiringBefore time.Time `form:"expiring_before" time_format:"2006-01-02T15:04:05Z07:00"`
+	// Limit pages the list; without it every payment is returned.
+	Limit         int    `form:"limit" binding:"omitempty,min=1,max=100"`
+	StartingAfter string `form:"starting_after"`
+}
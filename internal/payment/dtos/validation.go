@@ -0,0 +1,38 @@
+package dtos
+
+import (
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/williamkoller/payment-system/pkg/money"
+)
+
+// RegisterValidations adds the custom binding tags used by the payment DTOs
+// to gin's validator. It must run before the first request is bound.
+func RegisterValidations() error {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return nil
+	}
+	err := v.RegisterValidation("currency", func(fl validator.FieldLevel) bool {
+		return money.IsSupported(fl.Field().String())
+	})
+	if err != nil {
+		return err
+	}
+
+	v.RegisterStructValidation(validateChargeLimits, AddPaymentDto{})
+	return nil
+}
+
+// validateChargeLimits rejects amounts outside the per-currency charge
+// limits. Unknown currencies are left to the "currency" tag.
+func validateChargeLimits(sl validator.StructLevel) {
+	dto := sl.Current().Interface().(AddPaymentDto)
+	m, err := money.New(dto.Amount, dto.Currency)
+	if err != nil {
+		return
+	}
+	if err := m.ValidateCharge(); err != nil {
+		sl.ReportError(dto.Amount, "amount", "Amount", "charge_limits", m.Currency.Code)
+	}
+}
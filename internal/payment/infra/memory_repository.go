@@ -1,6 +1,7 @@
 package infra
 
 import (
+	"context"
 	"errors"
 	"sync"
 
@@ -19,14 +20,14 @@ func NewInMemoryPaymentRepository() *InMemoryPaymentRepository {
 	}
 }
 
-func (r *InMemoryPaymentRepository) Save(p *domain.Payment) error {
+func (r *InMemoryPaymentRepository) Save(_ context.Context, p *domain.Payment) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	r.data[p.ID] = p
 	return nil
 }
 
-func (r *InMemoryPaymentRepository) FindByID(id string) (*domain.Payment, error) {
+func (r *InMemoryPaymentRepository) FindByID(_ context.Context, id string) (*domain.Payment, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	p, ok := r.data[id]
@@ -36,7 +37,7 @@ func (r *InMemoryPaymentRepository) FindByID(id string) (*domain.Payment, error)
 	return p, nil
 }
 
-func (r *InMemoryPaymentRepository) FindAll() ([]*domain.Payment, error) {
+func (r *InMemoryPaymentRepository) FindAll(_ context.Context) ([]*domain.Payment, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	ps := make([]*domain.Payment, 0, len(r.data))
@@ -46,21 +47,21 @@ func (r *InMemoryPaymentRepository) FindAll() ([]*domain.Payment, error) {
 	return ps, nil
 }
 
-func (r *InMemoryPaymentRepository) Remove(id string) error {
+func (r *InMemoryPaymentRepository) Remove(_ context.Context, id string) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	delete(r.data, id)
 	return nil
 }
 
-func (r *InMemoryPaymentRepository) Update(p *domain.Payment) error {
+func (r *InMemoryPaymentRepository) Update(_ context.Context, p *domain.Payment) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	r.data[p.ID] = p
 	return nil
 }
 
-func (r *InMemoryPaymentRepository) FindByStripeID(stripeID string) (*domain.Payment, error) {
+func (r *InMemoryPaymentRepository) FindByStripeID(_ context.Context, stripeID string) (*domain.Payment, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	p, ok := r.stripeMap[stripeID]
@@ -70,7 +71,7 @@ func (r *InMemoryPaymentRepository) FindByStripeID(stripeID string) (*domain.Pay
 	return p, nil
 }
 
-func (r *InMemoryPaymentRepository) FindByIdempotencyKey(idempotencyKey string) (*domain.Payment, error) {
+func (r *InMemoryPaymentRepository) FindByIdempotencyKey(_ context.Context, idempotencyKey string) (*domain.Payment, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	for _, p := range r.data {
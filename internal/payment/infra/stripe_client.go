@@ -9,13 +9,40 @@ import (
 	"github.com/sony/gobreaker"
 	"github.com/stripe/stripe-go"
 	"github.com/stripe/stripe-go/paymentintent"
+	"github.com/stripe/stripe-go/paymentmethod"
 	"github.com/stripe/stripe-go/refund"
 	"github.com/williamkoller/payment-system/config"
+	"github.com/williamkoller/payment-system/internal/metrics"
 	"github.com/williamkoller/payment-system/pkg/logger"
+	"github.com/williamkoller/payment-system/pkg/requestid"
+	"github.com/williamkoller/payment-system/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+type PaymentIntentInput struct {
+	PaymentID     string
+	Amount        int64
+	Currency      string
+	Email         string
+	PaymentMethod string
+	// RequestThreeDSecure forces a 3D Secure challenge regardless of the
+	// issuer's own rules.
+	RequestThreeDSecure bool
+}
+
+// Card is what the gateway knows about the card a payment is charged to.
+// The fields are empty when the payment method is not a card.
+type Card struct {
+	Fingerprint string
+	BIN         string
+	Country     string
+}
+
 type StripeClient interface {
-	CreatePaymentIntent(ctx context.Context, amount int64, currency, email string, paymentMethod string) (*stripe.PaymentIntent, error)
+	CreatePaymentIntent(ctx context.Context, input PaymentIntentInput) (*stripe.PaymentIntent, error)
+	// Card describes the card of the payment method the client charges.
+	Card(ctx context.Context) (Card, error)
 	Capture(ctx context.Context, piID string) error
 	Cancel(ctx context.Context, piID string) error
 	Refund(ctx context.Context, stripeID string, amount int64) error
@@ -24,12 +51,16 @@ type StripeClient interface {
 var configuration, _ = config.LoadConfiguration()
 
 type stripeClient struct {
-	cb *gobreaker.CircuitBreaker
+	cb            *gobreaker.CircuitBreaker
+	intents       paymentintent.Client
+	refunds       refund.Client
+	methods       paymentmethod.Client
+	paymentMethod string
 }
 
-func newDefaultCircuitBreaker() *gobreaker.CircuitBreaker {
+func newDefaultCircuitBreaker(name string) *gobreaker.CircuitBreaker {
 	settings := gobreaker.Settings{
-		Name:        "Stripe",
+		Name:        name,
 		MaxRequests: 2,
 		Interval:    60 * time.Second,
 		Timeout:     10 * time.Second,
@@ -38,19 +69,82 @@ func newDefaultCircuitBreaker() *gobreaker.CircuitBreaker {
 		},
 		OnStateChange: func(name string, from, to gobreaker.State) {
 			logger.Default().Infow("circuit breaker state changed", "name", name, "from", from.String(), "to", to.String())
+			metrics.SetCircuitBreakerState(name, to)
 		},
 	}
+	metrics.SetCircuitBreakerState(settings.Name, gobreaker.StateClosed)
 	return gobreaker.NewCircuitBreaker(settings)
 }
 
+// NewStripeClient returns the client for the platform's own Stripe account.
 func NewStripeClient() StripeClient {
 	stripe.Key = configuration.Stripe.StripeApiKey
+	return newStripeClient("Stripe", configuration.Stripe.StripeApiKey, configuration.Stripe.StripeMethod)
+}
+
+// NewMerchantStripeClient returns a client for a merchant's own Stripe
+// account. It has its own circuit breaker, so one merchant's failing
+// credentials cannot open the breaker for everyone else.
+func NewMerchantStripeClient(merchantID, secretKey, paymentMethod string) StripeClient {
+	if paymentMethod == "" {
+		paymentMethod = configuration.Stripe.StripeMethod
+	}
+	return newStripeClient("Stripe:"+merchantID, secretKey, paymentMethod)
+}
+
+func newStripeClient(name, secretKey, paymentMethod string) *stripeClient {
+	backend := stripe.GetBackend(stripe.APIBackend)
 	return &stripeClient{
-		cb: newDefaultCircuitBreaker(),
+		cb:            newDefaultCircuitBreaker(name),
+		intents:       paymentintent.Client{B: backend, Key: secretKey},
+		refunds:       refund.Client{B: backend, Key: secretKey},
+		methods:       paymentmethod.Client{B: backend, Key: secretKey},
+		paymentMethod: paymentMethod,
 	}
 }
 
-func (c *stripeClient) CreatePaymentIntent(ctx context.Context, amount int64, currency, email string, paymentMethod string) (*stripe.PaymentIntent, error) {
+// BreakerState exposes the circuit breaker state to readiness checks.
+func (c *stripeClient) BreakerState() gobreaker.State {
+	return c.cb.State()
+}
+
+// startSpan opens the client span of a Stripe operation, tagged with the
+// circuit breaker state the call is attempted under.
+func (c *stripeClient) startSpan(ctx context.Context, operation string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs, attribute.String("stripe.circuit_breaker.state", c.cb.State().String()))
+	return tracing.Start(ctx, "stripe."+operation, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+func (c *stripeClient) finishSpan(span trace.Span, operation string, start time.Time, err error) {
+	if errors.Is(err, gobreaker.ErrOpenState) || errors.Is(err, gobreaker.ErrTooManyRequests) {
+		span.AddEvent("circuit breaker rejected call")
+	}
+	metrics.ObserveStripeCall(operation, start, &err)
+	tracing.End(span, err)
+}
+
+// withIdempotencyKey scopes the Stripe idempotency key to the inbound
+// request, so retries of the same request (ours, or the client's with the
+// same X-Request-ID) never repeat a side effect on Stripe.
+func withIdempotencyKey(ctx context.Context, params *stripe.Params, operation, target string) {
+	if id := requestid.FromContext(ctx); id != "" {
+		params.SetIdempotencyKey(id + ":" + operation + ":" + target)
+	}
+}
+
+// withRequestMetadata records the request id on objects Stripe stores
+// metadata for, so it comes back on the webhooks they trigger.
+func withRequestMetadata(ctx context.Context, params *stripe.Params) {
+	if id := requestid.FromContext(ctx); id != "" {
+		params.AddMetadata("request_id", id)
+	}
+}
+
+func (c *stripeClient) CreatePaymentIntent(ctx context.Context, input PaymentIntentInput) (_ *stripe.PaymentIntent, err error) {
+	start := time.Now()
+	ctx, span := c.startSpan(ctx, "create_payment_intent", attribute.Int64("payment.amount", input.Amount), attribute.String("payment.currency", input.Currency))
+	defer func() { c.finishSpan(span, "create_payment_intent", start, err) }()
+
 	result, err := c.cb.Execute(func() (interface{}, error) {
 		select {
 		case <-ctx.Done():
@@ -63,16 +157,28 @@ func (c *stripeClient) CreatePaymentIntent(ctx context.Context, amount int64, cu
 
 		for i := 0; i < maxRetries; i++ {
 			params := &stripe.PaymentIntentParams{
-				Amount:             stripe.Int64(amount),
-				Currency:           stripe.String(currency),
-				ReceiptEmail:       stripe.String(email),
+				Amount:             stripe.Int64(input.Amount),
+				Currency:           stripe.String(input.Currency),
+				ReceiptEmail:       stripe.String(input.Email),
 				CaptureMethod:      stripe.String(string(stripe.PaymentIntentCaptureMethodManual)),
 				Confirm:            stripe.Bool(true),
-				PaymentMethod:      stripe.String(configuration.Stripe.StripeMethod),
-				PaymentMethodTypes: []*string{stripe.String(paymentMethod)},
+				PaymentMethod:      stripe.String(c.paymentMethod),
+				PaymentMethodTypes: []*string{stripe.String(input.PaymentMethod)},
+			}
+			params.AddMetadata("payment_id", input.PaymentID)
+			withRequestMetadata(ctx, &params.Params)
+			withIdempotencyKey(ctx, &params.Params, "create_payment_intent", input.PaymentID)
+			if input.RequestThreeDSecure {
+				params.PaymentMethodOptions = &stripe.PaymentIntentPaymentMethodOptionsParams{
+					Card: &stripe.PaymentIntentPaymentMethodOptionsCardParams{
+						RequestThreeDSecure: stripe.String(string(stripe.PaymentIntentPaymentMethodOptionsCardRequestThreeDSecureAny)),
+					},
+				}
 			}
 
-			pi, err := paymentintent.New(params)
+			_, attempt := tracing.Start(ctx, "stripe.create_payment_intent.attempt", trace.WithAttributes(attribute.Int("stripe.attempt", i+1)))
+			pi, err := c.intents.New(params)
+			tracing.End(attempt, err)
 			if err == nil {
 				return pi, nil
 			}
@@ -80,10 +186,10 @@ func (c *stripeClient) CreatePaymentIntent(ctx context.Context, amount int64, cu
 			var stripeErr *stripe.Error
 			if errors.As(err, &stripeErr) {
 				if stripeErr.HTTPStatusCode == 401 {
-					return nil, fmt.Errorf("stripe unauthorized: %s", stripeErr.Msg)
+					return nil, fmt.Errorf("stripe unauthorized: %w", err)
 				}
 				if stripeErr.HTTPStatusCode >= 400 && stripeErr.HTTPStatusCode < 500 {
-					return nil, fmt.Errorf("stripe request error: %s", stripeErr.Msg)
+					return nil, fmt.Errorf("stripe request error: %w", err)
 				}
 			}
 
@@ -107,7 +213,11 @@ func (c *stripeClient) CreatePaymentIntent(ctx context.Context, amount int64, cu
 	return pi, nil
 }
 
-func (c *stripeClient) Capture(ctx context.Context, piID string) error {
+func (c *stripeClient) Card(ctx context.Context) (_ Card, err error) {
+	start := time.Now()
+	ctx, span := c.startSpan(ctx, "get_payment_method", attribute.String("stripe.payment_method", c.paymentMethod))
+	defer func() { c.finishSpan(span, "get_payment_method", start, err) }()
+
 	result, err := c.cb.Execute(func() (interface{}, error) {
 		select {
 		case <-ctx.Done():
@@ -115,7 +225,38 @@ func (c *stripeClient) Capture(ctx context.Context, piID string) error {
 		default:
 		}
 
-		return paymentintent.Capture(piID, &stripe.PaymentIntentCaptureParams{})
+		return c.methods.Get(c.paymentMethod, &stripe.PaymentMethodParams{})
+	})
+	if err != nil {
+		return Card{}, err
+	}
+
+	method, ok := result.(*stripe.PaymentMethod)
+	if !ok {
+		return Card{}, errors.New("unexpected result type from Stripe payment method")
+	}
+	if method.Card == nil {
+		return Card{}, nil
+	}
+
+	return Card{Fingerprint: method.Card.Fingerprint, BIN: method.Card.IIN, Country: method.Card.Country}, nil
+}
+
+func (c *stripeClient) Capture(ctx context.Context, piID string) (err error) {
+	start := time.Now()
+	ctx, span := c.startSpan(ctx, "capture", attribute.String("stripe.payment_intent", piID))
+	defer func() { c.finishSpan(span, "capture", start, err) }()
+
+	result, err := c.cb.Execute(func() (interface{}, error) {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+
+		params := &stripe.PaymentIntentCaptureParams{}
+		withIdempotencyKey(ctx, &params.Params, "capture", piID)
+		return c.intents.Capture(piID, params)
 	})
 
 	if err != nil {
@@ -129,7 +270,11 @@ func (c *stripeClient) Capture(ctx context.Context, piID string) error {
 	return nil
 }
 
-func (c *stripeClient) Cancel(ctx context.Context, piID string) error {
+func (c *stripeClient) Cancel(ctx context.Context, piID string) (err error) {
+	start := time.Now()
+	ctx, span := c.startSpan(ctx, "cancel", attribute.String("stripe.payment_intent", piID))
+	defer func() { c.finishSpan(span, "cancel", start, err) }()
+
 	result, err := c.cb.Execute(func() (interface{}, error) {
 		select {
 		case <-ctx.Done():
@@ -137,7 +282,9 @@ func (c *stripeClient) Cancel(ctx context.Context, piID string) error {
 		default:
 		}
 
-		return paymentintent.Cancel(piID, &stripe.PaymentIntentCancelParams{})
+		params := &stripe.PaymentIntentCancelParams{}
+		withIdempotencyKey(ctx, &params.Params, "cancel", piID)
+		return c.intents.Cancel(piID, params)
 	})
 
 	if err != nil {
@@ -151,10 +298,14 @@ func (c *stripeClient) Cancel(ctx context.Context, piID string) error {
 	return nil
 }
 
-func (c *stripeClient) Refund(ctx context.Context, stripeID string, amount int64) error {
+func (c *stripeClient) Refund(ctx context.Context, stripeID string, amount int64) (err error) {
+	start := time.Now()
+	ctx, span := c.startSpan(ctx, "refund", attribute.String("stripe.payment_intent", stripeID))
+	defer func() { c.finishSpan(span, "refund", start, err) }()
+
 	logger.Info("stripe payment intent ID", "StripeID", stripeID)
 
-	_, err := c.cb.Execute(func() (interface{}, error) {
+	_, err = c.cb.Execute(func() (interface{}, error) {
 		select {
 		case <-ctx.Done():
 			return nil, ctx.Err()
@@ -165,8 +316,11 @@ func (c *stripeClient) Refund(ctx context.Context, stripeID string, amount int64
 			Amount:        stripe.Int64(amount),
 			PaymentIntent: stripe.String(stripeID),
 		}
+		params.AddMetadata("payment_intent", stripeID)
+		withRequestMetadata(ctx, &params.Params)
+		withIdempotencyKey(ctx, &params.Params, "refund", stripeID)
 
-		return refund.New(params)
+		return c.refunds.New(params)
 	})
 
 	return err
@@ -0,0 +1,60 @@
+package infra
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"sync"
+)
+
+// CredentialSource looks up a merchant's own Stripe credentials. An empty
+// secret key means the merchant charges through the platform account.
+type CredentialSource interface {
+	StripeCredentials(ctx context.Context, merchantID string) (secretKey, paymentMethod string, err error)
+}
+
+type cachedClient struct {
+	fingerprint [32]byte
+	client      StripeClient
+}
+
+// StripeClients resolves the client of a gateway account at request time:
+// "" is the platform account, any other value a merchant with its own
+// credentials. Clients are cached and rebuilt when the credentials change.
+type StripeClients struct {
+	Platform    StripeClient
+	Credentials CredentialSource
+
+	mu      sync.Mutex
+	clients map[string]cachedClient
+}
+
+func NewStripeClients(platform StripeClient, credentials CredentialSource) *StripeClients {
+	return &StripeClients{Platform: platform, Credentials: credentials, clients: make(map[string]cachedClient)}
+}
+
+func (s *StripeClients) ForAccount(ctx context.Context, account string) (StripeClient, error) {
+	if account == "" {
+		return s.Platform, nil
+	}
+
+	secretKey, paymentMethod, err := s.Credentials.StripeCredentials(ctx, account)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load Stripe credentials of merchant %s: %w", account, err)
+	}
+	if secretKey == "" {
+		return nil, fmt.Errorf("merchant %s has no Stripe credentials", account)
+	}
+
+	fingerprint := sha256.Sum256([]byte(secretKey + "\x00" + paymentMethod))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cached, ok := s.clients[account]; ok && cached.fingerprint == fingerprint {
+		return cached.client, nil
+	}
+
+	client := NewMerchantStripeClient(account, secretKey, paymentMethod)
+	s.clients[account] = cachedClient{fingerprint: fingerprint, client: client}
+	return client, nil
+}
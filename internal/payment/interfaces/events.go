@@ -0,0 +1,99 @@
+package interfaces
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"github.com/williamkoller/payment-system/internal/middleware"
+	"github.com/williamkoller/payment-system/internal/payment/application"
+	"github.com/williamkoller/payment-system/internal/payment/domain"
+	"github.com/williamkoller/payment-system/internal/payment/dtos"
+	"github.com/williamkoller/payment-system/pkg/apperror"
+)
+
+// StatusEvent names the server-sent events of status changes.
+const StatusEvent = "status"
+
+// StreamEvents streams the payment's status changes as server-sent events,
+// starting with its timeline. Each event's id is the id of its timeline
+// entry, so an EventSource that reconnects with Last-Event-ID picks up
+// where it left off. The stream stays open until the client goes away.
+func (h *PaymentHandler) StreamEvents(c *gin.Context) {
+	var uri dtos.IdentifyPaymentDto
+	if err := c.ShouldBindUri(&uri); err != nil {
+		middleware.Problem(c, apperror.Validation(err))
+		return
+	}
+
+	ctx := c.Request.Context()
+	lastEventID := c.GetHeader("Last-Event-ID")
+	backlog, sub, err := h.Usecase.WatchStatus(ctx, uri, lastEventID)
+	if errors.Is(err, application.ErrTooManyStreams) {
+		c.Header("Retry-After", "5")
+	}
+	if err != nil {
+		middleware.Problem(c, err)
+		return
+	}
+	defer sub.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	stream := &statusStream{w: c.Writer, sent: make(map[string]bool)}
+	stream.send(backlog)
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(h.Usecase.Events.Heartbeat())
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case change, ok := <-sub.C:
+			if !ok {
+				// Fallen behind or shutting down; the client reconnects
+				// and resumes from the timeline.
+				return
+			}
+			stream.send([]*domain.StatusChange{change})
+		case <-heartbeat.C:
+			// Changes made by other replicas only show up in the timeline,
+			// possibly before changes this stream has sent already, so
+			// only the client's resume point bounds what is re-read.
+			changes, err := h.Usecase.Timeline(ctx, uri)
+			if err != nil {
+				if ctx.Err() == nil {
+					middleware.FromContext(c).Warnw("Cannot read payment timeline for event stream", "payment_id", uri.PaymentID, "err", err)
+				}
+				return
+			}
+			stream.send(application.ChangesAfter(changes, lastEventID))
+			_, _ = io.WriteString(c.Writer, ": heartbeat\n\n")
+		}
+		c.Writer.Flush()
+	}
+}
+
+// statusStream writes status changes as events, each at most once.
+type statusStream struct {
+	w    io.Writer
+	sent map[string]bool
+}
+
+func (s *statusStream) send(changes []*domain.StatusChange) {
+	for _, change := range changes {
+		if s.sent[change.ID] {
+			continue
+		}
+		s.sent[change.ID] = true
+		_ = sse.Encode(s.w, sse.Event{Id: change.ID, Event: StatusEvent, Data: ToStatusChangeResponse(change)})
+	}
+}
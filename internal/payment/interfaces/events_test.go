@@ -0,0 +1,100 @@
+package interfaces_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/williamkoller/payment-system/internal/payment/application"
+	"github.com/williamkoller/payment-system/internal/payment/domain"
+	"github.com/williamkoller/payment-system/internal/payment/interfaces"
+	"github.com/williamkoller/payment-system/pkg/logger"
+)
+
+// fakeTimeline serves one payment's timeline. The first read runs
+// onFirstRead, which stands in for writes that happen once the stream has
+// subscribed.
+type fakeTimeline struct {
+	application.PaymentRepository
+	changes     []*domain.StatusChange
+	onFirstRead func()
+	reads       int
+}
+
+func (f *fakeTimeline) FindByID(_ context.Context, id string) (*domain.Payment, error) {
+	return &domain.Payment{ID: id}, nil
+}
+
+func (f *fakeTimeline) FindStatusChanges(context.Context, string) ([]*domain.StatusChange, error) {
+	changes := f.changes
+	if f.reads++; f.reads == 1 && f.onFirstRead != nil {
+		f.onFirstRead()
+	}
+	return changes, nil
+}
+
+func change(id string, from, to domain.PaymentStatus) *domain.StatusChange {
+	return &domain.StatusChange{ID: id, PaymentID: "pay_1", FromStatus: from, ToStatus: to, Source: domain.SourceAPI}
+}
+
+func TestStreamEvents_CatchesUpOnOtherReplicas(t *testing.T) {
+	require.NoError(t, logger.InitLogger("dev"))
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name        string
+		lastEventID string
+		want        []string
+	}{
+		{"new stream", "", []string{"chg_1", "chg_3", "chg_2"}},
+		{"resumed stream", "chg_1", []string{"chg_3", "chg_2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			broker := application.NewStatusBroker(0, 0)
+			broker.HeartbeatInterval = 10 * time.Millisecond
+
+			created := change("chg_1", "", domain.StatusCompleted)
+			// chg_2 is written by another replica and only shows up in the
+			// timeline; chg_3, written here after it, reaches the stream
+			// first.
+			remote := change("chg_2", domain.StatusCompleted, domain.StatusCaptured)
+			local := change("chg_3", domain.StatusCaptured, domain.StatusRefund)
+			repo := &fakeTimeline{changes: []*domain.StatusChange{created}}
+			repo.onFirstRead = func() {
+				repo.changes = append(repo.changes, remote, local)
+				broker.Publish(local)
+			}
+
+			usecase := application.NewPaymentUseCase(repo, nil)
+			usecase.Events = broker
+			e := gin.New()
+			e.GET("/payments/:payment_id/events", interfaces.NewPaymentHandler(usecase).StreamEvents)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			req := httptest.NewRequest(http.MethodGet, "/payments/pay_1/events", nil).WithContext(ctx)
+			if tt.lastEventID != "" {
+				req.Header.Set("Last-Event-ID", tt.lastEventID)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			require.Equal(t, http.StatusOK, rec.Code)
+			var ids []string
+			for _, line := range strings.Split(rec.Body.String(), "\n") {
+				if id, ok := strings.CutPrefix(line, "id:"); ok {
+					ids = append(ids, id)
+				}
+			}
+			assert.Equal(t, tt.want, ids, "every change is sent once, after the client's resume point")
+		})
+	}
+}
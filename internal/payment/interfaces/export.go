@@ -0,0 +1,308 @@
+package interfaces
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/williamkoller/payment-system/internal/middleware"
+	"github.com/williamkoller/payment-system/internal/payment/domain"
+	"github.com/williamkoller/payment-system/internal/payment/dtos"
+	"github.com/williamkoller/payment-system/pkg/apperror"
+	"github.com/williamkoller/payment-system/pkg/money"
+)
+
+// Export formats and the media types that select them.
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+
+	MediaTypeCSV    = "text/csv"
+	MediaTypeNDJSON = "application/x-ndjson"
+)
+
+// exportFlushEvery is how many rows are buffered before they are sent.
+const exportFlushEvery = 100
+
+var (
+	ErrUnknownColumn   = apperror.New(apperror.KindValidation, "unknown_export_column", "unknown export column")
+	ErrUnknownTimezone = apperror.New(apperror.KindValidation, "unknown_timezone", "unknown timezone")
+	ErrNotAcceptable   = apperror.New(apperror.KindNotAcceptable, "not_acceptable", "exports are available as text/csv or application/x-ndjson")
+)
+
+// ExportPayments streams the payments ListPayments would return, as CSV
+// or NDJSON depending on the Accept header (CSV without one). Rows are
+// written as they are read from the database, so the export never sits in
+// memory. Errors before the first row get a problem response; after it
+// the connection is cut, so the client sees a broken download rather than
+// a short file.
+func (h *PaymentHandler) ExportPayments(c *gin.Context) {
+	var query dtos.ExportPaymentsDto
+	if err := c.ShouldBindQuery(&query); err != nil {
+		middleware.Problem(c, apperror.Validation(err))
+		return
+	}
+
+	mediaType := c.NegotiateFormat(MediaTypeCSV, MediaTypeNDJSON, "application/ndjson")
+	if mediaType == "" {
+		middleware.Problem(c, ErrNotAcceptable)
+		return
+	}
+	format := FormatNDJSON
+	if mediaType == MediaTypeCSV {
+		format = FormatCSV
+	}
+
+	opts, err := ParseExportOptions(format, query.Columns, query.Timezone)
+	if err != nil {
+		middleware.Problem(c, err)
+		return
+	}
+
+	w := NewExportWriter(c.Writer, opts)
+	started := false
+	start := func() error {
+		started = true
+		c.Header("Content-Type", mediaType+"; charset=utf-8")
+		c.Header("Content-Disposition", `attachment; filename="payments.`+format+`"`)
+		c.Status(http.StatusOK)
+		return w.WriteHeader()
+	}
+
+	rows := 0
+	err = h.Usecase.ExportPayments(c.Request.Context(), query.ListPaymentsDto, func(p *domain.Payment) error {
+		if !started {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		if err := w.Write(p); err != nil {
+			return err
+		}
+		if rows++; rows%exportFlushEvery == 0 {
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			c.Writer.Flush()
+		}
+		return nil
+	})
+	if err != nil && !started {
+		middleware.Problem(c, err)
+		return
+	}
+	if err == nil && !started {
+		err = start()
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		if c.Request.Context().Err() == nil {
+			middleware.FromContext(c).Errorw("Payment export failed", "rows", rows, "err", err)
+		}
+		abortResponse(c)
+		return
+	}
+	c.Writer.Flush()
+}
+
+// abortResponse closes the connection under a response already under way.
+// Over HTTP/2, which cannot be hijacked, the response just ends.
+func abortResponse(c *gin.Context) {
+	rw, ok := c.Writer.(interface{ Unwrap() http.ResponseWriter })
+	if !ok {
+		return
+	}
+	if conn, _, err := http.NewResponseController(rw.Unwrap()).Hijack(); err == nil {
+		_ = conn.Close()
+	}
+}
+
+// DefaultExportColumns are every column, in the order of an export that
+// does not choose.
+var DefaultExportColumns = []string{
+	"id", "merchant_id", "status",
+	"amount", "currency", "display_amount",
+	"settlement_amount", "settlement_currency", "settlement_display_amount", "fx_rate",
+	"email", "payment_method", "stripe_id",
+	"authorization_expires_at", "created_at", "updated_at",
+}
+
+// ExportOptions says how payments are written. ParseExportOptions builds
+// them from request parameters.
+type ExportOptions struct {
+	Format   string
+	Columns  []string
+	Location *time.Location
+}
+
+// ParseExportOptions validates a comma-separated column list, "" meaning
+// DefaultExportColumns, and an IANA timezone, "" meaning UTC.
+func ParseExportOptions(format, columns, timezone string) (ExportOptions, error) {
+	opts := ExportOptions{Format: format, Columns: DefaultExportColumns, Location: time.UTC}
+
+	if columns != "" {
+		opts.Columns = nil
+		for _, column := range strings.Split(columns, ",") {
+			column = strings.TrimSpace(column)
+			if !slices.Contains(DefaultExportColumns, column) {
+				return ExportOptions{}, ErrUnknownColumn.WithMessage("unknown export column " + strconv.Quote(column))
+			}
+			opts.Columns = append(opts.Columns, column)
+		}
+	}
+
+	if timezone != "" {
+		loc, err := time.LoadLocation(timezone)
+		if err != nil {
+			return ExportOptions{}, ErrUnknownTimezone.WithMessage("unknown timezone " + strconv.Quote(timezone)).Wrap(err)
+		}
+		opts.Location = loc
+	}
+
+	return opts, nil
+}
+
+// ExportWriter writes payments one at a time as CSV, with a header row, or
+// as NDJSON, one object per line with the columns as string members.
+type ExportWriter struct {
+	opts   ExportOptions
+	csv    *csv.Writer
+	json   *json.Encoder
+	record []string
+}
+
+func NewExportWriter(w io.Writer, opts ExportOptions) *ExportWriter {
+	e := &ExportWriter{opts: opts, record: make([]string, len(opts.Columns))}
+	if opts.Format == FormatNDJSON {
+		e.json = json.NewEncoder(w)
+	} else {
+		e.csv = csv.NewWriter(w)
+	}
+	return e
+}
+
+// WriteHeader writes the CSV header row; NDJSON has none.
+func (e *ExportWriter) WriteHeader() error {
+	if e.csv == nil {
+		return nil
+	}
+	return e.csv.Write(e.opts.Columns)
+}
+
+func (e *ExportWriter) Write(p *domain.Payment) error {
+	for i, column := range e.opts.Columns {
+		e.record[i] = exportValue(p, column, e.opts.Location)
+	}
+	if e.csv != nil {
+		return e.csv.Write(e.record)
+	}
+	return e.json.Encode(exportObject{columns: e.opts.Columns, values: e.record})
+}
+
+// Flush writes out what the CSV writer buffered.
+func (e *ExportWriter) Flush() error {
+	if e.csv == nil {
+		return nil
+	}
+	e.csv.Flush()
+	return e.csv.Error()
+}
+
+// exportValue renders one column. Amounts are in major units of their
+// currency, e.g. "10.50" for 1050 USD, and dates are RFC 3339 in loc.
+func exportValue(p *domain.Payment, column string, loc *time.Location) string {
+	switch column {
+	case "id":
+		return p.ID
+	case "merchant_id":
+		return p.MerchantID
+	case "status":
+		return string(p.Status)
+	case "amount":
+		return decimalAmount(p.Amount, p.Currency)
+	case "currency":
+		return p.Currency
+	case "display_amount":
+		return displayAmount(p.Amount, p.Currency)
+	case "settlement_amount":
+		return decimalAmount(p.SettlementAmount, p.SettlementCurrency)
+	case "settlement_currency":
+		return p.SettlementCurrency
+	case "settlement_display_amount":
+		return displayAmount(p.SettlementAmount, p.SettlementCurrency)
+	case "fx_rate":
+		return p.FxRate
+	case "email":
+		return p.Email
+	case "payment_method":
+		return p.PaymentMethod
+	case "stripe_id":
+		return p.StripeID
+	case "authorization_expires_at":
+		return exportTime(p.AuthorizationExpiresAt, loc)
+	case "created_at":
+		return exportTime(&p.CreatedAt, loc)
+	case "updated_at":
+		return exportTime(&p.UpdatedAt, loc)
+	default:
+		return ""
+	}
+}
+
+// exportObject marshals as a JSON object keeping the columns' order.
+type exportObject struct {
+	columns []string
+	values  []string
+}
+
+func (o exportObject) MarshalJSON() ([]byte, error) {
+	buf := []byte{'{'}
+	for i, column := range o.columns {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		key, err := json.Marshal(column)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(o.values[i])
+		if err != nil {
+			return nil, err
+		}
+		buf = append(append(append(buf, key...), ':'), value...)
+	}
+	return append(buf, '}'), nil
+}
+
+func decimalAmount(amount int64, currency string) string {
+	if currency == "" {
+		return ""
+	}
+	m, err := money.New(amount, currency)
+	if err != nil {
+		return strconv.FormatInt(amount, 10)
+	}
+	return m.Decimal()
+}
+
+func displayAmount(amount int64, currency string) string {
+	if currency == "" {
+		return ""
+	}
+	return formatAmount(amount, currency)
+}
+
+func exportTime(t *time.Time, loc *time.Location) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.In(loc).Format(time.RFC3339)
+}
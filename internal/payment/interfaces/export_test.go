@@ -0,0 +1,111 @@
+package interfaces_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/williamkoller/payment-system/internal/payment/application"
+	"github.com/williamkoller/payment-system/internal/payment/domain"
+	"github.com/williamkoller/payment-system/internal/payment/interfaces"
+	"github.com/williamkoller/payment-system/pkg/logger"
+)
+
+// fakePayments only implements Each; the export needs nothing else.
+type fakePayments struct {
+	application.PaymentRepository
+	payments []*domain.Payment
+}
+
+func (f *fakePayments) Each(_ context.Context, _ domain.PaymentFilter, fn func(*domain.Payment) error) error {
+	for _, p := range f.payments {
+		if err := fn(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newExportServer(t *testing.T, payments ...*domain.Payment) *gin.Engine {
+	t.Helper()
+	require.NoError(t, logger.InitLogger("dev"))
+	gin.SetMode(gin.TestMode)
+
+	usecase := application.NewPaymentUseCase(&fakePayments{payments: payments}, nil)
+	e := gin.New()
+	e.GET("/payments/export", interfaces.NewPaymentHandler(usecase).ExportPayments)
+	return e
+}
+
+func export(e *gin.Engine, query, accept string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/payments/export"+query, nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestExportPayments(t *testing.T) {
+	created := time.Date(2026, 10, 19, 2, 30, 0, 0, time.UTC)
+	e := newExportServer(t,
+		&domain.Payment{ID: "pay_1", Amount: 1050, Currency: "USD", Status: domain.StatusCaptured, Email: "a@example.com", CreatedAt: created},
+		&domain.Payment{ID: "pay_2", Amount: 1050, Currency: "JPY", Status: domain.StatusCompleted, Email: "b,c@example.com", CreatedAt: created},
+	)
+
+	rec := export(e, "?columns=id,amount,display_amount,email,created_at&timezone=America/Sao_Paulo", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal(t, strings.Join([]string{
+		"id,amount,display_amount,email,created_at",
+		"pay_1,10.50,$10.50,a@example.com,2026-10-18T23:30:00-03:00",
+		`pay_2,1050,¥1050,"b,c@example.com",2026-10-18T23:30:00-03:00`,
+		"",
+	}, "\n"), rec.Body.String())
+
+	rec = export(e, "?columns=status,id", "application/x-ndjson")
+	require.Equal(t, http.StatusOK, rec.Code)
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Equal(t, `{"status":"CAPTURED","id":"pay_1"}`, lines[0], "members keep the columns' order")
+	var row map[string]string
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &row))
+	assert.Equal(t, "pay_2", row["id"])
+
+	rec = export(newExportServer(t), "", "text/csv")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, strings.Join(interfaces.DefaultExportColumns, ",")+"\n", rec.Body.String(), "an empty export still has its header")
+}
+
+func TestExportPayments_Rejects(t *testing.T) {
+	e := newExportServer(t)
+
+	tests := []struct {
+		name   string
+		query  string
+		accept string
+		status int
+		code   string
+	}{
+		{"unknown column", "?columns=id,secret", "", http.StatusBadRequest, "unknown_export_column"},
+		{"unknown timezone", "?timezone=Mars/Olympus", "", http.StatusBadRequest, "unknown_timezone"},
+		{"unsupported format", "", "application/xml", http.StatusNotAcceptable, "not_acceptable"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := export(e, tt.query, tt.accept)
+			assert.Equal(t, tt.status, rec.Code)
+			var problem struct{ Code string }
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+			assert.Equal(t, tt.code, problem.Code)
+		})
+	}
+}
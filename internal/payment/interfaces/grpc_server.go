@@ -0,0 +1,225 @@
+package interfaces
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/williamkoller/payment-system/internal/middleware"
+	"github.com/williamkoller/payment-system/internal/payment/application"
+	"github.com/williamkoller/payment-system/internal/payment/domain"
+	"github.com/williamkoller/payment-system/internal/payment/dtos"
+	"github.com/williamkoller/payment-system/pkg/api/paymentv1"
+	"github.com/williamkoller/payment-system/pkg/apperror"
+	"github.com/williamkoller/payment-system/pkg/clientip"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// DefaultWatchInterval is how often WatchPayment looks for changes when
+// none is configured.
+const DefaultWatchInterval = time.Second
+
+// finalStatuses end a WatchPayment stream: nothing moves a payment out of
+// them but a refund, which the stream does not wait for.
+var finalStatuses = map[domain.PaymentStatus]bool{
+	domain.StatusCaptured: true,
+	domain.StatusCanceled: true,
+	domain.StatusFailed:   true,
+	domain.StatusRefund:   true,
+}
+
+// PaymentServer serves paymentv1.PaymentService over the same use case as
+// PaymentHandler, validating requests with the same rules.
+type PaymentServer struct {
+	paymentv1.UnimplementedPaymentServiceServer
+	Usecase *application.PaymentUseCase
+	// WatchInterval is how often WatchPayment reloads the payment.
+	WatchInterval time.Duration
+}
+
+func NewPaymentServer(usecase *application.PaymentUseCase) *PaymentServer {
+	return &PaymentServer{Usecase: usecase, WatchInterval: DefaultWatchInterval}
+}
+
+func (s *PaymentServer) CreatePayment(ctx context.Context, req *paymentv1.CreatePaymentRequest) (*paymentv1.Payment, error) {
+	dto := dtos.AddPaymentDto{
+		Amount:        req.GetAmount(),
+		Currency:      req.GetCurrency(),
+		Email:         req.GetEmail(),
+		PaymentMethod: req.GetPaymentMethod(),
+		FxQuoteID:     req.GetFxQuoteId(),
+	}
+	if err := validate(&dto); err != nil {
+		return nil, err
+	}
+
+	payment, err := s.Usecase.CreatePayment(ctx, application.PaymentInput{
+		Amount:         dto.Amount,
+		Currency:       dto.Currency,
+		Email:          dto.Email,
+		PaymentMethod:  dto.PaymentMethod,
+		FxQuoteID:      dto.FxQuoteID,
+		IP:             clientip.FromContext(ctx),
+		IdempotencyKey: idempotencyKey(ctx),
+	})
+	if errors.Is(err, application.ErrAlreadyProcessed) {
+		return ToPaymentMessage(payment), nil
+	}
+	if err != nil {
+		return nil, middleware.GRPCError(err)
+	}
+	return ToPaymentMessage(payment), nil
+}
+
+func (s *PaymentServer) GetPayment(ctx context.Context, req *paymentv1.GetPaymentRequest) (*paymentv1.Payment, error) {
+	id, err := identify(req.GetPaymentId())
+	if err != nil {
+		return nil, err
+	}
+	payment, err := s.Usecase.FindPaymentByID(ctx, id)
+	if err != nil {
+		return nil, middleware.GRPCError(err)
+	}
+	return ToPaymentMessage(payment), nil
+}
+
+func (s *PaymentServer) ListPayments(ctx context.Context, req *paymentv1.ListPaymentsRequest) (*paymentv1.ListPaymentsResponse, error) {
+	var query dtos.ListPaymentsDto
+	if req.GetExpiringBefore() != nil {
+		query.ExpiringBefore = req.GetExpiringBefore().AsTime()
+	}
+
+	payments, err := s.Usecase.ListPayments(ctx, query)
+	if err != nil {
+		return nil, middleware.GRPCError(err)
+	}
+
+	resp := &paymentv1.ListPaymentsResponse{Payments: make([]*paymentv1.Payment, 0, len(payments))}
+	for _, p := range payments {
+		resp.Payments = append(resp.Payments, ToPaymentMessage(p))
+	}
+	return resp, nil
+}
+
+func (s *PaymentServer) Capture(ctx context.Context, req *paymentv1.CaptureRequest) (*paymentv1.Payment, error) {
+	return s.operate(ctx, req.GetPaymentId(), s.Usecase.Capture)
+}
+
+func (s *PaymentServer) Cancel(ctx context.Context, req *paymentv1.CancelRequest) (*paymentv1.Payment, error) {
+	return s.operate(ctx, req.GetPaymentId(), s.Usecase.Cancel)
+}
+
+func (s *PaymentServer) Refund(ctx context.Context, req *paymentv1.RefundRequest) (*paymentv1.Payment, error) {
+	refund := dtos.PaymentRefundDto{Amount: req.GetAmount()}
+	if err := validate(&refund); err != nil {
+		return nil, err
+	}
+	return s.operate(ctx, req.GetPaymentId(), func(ctx context.Context, id dtos.IdentifyPaymentDto) (*domain.Payment, error) {
+		return s.Usecase.Refund(ctx, id, refund)
+	})
+}
+
+func (s *PaymentServer) operate(ctx context.Context, paymentID string, fn func(context.Context, dtos.IdentifyPaymentDto) (*domain.Payment, error)) (*paymentv1.Payment, error) {
+	id, err := identify(paymentID)
+	if err != nil {
+		return nil, err
+	}
+	payment, err := fn(ctx, id)
+	if err != nil {
+		return nil, middleware.GRPCError(err)
+	}
+	return ToPaymentMessage(payment), nil
+}
+
+// WatchPayment polls the payment every WatchInterval and sends it whenever
+// it changed, until it reaches a final status or the caller goes away.
+func (s *PaymentServer) WatchPayment(req *paymentv1.WatchPaymentRequest, stream grpc.ServerStreamingServer[paymentv1.Payment]) error {
+	id, err := identify(req.GetPaymentId())
+	if err != nil {
+		return err
+	}
+
+	ctx := stream.Context()
+	ticker := time.NewTicker(s.WatchInterval)
+	defer ticker.Stop()
+
+	var sent time.Time
+	var sentStatus domain.PaymentStatus
+	for {
+		payment, err := s.Usecase.FindPaymentByID(ctx, id)
+		if err != nil {
+			return middleware.GRPCError(err)
+		}
+		if sent.IsZero() || !payment.UpdatedAt.Equal(sent) || payment.Status != sentStatus {
+			if err := stream.Send(ToPaymentMessage(payment)); err != nil {
+				return err
+			}
+			sent, sentStatus = payment.UpdatedAt, payment.Status
+		}
+		if finalStatuses[payment.Status] {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// idempotencyKey reads the Idempotency-Key the HTTP API takes as a header
+// from the call's metadata.
+func idempotencyKey(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(IdempotencyKeyHeader); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func identify(paymentID string) (dtos.IdentifyPaymentDto, error) {
+	id := dtos.IdentifyPaymentDto{PaymentID: paymentID}
+	return id, validate(&id)
+}
+
+// validate applies the binding rules the HTTP handlers bind requests with.
+func validate(dto any) error {
+	if err := binding.Validator.ValidateStruct(dto); err != nil {
+		return middleware.GRPCError(apperror.Validation(err))
+	}
+	return nil
+}
+
+func ToPaymentMessage(p *domain.Payment) *paymentv1.Payment {
+	msg := &paymentv1.Payment{
+		Id:             p.ID,
+		MerchantId:     p.MerchantID,
+		Amount:         p.Amount,
+		Currency:       p.Currency,
+		DisplayAmount:  formatAmount(p.Amount, p.Currency),
+		Status:         string(p.Status),
+		Email:          p.Email,
+		StripeId:       p.StripeID,
+		PaymentMethod:  p.PaymentMethod,
+		IdempotencyKey: p.IdempotencyKey,
+		CreatedAt:      timestamppb.New(p.CreatedAt),
+		UpdatedAt:      timestamppb.New(p.UpdatedAt),
+	}
+	if p.SettlementCurrency != "" {
+		msg.Settlement = &paymentv1.Settlement{
+			Amount:        p.SettlementAmount,
+			Currency:      p.SettlementCurrency,
+			DisplayAmount: formatAmount(p.SettlementAmount, p.SettlementCurrency),
+			FxRate:        p.FxRate,
+			FxQuoteId:     p.FxQuoteID,
+		}
+	}
+	if p.AuthorizationExpiresAt != nil {
+		msg.AuthorizationExpiresAt = timestamppb.New(*p.AuthorizationExpiresAt)
+	}
+	return msg
+}
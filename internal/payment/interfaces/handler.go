@@ -1,15 +1,23 @@
 package interfaces
 
 import (
+	"context"
+	"errors"
 	"net/http"
-	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/williamkoller/payment-system/internal/middleware"
 	"github.com/williamkoller/payment-system/internal/payment/application"
+	"github.com/williamkoller/payment-system/internal/payment/domain"
 	"github.com/williamkoller/payment-system/internal/payment/dtos"
+	"github.com/williamkoller/payment-system/pkg/apperror"
 )
 
+// IdempotencyKeyHeader carries the client's idempotency key on payment
+// creation. Retries with the same key return the payment the first request
+// created instead of charging again.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
 type PaymentHandler struct {
 	Usecase *application.PaymentUseCase
 }
@@ -23,51 +31,36 @@ func (h *PaymentHandler) CreatePayment(c *gin.Context) {
 	var dto dtos.AddPaymentDto
 
 	if err := c.ShouldBindJSON(&dto); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		middleware.Problem(c, apperror.Validation(err))
 		return
 	}
 
-	payment, err := h.Usecase.CreatePayment(application.PaymentInput{
-		Amount:        dto.Amount,
-		Currency:      dto.Currency,
-		Email:         dto.Email,
-		PaymentMethod: dto.PaymentMethod,
+	payment, err := h.Usecase.CreatePayment(c.Request.Context(), application.PaymentInput{
+		Amount:         dto.Amount,
+		Currency:       dto.Currency,
+		Email:          dto.Email,
+		PaymentMethod:  dto.PaymentMethod,
+		FxQuoteID:      dto.FxQuoteID,
+		IP:             c.ClientIP(),
+		IdempotencyKey: c.GetHeader(IdempotencyKeyHeader),
 	})
 
+	if errors.Is(err, application.ErrAlreadyProcessed) {
+		log.Infow("Payment already processed, returning existing transaction", "id", payment.ID)
+		c.JSON(http.StatusOK, ToPaymentResponse(payment))
+		return
+	}
+
 	if err != nil {
-		status := ""
-		if payment != nil {
-			status = string(payment.Status)
-		}
-
-		var httpCode int
-		var message string
-
-		switch {
-		case strings.Contains(err.Error(), "already processed"):
-			httpCode = http.StatusOK
-			message = "Payment already processed — returning existing transaction"
-		case strings.Contains(err.Error(), "already exists"):
-			httpCode = http.StatusConflict
-			message = "Payment with this idempotency key already exists"
-		case strings.Contains(err.Error(), "stripe payment failed"):
-			httpCode = http.StatusBadGateway
-			message = "Payment creation failed due to Stripe error"
-		default:
-			httpCode = http.StatusInternalServerError
-			message = "Unexpected error while creating payment"
-		}
-
-		log.Errorw("Error creating payment", "err", err.Error(), "status", status)
-		c.JSON(httpCode, gin.H{
-			"error":   err.Error(),
-			"status":  status,
-			"message": message,
-		})
+		middleware.Problem(c, err, paymentExtensions(payment))
 		return
 	}
 
 	log.Infow("Created payment", "id", payment.ID, "status", payment.Status)
+	if payment.Status == domain.StatusReview {
+		c.JSON(http.StatusAccepted, ToPaymentResponse(payment))
+		return
+	}
 	c.JSON(http.StatusCreated, ToPaymentResponse(payment))
 }
 
@@ -75,18 +68,13 @@ func (h *PaymentHandler) GetPaymentByID(c *gin.Context) {
 	var uri dtos.IdentifyPaymentDto
 
 	if err := c.ShouldBindUri(&uri); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payment ID"})
+		middleware.Problem(c, apperror.Validation(err))
 		return
 	}
 
-	paymentFound, err := h.Usecase.FindPaymentByID(uri)
+	paymentFound, err := h.Usecase.FindPaymentByID(c.Request.Context(), uri)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-		return
-	}
-
-	if paymentFound == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "payment not found"})
+		middleware.Problem(c, err)
 		return
 	}
 
@@ -96,17 +84,64 @@ func (h *PaymentHandler) GetPaymentByID(c *gin.Context) {
 	c.JSON(http.StatusOK, ToPaymentResponse(paymentFound))
 }
 
+func (h *PaymentHandler) ListPayments(c *gin.Context) {
+	var query dtos.ListPaymentsDto
+	if err := c.ShouldBindQuery(&query); err != nil {
+		middleware.Problem(c, apperror.Validation(err))
+		return
+	}
+
+	payments, err := h.Usecase.ListPayments(c.Request.Context(), query)
+	if err != nil {
+		middleware.Problem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ToPaymentResponses(payments))
+}
+
+func (h *PaymentHandler) GetPaymentTimeline(c *gin.Context) {
+	var uri dtos.IdentifyPaymentDto
+	if err := c.ShouldBindUri(&uri); err != nil {
+		middleware.Problem(c, apperror.Validation(err))
+		return
+	}
+
+	changes, err := h.Usecase.Timeline(c.Request.Context(), uri)
+	if err != nil {
+		middleware.Problem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ToTimelineResponse(changes))
+}
+
+func (h *PaymentHandler) GetFailedAttempts(c *gin.Context) {
+	var uri dtos.IdentifyPaymentDto
+	if err := c.ShouldBindUri(&uri); err != nil {
+		middleware.Problem(c, apperror.Validation(err))
+		return
+	}
+
+	attempts, err := h.Usecase.FailedAttempts(c.Request.Context(), uri)
+	if err != nil {
+		middleware.Problem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ToFailedAttemptResponses(attempts))
+}
+
 func (h *PaymentHandler) CapturePayment(c *gin.Context) {
 	var uri dtos.IdentifyPaymentDto
 	if err := c.ShouldBindUri(&uri); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payment ID"})
+		middleware.Problem(c, apperror.Validation(err))
+		return
 	}
-	log := middleware.FromContext(c)
 	ctx := c.Request.Context()
 	payment, err := h.Usecase.Capture(ctx, uri)
 	if err != nil {
-		log.Errorw("Capture failed", "err", err.Error())
-		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		middleware.Problem(c, err, paymentExtensions(payment))
 		return
 	}
 
@@ -116,14 +151,13 @@ func (h *PaymentHandler) CapturePayment(c *gin.Context) {
 func (h *PaymentHandler) CancelPayment(c *gin.Context) {
 	var uri dtos.IdentifyPaymentDto
 	if err := c.ShouldBindUri(&uri); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payment ID"})
+		middleware.Problem(c, apperror.Validation(err))
+		return
 	}
-	log := middleware.FromContext(c)
 	ctx := c.Request.Context()
 	payment, err := h.Usecase.Cancel(ctx, uri)
 	if err != nil {
-		log.Errorw("Cancel failed", "err", err.Error())
-		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		middleware.Problem(c, err, paymentExtensions(payment))
 		return
 	}
 
@@ -136,13 +170,13 @@ func (h *PaymentHandler) RefundPayment(c *gin.Context) {
 
 	var uri dtos.IdentifyPaymentDto
 	if err := c.ShouldBindUri(&uri); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payment id"})
+		middleware.Problem(c, apperror.Validation(err))
 		return
 	}
 
 	var pr dtos.PaymentRefundDto
 	if err := c.ShouldBindJSON(&pr); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid amount"})
+		middleware.Problem(c, apperror.Validation(err))
 		return
 	}
 
@@ -150,10 +184,45 @@ func (h *PaymentHandler) RefundPayment(c *gin.Context) {
 
 	payment, err := h.Usecase.Refund(ctx, uri, pr)
 	if err != nil {
-		log.Errorw("Refund failed", "err", err.Error())
-		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		middleware.Problem(c, err, paymentExtensions(payment))
+		return
+	}
+
+	c.JSON(http.StatusOK, ToPaymentResponse(payment))
+}
+
+func (h *PaymentHandler) ApproveReview(c *gin.Context) {
+	h.review(c, "approve", h.Usecase.ApproveReview)
+}
+
+func (h *PaymentHandler) RejectReview(c *gin.Context) {
+	h.review(c, "reject", h.Usecase.RejectReview)
+}
+
+func (h *PaymentHandler) review(c *gin.Context, action string, fn func(context.Context, dtos.IdentifyPaymentDto) (*domain.Payment, error)) {
+	log := middleware.FromContext(c)
+
+	var uri dtos.IdentifyPaymentDto
+	if err := c.ShouldBindUri(&uri); err != nil {
+		middleware.Problem(c, apperror.Validation(err))
+		return
+	}
+
+	payment, err := fn(c.Request.Context(), uri)
+	if err != nil {
+		middleware.Problem(c, err, paymentExtensions(payment))
 		return
 	}
 
+	log.Infow("Reviewed payment", "id", payment.ID, "action", action)
 	c.JSON(http.StatusOK, ToPaymentResponse(payment))
 }
+
+// paymentExtensions adds the payment a failed operation left behind to the
+// problem body, so clients can tell e.g. a declined payment's id.
+func paymentExtensions(payment *domain.Payment) gin.H {
+	if payment == nil {
+		return nil
+	}
+	return gin.H{"payment_id": payment.ID, "payment_status": payment.Status}
+}
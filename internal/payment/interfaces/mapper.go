@@ -1,35 +1,136 @@
 package interfaces
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/williamkoller/payment-system/internal/payment/domain"
+	"github.com/williamkoller/payment-system/pkg/money"
 )
 
+type SettlementResponse struct {
+	Amount        int64  `json:"amount"`
+	Currency      string `json:"currency"`
+	DisplayAmount string `json:"display_amount"`
+	FxRate        string `json:"fx_rate"`
+	FxQuoteID     string `json:"fx_quote_id,omitempty"`
+}
+
 type PaymentResponse struct {
 	ID             string               `json:"id"`
+	MerchantID     string               `json:"merchant_id,omitempty"`
 	Amount         int64                `json:"amount"`
 	Currency       string               `json:"currency"`
+	DisplayAmount  string               `json:"display_amount"`
 	Status         domain.PaymentStatus `json:"status"`
 	Email          string               `json:"email"`
 	StripeID       string               `json:"stripe_id"`
 	PaymentMethod  string               `json:"payment_method"`
 	IdempotencyKey string               `json:"idempotency_key"`
-	CreatedAt      time.Time            `json:"created_at"`
-	UpdatedAt      time.Time            `json:"updated_at"`
+	// Settlement is left out for payments stored before settlement amounts
+	// were recorded.
+	Settlement *SettlementResponse `json:"settlement,omitempty"`
+	// AuthorizationExpiresAt is only present while the payment holds an
+	// uncaptured authorization.
+	AuthorizationExpiresAt *time.Time `json:"authorization_expires_at,omitempty"`
+	CreatedAt              time.Time  `json:"created_at"`
+	UpdatedAt              time.Time  `json:"updated_at"`
 }
 
 func ToPaymentResponse(p *domain.Payment) PaymentResponse {
 	return PaymentResponse{
-		ID:             p.ID,
-		Amount:         p.Amount,
-		Currency:       p.Currency,
-		Status:         p.Status,
-		Email:          p.Email,
-		StripeID:       p.StripeID,
-		PaymentMethod:  p.PaymentMethod,
-		IdempotencyKey: p.IdempotencyKey,
-		CreatedAt:      p.CreatedAt,
-		UpdatedAt:      p.UpdatedAt,
+		ID:                     p.ID,
+		MerchantID:             p.MerchantID,
+		Amount:                 p.Amount,
+		Currency:               p.Currency,
+		DisplayAmount:          formatAmount(p.Amount, p.Currency),
+		Status:                 p.Status,
+		Email:                  p.Email,
+		StripeID:               p.StripeID,
+		PaymentMethod:          p.PaymentMethod,
+		IdempotencyKey:         p.IdempotencyKey,
+		Settlement:             toSettlementResponse(p),
+		AuthorizationExpiresAt: p.AuthorizationExpiresAt,
+		CreatedAt:              p.CreatedAt,
+		UpdatedAt:              p.UpdatedAt,
+	}
+}
+
+func toSettlementResponse(p *domain.Payment) *SettlementResponse {
+	if p.SettlementCurrency == "" {
+		return nil
+	}
+	return &SettlementResponse{
+		Amount:        p.SettlementAmount,
+		Currency:      p.SettlementCurrency,
+		DisplayAmount: formatAmount(p.SettlementAmount, p.SettlementCurrency),
+		FxRate:        p.FxRate,
+		FxQuoteID:     p.FxQuoteID,
+	}
+}
+
+func ToPaymentResponses(ps []*domain.Payment) []PaymentResponse {
+	responses := make([]PaymentResponse, 0, len(ps))
+	for _, p := range ps {
+		responses = append(responses, ToPaymentResponse(p))
+	}
+	return responses
+}
+
+// StatusChangeResponse is one entry of a payment's timeline. From is empty
+// for the change that created the payment.
+type StatusChangeResponse struct {
+	From      domain.PaymentStatus `json:"from"`
+	To        domain.PaymentStatus `json:"to"`
+	Source    domain.ChangeSource  `json:"source"`
+	Reason    string               `json:"reason,omitempty"`
+	ErrorCode string               `json:"error_code,omitempty"`
+	At        time.Time            `json:"at"`
+}
+
+func ToTimelineResponse(changes []*domain.StatusChange) []StatusChangeResponse {
+	responses := make([]StatusChangeResponse, 0, len(changes))
+	for _, c := range changes {
+		responses = append(responses, ToStatusChangeResponse(c))
+	}
+	return responses
+}
+
+func ToStatusChangeResponse(c *domain.StatusChange) StatusChangeResponse {
+	return StatusChangeResponse{
+		From:      c.FromStatus,
+		To:        c.ToStatus,
+		Source:    c.Source,
+		Reason:    c.Reason,
+		ErrorCode: c.ErrorCode,
+		At:        c.CreatedAt,
+	}
+}
+
+type FailedAttemptResponse struct {
+	Operation string    `json:"operation"`
+	ErrorCode string    `json:"error_code"`
+	Message   string    `json:"message"`
+	At        time.Time `json:"at"`
+}
+
+func ToFailedAttemptResponses(attempts []*domain.FailedAttempt) []FailedAttemptResponse {
+	responses := make([]FailedAttemptResponse, 0, len(attempts))
+	for _, a := range attempts {
+		responses = append(responses, FailedAttemptResponse{
+			Operation: a.Operation,
+			ErrorCode: a.ErrorCode,
+			Message:   a.Message,
+			At:        a.CreatedAt,
+		})
+	}
+	return responses
+}
+
+func formatAmount(amount int64, currency string) string {
+	m, err := money.New(amount, currency)
+	if err != nil {
+		return fmt.Sprintf("%d %s", amount, currency)
 	}
+	return m.Format()
 }
@@ -0,0 +1,44 @@
+package interfaces_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/williamkoller/payment-system/internal/payment/domain"
+	"github.com/williamkoller/payment-system/internal/payment/interfaces"
+)
+
+func TestToPaymentResponse_DisplayAmount(t *testing.T) {
+	tests := []struct {
+		amount   int64
+		currency string
+		want     string
+	}{
+		{1050, "USD", "$10.50"},
+		{1050, "JPY", "¥1050"},
+		{1050, "XYZ", "1050 XYZ"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.currency, func(t *testing.T) {
+			response := interfaces.ToPaymentResponse(&domain.Payment{ID: "pay_1", Amount: tt.amount, Currency: tt.currency})
+			assert.Equal(t, tt.want, response.DisplayAmount)
+		})
+	}
+}
+
+func TestToPaymentResponse_Settlement(t *testing.T) {
+	payment := &domain.Payment{ID: "pay_1", Amount: 1000, Currency: "USD"}
+	payment.SetSettlement(5500, "BRL", "5.50", "quote_1")
+
+	response := interfaces.ToPaymentResponse(payment)
+	assert.Equal(t, &interfaces.SettlementResponse{
+		Amount:        5500,
+		Currency:      "BRL",
+		DisplayAmount: "R$55.00",
+		FxRate:        "5.50",
+		FxQuoteID:     "quote_1",
+	}, response.Settlement)
+
+	legacy := interfaces.ToPaymentResponse(&domain.Payment{ID: "pay_2", Amount: 1000, Currency: "USD"})
+	assert.Nil(t, legacy.Settlement, "payments without a settlement currency leave it out")
+}
@@ -1 +1,18 @@
 package middleware
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/williamkoller/payment-system/internal/metrics"
+)
+
+// Metrics records request count and latency per route template, so
+// /payments/:payment_id is a single series regardless of the id.
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		metrics.ObserveHTTPRequest(c.FullPath(), c.Request.Method, c.Writer.Status(), time.Since(start))
+	}
+}
@@ -0,0 +1,307 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/williamkoller/payment-system/internal/payment/domain"
+	"github.com/williamkoller/payment-system/pkg/pii"
+	"github.com/williamkoller/payment-system/pkg/tenant"
+	"github.com/williamkoller/payment-system/pkg/ulid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DefaultSnapshotEvery is how many events a payment accumulates between
+// snapshots when none is configured.
+const DefaultSnapshotEvery = 100
+
+type paymentSnapshot struct {
+	PaymentID  string
+	MerchantID string
+	Version    int64
+	State      string
+	CreatedAt  time.Time
+}
+
+// EventPaymentRepository stores payments as streams of events in
+// payment_events, the source of truth FindByID rebuilds payments from.
+// Every write also projects the payment into the payments table, which
+// serves the other queries.
+//
+// Payments stored before the event store was enabled have no stream until
+// their next update, which starts it from the projected row. Until then
+// FindByID reads them from the projection.
+type EventPaymentRepository struct {
+	db     *gorm.DB
+	cipher *pii.Cipher
+	// SnapshotEvery is how many events a payment accumulates between
+	// snapshots of its state, which bound how many events a read replays.
+	SnapshotEvery int64
+}
+
+func NewEventPaymentRepository(db *gorm.DB, snapshotEvery int64) *EventPaymentRepository {
+	if snapshotEvery <= 0 {
+		snapshotEvery = DefaultSnapshotEvery
+	}
+	return &EventPaymentRepository{db: db, cipher: pii.Default(), SnapshotEvery: snapshotEvery}
+}
+
+// WithCipher returns a copy of r that encrypts with c instead.
+func (r *EventPaymentRepository) WithCipher(c *pii.Cipher) *EventPaymentRepository {
+	return &EventPaymentRepository{db: r.db, cipher: c, SnapshotEvery: r.SnapshotEvery}
+}
+
+// projection returns the CRUD repository over db, which is the
+// transaction writes run in.
+func (r *EventPaymentRepository) projection(db *gorm.DB) *PaymentRepositoryImpl {
+	return &PaymentRepositoryImpl{db: db, cipher: r.cipher}
+}
+
+// sealed returns a copy of p holding its email encrypted, as events and
+// snapshots store it.
+func (r *EventPaymentRepository) sealed(p *domain.Payment) (*domain.Payment, error) {
+	email, err := r.cipher.Encrypt(p.Email)
+	if err != nil {
+		return nil, err
+	}
+	p.EmailIndex = r.cipher.BlindIndex(p.Email)
+	sealed := *p
+	sealed.Email = email
+	return &sealed, nil
+}
+
+func (r *EventPaymentRepository) Save(ctx context.Context, payment *domain.Payment) (*domain.Payment, error) {
+	sealed, err := r.sealed(payment)
+	if err != nil {
+		return nil, err
+	}
+	events, err := domain.EventsBetween(nil, sealed)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockStream(tx, payment.ID); err != nil {
+			return err
+		}
+		if err := r.append(tx, nil, 0, events); err != nil {
+			return err
+		}
+		_, err := r.projection(tx).Save(ctx, payment)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
+
+func (r *EventPaymentRepository) Update(ctx context.Context, p *domain.Payment) error {
+	sealed, err := r.sealed(p)
+	if err != nil {
+		return err
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockStream(tx, p.ID); err != nil {
+			return err
+		}
+
+		current, version, err := r.load(ctx, tx, p.ID)
+		if errors.Is(err, domain.ErrNoEvents) {
+			current, version, err = r.startStream(ctx, tx, p.ID)
+		}
+		if err != nil {
+			return err
+		}
+
+		events, err := domain.EventsBetween(current, sealed)
+		if err != nil {
+			return err
+		}
+		if err := r.append(tx, current, version, events); err != nil {
+			return err
+		}
+		return r.projection(tx).Update(ctx, p)
+	})
+}
+
+// startStream gives a payment stored before the event store was enabled a
+// stream, starting from its projected row.
+func (r *EventPaymentRepository) startStream(ctx context.Context, tx *gorm.DB, id string) (*domain.Payment, int64, error) {
+	var row domain.Payment
+	if err := tx.Scopes(tenant.Scope(ctx)).First(&row, "id = ?", id).Error; err != nil {
+		return nil, 0, err
+	}
+
+	events, err := domain.EventsBetween(nil, &row)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := r.append(tx, nil, 0, events); err != nil {
+		return nil, 0, err
+	}
+	return &row, int64(len(events)), nil
+}
+
+// append stores events after version, the version current was rebuilt at,
+// and snapshots the result whenever the stream crosses a multiple of
+// SnapshotEvery.
+func (r *EventPaymentRepository) append(tx *gorm.DB, current *domain.Payment, version int64, events []*domain.PaymentEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	for i, e := range events {
+		e.ID = ulid.NewULID()
+		e.Version = version + int64(i) + 1
+	}
+	if err := tx.Table("payment_events").Create(events).Error; err != nil {
+		return err
+	}
+
+	last := events[len(events)-1].Version
+	if version/r.SnapshotEvery == last/r.SnapshotEvery {
+		return nil
+	}
+
+	p, err := domain.Replay(current, events)
+	if err != nil {
+		return err
+	}
+	state, err := domain.EncodeSnapshot(p)
+	if err != nil {
+		return err
+	}
+	snapshot := paymentSnapshot{PaymentID: p.ID, MerchantID: p.MerchantID, Version: last, State: state, CreatedAt: time.Now()}
+	return tx.Table("payment_snapshots").
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "payment_id"}}, UpdateAll: true}).
+		Create(&snapshot).Error
+}
+
+// load rebuilds a payment from its latest snapshot and the events after
+// it, returning it with its email still sealed and the version it is at.
+func (r *EventPaymentRepository) load(ctx context.Context, db *gorm.DB, id string) (*domain.Payment, int64, error) {
+	var snapshots []paymentSnapshot
+	if err := db.WithContext(ctx).Scopes(tenant.Scope(ctx)).Table("payment_snapshots").Where("payment_id = ?", id).Limit(1).Find(&snapshots).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var current *domain.Payment
+	var version int64
+	if len(snapshots) > 0 {
+		var err error
+		if current, err = domain.DecodeSnapshot(snapshots[0].State); err != nil {
+			return nil, 0, err
+		}
+		version = snapshots[0].Version
+	}
+
+	var events []*domain.PaymentEvent
+	err := db.WithContext(ctx).Scopes(tenant.Scope(ctx)).Table("payment_events").
+		Where("payment_id = ? AND version > ?", id, version).
+		Order("version").
+		Find(&events).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	p, err := domain.Replay(current, events)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(events) > 0 {
+		version = events[len(events)-1].Version
+	}
+	return p, version, nil
+}
+
+// FindByID rebuilds the payment from its events, falling back to the
+// projection for payments that have no stream yet.
+func (r *EventPaymentRepository) FindByID(ctx context.Context, id string) (*domain.Payment, error) {
+	p, _, err := r.load(ctx, r.db, id)
+	if errors.Is(err, domain.ErrNoEvents) {
+		return r.projection(r.db).FindByID(ctx, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	email, err := r.cipher.Decrypt(p.Email)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decrypt email of payment %s: %w", p.ID, err)
+	}
+	p.Email = email
+	return p, nil
+}
+
+// FindEvents returns a payment's stream, oldest first.
+func (r *EventPaymentRepository) FindEvents(ctx context.Context, id string) ([]*domain.PaymentEvent, error) {
+	var events []*domain.PaymentEvent
+	err := r.db.WithContext(ctx).Scopes(tenant.Scope(ctx)).Table("payment_events").
+		Where("payment_id = ?", id).
+		Order("version").
+		Find(&events).Error
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// Remove deletes the payment's stream and snapshot along with its row.
+func (r *EventPaymentRepository) Remove(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Scopes(tenant.Scope(ctx)).Table("payment_snapshots").Where("payment_id = ?", id).Delete(&paymentSnapshot{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Scopes(tenant.Scope(ctx)).Table("payment_events").Where("payment_id = ?", id).Delete(&domain.PaymentEvent{}).Error; err != nil {
+			return err
+		}
+		return r.projection(tx).Remove(ctx, id)
+	})
+}
+
+func (r *EventPaymentRepository) FindAll(ctx context.Context) ([]*domain.Payment, error) {
+	return r.projection(r.db).FindAll(ctx)
+}
+
+func (r *EventPaymentRepository) FindByStripeID(ctx context.Context, stripeID string) (*domain.Payment, error) {
+	return r.projection(r.db).FindByStripeID(ctx, stripeID)
+}
+
+func (r *EventPaymentRepository) FindByIdempotencyKey(ctx context.Context, idempotencyKey string) (*domain.Payment, error) {
+	return r.projection(r.db).FindByIdempotencyKey(ctx, idempotencyKey)
+}
+
+func (r *EventPaymentRepository) FindCreatedBetween(ctx context.Context, gatewayAccount string, from, to time.Time) ([]*domain.Payment, error) {
+	return r.projection(r.db).FindCreatedBetween(ctx, gatewayAccount, from, to)
+}
+
+func (r *EventPaymentRepository) List(ctx context.Context, filter domain.PaymentFilter) ([]*domain.Payment, error) {
+	return r.projection(r.db).List(ctx, filter)
+}
+
+func (r *EventPaymentRepository) Each(ctx context.Context, filter domain.PaymentFilter, fn func(*domain.Payment) error) error {
+	return r.projection(r.db).Each(ctx, filter, fn)
+}
+
+func (r *EventPaymentRepository) FindStatusChanges(ctx context.Context, paymentID string) ([]*domain.StatusChange, error) {
+	return r.projection(r.db).FindStatusChanges(ctx, paymentID)
+}
+
+func (r *EventPaymentRepository) SaveFailedAttempt(ctx context.Context, attempt *domain.FailedAttempt) error {
+	return r.projection(r.db).SaveFailedAttempt(ctx, attempt)
+}
+
+func (r *EventPaymentRepository) FindFailedAttempts(ctx context.Context, paymentID string) ([]*domain.FailedAttempt, error) {
+	return r.projection(r.db).FindFailedAttempts(ctx, paymentID)
+}
+
+// lockStream serializes writers of one payment's stream until the
+// transaction ends, so versions are assigned without gaps or races.
+func lockStream(tx *gorm.DB, paymentID string) error {
+	return tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "payment:"+paymentID).Error
+}
@@ -0,0 +1,200 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"path/filepath"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/williamkoller/payment-system/internal/payment/domain"
+	"github.com/williamkoller/payment-system/internal/payment/repository"
+	"github.com/williamkoller/payment-system/pkg/pii"
+)
+
+var eventColumns = []string{"id", "payment_id", "merchant_id", "version", "type", "data", "created_at"}
+
+// eventRows returns events as payment_events rows, versioned from 1.
+func eventRows(events []*domain.PaymentEvent) *sqlmock.Rows {
+	rows := sqlmock.NewRows(eventColumns)
+	for i, e := range events {
+		rows.AddRow("ev_"+e.PaymentID, e.PaymentID, e.MerchantID, int64(i+1), string(e.Type), e.Data, e.CreatedAt)
+	}
+	return rows
+}
+
+// insertedEvents returns the arguments of an INSERT of n events at the
+// versions from first on, capturing each event's data.
+func insertedEvents(first int64, data ...*capture) []driver.Value {
+	var args []driver.Value
+	for i, d := range data {
+		args = append(args, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), first+int64(i), sqlmock.AnyArg(), d, sqlmock.AnyArg())
+	}
+	return args
+}
+
+// storedPayment returns a pending payment and the events of its stream.
+func storedPayment(t *testing.T) (*domain.Payment, []*domain.PaymentEvent) {
+	t.Helper()
+	p, err := domain.NewPayment("pay_1", 1000, "USD", "user@example.com", "card")
+	require.NoError(t, err)
+	events, err := domain.EventsBetween(nil, p)
+	require.NoError(t, err)
+	p.StatusChangesSaved()
+	return p, events
+}
+
+func TestEventPaymentRepository_Update_SnapshotsEverySnapshotEvery(t *testing.T) {
+	tests := []struct {
+		name          string
+		snapshotEvery int64
+		snapshot      bool
+	}{
+		// The stream is at version 2; completing the payment appends
+		// versions 3 and 4.
+		{"below the threshold", 10, false},
+		{"crossing the threshold", 4, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gormDB, mock := setupMockDB(t)
+			repo := repository.NewEventPaymentRepository(gormDB, tt.snapshotEvery)
+
+			p, events := storedPayment(t)
+			require.Len(t, events, 2)
+			p.Complete()
+
+			mock.ExpectBegin()
+			mock.ExpectExec(`SELECT pg_advisory_xact_lock`).
+				WithArgs("payment:" + p.ID).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectQuery(`SELECT \* FROM "payment_snapshots"`).
+				WillReturnRows(sqlmock.NewRows([]string{"payment_id"}))
+			mock.ExpectQuery(`SELECT \* FROM "payment_events" WHERE payment_id = \$1 AND version > \$2 ORDER BY version`).
+				WithArgs(p.ID, int64(0)).
+				WillReturnRows(eventRows(events))
+			mock.ExpectExec(`INSERT INTO "payment_events"`).
+				WithArgs(insertedEvents(3, &capture{}, &capture{})...).
+				WillReturnResult(sqlmock.NewResult(2, 2))
+			if tt.snapshot {
+				mock.ExpectExec(`INSERT INTO "payment_snapshots" .* ON CONFLICT \("payment_id"\) DO UPDATE`).
+					WithArgs(p.ID, p.MerchantID, int64(4), sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+			}
+			mock.ExpectExec(`UPDATE "payments"`).
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.ExpectExec(`INSERT INTO "payment_status_history"`).
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.ExpectCommit()
+
+			require.NoError(t, repo.Update(context.Background(), p))
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestEventPaymentRepository_Update_StartsStreamOfLegacyPayment(t *testing.T) {
+	gormDB, mock := setupMockDB(t)
+	repo := repository.NewEventPaymentRepository(gormDB, 100)
+
+	p := &domain.Payment{ID: "pay_1", Amount: 1000, Currency: "USD", Status: domain.StatusPending, Email: "user@example.com", PaymentMethod: "card"}
+	p.Complete()
+
+	created := &capture{}
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT pg_advisory_xact_lock`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT \* FROM "payment_snapshots"`).
+		WillReturnRows(sqlmock.NewRows([]string{"payment_id"}))
+	mock.ExpectQuery(`SELECT \* FROM "payment_events"`).
+		WillReturnRows(sqlmock.NewRows(eventColumns))
+	mock.ExpectQuery(`SELECT \* FROM "payments" WHERE id = \$1`).
+		WithArgs(p.ID, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "amount", "currency", "status", "email", "payment_method"}).
+			AddRow(p.ID, 1000, "USD", domain.StatusPending, "user@example.com", "card"))
+	mock.ExpectExec(`INSERT INTO "payment_events"`).
+		WithArgs(insertedEvents(1, created)...).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO "payment_events"`).
+		WithArgs(insertedEvents(2, &capture{}, &capture{})...).
+		WillReturnResult(sqlmock.NewResult(2, 2))
+	mock.ExpectExec(`UPDATE "payments"`).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO "payment_status_history"`).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	require.NoError(t, repo.Update(context.Background(), p))
+	assert.Contains(t, created.value, `"status":"PENDING"`, "the stream starts from the stored row")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEventPaymentRepository_FindByID_FallsBackToProjection(t *testing.T) {
+	gormDB, mock := setupMockDB(t)
+	repo := repository.NewEventPaymentRepository(gormDB, 100)
+
+	mock.ExpectQuery(`SELECT \* FROM "payment_snapshots"`).
+		WillReturnRows(sqlmock.NewRows([]string{"payment_id"}))
+	mock.ExpectQuery(`SELECT \* FROM "payment_events"`).
+		WillReturnRows(sqlmock.NewRows(eventColumns))
+	mock.ExpectQuery(`SELECT \* FROM "payments" WHERE id = \$1`).
+		WithArgs("pay_1", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status", "email"}).
+			AddRow("pay_1", domain.StatusCompleted, "user@example.com"))
+
+	found, err := repo.FindByID(context.Background(), "pay_1")
+	require.NoError(t, err)
+	assert.Equal(t, domain.StatusCompleted, found.Status)
+	assert.Equal(t, "user@example.com", found.Email)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEventPaymentRepository_SealsEmail(t *testing.T) {
+	gormDB, mock := setupMockDB(t)
+	path := filepath.Join(t.TempDir(), "pii-keys.json")
+	require.NoError(t, pii.AddKeyFile(path, "k1"))
+	keys, err := pii.LoadKeyFile(path)
+	require.NoError(t, err)
+	repo := repository.NewEventPaymentRepository(gormDB, 2).WithCipher(pii.New(keys))
+
+	p, err := domain.NewPayment("pay_1", 1000, "USD", "user@example.com", "card")
+	require.NoError(t, err)
+
+	created, changed, snapshot := &capture{}, &capture{}, &capture{}
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT pg_advisory_xact_lock`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`INSERT INTO "payment_events"`).
+		WithArgs(insertedEvents(1, created, changed)...).
+		WillReturnResult(sqlmock.NewResult(2, 2))
+	mock.ExpectExec(`INSERT INTO "payment_snapshots"`).
+		WithArgs(p.ID, p.MerchantID, int64(2), snapshot, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO "payments"`).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO "payment_status_history"`).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	_, err = repo.Save(context.Background(), p)
+	require.NoError(t, err)
+	assert.Equal(t, "user@example.com", p.Email, "the caller's payment keeps the plain text")
+	for _, stored := range []string{created.value, snapshot.value} {
+		assert.Contains(t, stored, `"email":"pii:k1:`)
+		assert.NotContains(t, stored, "example.com")
+	}
+
+	mock.ExpectQuery(`SELECT \* FROM "payment_snapshots"`).
+		WillReturnRows(sqlmock.NewRows([]string{"payment_id", "merchant_id", "version", "state"}).
+			AddRow(p.ID, p.MerchantID, 2, snapshot.value))
+	mock.ExpectQuery(`SELECT \* FROM "payment_events"`).
+		WithArgs(p.ID, int64(2)).
+		WillReturnRows(sqlmock.NewRows(eventColumns))
+
+	found, err := repo.FindByID(context.Background(), p.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "user@example.com", found.Email)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -1,75 +1,268 @@
 package repository
 
 import (
+	"context"
+	"fmt"
+	"time"
+
 	"github.com/williamkoller/payment-system/internal/payment/domain"
+	"github.com/williamkoller/payment-system/pkg/pii"
+	"github.com/williamkoller/payment-system/pkg/tenant"
+	"github.com/williamkoller/payment-system/pkg/ulid"
 	"gorm.io/gorm"
 )
 
 type PaymentRepository interface {
-	Save(payment domain.Payment) (*domain.Payment, error)
-	FindByID(id string) (*domain.Payment, error)
-	FindAll() ([]*domain.Payment, error)
-	Remove(id string) error
-	Update(p *domain.Payment) error
-	FindByStripeID(stripeID string) (*domain.Payment, error)
-	FindByIdempotencyKey(idempotencyKey string) (*domain.Payment, error)
+	Save(ctx context.Context, payment *domain.Payment) (*domain.Payment, error)
+	FindByID(ctx context.Context, id string) (*domain.Payment, error)
+	FindAll(ctx context.Context) ([]*domain.Payment, error)
+	Remove(ctx context.Context, id string) error
+	Update(ctx context.Context, p *domain.Payment) error
+	FindByStripeID(ctx context.Context, stripeID string) (*domain.Payment, error)
+	FindByIdempotencyKey(ctx context.Context, idempotencyKey string) (*domain.Payment, error)
+	FindCreatedBetween(ctx context.Context, gatewayAccount string, from, to time.Time) ([]*domain.Payment, error)
+	List(ctx context.Context, filter domain.PaymentFilter) ([]*domain.Payment, error)
+	Each(ctx context.Context, filter domain.PaymentFilter, fn func(*domain.Payment) error) error
+	FindStatusChanges(ctx context.Context, paymentID string) ([]*domain.StatusChange, error)
+	SaveFailedAttempt(ctx context.Context, attempt *domain.FailedAttempt) error
+	FindFailedAttempts(ctx context.Context, paymentID string) ([]*domain.FailedAttempt, error)
 }
 
 type PaymentRepositoryImpl struct {
-	db *gorm.DB
+	db     *gorm.DB
+	cipher *pii.Cipher
 }
 
+// NewPaymentRepository stores emails encrypted with the process-wide PII
+// cipher.
 func NewPaymentRepository(db *gorm.DB) *PaymentRepositoryImpl {
-	return &PaymentRepositoryImpl{db: db}
+	return &PaymentRepositoryImpl{db: db, cipher: pii.Default()}
+}
+
+// WithCipher returns a copy of r that encrypts with c instead.
+func (r *PaymentRepositoryImpl) WithCipher(c *pii.Cipher) *PaymentRepositoryImpl {
+	return &PaymentRepositoryImpl{db: r.db, cipher: c}
 }
 
-func (r *PaymentRepositoryImpl) Save(payment *domain.Payment) (*domain.Payment, error) {
-	if err := r.db.Create(&payment).Error; err != nil {
+// scoped restricts reads and writes to the merchant ctx acts for, so one
+// merchant's key can never reach another merchant's payments.
+func (r *PaymentRepositoryImpl) scoped(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).Scopes(tenant.Scope(ctx))
+}
+
+// Save stores a copy of payment with its email encrypted; payment itself
+// keeps the plain text.
+func (r *PaymentRepositoryImpl) Save(ctx context.Context, payment *domain.Payment) (*domain.Payment, error) {
+	sealed := *payment
+	email, err := r.cipher.Encrypt(payment.Email)
+	if err != nil {
+		return nil, err
+	}
+	sealed.Email = email
+	sealed.EmailIndex = r.cipher.BlindIndex(payment.Email)
+
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&sealed).Error; err != nil {
+			return err
+		}
+		return saveStatusChanges(ctx, tx, payment)
+	})
+	if err != nil {
 		return nil, err
 	}
+	payment.EmailIndex = sealed.EmailIndex
+	payment.StatusChangesSaved()
 	return payment, nil
 }
 
-func (r *PaymentRepositoryImpl) FindByID(id string) (*domain.Payment, error) {
+func (r *PaymentRepositoryImpl) open(payments ...*domain.Payment) error {
+	for _, p := range payments {
+		email, err := r.cipher.Decrypt(p.Email)
+		if err != nil {
+			return fmt.Errorf("cannot decrypt email of payment %s: %w", p.ID, err)
+		}
+		p.Email = email
+	}
+	return nil
+}
+
+func (r *PaymentRepositoryImpl) FindByID(ctx context.Context, id string) (*domain.Payment, error) {
 	var payment domain.Payment
-	if err := r.db.First(&payment, "id = ?", id).Error; err != nil {
+	if err := r.scoped(ctx).First(&payment, "id = ?", id).Error; err != nil {
 		return nil, err
 	}
-	return &payment, nil
+	return &payment, r.open(&payment)
 }
 
-func (r *PaymentRepositoryImpl) FindAll() ([]*domain.Payment, error) {
+func (r *PaymentRepositoryImpl) FindAll(ctx context.Context) ([]*domain.Payment, error) {
 	var payments []*domain.Payment
-	if err := r.db.Find(&payments).Error; err != nil {
+	if err := r.scoped(ctx).Find(&payments).Error; err != nil {
 		return nil, err
 	}
-	return payments, nil
+	return payments, r.open(payments...)
+}
+
+func (r *PaymentRepositoryImpl) Remove(ctx context.Context, id string) error {
+	return r.scoped(ctx).Delete(&domain.Payment{}, "id = ?", id).Error
+}
+
+// Update leaves the email alone: it never changes after creation, and
+// rewriting it would re-encrypt it on every status change.
+func (r *PaymentRepositoryImpl) Update(ctx context.Context, p *domain.Payment) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Scopes(tenant.Scope(ctx)).Model(&domain.Payment{}).
+			Select("StripeID", "Amount", "Currency", "Status", "PaymentMethod", "IdempotencyKey",
+				"SettlementAmount", "SettlementCurrency", "FxRate", "FxQuoteID",
+				"AuthorizationExpiresAt", "ExpiryAlertedAt").
+			Where("id = ?", p.ID).
+			Updates(p).Error
+		if err != nil {
+			return err
+		}
+		return saveStatusChanges(ctx, tx, p)
+	})
+	if err != nil {
+		return err
+	}
+	p.StatusChangesSaved()
+	return nil
+}
+
+// saveStatusChanges appends the payment's pending status changes to its
+// timeline, attributed to the source ctx carries.
+func saveStatusChanges(ctx context.Context, tx *gorm.DB, p *domain.Payment) error {
+	changes := p.PendingStatusChanges()
+	if len(changes) == 0 {
+		return nil
+	}
+
+	source := domain.SourceFromContext(ctx)
+	for _, c := range changes {
+		if c.ID == "" {
+			c.ID = ulid.NewULID()
+		}
+		c.PaymentID = p.ID
+		c.MerchantID = p.MerchantID
+		c.Source = source
+	}
+	return tx.Table("payment_status_history").Create(changes).Error
 }
 
-func (r *PaymentRepositoryImpl) Remove(id string) error {
-	return r.db.Delete(&domain.Payment{}, "id = ?", id).Error
+func (r *PaymentRepositoryImpl) SaveFailedAttempt(ctx context.Context, attempt *domain.FailedAttempt) error {
+	return r.db.WithContext(ctx).Table("payment_failed_attempts").Create(attempt).Error
 }
 
-func (r *PaymentRepositoryImpl) Update(p *domain.Payment) error {
-	return r.db.Model(&domain.Payment{}).
-		Select("StripeID", "Amount", "Currency", "Status", "Email", "PaymentMethod", "IdempotencyKey").
-		Where("id = ?", p.ID).
-		Updates(p).Error
+// FindFailedAttempts returns the failed operations on a payment, oldest
+// first.
+func (r *PaymentRepositoryImpl) FindFailedAttempts(ctx context.Context, paymentID string) ([]*domain.FailedAttempt, error) {
+	var attempts []*domain.FailedAttempt
+	err := r.scoped(ctx).Table("payment_failed_attempts").
+		Where("payment_id = ?", paymentID).
+		Order("created_at, id").
+		Find(&attempts).Error
+	if err != nil {
+		return nil, err
+	}
+	return attempts, nil
 }
 
-func (r *PaymentRepositoryImpl) FindByStripeID(stripeID string) (*domain.Payment, error) {
+// FindStatusChanges returns the timeline of a payment, oldest first.
+func (r *PaymentRepositoryImpl) FindStatusChanges(ctx context.Context, paymentID string) ([]*domain.StatusChange, error) {
+	var changes []*domain.StatusChange
+	err := r.scoped(ctx).Table("payment_status_history").
+		Where("payment_id = ?", paymentID).
+		Order("created_at, id").
+		Find(&changes).Error
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+func (r *PaymentRepositoryImpl) FindByStripeID(ctx context.Context, stripeID string) (*domain.Payment, error) {
 	var payment domain.Payment
-	if err := r.db.First(&payment, "stripe_id = ?", stripeID).Error; err != nil {
+	if err := r.scoped(ctx).First(&payment, "stripe_id = ?", stripeID).Error; err != nil {
 		return nil, err
 	}
-	return &payment, nil
+	return &payment, r.open(&payment)
 }
 
-func (r *PaymentRepositoryImpl) FindByIdempotencyKey(idempotencyKey string) (*domain.Payment, error) {
+func (r *PaymentRepositoryImpl) FindByIdempotencyKey(ctx context.Context, idempotencyKey string) (*domain.Payment, error) {
 	var payment domain.Payment
-	if err := r.db.First(&payment, "idempotency_key = ?", idempotencyKey).Error; err != nil {
+	if err := r.scoped(ctx).First(&payment, "idempotency_key = ?", idempotencyKey).Error; err != nil {
+		return nil, err
+	}
+
+	return &payment, r.open(&payment)
+}
+
+// FindCreatedBetween returns the payments authorized on gatewayAccount ("" for
+// the platform account) created in [from, to).
+func (r *PaymentRepositoryImpl) FindCreatedBetween(ctx context.Context, gatewayAccount string, from, to time.Time) ([]*domain.Payment, error) {
+	var payments []*domain.Payment
+	err := r.scoped(ctx).
+		Where("gateway_account = ? AND created_at >= ? AND created_at < ?", gatewayAccount, from, to).
+		Find(&payments).Error
+	if err != nil {
 		return nil, err
 	}
+	return payments, r.open(payments...)
+}
 
-	return &payment, nil
+func (r *PaymentRepositoryImpl) List(ctx context.Context, filter domain.PaymentFilter) ([]*domain.Payment, error) {
+	var payments []*domain.Payment
+	if err := r.listQuery(ctx, filter).Find(&payments).Error; err != nil {
+		return nil, err
+	}
+	return payments, r.open(payments...)
+}
+
+// Each calls fn with the payments List would return, in the same order,
+// reading them off the open query one row at a time instead of loading
+// them all. It stops at the first error fn returns.
+func (r *PaymentRepositoryImpl) Each(ctx context.Context, filter domain.PaymentFilter, fn func(*domain.Payment) error) error {
+	rows, err := r.listQuery(ctx, filter).Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var payment domain.Payment
+		if err := r.db.ScanRows(rows, &payment); err != nil {
+			return err
+		}
+		if err := r.open(&payment); err != nil {
+			return err
+		}
+		if err := fn(&payment); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (r *PaymentRepositoryImpl) listQuery(ctx context.Context, filter domain.PaymentFilter) *gorm.DB {
+	query := r.scoped(ctx).Model(&domain.Payment{})
+
+	// Pages continue after the row of the StartingAfter payment in the
+	// list's order, which the id makes total.
+	if filter.ExpiringBefore != nil {
+		query = query.
+			Where("status = ?", domain.StatusCompleted).
+			Where("authorization_expires_at < ?", *filter.ExpiringBefore).
+			Order("authorization_expires_at, id")
+		if filter.StartingAfter != "" {
+			query = query.Where("(authorization_expires_at, id) > (SELECT authorization_expires_at, id FROM payments WHERE id = ?)", filter.StartingAfter)
+		}
+	} else {
+		query = query.Order("created_at DESC, id DESC")
+		if filter.StartingAfter != "" {
+			query = query.Where("(created_at, id) < (SELECT created_at, id FROM payments WHERE id = ?)", filter.StartingAfter)
+		}
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	return query
 }
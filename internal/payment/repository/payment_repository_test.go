@@ -1,13 +1,20 @@
 package repository_test
 
 import (
+	"context"
+	"database/sql/driver"
 	"errors"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/williamkoller/payment-system/internal/payment/domain"
 	"github.com/williamkoller/payment-system/internal/payment/repository"
+	"github.com/williamkoller/payment-system/pkg/auth"
+	"github.com/williamkoller/payment-system/pkg/pii"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
@@ -46,19 +53,22 @@ func TestPaymentRepository_Create_Success(t *testing.T) {
 	mock.ExpectBegin()
 	mock.ExpectExec(`INSERT INTO "payments"`).
 		WithArgs(
-			p.ID, p.StripeID, p.Amount, p.Currency, p.Status,
-			p.Email, p.PaymentMethod, p.IdempotencyKey,
+			p.ID, p.MerchantID, p.GatewayAccount, p.StripeID, p.Amount, p.Currency, p.Status,
+			p.Email, sqlmock.AnyArg(), p.PaymentMethod, p.IdempotencyKey,
+			p.SettlementAmount, p.SettlementCurrency, p.FxRate, p.FxQuoteID,
+			nil, nil,
 			sqlmock.AnyArg(),
 			sqlmock.AnyArg(),
 		).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectCommit()
 
-	created, err := repo.Save(p)
+	created, err := repo.Save(context.Background(), p)
 	assert.NoError(t, err)
 	assert.NotNil(t, created)
 	assert.Equal(t, p.ID, created.ID)
 	assert.Equal(t, p.Email, created.Email)
+	assert.NotEmpty(t, created.EmailIndex)
 
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("there were unmet expectations: %v", err)
@@ -80,7 +90,7 @@ func TestPaymentRepository_FindByID_Success(t *testing.T) {
 		WithArgs(id, sqlmock.AnyArg()).
 		WillReturnRows(rows)
 
-	found, err := repo.FindByID(id)
+	found, err := repo.FindByID(context.Background(), id)
 	assert.NoError(t, err)
 	assert.NotNil(t, found)
 	assert.Equal(t, id, found.ID)
@@ -91,6 +101,23 @@ func TestPaymentRepository_FindByID_Success(t *testing.T) {
 	}
 }
 
+func TestPaymentRepository_FindByID_ScopedToMerchant(t *testing.T) {
+	gormDB, mock := setupMockDB(t)
+	repo := repository.NewPaymentRepository(gormDB)
+
+	mock.ExpectQuery(`SELECT \* FROM "payments" WHERE id = \$1 AND merchant_id = \$2`).
+		WithArgs("id‑123", "merchant_b", sqlmock.AnyArg()).
+		WillReturnError(gorm.ErrRecordNotFound)
+
+	ctx := auth.NewContext(context.Background(), &auth.Principal{MerchantID: "merchant_b"})
+	_, err := repo.FindByID(ctx, "id‑123")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet expectations: %v", err)
+	}
+}
+
 func TestPaymentRepository_FindAll_Success(t *testing.T) {
 	gormDB, mock := setupMockDB(t)
 	repo := repository.NewPaymentRepository(gormDB)
@@ -107,7 +134,7 @@ func TestPaymentRepository_FindAll_Success(t *testing.T) {
 	mock.ExpectQuery(`SELECT \* FROM "payments"`).
 		WillReturnRows(rows)
 
-	found, err := repo.FindAll()
+	found, err := repo.FindAll(context.Background())
 	assert.NoError(t, err)
 	assert.NotNil(t, found)
 	assert.Len(t, found, 1)
@@ -142,16 +169,21 @@ func TestPaymentRepository_Update_Success(t *testing.T) {
 			p.Amount,
 			p.Currency,
 			p.Status,
-			p.Email,
 			p.PaymentMethod,
 			p.IdempotencyKey,
+			p.SettlementAmount,
+			p.SettlementCurrency,
+			p.FxRate,
+			p.FxQuoteID,
+			nil,
+			nil,
 			sqlmock.AnyArg(),
 			p.ID,
 		).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectCommit()
 
-	err := repo.Update(p)
+	err := repo.Update(context.Background(), p)
 	assert.NoError(t, err)
 
 	if err := mock.ExpectationsWereMet(); err != nil {
@@ -171,7 +203,7 @@ func TestPaymentRepository_Remove_Success(t *testing.T) {
 		WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectCommit()
 
-	err := repo.Remove(id)
+	err := repo.Remove(context.Background(), id)
 	assert.NoError(t, err)
 
 	if err := mock.ExpectationsWereMet(); err != nil {
@@ -189,11 +221,11 @@ func TestPaymentRepository_Create_Error(t *testing.T) {
 
 	mock.ExpectBegin()
 	mock.ExpectExec(`INSERT INTO "payments"`).
-		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
 		WillReturnError(errors.New("db insert error"))
 	mock.ExpectRollback()
 
-	created, err := repo.Save(p)
+	created, err := repo.Save(context.Background(), p)
 	assert.Error(t, err)
 	assert.Nil(t, created)
 }
@@ -208,7 +240,7 @@ func TestPaymentRepository_FindByID_Error(t *testing.T) {
 		WithArgs(id, sqlmock.AnyArg()).
 		WillReturnError(errors.New("db find error"))
 
-	found, err := repo.FindByID(id)
+	found, err := repo.FindByID(context.Background(), id)
 	assert.Error(t, err)
 	assert.Nil(t, found)
 }
@@ -220,7 +252,128 @@ func TestPaymentRepository_FindAll_Error(t *testing.T) {
 	mock.ExpectQuery(`SELECT \* FROM "payments"`).
 		WillReturnError(errors.New("db find all error"))
 
-	found, err := repo.FindAll()
+	found, err := repo.FindAll(context.Background())
 	assert.Error(t, err)
 	assert.Nil(t, found)
 }
+
+func TestPaymentRepository_List_ExpiringBefore(t *testing.T) {
+	gormDB, mock := setupMockDB(t)
+	repo := repository.NewPaymentRepository(gormDB)
+
+	before := time.Now().Add(24 * time.Hour)
+	rows := sqlmock.NewRows([]string{
+		"id", "stripe_id", "amount", "currency", "status", "email", "payment_method", "idempotency_key", "authorization_expires_at",
+	}).AddRow(
+		"id‑123", "stripe_1", 1000, "USD", "COMPLETED", "user@example.com", "card", "idem‑123", before.Add(-time.Hour),
+	)
+
+	mock.ExpectQuery(`SELECT \* FROM "payments" WHERE status = \$1 AND authorization_expires_at < \$2 ORDER BY authorization_expires_at`).
+		WithArgs(domain.StatusCompleted, before).
+		WillReturnRows(rows)
+
+	found, err := repo.List(context.Background(), domain.PaymentFilter{ExpiringBefore: &before})
+	assert.NoError(t, err)
+	assert.Len(t, found, 1)
+	assert.NotNil(t, found[0].AuthorizationExpiresAt)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestPaymentRepository_List_Page(t *testing.T) {
+	gormDB, mock := setupMockDB(t)
+	repo := repository.NewPaymentRepository(gormDB)
+
+	rows := sqlmock.NewRows([]string{"id", "amount", "currency", "status"}).
+		AddRow("pay_2", 1000, "USD", "CAPTURED")
+
+	mock.ExpectQuery(`SELECT \* FROM "payments" WHERE \(created_at, id\) < \(SELECT created_at, id FROM payments WHERE id = \$1\) ORDER BY created_at DESC, id DESC LIMIT \$2`).
+		WithArgs("pay_3", 2).
+		WillReturnRows(rows)
+
+	found, err := repo.List(context.Background(), domain.PaymentFilter{Limit: 2, StartingAfter: "pay_3"})
+	assert.NoError(t, err)
+	assert.Len(t, found, 1)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+// capture is a sqlmock argument matcher that records the value it sees.
+type capture struct{ value string }
+
+func (c *capture) Match(v driver.Value) bool {
+	s, ok := v.(string)
+	c.value = s
+	return ok
+}
+
+func TestPaymentRepository_EncryptsEmail(t *testing.T) {
+	gormDB, mock := setupMockDB(t)
+	path := filepath.Join(t.TempDir(), "pii-keys.json")
+	require.NoError(t, pii.AddKeyFile(path, "k1"))
+	keys, err := pii.LoadKeyFile(path)
+	require.NoError(t, err)
+	cipher := pii.New(keys)
+	repo := repository.NewPaymentRepository(gormDB).WithCipher(cipher)
+
+	p := &domain.Payment{ID: "id-123", Amount: 1000, Currency: "USD", Status: "PENDING", Email: "user@example.com", PaymentMethod: "card"}
+
+	stored := &capture{}
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO "payments"`).
+		WithArgs(
+			p.ID, p.MerchantID, p.GatewayAccount, p.StripeID, p.Amount, p.Currency, p.Status,
+			stored, cipher.BlindIndex("user@example.com"), p.PaymentMethod, p.IdempotencyKey,
+			p.SettlementAmount, p.SettlementCurrency, p.FxRate, p.FxQuoteID,
+			nil, nil, sqlmock.AnyArg(), sqlmock.AnyArg(),
+		).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	_, err = repo.Save(context.Background(), p)
+	require.NoError(t, err)
+	assert.Equal(t, "user@example.com", p.Email, "the caller's payment keeps the plain text")
+	assert.Equal(t, "k1", pii.KeyID(stored.value))
+	assert.NotContains(t, stored.value, "example.com")
+
+	mock.ExpectQuery(`SELECT \* FROM "payments"`).
+		WithArgs(p.ID, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email"}).AddRow(p.ID, stored.value))
+
+	found, err := repo.FindByID(context.Background(), p.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "user@example.com", found.Email)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPaymentRepository_Update_RecordsStatusChange(t *testing.T) {
+	gormDB, mock := setupMockDB(t)
+	repo := repository.NewPaymentRepository(gormDB)
+
+	p := &domain.Payment{ID: "id-123", StripeID: "stripe_1", Amount: 1000, Currency: "USD", Status: domain.StatusCompleted, PaymentMethod: "card"}
+	p.Fail()
+	p.Explain("stripe capture failed", "card_declined")
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "payments"`).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO "payment_status_history"`).
+		WithArgs(
+			sqlmock.AnyArg(), p.ID, "",
+			domain.StatusCompleted, domain.StatusFailed, domain.SourceWebhook,
+			"stripe capture failed", "card_declined", sqlmock.AnyArg(),
+		).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	ctx := domain.WithSource(context.Background(), domain.SourceWebhook)
+	require.NoError(t, repo.Update(ctx, p))
+	assert.Empty(t, p.PendingStatusChanges())
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
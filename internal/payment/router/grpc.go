@@ -0,0 +1,50 @@
+package router
+
+import (
+	"github.com/williamkoller/payment-system/config"
+	"github.com/williamkoller/payment-system/internal/middleware"
+	"github.com/williamkoller/payment-system/internal/payment/application"
+	"github.com/williamkoller/payment-system/internal/payment/dtos"
+	"github.com/williamkoller/payment-system/internal/payment/interfaces"
+	"github.com/williamkoller/payment-system/pkg/api/paymentv1"
+	"github.com/williamkoller/payment-system/pkg/auth"
+	"google.golang.org/grpc"
+)
+
+// grpcScopes requires of each gRPC method the scope of the HTTP route it
+// mirrors.
+var grpcScopes = map[string]auth.Scope{
+	paymentv1.PaymentService_CreatePayment_FullMethodName: auth.ScopePaymentsWrite,
+	paymentv1.PaymentService_GetPayment_FullMethodName:    auth.ScopePaymentsRead,
+	paymentv1.PaymentService_ListPayments_FullMethodName:  auth.ScopePaymentsRead,
+	paymentv1.PaymentService_Capture_FullMethodName:       auth.ScopePaymentsWrite,
+	paymentv1.PaymentService_Cancel_FullMethodName:        auth.ScopePaymentsWrite,
+	paymentv1.PaymentService_Refund_FullMethodName:        auth.ScopeRefundsWrite,
+	paymentv1.PaymentService_WatchPayment_FullMethodName:  auth.ScopePaymentsRead,
+}
+
+// NewGRPCServer serves the payment service over gRPC, authenticating calls
+// with authenticator, or as an admin when it is nil (AUTH_ENABLED=false).
+func NewGRPCServer(usecase *application.PaymentUseCase, authenticator middleware.Authenticator, cfg config.GRPCConfiguration, opts ...grpc.ServerOption) *grpc.Server {
+	if err := dtos.RegisterValidations(); err != nil {
+		panic("cannot register payment validations: " + err.Error())
+	}
+
+	logUnary, logStream := middleware.GRPCLogger()
+	authUnary, authStream := middleware.GRPCAnonymous()
+	if authenticator != nil {
+		authUnary, authStream = middleware.GRPCAuth(authenticator, grpcScopes)
+	}
+
+	opts = append(opts,
+		grpc.ChainUnaryInterceptor(logUnary, authUnary),
+		grpc.ChainStreamInterceptor(logStream, authStream),
+	)
+	server := grpc.NewServer(opts...)
+	service := interfaces.NewPaymentServer(usecase)
+	if cfg.WatchInterval > 0 {
+		service.WatchInterval = cfg.WatchInterval
+	}
+	paymentv1.RegisterPaymentServiceServer(server, service)
+	return server
+}
@@ -1,25 +1,72 @@
 package router
 
 import (
+	"fmt"
+
 	"github.com/gin-gonic/gin"
+	"github.com/williamkoller/payment-system/config"
+	"github.com/williamkoller/payment-system/internal/middleware"
 	"github.com/williamkoller/payment-system/internal/payment/application"
+	"github.com/williamkoller/payment-system/internal/payment/dtos"
 	"github.com/williamkoller/payment-system/internal/payment/infra"
 	"github.com/williamkoller/payment-system/internal/payment/interfaces"
 	"github.com/williamkoller/payment-system/internal/payment/repository"
+	ratelimit "github.com/williamkoller/payment-system/internal/ratelimit/domain"
+	"github.com/williamkoller/payment-system/pkg/auth"
 	"gorm.io/gorm"
 )
 
-func SetupRouter(e *gin.Engine, db *gorm.DB) {
-	repo := repository.NewPaymentRepository(db)
+// NewPaymentRepository returns the payment store cfg selects. Every
+// writer of payments must use the same one, or the event store misses
+// their changes.
+func NewPaymentRepository(db *gorm.DB, cfg config.PaymentStoreConfiguration) (repository.PaymentRepository, error) {
+	switch cfg.Store {
+	case "events":
+		return repository.NewEventPaymentRepository(db, int64(cfg.SnapshotEvery)), nil
+	case "crud", "":
+		return repository.NewPaymentRepository(db), nil
+	default:
+		return nil, fmt.Errorf("unknown PAYMENT_STORE: %s", cfg.Store)
+	}
+}
+
+func NewPaymentUseCase(db *gorm.DB, cfg config.PaymentStoreConfiguration) (*application.PaymentUseCase, error) {
+	repo, err := NewPaymentRepository(db, cfg)
+	if err != nil {
+		return nil, err
+	}
 	stripeClient := infra.NewStripeClient()
-	usecase := application.NewPaymentUseCase(repo, stripeClient)
+	return application.NewPaymentUseCase(repo, stripeClient), nil
+}
+
+// SetupRouter mounts the payment routes behind authn, each requiring the
+// scope that matches what it can do and rate limited by its class.
+func SetupRouter(e *gin.Engine, usecase *application.PaymentUseCase, authn gin.HandlerFunc, limits *middleware.RateLimits) {
+	if err := dtos.RegisterValidations(); err != nil {
+		panic("cannot register payment validations: " + err.Error())
+	}
+
 	handler := interfaces.NewPaymentHandler(usecase)
-	payments := e.Group("/payments")
+	read := middleware.RequireScope(auth.ScopePaymentsRead)
+	write := middleware.RequireScope(auth.ScopePaymentsWrite)
+	refund := middleware.RequireScope(auth.ScopeRefundsWrite)
+	review := middleware.RequireScope(auth.ScopeReviewsWrite)
+	readLimit := limits.PerClient(ratelimit.ClassRead)
+	writeLimit := limits.PerClient(ratelimit.ClassWrite)
+
+	payments := e.Group("/payments", limits.PerIP(), authn)
 	{
-		payments.POST("/", handler.CreatePayment)
-		payments.GET("/:payment_id", handler.GetPaymentByID)
-		payments.POST("/:payment_id/capture", handler.CapturePayment)
-		payments.POST("/:payment_id/cancel", handler.CancelPayment)
-		payments.POST("/:payment_id/refund", handler.RefundPayment)
+		payments.POST("/", write, writeLimit, handler.CreatePayment)
+		payments.GET("/", read, readLimit, handler.ListPayments)
+		payments.GET("/export", read, readLimit, handler.ExportPayments)
+		payments.GET("/:payment_id", read, readLimit, handler.GetPaymentByID)
+		payments.GET("/:payment_id/timeline", read, readLimit, handler.GetPaymentTimeline)
+		payments.GET("/:payment_id/attempts", read, readLimit, handler.GetFailedAttempts)
+		payments.GET("/:payment_id/events", read, limits.PerClientStream(ratelimit.ClassRead), handler.StreamEvents)
+		payments.POST("/:payment_id/capture", write, writeLimit, handler.CapturePayment)
+		payments.POST("/:payment_id/cancel", write, writeLimit, handler.CancelPayment)
+		payments.POST("/:payment_id/refund", refund, writeLimit, handler.RefundPayment)
+		payments.POST("/:payment_id/review/approve", review, writeLimit, handler.ApproveReview)
+		payments.POST("/:payment_id/review/reject", review, writeLimit, handler.RejectReview)
 	}
 }
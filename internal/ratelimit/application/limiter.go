@@ -0,0 +1,116 @@
+package application
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/williamkoller/payment-system/internal/ratelimit/domain"
+	"github.com/williamkoller/payment-system/pkg/logger"
+)
+
+// Store keeps token buckets. Take must be atomic per key so that replicas
+// sharing a store share the limit.
+type Store interface {
+	Take(ctx context.Context, key string, limit domain.Limit, now time.Time) (domain.Decision, error)
+}
+
+// Limiter applies the configured limit of each route class to a subject:
+// a client IP, or the merchant behind an API key.
+type Limiter struct {
+	Store  Store
+	Limits map[domain.Class]domain.Limit
+	// Concurrency caps the requests one subject may have in flight on this
+	// replica. Zero disables the cap.
+	Concurrency int
+	Now         func() time.Time
+
+	mu       sync.Mutex
+	inFlight map[string]int
+}
+
+func NewLimiter(store Store, limits map[domain.Class]domain.Limit, concurrency int) *Limiter {
+	return &Limiter{
+		Store:       store,
+		Limits:      limits,
+		Concurrency: concurrency,
+		Now:         time.Now,
+		inFlight:    make(map[string]int),
+	}
+}
+
+// Allow takes a token from subject's bucket for class. ok is false when the
+// class has no limit, in which case the decision is meaningless.
+func (l *Limiter) Allow(ctx context.Context, class domain.Class, subject string) (decision domain.Decision, ok bool, err error) {
+	limit := l.Limits[class]
+	if !limit.Enabled() {
+		return domain.Decision{}, false, nil
+	}
+
+	decision, err = l.Store.Take(ctx, string(class)+":"+subject, limit, l.Now())
+	if err != nil {
+		return domain.Decision{}, false, err
+	}
+	return decision, true, nil
+}
+
+// Acquire reserves one of subject's concurrent request slots. The returned
+// release must be called once the request is done; ok is false when every
+// slot is taken.
+func (l *Limiter) Acquire(subject string) (release func(), ok bool) {
+	if l.Concurrency <= 0 {
+		return func() {}, true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.inFlight[subject] >= l.Concurrency {
+		return nil, false
+	}
+	l.inFlight[subject]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			if l.inFlight[subject]--; l.inFlight[subject] <= 0 {
+				delete(l.inFlight, subject)
+			}
+		})
+	}, true
+}
+
+// IdleDeleter is implemented by stores that need expired buckets removed.
+type IdleDeleter interface {
+	DeleteIdleSince(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Sweep periodically deletes buckets idle for longer than the longest
+// configured window, when the store needs it. It blocks until ctx is done.
+func (l *Limiter) Sweep(ctx context.Context, interval time.Duration) {
+	deleter, ok := l.Store.(IdleDeleter)
+	if !ok {
+		return
+	}
+
+	var idle time.Duration
+	for _, limit := range l.Limits {
+		idle = max(idle, limit.Per)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := deleter.DeleteIdleSince(ctx, l.Now().Add(-idle)); err != nil {
+				logger.Error("cannot delete idle rate limit buckets", "err", err)
+			}
+		}
+	}
+}
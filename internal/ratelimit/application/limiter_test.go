@@ -0,0 +1,93 @@
+package application_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/williamkoller/payment-system/internal/ratelimit/application"
+	"github.com/williamkoller/payment-system/internal/ratelimit/domain"
+	"github.com/williamkoller/payment-system/internal/ratelimit/infra"
+)
+
+func TestLimiter_Allow(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	limiter := application.NewLimiter(infra.NewMemoryStore(), map[domain.Class]domain.Limit{
+		domain.ClassWrite: {Requests: 3, Per: time.Minute},
+	}, 0)
+	limiter.Now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for i := 2; i >= 0; i-- {
+		d, ok, err := limiter.Allow(ctx, domain.ClassWrite, "merchant:m1")
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.True(t, d.Allowed)
+		assert.Equal(t, i, d.Remaining)
+	}
+
+	d, _, err := limiter.Allow(ctx, domain.ClassWrite, "merchant:m1")
+	require.NoError(t, err)
+	assert.False(t, d.Allowed)
+	assert.Equal(t, 20*time.Second, d.RetryAfter)
+	assert.Equal(t, time.Minute, d.Reset)
+
+	now = now.Add(20 * time.Second)
+	d, _, err = limiter.Allow(ctx, domain.ClassWrite, "merchant:m1")
+	require.NoError(t, err)
+	assert.True(t, d.Allowed)
+
+	_, ok, err := limiter.Allow(ctx, domain.ClassRead, "merchant:m1")
+	require.NoError(t, err)
+	assert.False(t, ok, "classes without a limit are not limited")
+}
+
+func TestLimiter_Acquire(t *testing.T) {
+	limiter := application.NewLimiter(infra.NewMemoryStore(), nil, 1)
+
+	release, ok := limiter.Acquire("merchant:m1")
+	require.True(t, ok)
+
+	_, ok = limiter.Acquire("merchant:m1")
+	assert.False(t, ok)
+
+	_, ok = limiter.Acquire("merchant:m2")
+	assert.True(t, ok)
+
+	release()
+	release()
+	_, ok = limiter.Acquire("merchant:m1")
+	assert.True(t, ok)
+	_, ok = limiter.Acquire("merchant:m1")
+	assert.False(t, ok, "releasing twice frees a single slot")
+}
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    domain.Limit
+		wantErr bool
+	}{
+		{"100/s", domain.Limit{Requests: 100, Per: time.Second}, false},
+		{"600/m", domain.Limit{Requests: 600, Per: time.Minute}, false},
+		{"0", domain.Limit{}, false},
+		{"", domain.Limit{}, false},
+		{"10", domain.Limit{}, true},
+		{"10/d", domain.Limit{}, true},
+		{"-1/s", domain.Limit{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := domain.ParseLimit(tt.in)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, domain.ErrInvalidLimit)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
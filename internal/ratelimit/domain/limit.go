@@ -0,0 +1,145 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/williamkoller/payment-system/pkg/auth"
+)
+
+// Class groups routes that share a limit, so cheap reads cannot starve a
+// client's budget for payments.
+type Class string
+
+const (
+	ClassIP    Class = "ip"
+	ClassRead  Class = "read"
+	ClassWrite Class = "write"
+)
+
+// Subject is what per-client limits count a principal against: its
+// merchant, or its key when it has none. It is "" for anonymous callers.
+func Subject(principal *auth.Principal) string {
+	switch {
+	case principal == nil:
+		return ""
+	case principal.MerchantID != "":
+		return "merchant:" + principal.MerchantID
+	case principal.KeyID != "":
+		return "key:" + principal.KeyID
+	default:
+		return ""
+	}
+}
+
+var ErrInvalidLimit = errors.New(`rate limit must look like "<requests>/<s|m|h>"`)
+
+// Limit is a token bucket holding up to Requests tokens and refilling all
+// of them every Per. It allows bursts of Requests and a sustained rate of
+// Requests/Per.
+type Limit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// ParseLimit parses "100/s", "600/m" or "5000/h". "0" or "" disables the
+// limit.
+func ParseLimit(s string) (Limit, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "0" {
+		return Limit{}, nil
+	}
+
+	count, unit, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("%w: %q", ErrInvalidLimit, s)
+	}
+	requests, err := strconv.Atoi(count)
+	if err != nil || requests < 0 {
+		return Limit{}, fmt.Errorf("%w: %q", ErrInvalidLimit, s)
+	}
+
+	var per time.Duration
+	switch unit {
+	case "s":
+		per = time.Second
+	case "m":
+		per = time.Minute
+	case "h":
+		per = time.Hour
+	default:
+		return Limit{}, fmt.Errorf("%w: %q", ErrInvalidLimit, s)
+	}
+
+	return Limit{Requests: requests, Per: per}, nil
+}
+
+func (l Limit) Enabled() bool {
+	return l.Requests > 0 && l.Per > 0
+}
+
+// Policy renders the limit as a RateLimit-Policy value, e.g. "100;w=60".
+func (l Limit) Policy() string {
+	return fmt.Sprintf("%d;w=%d", l.Requests, int(l.Per.Seconds()))
+}
+
+func (l Limit) String() string {
+	return fmt.Sprintf("%d/%s", l.Requests, l.Per)
+}
+
+// perToken is how long the bucket takes to refill one token.
+func (l Limit) perToken() time.Duration {
+	return l.Per / time.Duration(l.Requests)
+}
+
+// Decision is the outcome of taking one token from a bucket.
+type Decision struct {
+	Allowed   bool
+	Limit     Limit
+	Remaining int
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is how long until the next token, set when not Allowed.
+	RetryAfter time.Duration
+}
+
+// Bucket is the stored state of one token bucket.
+type Bucket struct {
+	Key       string
+	Tokens    float64
+	UpdatedAt time.Time
+}
+
+// NewBucket returns a full bucket.
+func NewBucket(key string, limit Limit, now time.Time) *Bucket {
+	return &Bucket{Key: key, Tokens: float64(limit.Requests), UpdatedAt: now}
+}
+
+// Take refills the bucket for the time elapsed since it was last updated,
+// then takes one token if there is one.
+func (b *Bucket) Take(limit Limit, now time.Time) Decision {
+	capacity := float64(limit.Requests)
+	if elapsed := now.Sub(b.UpdatedAt); elapsed > 0 {
+		b.Tokens = math.Min(capacity, b.Tokens+float64(elapsed)/float64(limit.perToken()))
+	}
+	if b.Tokens > capacity {
+		// The limit was lowered since the bucket was stored.
+		b.Tokens = capacity
+	}
+	b.UpdatedAt = now
+
+	d := Decision{Limit: limit}
+	if b.Tokens >= 1 {
+		b.Tokens--
+		d.Allowed = true
+	} else {
+		d.RetryAfter = time.Duration((1 - b.Tokens) * float64(limit.perToken()))
+	}
+	d.Remaining = int(b.Tokens)
+	d.Reset = time.Duration((capacity - b.Tokens) * float64(limit.perToken()))
+	return d
+}
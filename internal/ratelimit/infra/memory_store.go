@@ -0,0 +1,59 @@
+package infra
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/williamkoller/payment-system/internal/ratelimit/domain"
+)
+
+const sweepInterval = time.Minute
+
+type memoryEntry struct {
+	bucket *domain.Bucket
+	per    time.Duration
+}
+
+// MemoryStore keeps buckets in process. Each replica enforces its own
+// limits, so use the Postgres store when running more than one.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryEntry
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*memoryEntry)}
+}
+
+func (s *MemoryStore) Take(_ context.Context, key string, limit domain.Limit, now time.Time) (domain.Decision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+
+	entry, ok := s.buckets[key]
+	if !ok {
+		entry = &memoryEntry{bucket: domain.NewBucket(key, limit, now)}
+		s.buckets[key] = entry
+	}
+	entry.per = limit.Per
+
+	return entry.bucket.Take(limit, now), nil
+}
+
+// sweep drops buckets idle long enough to have refilled completely; they
+// are indistinguishable from new ones.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for key, entry := range s.buckets {
+		if now.Sub(entry.bucket.UpdatedAt) >= entry.per {
+			delete(s.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/williamkoller/payment-system/internal/ratelimit/domain"
+	"gorm.io/gorm"
+)
+
+// BucketRepositoryImpl stores buckets in Postgres so every replica draws
+// from the same ones. Each take locks its bucket row for the duration of a
+// short transaction.
+type BucketRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewBucketRepository(db *gorm.DB) *BucketRepositoryImpl {
+	return &BucketRepositoryImpl{db: db}
+}
+
+func (r *BucketRepositoryImpl) Take(ctx context.Context, key string, limit domain.Limit, now time.Time) (domain.Decision, error) {
+	var decision domain.Decision
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(
+			"INSERT INTO rate_limit_buckets (key, tokens, updated_at) VALUES (?, ?, ?) ON CONFLICT (key) DO NOTHING",
+			key, float64(limit.Requests), now,
+		).Error
+		if err != nil {
+			return err
+		}
+
+		var bucket domain.Bucket
+		err = tx.Raw("SELECT key, tokens, updated_at FROM rate_limit_buckets WHERE key = ? FOR UPDATE", key).
+			Scan(&bucket).Error
+		if err != nil {
+			return err
+		}
+
+		decision = bucket.Take(limit, now)
+
+		return tx.Exec(
+			"UPDATE rate_limit_buckets SET tokens = ?, updated_at = ? WHERE key = ?",
+			bucket.Tokens, bucket.UpdatedAt, key,
+		).Error
+	})
+
+	return decision, err
+}
+
+// DeleteIdleSince removes buckets untouched since before. They would be
+// full again by now, so dropping them changes no decision.
+func (r *BucketRepositoryImpl) DeleteIdleSince(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Exec("DELETE FROM rate_limit_buckets WHERE updated_at < ?", before)
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,46 @@
+package router
+
+import (
+	"fmt"
+
+	"github.com/williamkoller/payment-system/config"
+	"github.com/williamkoller/payment-system/internal/ratelimit/application"
+	"github.com/williamkoller/payment-system/internal/ratelimit/domain"
+	"github.com/williamkoller/payment-system/internal/ratelimit/infra"
+	"github.com/williamkoller/payment-system/internal/ratelimit/repository"
+	"gorm.io/gorm"
+)
+
+func NewStore(db *gorm.DB, cfg config.RateLimitConfiguration) (application.Store, error) {
+	switch cfg.Store {
+	case "postgres":
+		return repository.NewBucketRepository(db), nil
+	case "memory":
+		return infra.NewMemoryStore(), nil
+	default:
+		return nil, fmt.Errorf("unknown RATE_LIMIT_STORE: %s", cfg.Store)
+	}
+}
+
+func NewLimiter(db *gorm.DB, cfg config.RateLimitConfiguration) (*application.Limiter, error) {
+	store, err := NewStore(db, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	specs := map[domain.Class]struct{ env, value string }{
+		domain.ClassIP:    {"RATE_LIMIT_IP", cfg.IP},
+		domain.ClassRead:  {"RATE_LIMIT_READ", cfg.Read},
+		domain.ClassWrite: {"RATE_LIMIT_WRITE", cfg.Write},
+	}
+	limits := make(map[domain.Class]domain.Limit, len(specs))
+	for class, spec := range specs {
+		limit, err := domain.ParseLimit(spec.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", spec.env, err)
+		}
+		limits[class] = limit
+	}
+
+	return application.NewLimiter(store, limits, cfg.Concurrency), nil
+}
@@ -0,0 +1,342 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	paymentApplication "github.com/williamkoller/payment-system/internal/payment/application"
+	paymentDomain "github.com/williamkoller/payment-system/internal/payment/domain"
+	"github.com/williamkoller/payment-system/internal/reconciliation/domain"
+	"github.com/williamkoller/payment-system/pkg/apperror"
+	"github.com/williamkoller/payment-system/pkg/logger"
+	"github.com/williamkoller/payment-system/pkg/ulid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidRange = apperror.New(apperror.KindValidation, "invalid_range", "reconciliation range start must be before its end")
+	ErrRunNotFound  = apperror.New(apperror.KindNotFound, "reconciliation_run_not_found", "reconciliation run not found")
+)
+
+type PaymentRepository interface {
+	FindByStripeID(ctx context.Context, stripeID string) (*paymentDomain.Payment, error)
+	FindCreatedBetween(ctx context.Context, gatewayAccount string, from, to time.Time) ([]*paymentDomain.Payment, error)
+	Update(ctx context.Context, payment *paymentDomain.Payment) error
+}
+
+type ReconciliationRepository interface {
+	SaveRun(ctx context.Context, run *domain.ReconciliationRun) error
+	UpdateRun(ctx context.Context, run *domain.ReconciliationRun) error
+	SaveDiscrepancy(ctx context.Context, d *domain.ReconciliationDiscrepancy) error
+	FindRunByID(ctx context.Context, id string) (*domain.ReconciliationRun, error)
+	FindDiscrepanciesByRunID(ctx context.Context, runID string) ([]*domain.ReconciliationDiscrepancy, error)
+	// LastScheduledRun returns the successful scheduled run that reached
+	// furthest, or gorm.ErrRecordNotFound.
+	LastScheduledRun(ctx context.Context) (*domain.ReconciliationRun, error)
+}
+
+// GatewayLister pages through the gateway's payment intents and balance
+// transactions created inside [from, to) and hands each of them to fn.
+type GatewayLister interface {
+	ListIntents(ctx context.Context, from, to time.Time, fn func(domain.GatewayIntent) error) error
+	ListBalanceTransactions(ctx context.Context, from, to time.Time, fn func(domain.GatewayBalanceTransaction) error) error
+}
+
+// AccountSource lists the merchants with their own Stripe account, whose
+// payments are reconciled against that account rather than the platform's.
+type AccountSource interface {
+	GatewayAccounts(ctx context.Context) ([]string, error)
+	Lister(ctx context.Context, account string) (GatewayLister, error)
+}
+
+type Reconciler struct {
+	Payments PaymentRepository
+	Runs     ReconciliationRepository
+	// Gateway lists the platform account's intents and balance
+	// transactions.
+	Gateway GatewayLister
+	// Accounts is optional; without it only the platform account is
+	// reconciled.
+	Accounts AccountSource
+	// Events is optional; it is told about the status changes repairs
+	// make.
+	Events *paymentApplication.StatusBroker
+}
+
+type RunReport struct {
+	Run           *domain.ReconciliationRun
+	Discrepancies []*domain.ReconciliationDiscrepancy
+}
+
+func NewReconciler(payments PaymentRepository, runs ReconciliationRepository, gateway GatewayLister) *Reconciler {
+	return &Reconciler{Payments: payments, Runs: runs, Gateway: gateway}
+}
+
+func (r *Reconciler) Run(ctx context.Context, from, to time.Time) (*domain.ReconciliationRun, error) {
+	if !from.Before(to) {
+		return nil, ErrInvalidRange
+	}
+	return r.run(ctx, domain.NewReconciliationRun(ulid.NewULID(), from, to))
+}
+
+// CatchUp runs the scheduled reconciliation up to until, one window at a
+// time, starting where the last successful scheduled run ended, or a window
+// before until when there is none. It stops at the first failed run, so the
+// next call retries that window.
+func (r *Reconciler) CatchUp(ctx context.Context, until time.Time, window time.Duration) error {
+	from := until.Add(-window)
+	last, err := r.Runs.LastScheduledRun(ctx)
+	switch {
+	case err == nil:
+		from = last.RangeTo
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return fmt.Errorf("cannot find the last reconciliation run: %w", err)
+	}
+
+	for from.Before(until) {
+		to := from.Add(window)
+		if to.After(until) {
+			to = until
+		}
+
+		run := domain.NewReconciliationRun(ulid.NewULID(), from, to)
+		run.Scheduled = true
+		if _, err := r.run(ctx, run); err != nil {
+			return err
+		}
+		from = to
+	}
+	return nil
+}
+
+func (r *Reconciler) run(ctx context.Context, run *domain.ReconciliationRun) (*domain.ReconciliationRun, error) {
+	if err := r.Runs.SaveRun(ctx, run); err != nil {
+		return nil, fmt.Errorf("cannot save reconciliation run: %w", err)
+	}
+
+	err := r.reconcile(ctx, run)
+	run.Finish(err)
+
+	if updateErr := r.Runs.UpdateRun(ctx, run); updateErr != nil {
+		return run, fmt.Errorf("cannot update reconciliation run: %w", updateErr)
+	}
+
+	logger.Info("reconciliation run finished",
+		"run_id", run.ID,
+		"status", run.Status,
+		"checked", run.Checked,
+		"repaired", run.Repaired,
+		"discrepancies", run.Discrepancies,
+	)
+
+	if err != nil {
+		return run, fmt.Errorf("reconciliation run failed: %w", err)
+	}
+
+	return run, nil
+}
+
+func (r *Reconciler) FindRun(ctx context.Context, id string) (*RunReport, error) {
+	run, err := r.Runs.FindRunByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrRunNotFound.Wrap(err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	discrepancies, err := r.Runs.FindDiscrepanciesByRunID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RunReport{Run: run, Discrepancies: discrepancies}, nil
+}
+
+func (r *Reconciler) reconcile(ctx context.Context, run *domain.ReconciliationRun) error {
+	if err := r.reconcileAccount(ctx, run, "", r.Gateway); err != nil {
+		return err
+	}
+	if r.Accounts == nil {
+		return nil
+	}
+
+	accounts, err := r.Accounts.GatewayAccounts(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot list merchant gateway accounts: %w", err)
+	}
+	for _, account := range accounts {
+		lister, err := r.Accounts.Lister(ctx, account)
+		if err != nil {
+			return fmt.Errorf("cannot reach gateway account %s: %w", account, err)
+		}
+		if err := r.reconcileAccount(ctx, run, account, lister); err != nil {
+			return fmt.Errorf("gateway account %s: %w", account, err)
+		}
+	}
+	return nil
+}
+
+func (r *Reconciler) reconcileAccount(ctx context.Context, run *domain.ReconciliationRun, account string, gateway GatewayLister) error {
+	seen := make(map[string]struct{})
+
+	err := gateway.ListIntents(ctx, run.RangeFrom, run.RangeTo, func(intent domain.GatewayIntent) error {
+		seen[intent.StripeID] = struct{}{}
+		run.Checked++
+		return r.compare(ctx, run, intent)
+	})
+	if err != nil {
+		return err
+	}
+
+	payments, err := r.Payments.FindCreatedBetween(ctx, account, run.RangeFrom, run.RangeTo)
+	if err != nil {
+		return err
+	}
+
+	for _, payment := range payments {
+		if payment.StripeID == "" {
+			continue
+		}
+		if _, ok := seen[payment.StripeID]; ok {
+			continue
+		}
+		err := r.record(ctx, run, &domain.ReconciliationDiscrepancy{
+			Kind:        domain.KindMissingGateway,
+			PaymentID:   payment.ID,
+			StripeID:    payment.StripeID,
+			LocalStatus: string(payment.Status),
+			LocalAmount: payment.Amount,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Intents are checked first, so a payment they repaired already matches
+	// its balance transactions.
+	return gateway.ListBalanceTransactions(ctx, run.RangeFrom, run.RangeTo, func(txn domain.GatewayBalanceTransaction) error {
+		run.Checked++
+		return r.compareTransaction(ctx, run, txn)
+	})
+}
+
+func (r *Reconciler) compare(ctx context.Context, run *domain.ReconciliationRun, intent domain.GatewayIntent) error {
+	payment, err := r.Payments.FindByStripeID(ctx, intent.StripeID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return r.record(ctx, run, &domain.ReconciliationDiscrepancy{
+			Kind:         domain.KindMissingLocal,
+			StripeID:     intent.StripeID,
+			StripeStatus: intent.StripeStatus,
+			StripeAmount: intent.Amount,
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	base := domain.ReconciliationDiscrepancy{
+		PaymentID:    payment.ID,
+		StripeID:     intent.StripeID,
+		LocalStatus:  string(payment.Status),
+		StripeStatus: intent.StripeStatus,
+		LocalAmount:  payment.Amount,
+		StripeAmount: intent.Amount,
+	}
+
+	if payment.Amount != intent.Amount {
+		d := base
+		d.Kind = domain.KindAmountMismatch
+		if err := r.record(ctx, run, &d); err != nil {
+			return err
+		}
+	}
+
+	if payment.Currency != intent.Currency {
+		d := base
+		d.Kind = domain.KindCurrencyMismatch
+		if err := r.record(ctx, run, &d); err != nil {
+			return err
+		}
+	}
+
+	if payment.Status == intent.Status {
+		return nil
+	}
+
+	d := base
+	return r.mismatch(ctx, run, payment, &d, intent.Status, "Stripe status "+intent.StripeStatus)
+}
+
+// compareTransaction checks that the payment a balance transaction belongs
+// to has reached the status the transaction implies.
+func (r *Reconciler) compareTransaction(ctx context.Context, run *domain.ReconciliationRun, txn domain.GatewayBalanceTransaction) error {
+	payment, err := r.Payments.FindByStripeID(ctx, txn.StripeID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return r.record(ctx, run, &domain.ReconciliationDiscrepancy{
+			Kind:         domain.KindMissingLocal,
+			StripeID:     txn.StripeID,
+			StripeStatus: txn.Type,
+			StripeAmount: txn.Amount,
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	if domain.Settled(payment.Status, txn.Status) {
+		return nil
+	}
+
+	return r.mismatch(ctx, run, payment, &domain.ReconciliationDiscrepancy{
+		PaymentID:    payment.ID,
+		StripeID:     txn.StripeID,
+		LocalStatus:  string(payment.Status),
+		StripeStatus: txn.Type,
+		LocalAmount:  payment.Amount,
+		StripeAmount: txn.Amount,
+	}, txn.Status, "Stripe balance transaction "+txn.ID)
+}
+
+// mismatch records that payment is not in the status the gateway shows,
+// moving it there first when that is safe.
+func (r *Reconciler) mismatch(ctx context.Context, run *domain.ReconciliationRun, payment *paymentDomain.Payment, d *domain.ReconciliationDiscrepancy, status paymentDomain.PaymentStatus, evidence string) error {
+	d.Kind = domain.KindStatusMismatch
+
+	if domain.CanRepair(payment.Status, status) {
+		applyStatus(payment, status)
+		payment.Explain("repaired to match "+evidence, "")
+		if err := paymentApplication.UpdateAndPublish(paymentDomain.WithSource(ctx, paymentDomain.SourceReconciliation), r.Payments, r.Events, payment); err != nil {
+			return fmt.Errorf("cannot repair payment %s: %w", payment.ID, err)
+		}
+		d.Repaired = true
+		run.Repaired++
+	}
+
+	return r.record(ctx, run, d)
+}
+
+func (r *Reconciler) record(ctx context.Context, run *domain.ReconciliationRun, d *domain.ReconciliationDiscrepancy) error {
+	d.ID = ulid.NewULID()
+	d.RunID = run.ID
+	d.CreatedAt = time.Now()
+	run.Discrepancies++
+	return r.Runs.SaveDiscrepancy(ctx, d)
+}
+
+func applyStatus(payment *paymentDomain.Payment, status paymentDomain.PaymentStatus) {
+	switch status {
+	case paymentDomain.StatusCompleted:
+		payment.Complete()
+	case paymentDomain.StatusCaptured:
+		payment.Capture()
+	case paymentDomain.StatusCanceled:
+		payment.Cancel()
+	case paymentDomain.StatusRefund:
+		payment.Refund()
+	case paymentDomain.StatusFailed:
+		payment.Fail()
+	}
+}
@@ -0,0 +1,264 @@
+package application_test
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	paymentApplication "github.com/williamkoller/payment-system/internal/payment/application"
+	paymentDomain "github.com/williamkoller/payment-system/internal/payment/domain"
+	"github.com/williamkoller/payment-system/internal/reconciliation/application"
+	"github.com/williamkoller/payment-system/internal/reconciliation/domain"
+	"gorm.io/gorm"
+)
+
+type fakePayments struct {
+	byStripeID map[string]*paymentDomain.Payment
+	updated    []*paymentDomain.Payment
+}
+
+func (f *fakePayments) FindByStripeID(_ context.Context, stripeID string) (*paymentDomain.Payment, error) {
+	p, ok := f.byStripeID[stripeID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return p, nil
+}
+
+func (f *fakePayments) FindCreatedBetween(_ context.Context, _ string, from, to time.Time) ([]*paymentDomain.Payment, error) {
+	ps := make([]*paymentDomain.Payment, 0, len(f.byStripeID))
+	for _, p := range f.byStripeID {
+		if !p.CreatedAt.Before(from) && p.CreatedAt.Before(to) {
+			ps = append(ps, p)
+		}
+	}
+	return ps, nil
+}
+
+func (f *fakePayments) Update(_ context.Context, p *paymentDomain.Payment) error {
+	f.updated = append(f.updated, p)
+	return nil
+}
+
+type fakeRuns struct {
+	runs          map[string]*domain.ReconciliationRun
+	discrepancies []*domain.ReconciliationDiscrepancy
+}
+
+func (f *fakeRuns) SaveRun(_ context.Context, run *domain.ReconciliationRun) error {
+	f.runs[run.ID] = run
+	return nil
+}
+
+func (f *fakeRuns) UpdateRun(_ context.Context, run *domain.ReconciliationRun) error {
+	f.runs[run.ID] = run
+	return nil
+}
+
+func (f *fakeRuns) SaveDiscrepancy(_ context.Context, d *domain.ReconciliationDiscrepancy) error {
+	f.discrepancies = append(f.discrepancies, d)
+	return nil
+}
+
+func (f *fakeRuns) FindRunByID(_ context.Context, id string) (*domain.ReconciliationRun, error) {
+	run, ok := f.runs[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return run, nil
+}
+
+func (f *fakeRuns) FindDiscrepanciesByRunID(_ context.Context, runID string) ([]*domain.ReconciliationDiscrepancy, error) {
+	return f.discrepancies, nil
+}
+
+func (f *fakeRuns) LastScheduledRun(context.Context) (*domain.ReconciliationRun, error) {
+	var last *domain.ReconciliationRun
+	for _, run := range f.runs {
+		if run.Scheduled && run.Status == domain.RunStatusSucceeded && (last == nil || run.RangeTo.After(last.RangeTo)) {
+			last = run
+		}
+	}
+	if last == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return last, nil
+}
+
+type fakeGateway struct {
+	intents      []domain.GatewayIntent
+	transactions []domain.GatewayBalanceTransaction
+	err          error
+}
+
+func (f *fakeGateway) ListIntents(_ context.Context, _, _ time.Time, fn func(domain.GatewayIntent) error) error {
+	if f.err != nil {
+		return f.err
+	}
+	for _, intent := range f.intents {
+		if err := fn(intent); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *fakeGateway) ListBalanceTransactions(_ context.Context, _, _ time.Time, fn func(domain.GatewayBalanceTransaction) error) error {
+	for _, txn := range f.transactions {
+		if err := fn(txn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func kinds(ds []*domain.ReconciliationDiscrepancy) map[string]domain.DiscrepancyKind {
+	m := make(map[string]domain.DiscrepancyKind, len(ds))
+	for _, d := range ds {
+		m[d.StripeID] = d.Kind
+	}
+	return m
+}
+
+func TestReconciler_Run(t *testing.T) {
+	to := time.Now()
+	created := to.Add(-time.Hour)
+	payments := &fakePayments{byStripeID: map[string]*paymentDomain.Payment{
+		"pi_ok":       {ID: "p1", StripeID: "pi_ok", Amount: 1000, Currency: "USD", Status: paymentDomain.StatusCompleted, CreatedAt: created},
+		"pi_captured": {ID: "p2", StripeID: "pi_captured", Amount: 1000, Currency: "USD", Status: paymentDomain.StatusCompleted, CreatedAt: created},
+		"pi_failed":   {ID: "p3", StripeID: "pi_failed", Amount: 1000, Currency: "USD", Status: paymentDomain.StatusFailed, CreatedAt: created},
+		"pi_gone":     {ID: "p4", StripeID: "pi_gone", Amount: 1000, Currency: "USD", Status: paymentDomain.StatusCompleted, CreatedAt: created},
+	}}
+	runs := &fakeRuns{runs: map[string]*domain.ReconciliationRun{}}
+	gateway := &fakeGateway{intents: []domain.GatewayIntent{
+		{StripeID: "pi_ok", Amount: 1000, Currency: "USD", Status: paymentDomain.StatusCompleted},
+		{StripeID: "pi_captured", Amount: 1000, Currency: "USD", Status: paymentDomain.StatusCaptured},
+		{StripeID: "pi_failed", Amount: 1000, Currency: "USD", Status: paymentDomain.StatusCaptured},
+		{StripeID: "pi_unknown", Amount: 500, Currency: "USD", Status: paymentDomain.StatusCompleted},
+	}}
+
+	reconciler := application.NewReconciler(payments, runs, gateway)
+	reconciler.Events = paymentApplication.NewStatusBroker(0, 0)
+	sub, err := reconciler.Events.Subscribe("p2", "")
+	require.NoError(t, err)
+	defer sub.Close()
+	run, err := reconciler.Run(context.Background(), to.Add(-24*time.Hour), to)
+
+	assert.NoError(t, err)
+	assert.Equal(t, domain.RunStatusSucceeded, run.Status)
+	assert.Equal(t, 4, run.Checked)
+	assert.Equal(t, 1, run.Repaired)
+	assert.Equal(t, 4, run.Discrepancies)
+
+	assert.Equal(t, map[string]domain.DiscrepancyKind{
+		"pi_captured": domain.KindStatusMismatch,
+		"pi_failed":   domain.KindStatusMismatch,
+		"pi_unknown":  domain.KindMissingLocal,
+		"pi_gone":     domain.KindMissingGateway,
+	}, kinds(runs.discrepancies))
+
+	assert.Len(t, payments.updated, 1)
+	assert.Equal(t, paymentDomain.StatusCaptured, payments.byStripeID["pi_captured"].Status)
+	assert.Equal(t, paymentDomain.StatusFailed, payments.byStripeID["pi_failed"].Status)
+
+	select {
+	case change := <-sub.C:
+		assert.Equal(t, paymentDomain.StatusCaptured, change.ToStatus, "repairs reach event streams")
+	default:
+		t.Fatal("the repair was not published")
+	}
+}
+
+func TestReconciler_Run_InvalidRange(t *testing.T) {
+	reconciler := application.NewReconciler(&fakePayments{}, &fakeRuns{}, &fakeGateway{})
+	now := time.Now()
+
+	run, err := reconciler.Run(context.Background(), now, now)
+	assert.Error(t, err)
+	assert.Nil(t, run)
+}
+
+func TestReconciler_Run_BalanceTransactions(t *testing.T) {
+	// The payments were created in an earlier window; only their balance
+	// transactions fall in this one.
+	payments := &fakePayments{byStripeID: map[string]*paymentDomain.Payment{
+		"pi_settled":       {ID: "p1", StripeID: "pi_settled", Amount: 1000, Currency: "USD", Status: paymentDomain.StatusCaptured},
+		"pi_refunded":      {ID: "p2", StripeID: "pi_refunded", Amount: 1000, Currency: "USD", Status: paymentDomain.StatusRefund},
+		"pi_uncaptured":    {ID: "p3", StripeID: "pi_uncaptured", Amount: 1000, Currency: "USD", Status: paymentDomain.StatusCompleted},
+		"pi_refund_missed": {ID: "p4", StripeID: "pi_refund_missed", Amount: 1000, Currency: "USD", Status: paymentDomain.StatusCaptured},
+		"pi_failed":        {ID: "p5", StripeID: "pi_failed", Amount: 1000, Currency: "USD", Status: paymentDomain.StatusFailed},
+	}}
+	runs := &fakeRuns{runs: map[string]*domain.ReconciliationRun{}}
+	gateway := &fakeGateway{transactions: []domain.GatewayBalanceTransaction{
+		{ID: "txn_1", StripeID: "pi_settled", Type: "charge", Status: paymentDomain.StatusCaptured, Amount: 1000},
+		{ID: "txn_2", StripeID: "pi_refunded", Type: "charge", Status: paymentDomain.StatusCaptured, Amount: 1000},
+		{ID: "txn_3", StripeID: "pi_uncaptured", Type: "charge", Status: paymentDomain.StatusCaptured, Amount: 1000},
+		{ID: "txn_4", StripeID: "pi_refund_missed", Type: "refund", Status: paymentDomain.StatusRefund, Amount: -1000},
+		{ID: "txn_5", StripeID: "pi_failed", Type: "charge", Status: paymentDomain.StatusCaptured, Amount: 1000},
+		{ID: "txn_6", StripeID: "pi_unknown", Type: "charge", Status: paymentDomain.StatusCaptured, Amount: 500},
+	}}
+
+	to := time.Now()
+	run, err := application.NewReconciler(payments, runs, gateway).Run(context.Background(), to.Add(-24*time.Hour), to)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 6, run.Checked)
+	assert.Equal(t, 2, run.Repaired)
+	assert.Equal(t, map[string]domain.DiscrepancyKind{
+		"pi_uncaptured":    domain.KindStatusMismatch,
+		"pi_refund_missed": domain.KindStatusMismatch,
+		"pi_failed":        domain.KindStatusMismatch,
+		"pi_unknown":       domain.KindMissingLocal,
+	}, kinds(runs.discrepancies), "a refunded payment was captured first")
+
+	assert.Equal(t, paymentDomain.StatusCaptured, payments.byStripeID["pi_uncaptured"].Status)
+	assert.Equal(t, paymentDomain.StatusRefund, payments.byStripeID["pi_refund_missed"].Status)
+	assert.Equal(t, paymentDomain.StatusFailed, payments.byStripeID["pi_failed"].Status, "failed payments are not repaired")
+}
+
+func TestReconciler_CatchUp(t *testing.T) {
+	runs := &fakeRuns{runs: map[string]*domain.ReconciliationRun{}}
+	gateway := &fakeGateway{}
+	reconciler := application.NewReconciler(&fakePayments{}, runs, gateway)
+	ctx := context.Background()
+	day := 24 * time.Hour
+	midnight := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+
+	ranges := func() [][2]time.Time {
+		var rs [][2]time.Time
+		for _, run := range runs.runs {
+			rs = append(rs, [2]time.Time{run.RangeFrom, run.RangeTo})
+		}
+		sort.Slice(rs, func(i, j int) bool { return rs[i][0].Before(rs[j][0]) })
+		return rs
+	}
+
+	require.NoError(t, reconciler.CatchUp(ctx, midnight, day))
+	assert.Equal(t, [][2]time.Time{{midnight.Add(-day), midnight}}, ranges(), "the first run covers one window")
+
+	require.NoError(t, reconciler.CatchUp(ctx, midnight, day))
+	assert.Len(t, runs.runs, 1, "nothing new to reconcile")
+
+	gateway.err = errors.New("stripe down")
+	assert.Error(t, reconciler.CatchUp(ctx, midnight.Add(day), day))
+	assert.Len(t, runs.runs, 2)
+
+	gateway.err = nil
+	require.NoError(t, reconciler.CatchUp(ctx, midnight.Add(3*day), day))
+	assert.Equal(t, [][2]time.Time{
+		{midnight.Add(-day), midnight},
+		{midnight, midnight.Add(day)},
+		{midnight, midnight.Add(day)},
+		{midnight.Add(day), midnight.Add(2 * day)},
+		{midnight.Add(2 * day), midnight.Add(3 * day)},
+	}, ranges(), "a failed window is retried and missed days are caught up one window at a time")
+
+	_, err := reconciler.Run(ctx, midnight.Add(10*day), midnight.Add(11*day))
+	require.NoError(t, err)
+	require.NoError(t, reconciler.CatchUp(ctx, midnight.Add(4*day), day))
+	assert.Equal(t, midnight.Add(4*day), ranges()[len(ranges())-2][1], "on-demand runs do not move the schedule")
+}
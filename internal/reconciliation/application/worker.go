@@ -0,0 +1,45 @@
+package application
+
+import (
+	"context"
+	"time"
+
+	"github.com/williamkoller/payment-system/pkg/heartbeat"
+	"github.com/williamkoller/payment-system/pkg/logger"
+)
+
+// Worker runs the reconciler on a fixed interval. Each tick reconciles, in
+// windows, whatever has not been reconciled yet up to the previous
+// midnight (UTC), so a tick with nothing new does nothing and missed days
+// are caught up.
+type Worker struct {
+	reconciler *Reconciler
+	interval   time.Duration
+	window     time.Duration
+
+	// Heartbeat beats every time the loop wakes up, for readiness checks.
+	Heartbeat heartbeat.Heartbeat
+}
+
+func NewWorker(reconciler *Reconciler, interval, window time.Duration) *Worker {
+	return &Worker{reconciler: reconciler, interval: interval, window: window}
+}
+
+func (w *Worker) Start(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	w.Heartbeat.Beat()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			w.Heartbeat.Beat()
+			until := now.UTC().Truncate(24 * time.Hour)
+			if err := w.reconciler.CatchUp(ctx, until, w.window); err != nil {
+				logger.Error("scheduled reconciliation failed", "err", err)
+			}
+		}
+	}
+}
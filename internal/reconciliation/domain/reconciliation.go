@@ -0,0 +1,126 @@
+package domain
+
+import (
+	"time"
+
+	paymentDomain "github.com/williamkoller/payment-system/internal/payment/domain"
+)
+
+type RunStatus string
+
+const (
+	RunStatusRunning   RunStatus = "RUNNING"
+	RunStatusSucceeded RunStatus = "SUCCEEDED"
+	RunStatusFailed    RunStatus = "FAILED"
+)
+
+type DiscrepancyKind string
+
+const (
+	KindStatusMismatch   DiscrepancyKind = "STATUS_MISMATCH"
+	KindAmountMismatch   DiscrepancyKind = "AMOUNT_MISMATCH"
+	KindCurrencyMismatch DiscrepancyKind = "CURRENCY_MISMATCH"
+	KindMissingLocal     DiscrepancyKind = "MISSING_LOCAL"
+	KindMissingGateway   DiscrepancyKind = "MISSING_GATEWAY"
+)
+
+// GatewayIntent is the gateway side of a payment, already translated into
+// our own status vocabulary so it can be compared with domain.Payment.
+type GatewayIntent struct {
+	StripeID     string
+	Amount       int64
+	Currency     string
+	Status       paymentDomain.PaymentStatus
+	StripeStatus string
+	CreatedAt    time.Time
+}
+
+// GatewayBalanceTransaction is a movement of funds on the gateway for a
+// payment: a charge settling or a refund going out. Status is the payment
+// status it implies. Amount is in the balance currency, which may differ
+// from the payment's, so it is reported but not compared.
+type GatewayBalanceTransaction struct {
+	ID        string
+	StripeID  string
+	Type      string
+	Status    paymentDomain.PaymentStatus
+	Amount    int64
+	Currency  string
+	CreatedAt time.Time
+}
+
+type ReconciliationRun struct {
+	ID            string
+	Status        RunStatus
+	RangeFrom     time.Time
+	RangeTo       time.Time
+	Checked       int
+	Repaired      int
+	Discrepancies int
+	Error         string
+	StartedAt     time.Time
+	FinishedAt    *time.Time
+	// Scheduled runs are the worker's; the next one starts where the last
+	// successful one ended.
+	Scheduled bool
+}
+
+type ReconciliationDiscrepancy struct {
+	ID           string
+	RunID        string
+	Kind         DiscrepancyKind
+	PaymentID    string
+	StripeID     string
+	LocalStatus  string
+	StripeStatus string
+	LocalAmount  int64
+	StripeAmount int64
+	Repaired     bool
+	CreatedAt    time.Time
+}
+
+func NewReconciliationRun(id string, from, to time.Time) *ReconciliationRun {
+	return &ReconciliationRun{
+		ID:        id,
+		Status:    RunStatusRunning,
+		RangeFrom: from,
+		RangeTo:   to,
+		StartedAt: time.Now(),
+	}
+}
+
+// Settled reports whether a payment in status already reflects a balance
+// transaction implying implied; a refunded payment was captured first.
+func Settled(status, implied paymentDomain.PaymentStatus) bool {
+	return status == implied ||
+		(implied == paymentDomain.StatusCaptured && status == paymentDomain.StatusRefund)
+}
+
+func (r *ReconciliationRun) Finish(err error) {
+	now := time.Now()
+	r.FinishedAt = &now
+	if err != nil {
+		r.Status = RunStatusFailed
+		r.Error = err.Error()
+		return
+	}
+	r.Status = RunStatusSucceeded
+}
+
+// CanRepair reports whether moving a payment from its local status to the
+// status observed on the gateway is a forward-only transition that can be
+// applied without human review.
+func CanRepair(local, gateway paymentDomain.PaymentStatus) bool {
+	switch local {
+	case paymentDomain.StatusPending:
+		return gateway != paymentDomain.StatusPending
+	case paymentDomain.StatusCompleted:
+		return gateway == paymentDomain.StatusCaptured ||
+			gateway == paymentDomain.StatusCanceled ||
+			gateway == paymentDomain.StatusRefund
+	case paymentDomain.StatusCaptured:
+		return gateway == paymentDomain.StatusRefund
+	default:
+		return false
+	}
+}
@@ -0,0 +1,135 @@
+package infra
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/stripe/stripe-go"
+	"github.com/stripe/stripe-go/balancetransaction"
+	"github.com/stripe/stripe-go/paymentintent"
+	paymentDomain "github.com/williamkoller/payment-system/internal/payment/domain"
+	"github.com/williamkoller/payment-system/internal/reconciliation/domain"
+)
+
+type stripeLister struct {
+	intents      paymentintent.Client
+	transactions balancetransaction.Client
+}
+
+func NewStripeLister(apiKey string) *stripeLister {
+	backend := stripe.GetBackend(stripe.APIBackend)
+	return &stripeLister{
+		intents:      paymentintent.Client{B: backend, Key: apiKey},
+		transactions: balancetransaction.Client{B: backend, Key: apiKey},
+	}
+}
+
+func (l *stripeLister) ListIntents(ctx context.Context, from, to time.Time, fn func(domain.GatewayIntent) error) error {
+	params := &stripe.PaymentIntentListParams{
+		ListParams: stripe.ListParams{
+			Context: ctx,
+			Limit:   stripe.Int64(100),
+		},
+		CreatedRange: &stripe.RangeQueryParams{
+			GreaterThanOrEqual: from.Unix(),
+			LesserThan:         to.Unix(),
+		},
+	}
+
+	iter := l.intents.List(params)
+	for iter.Next() {
+		if err := fn(toGatewayIntent(iter.PaymentIntent())); err != nil {
+			return err
+		}
+	}
+
+	return iter.Err()
+}
+
+// ListBalanceTransactions hands fn the charges and refunds of payment
+// intents. Other transactions, e.g. payouts and fees, are skipped.
+func (l *stripeLister) ListBalanceTransactions(ctx context.Context, from, to time.Time, fn func(domain.GatewayBalanceTransaction) error) error {
+	params := &stripe.BalanceTransactionListParams{
+		ListParams: stripe.ListParams{
+			Context: ctx,
+			Limit:   stripe.Int64(100),
+		},
+		CreatedRange: &stripe.RangeQueryParams{
+			GreaterThanOrEqual: from.Unix(),
+			LesserThan:         to.Unix(),
+		},
+	}
+	params.AddExpand("data.source")
+
+	iter := l.transactions.List(params)
+	for iter.Next() {
+		txn, ok := toGatewayBalanceTransaction(iter.BalanceTransaction())
+		if !ok {
+			continue
+		}
+		if err := fn(txn); err != nil {
+			return err
+		}
+	}
+
+	return iter.Err()
+}
+
+func toGatewayBalanceTransaction(bt *stripe.BalanceTransaction) (domain.GatewayBalanceTransaction, bool) {
+	txn := domain.GatewayBalanceTransaction{
+		ID:        bt.ID,
+		Type:      string(bt.Type),
+		Amount:    bt.Amount,
+		Currency:  strings.ToUpper(string(bt.Currency)),
+		CreatedAt: time.Unix(bt.Created, 0),
+	}
+	if bt.Source == nil {
+		return txn, false
+	}
+
+	switch {
+	case bt.Source.Charge != nil && bt.Source.Charge.PaymentIntent != "":
+		txn.StripeID = bt.Source.Charge.PaymentIntent
+		txn.Status = paymentDomain.StatusCaptured
+	case bt.Source.Refund != nil && bt.Source.Refund.PaymentIntent != nil:
+		txn.StripeID = bt.Source.Refund.PaymentIntent.ID
+		txn.Status = paymentDomain.StatusRefund
+	default:
+		return txn, false
+	}
+	return txn, true
+}
+
+func toGatewayIntent(pi *stripe.PaymentIntent) domain.GatewayIntent {
+	return domain.GatewayIntent{
+		StripeID:     pi.ID,
+		Amount:       pi.Amount,
+		Currency:     strings.ToUpper(pi.Currency),
+		Status:       mapStatus(pi),
+		StripeStatus: string(pi.Status),
+		CreatedAt:    time.Unix(pi.Created, 0),
+	}
+}
+
+func mapStatus(pi *stripe.PaymentIntent) paymentDomain.PaymentStatus {
+	switch pi.Status {
+	case stripe.PaymentIntentStatusRequiresCapture:
+		return paymentDomain.StatusCompleted
+	case stripe.PaymentIntentStatusSucceeded:
+		if pi.Charges != nil {
+			for _, ch := range pi.Charges.Data {
+				if ch.Refunded || ch.AmountRefunded > 0 {
+					return paymentDomain.StatusRefund
+				}
+			}
+		}
+		return paymentDomain.StatusCaptured
+	case stripe.PaymentIntentStatusCanceled:
+		return paymentDomain.StatusCanceled
+	case stripe.PaymentIntentStatusRequiresPaymentMethod:
+		return paymentDomain.StatusFailed
+	default:
+		return paymentDomain.StatusPending
+	}
+}
@@ -0,0 +1,38 @@
+package interfaces
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/williamkoller/payment-system/internal/middleware"
+	"github.com/williamkoller/payment-system/internal/reconciliation/application"
+	"github.com/williamkoller/payment-system/pkg/apperror"
+)
+
+type IdentifyRunDto struct {
+	RunID string `uri:"id" binding:"required"`
+}
+
+type ReconciliationHandler struct {
+	Reconciler *application.Reconciler
+}
+
+func NewReconciliationHandler(reconciler *application.Reconciler) *ReconciliationHandler {
+	return &ReconciliationHandler{Reconciler: reconciler}
+}
+
+func (h *ReconciliationHandler) GetRun(c *gin.Context) {
+	var uri IdentifyRunDto
+	if err := c.ShouldBindUri(&uri); err != nil {
+		middleware.Problem(c, apperror.Validation(err))
+		return
+	}
+
+	report, err := h.Reconciler.FindRun(c.Request.Context(), uri.RunID)
+	if err != nil {
+		middleware.Problem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ToRunResponse(report))
+}
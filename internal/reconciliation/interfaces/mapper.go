@@ -0,0 +1,66 @@
+package interfaces
+
+import (
+	"time"
+
+	"github.com/williamkoller/payment-system/internal/reconciliation/application"
+	"github.com/williamkoller/payment-system/internal/reconciliation/domain"
+)
+
+type DiscrepancyResponse struct {
+	ID           string                 `json:"id"`
+	Kind         domain.DiscrepancyKind `json:"kind"`
+	PaymentID    string                 `json:"payment_id"`
+	StripeID     string                 `json:"stripe_id"`
+	LocalStatus  string                 `json:"local_status"`
+	StripeStatus string                 `json:"stripe_status"`
+	LocalAmount  int64                  `json:"local_amount"`
+	StripeAmount int64                  `json:"stripe_amount"`
+	Repaired     bool                   `json:"repaired"`
+	CreatedAt    time.Time              `json:"created_at"`
+}
+
+type RunResponse struct {
+	ID            string                `json:"id"`
+	Status        domain.RunStatus      `json:"status"`
+	RangeFrom     time.Time             `json:"range_from"`
+	RangeTo       time.Time             `json:"range_to"`
+	Checked       int                   `json:"checked"`
+	Repaired      int                   `json:"repaired"`
+	Discrepancies []DiscrepancyResponse `json:"discrepancies"`
+	Error         string                `json:"error,omitempty"`
+	StartedAt     time.Time             `json:"started_at"`
+	FinishedAt    *time.Time            `json:"finished_at"`
+}
+
+func ToRunResponse(report *application.RunReport) RunResponse {
+	discrepancies := make([]DiscrepancyResponse, 0, len(report.Discrepancies))
+	for _, d := range report.Discrepancies {
+		discrepancies = append(discrepancies, DiscrepancyResponse{
+			ID:           d.ID,
+			Kind:         d.Kind,
+			PaymentID:    d.PaymentID,
+			StripeID:     d.StripeID,
+			LocalStatus:  d.LocalStatus,
+			StripeStatus: d.StripeStatus,
+			LocalAmount:  d.LocalAmount,
+			StripeAmount: d.StripeAmount,
+			Repaired:     d.Repaired,
+			CreatedAt:    d.CreatedAt,
+		})
+	}
+
+	run := report.Run
+	return RunResponse{
+		ID:            run.ID,
+		Status:        run.Status,
+		RangeFrom:     run.RangeFrom,
+		RangeTo:       run.RangeTo,
+		Checked:       run.Checked,
+		Repaired:      run.Repaired,
+		Discrepancies: discrepancies,
+		Error:         run.Error,
+		StartedAt:     run.StartedAt,
+		FinishedAt:    run.FinishedAt,
+	}
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/williamkoller/payment-system/internal/reconciliation/domain"
+	"gorm.io/gorm"
+)
+
+type ReconciliationRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewReconciliationRepository(db *gorm.DB) *ReconciliationRepositoryImpl {
+	return &ReconciliationRepositoryImpl{db: db}
+}
+
+func (r *ReconciliationRepositoryImpl) SaveRun(ctx context.Context, run *domain.ReconciliationRun) error {
+	return r.db.WithContext(ctx).Create(run).Error
+}
+
+func (r *ReconciliationRepositoryImpl) UpdateRun(ctx context.Context, run *domain.ReconciliationRun) error {
+	return r.db.WithContext(ctx).Model(&domain.ReconciliationRun{}).
+		Select("Status", "Checked", "Repaired", "Discrepancies", "Error", "FinishedAt").
+		Where("id = ?", run.ID).
+		Updates(run).Error
+}
+
+func (r *ReconciliationRepositoryImpl) SaveDiscrepancy(ctx context.Context, d *domain.ReconciliationDiscrepancy) error {
+	return r.db.WithContext(ctx).Create(d).Error
+}
+
+func (r *ReconciliationRepositoryImpl) FindRunByID(ctx context.Context, id string) (*domain.ReconciliationRun, error) {
+	var run domain.ReconciliationRun
+	if err := r.db.WithContext(ctx).First(&run, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+func (r *ReconciliationRepositoryImpl) LastScheduledRun(ctx context.Context) (*domain.ReconciliationRun, error) {
+	var run domain.ReconciliationRun
+	err := r.db.WithContext(ctx).
+		Where("scheduled AND status = ?", domain.RunStatusSucceeded).
+		Order("range_to DESC").
+		First(&run).Error
+	if err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+func (r *ReconciliationRepositoryImpl) FindDiscrepanciesByRunID(ctx context.Context, runID string) ([]*domain.ReconciliationDiscrepancy, error) {
+	var discrepancies []*domain.ReconciliationDiscrepancy
+	if err := r.db.WithContext(ctx).Where("run_id = ?", runID).Order("created_at").Find(&discrepancies).Error; err != nil {
+		return nil, err
+	}
+	return discrepancies, nil
+}
@@ -0,0 +1,65 @@
+package router
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"github.com/williamkoller/payment-system/config"
+	"github.com/williamkoller/payment-system/internal/middleware"
+	paymentRouter "github.com/williamkoller/payment-system/internal/payment/router"
+	"github.com/williamkoller/payment-system/internal/reconciliation/application"
+	"github.com/williamkoller/payment-system/internal/reconciliation/infra"
+	"github.com/williamkoller/payment-system/internal/reconciliation/interfaces"
+	"github.com/williamkoller/payment-system/internal/reconciliation/repository"
+	"github.com/williamkoller/payment-system/pkg/auth"
+	"gorm.io/gorm"
+)
+
+func NewReconciler(db *gorm.DB, cfg *config.ResponseConfiguration) (*application.Reconciler, error) {
+	payments, err := paymentRouter.NewPaymentRepository(db, cfg.PaymentStore)
+	if err != nil {
+		return nil, err
+	}
+	return application.NewReconciler(
+		payments,
+		repository.NewReconciliationRepository(db),
+		infra.NewStripeLister(cfg.Stripe.StripeApiKey),
+	), nil
+}
+
+// CredentialSource is the subset of the merchant service reconciliation
+// needs to reach merchants' own Stripe accounts.
+type CredentialSource interface {
+	GatewayAccounts(ctx context.Context) ([]string, error)
+	StripeCredentials(ctx context.Context, merchantID string) (secretKey, paymentMethod string, err error)
+}
+
+type merchantAccounts struct {
+	merchants CredentialSource
+}
+
+// MerchantAccounts lets the reconciler list intents and balance
+// transactions of every merchant with its own Stripe account.
+func MerchantAccounts(merchants CredentialSource) application.AccountSource {
+	return merchantAccounts{merchants: merchants}
+}
+
+func (m merchantAccounts) GatewayAccounts(ctx context.Context) ([]string, error) {
+	return m.merchants.GatewayAccounts(ctx)
+}
+
+func (m merchantAccounts) Lister(ctx context.Context, account string) (application.GatewayLister, error) {
+	secretKey, _, err := m.merchants.StripeCredentials(ctx, account)
+	if err != nil {
+		return nil, err
+	}
+	return infra.NewStripeLister(secretKey), nil
+}
+
+func SetupRouter(e *gin.Engine, reconciler *application.Reconciler, authn gin.HandlerFunc) {
+	handler := interfaces.NewReconciliationHandler(reconciler)
+	admin := e.Group("/admin/reconciliation", authn, middleware.RequireScope(auth.ScopePlatform))
+	{
+		admin.GET("/runs/:id", handler.GetRun)
+	}
+}
@@ -0,0 +1,109 @@
+package application
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/williamkoller/payment-system/internal/risk/domain"
+)
+
+type VelocityConfig struct {
+	Field  string `json:"field"`
+	Window string `json:"window"`
+	Max    int64  `json:"max"`
+	Score  int    `json:"score"`
+}
+
+type AmountThresholdConfig struct {
+	Currency string `json:"currency"`
+	Amount   int64  `json:"amount"`
+	Score    int    `json:"score"`
+}
+
+type ListConfig struct {
+	Values []string `json:"values"`
+	Score  int      `json:"score"`
+}
+
+// RulesConfig is the JSON document read from RISK_RULES_FILE.
+type RulesConfig struct {
+	Thresholds       domain.Thresholds       `json:"thresholds"`
+	Velocity         []VelocityConfig        `json:"velocity"`
+	AmountThresholds []AmountThresholdConfig `json:"amount_thresholds"`
+	CountryBlocklist ListConfig              `json:"country_blocklist"`
+	DisposableEmail  ListConfig              `json:"disposable_email"`
+}
+
+func DefaultRulesConfig() RulesConfig {
+	return RulesConfig{
+		Thresholds: domain.Thresholds{Require3DS: 30, Review: 60, Block: 90},
+		Velocity: []VelocityConfig{
+			{Field: FieldEmail, Window: "1h", Max: 5, Score: 40},
+			{Field: FieldIP, Window: "1h", Max: 10, Score: 40},
+			{Field: FieldCardFingerprint, Window: "24h", Max: 5, Score: 50},
+		},
+		AmountThresholds: []AmountThresholdConfig{
+			{Currency: "BRL", Amount: 500000, Score: 30},
+			{Currency: "USD", Amount: 100000, Score: 30},
+			{Currency: "EUR", Amount: 100000, Score: 30},
+		},
+		CountryBlocklist: ListConfig{Values: []string{"KP", "IR", "SY", "CU"}, Score: 100},
+		DisposableEmail: ListConfig{
+			Values: []string{"mailinator.com", "10minutemail.com", "guerrillamail.com", "tempmail.com", "yopmail.com"},
+			Score:  40,
+		},
+	}
+}
+
+func LoadRulesConfig(path string) (RulesConfig, error) {
+	if path == "" {
+		return DefaultRulesConfig(), nil
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return RulesConfig{}, fmt.Errorf("cannot read risk rules file: %w", err)
+	}
+
+	var cfg RulesConfig
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return RulesConfig{}, fmt.Errorf("invalid risk rules file: %w", err)
+	}
+	return cfg, nil
+}
+
+// Rules builds the rule set described by the configuration.
+func (c RulesConfig) Rules(counter VelocityCounter) ([]Rule, error) {
+	var rules []Rule
+
+	for _, v := range c.Velocity {
+		window, err := time.ParseDuration(v.Window)
+		if err != nil {
+			return nil, fmt.Errorf("invalid velocity window %q: %w", v.Window, err)
+		}
+		if v.Field != FieldEmail && v.Field != FieldIP && v.Field != FieldCardFingerprint {
+			return nil, fmt.Errorf("invalid velocity field %q", v.Field)
+		}
+		rules = append(rules, &VelocityRule{Field: v.Field, Window: window, Max: v.Max, Score: v.Score, Counter: counter})
+	}
+
+	if len(c.AmountThresholds) > 0 {
+		limits := make(map[string]AmountThresholdConfig, len(c.AmountThresholds))
+		for _, a := range c.AmountThresholds {
+			limits[a.Currency] = a
+		}
+		rules = append(rules, &AmountThresholdRule{Limits: limits})
+	}
+
+	if len(c.CountryBlocklist.Values) > 0 {
+		rules = append(rules, &CountryBlocklistRule{Countries: toSet(c.CountryBlocklist.Values), Score: c.CountryBlocklist.Score})
+	}
+
+	if len(c.DisposableEmail.Values) > 0 {
+		rules = append(rules, &DisposableEmailRule{Domains: toSet(c.DisposableEmail.Values), Score: c.DisposableEmail.Score})
+	}
+
+	return rules, nil
+}
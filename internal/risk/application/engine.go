@@ -0,0 +1,85 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/williamkoller/payment-system/internal/risk/domain"
+	"github.com/williamkoller/payment-system/pkg/apperror"
+	"github.com/williamkoller/payment-system/pkg/auth"
+	"github.com/williamkoller/payment-system/pkg/ulid"
+	"gorm.io/gorm"
+)
+
+var ErrAssessmentNotFound = apperror.New(apperror.KindNotFound, "risk_assessment_not_found", "risk assessment not found")
+
+type AssessmentRepository interface {
+	VelocityCounter
+	Save(ctx context.Context, assessment *domain.RiskAssessment) error
+	Update(ctx context.Context, assessment *domain.RiskAssessment) error
+	FindByPaymentID(ctx context.Context, paymentID string) (*domain.RiskAssessment, error)
+}
+
+type Engine struct {
+	Rules      []Rule
+	Thresholds domain.Thresholds
+	Repository AssessmentRepository
+}
+
+func NewEngine(cfg RulesConfig, repository AssessmentRepository) (*Engine, error) {
+	rules, err := cfg.Rules(repository)
+	if err != nil {
+		return nil, err
+	}
+	return &Engine{Rules: rules, Thresholds: cfg.Thresholds, Repository: repository}, nil
+}
+
+// Assess runs every rule against the subject, derives a decision from the
+// total score and stores the outcome against the payment. Rules are
+// evaluated before the assessment is saved so velocity counts only see
+// earlier attempts.
+func (e *Engine) Assess(ctx context.Context, paymentID string, subject domain.Subject) (*domain.RiskAssessment, error) {
+	subject.Email = strings.ToLower(subject.Email)
+
+	var matches []domain.RuleMatch
+	score := 0
+	for _, rule := range e.Rules {
+		match, err := rule.Evaluate(ctx, subject)
+		if err != nil {
+			return nil, fmt.Errorf("risk rule evaluation failed: %w", err)
+		}
+		if match != nil {
+			matches = append(matches, *match)
+			score += match.Score
+		}
+	}
+
+	assessment := domain.NewRiskAssessment(ulid.NewULID(), paymentID, subject, matches, e.Thresholds.Decide(score))
+	assessment.MerchantID = auth.MerchantID(ctx)
+	if err := e.Repository.Save(ctx, assessment); err != nil {
+		return nil, err
+	}
+	return assessment, nil
+}
+
+func (e *Engine) FindByPaymentID(ctx context.Context, paymentID string) (*domain.RiskAssessment, error) {
+	assessment, err := e.Repository.FindByPaymentID(ctx, paymentID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrAssessmentNotFound.Wrap(err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return assessment, nil
+}
+
+func (e *Engine) RecordReview(ctx context.Context, paymentID, reviewer string) error {
+	assessment, err := e.FindByPaymentID(ctx, paymentID)
+	if err != nil {
+		return err
+	}
+	assessment.MarkReviewed(reviewer)
+	return e.Repository.Update(ctx, assessment)
+}
@@ -0,0 +1,83 @@
+package application_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/williamkoller/payment-system/internal/risk/application"
+	"github.com/williamkoller/payment-system/internal/risk/domain"
+)
+
+type fakeAssessments struct {
+	saved  []*domain.RiskAssessment
+	counts map[string]int64
+}
+
+func (f *fakeAssessments) CountSince(_ context.Context, field, value string, _ time.Time) (int64, error) {
+	return f.counts[field+":"+value], nil
+}
+
+func (f *fakeAssessments) Save(_ context.Context, a *domain.RiskAssessment) error {
+	f.saved = append(f.saved, a)
+	return nil
+}
+
+func (f *fakeAssessments) Update(context.Context, *domain.RiskAssessment) error { return nil }
+
+func (f *fakeAssessments) FindByPaymentID(context.Context, string) (*domain.RiskAssessment, error) {
+	return f.saved[len(f.saved)-1], nil
+}
+
+func TestEngine_Assess(t *testing.T) {
+	repo := &fakeAssessments{counts: map[string]int64{"ip:10.0.0.1": 12}}
+	engine, err := application.NewEngine(application.DefaultRulesConfig(), repo)
+	assert.NoError(t, err)
+	ctx := context.Background()
+
+	cases := []struct {
+		name     string
+		subject  domain.Subject
+		decision domain.Decision
+		rules    []string
+	}{
+		{
+			name:     "clean",
+			subject:  domain.Subject{Email: "user@example.com", IP: "10.0.0.2", Amount: 1000, Currency: "USD"},
+			decision: domain.DecisionAllow,
+		},
+		{
+			name:     "large amount asks for 3ds",
+			subject:  domain.Subject{Email: "user@example.com", Amount: 200000, Currency: "USD"},
+			decision: domain.DecisionRequire3DS,
+			rules:    []string{"amount_threshold"},
+		},
+		{
+			name:     "disposable email with ip velocity goes to review",
+			subject:  domain.Subject{Email: "User@Mailinator.com", IP: "10.0.0.1", Amount: 1000, Currency: "USD"},
+			decision: domain.DecisionReview,
+			rules:    []string{"velocity_ip", "disposable_email"},
+		},
+		{
+			name:     "blocklisted country",
+			subject:  domain.Subject{Email: "user@example.com", Country: "kp", Amount: 1000, Currency: "USD"},
+			decision: domain.DecisionBlock,
+			rules:    []string{"country_blocklist"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assessment, err := engine.Assess(ctx, "pay_1", tc.subject)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.decision, assessment.Decision)
+
+			var rules []string
+			for _, m := range assessment.Matches() {
+				rules = append(rules, m.Rule)
+			}
+			assert.Equal(t, tc.rules, rules)
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/williamkoller/payment-system/internal/risk/domain"
+)
+
+const (
+	FieldEmail           = "email"
+	FieldIP              = "ip"
+	FieldCardFingerprint = "card_fingerprint"
+)
+
+// Rule inspects a payment attempt and returns a match when it considers the
+// attempt risky, or nil otherwise.
+type Rule interface {
+	Evaluate(ctx context.Context, subject domain.Subject) (*domain.RuleMatch, error)
+}
+
+// VelocityCounter counts previous assessments sharing a field value.
+type VelocityCounter interface {
+	CountSince(ctx context.Context, field, value string, since time.Time) (int64, error)
+}
+
+type VelocityRule struct {
+	Field   string
+	Window  time.Duration
+	Max     int64
+	Score   int
+	Counter VelocityCounter
+}
+
+func (r *VelocityRule) Evaluate(ctx context.Context, subject domain.Subject) (*domain.RuleMatch, error) {
+	value := subjectField(subject, r.Field)
+	if value == "" {
+		return nil, nil
+	}
+
+	count, err := r.Counter.CountSince(ctx, r.Field, value, time.Now().Add(-r.Window))
+	if err != nil {
+		return nil, err
+	}
+	if count < r.Max {
+		return nil, nil
+	}
+
+	return &domain.RuleMatch{
+		Rule:   "velocity_" + r.Field,
+		Score:  r.Score,
+		Reason: fmt.Sprintf("%d attempts in the last %s", count, r.Window),
+	}, nil
+}
+
+type AmountThresholdRule struct {
+	Limits map[string]AmountThresholdConfig
+}
+
+func (r *AmountThresholdRule) Evaluate(_ context.Context, subject domain.Subject) (*domain.RuleMatch, error) {
+	limit, ok := r.Limits[strings.ToUpper(subject.Currency)]
+	if !ok || subject.Amount < limit.Amount {
+		return nil, nil
+	}
+
+	return &domain.RuleMatch{
+		Rule:   "amount_threshold",
+		Score:  limit.Score,
+		Reason: fmt.Sprintf("amount %d %s is at or above %d", subject.Amount, limit.Currency, limit.Amount),
+	}, nil
+}
+
+type CountryBlocklistRule struct {
+	Countries map[string]struct{}
+	Score     int
+}
+
+func (r *CountryBlocklistRule) Evaluate(_ context.Context, subject domain.Subject) (*domain.RuleMatch, error) {
+	if _, ok := r.Countries[strings.ToUpper(subject.Country)]; !ok {
+		return nil, nil
+	}
+
+	return &domain.RuleMatch{
+		Rule:   "country_blocklist",
+		Score:  r.Score,
+		Reason: "country " + strings.ToUpper(subject.Country) + " is blocklisted",
+	}, nil
+}
+
+type DisposableEmailRule struct {
+	Domains map[string]struct{}
+	Score   int
+}
+
+func (r *DisposableEmailRule) Evaluate(_ context.Context, subject domain.Subject) (*domain.RuleMatch, error) {
+	at := strings.LastIndex(subject.Email, "@")
+	if at < 0 {
+		return nil, nil
+	}
+
+	emailDomain := strings.ToLower(subject.Email[at+1:])
+	if _, ok := r.Domains[emailDomain]; !ok {
+		return nil, nil
+	}
+
+	return &domain.RuleMatch{
+		Rule:   "disposable_email",
+		Score:  r.Score,
+		Reason: "email domain " + emailDomain + " is disposable",
+	}, nil
+}
+
+func subjectField(subject domain.Subject, field string) string {
+	switch field {
+	case FieldEmail:
+		return strings.ToLower(subject.Email)
+	case FieldIP:
+		return subject.IP
+	case FieldCardFingerprint:
+		return subject.CardFingerprint
+	default:
+		return ""
+	}
+}
+
+func toSet(values []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		set[strings.ToUpper(v)] = struct{}{}
+		set[strings.ToLower(v)] = struct{}{}
+	}
+	return set
+}
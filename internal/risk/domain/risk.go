@@ -0,0 +1,105 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type Decision string
+
+const (
+	DecisionAllow      Decision = "ALLOW"
+	DecisionRequire3DS Decision = "REQUIRE_3DS"
+	DecisionReview     Decision = "REVIEW"
+	DecisionBlock      Decision = "BLOCK"
+)
+
+// Subject is everything the rules engine knows about a payment attempt.
+type Subject struct {
+	Email           string
+	IP              string
+	CardFingerprint string
+	Country         string
+	Amount          int64
+	Currency        string
+}
+
+type RuleMatch struct {
+	Rule   string `json:"rule"`
+	Score  int    `json:"score"`
+	Reason string `json:"reason"`
+}
+
+type RiskAssessment struct {
+	ID         string
+	MerchantID string
+	PaymentID  string
+	Email      string
+	// EmailIndex is the blind index of Email, which is stored encrypted.
+	// Velocity counts match on it. It is set by the repository.
+	EmailIndex      string
+	IP              string
+	CardFingerprint string
+	Country         string
+	Score           int
+	Decision        Decision
+	MatchedRules    string
+	ReviewedBy      string
+	ReviewedAt      *time.Time
+	CreatedAt       time.Time
+}
+
+func NewRiskAssessment(id, paymentID string, subject Subject, matches []RuleMatch, decision Decision) *RiskAssessment {
+	score := 0
+	for _, m := range matches {
+		score += m.Score
+	}
+
+	raw, _ := json.Marshal(matches)
+
+	return &RiskAssessment{
+		ID:              id,
+		PaymentID:       paymentID,
+		Email:           subject.Email,
+		IP:              subject.IP,
+		CardFingerprint: subject.CardFingerprint,
+		Country:         subject.Country,
+		Score:           score,
+		Decision:        decision,
+		MatchedRules:    string(raw),
+		CreatedAt:       time.Now(),
+	}
+}
+
+func (a *RiskAssessment) Matches() []RuleMatch {
+	var matches []RuleMatch
+	_ = json.Unmarshal([]byte(a.MatchedRules), &matches)
+	return matches
+}
+
+func (a *RiskAssessment) MarkReviewed(reviewer string) {
+	now := time.Now()
+	a.ReviewedBy = reviewer
+	a.ReviewedAt = &now
+}
+
+// Thresholds map a total score to a decision; each applies when the score
+// is greater than or equal to it.
+type Thresholds struct {
+	Require3DS int `json:"require_3ds"`
+	Review     int `json:"review"`
+	Block      int `json:"block"`
+}
+
+func (t Thresholds) Decide(score int) Decision {
+	switch {
+	case t.Block > 0 && score >= t.Block:
+		return DecisionBlock
+	case t.Review > 0 && score >= t.Review:
+		return DecisionReview
+	case t.Require3DS > 0 && score >= t.Require3DS:
+		return DecisionRequire3DS
+	default:
+		return DecisionAllow
+	}
+}
@@ -0,0 +1,68 @@
+package interfaces
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/williamkoller/payment-system/internal/middleware"
+	"github.com/williamkoller/payment-system/internal/risk/application"
+	"github.com/williamkoller/payment-system/internal/risk/domain"
+	"github.com/williamkoller/payment-system/pkg/apperror"
+)
+
+type IdentifyPaymentDto struct {
+	PaymentID string `uri:"payment_id" binding:"required"`
+}
+
+type AssessmentResponse struct {
+	ID           string             `json:"id"`
+	PaymentID    string             `json:"payment_id"`
+	Score        int                `json:"score"`
+	Decision     domain.Decision    `json:"decision"`
+	MatchedRules []domain.RuleMatch `json:"matched_rules"`
+	ReviewedBy   string             `json:"reviewed_by,omitempty"`
+	ReviewedAt   *time.Time         `json:"reviewed_at,omitempty"`
+	CreatedAt    time.Time          `json:"created_at"`
+}
+
+func ToAssessmentResponse(a *domain.RiskAssessment) AssessmentResponse {
+	matches := a.Matches()
+	if matches == nil {
+		matches = []domain.RuleMatch{}
+	}
+	return AssessmentResponse{
+		ID:           a.ID,
+		PaymentID:    a.PaymentID,
+		Score:        a.Score,
+		Decision:     a.Decision,
+		MatchedRules: matches,
+		ReviewedBy:   a.ReviewedBy,
+		ReviewedAt:   a.ReviewedAt,
+		CreatedAt:    a.CreatedAt,
+	}
+}
+
+type RiskHandler struct {
+	Engine *application.Engine
+}
+
+func NewRiskHandler(engine *application.Engine) *RiskHandler {
+	return &RiskHandler{Engine: engine}
+}
+
+func (h *RiskHandler) GetAssessment(c *gin.Context) {
+	var uri IdentifyPaymentDto
+	if err := c.ShouldBindUri(&uri); err != nil {
+		middleware.Problem(c, apperror.Validation(err))
+		return
+	}
+
+	assessment, err := h.Engine.FindByPaymentID(c.Request.Context(), uri.PaymentID)
+	if err != nil {
+		middleware.Problem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ToAssessmentResponse(assessment))
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/williamkoller/payment-system/internal/risk/domain"
+	"github.com/williamkoller/payment-system/pkg/pii"
+	"github.com/williamkoller/payment-system/pkg/tenant"
+	"gorm.io/gorm"
+)
+
+// velocityColumns maps velocity fields to the column they are counted on.
+// Emails are encrypted, so they are matched on their blind index.
+var velocityColumns = map[string]string{
+	"email":            "email_index",
+	"ip":               "ip",
+	"card_fingerprint": "card_fingerprint",
+}
+
+type AssessmentRepositoryImpl struct {
+	db     *gorm.DB
+	cipher *pii.Cipher
+}
+
+func NewAssessmentRepository(db *gorm.DB) *AssessmentRepositoryImpl {
+	return &AssessmentRepositoryImpl{db: db, cipher: pii.Default()}
+}
+
+func (r *AssessmentRepositoryImpl) Save(ctx context.Context, assessment *domain.RiskAssessment) error {
+	sealed := *assessment
+	email, err := r.cipher.Encrypt(assessment.Email)
+	if err != nil {
+		return err
+	}
+	sealed.Email = email
+	sealed.EmailIndex = r.cipher.BlindIndex(assessment.Email)
+
+	if err := r.db.WithContext(ctx).Create(&sealed).Error; err != nil {
+		return err
+	}
+	assessment.EmailIndex = sealed.EmailIndex
+	return nil
+}
+
+func (r *AssessmentRepositoryImpl) Update(ctx context.Context, assessment *domain.RiskAssessment) error {
+	return r.db.WithContext(ctx).Scopes(tenant.Scope(ctx)).Model(&domain.RiskAssessment{}).
+		Select("ReviewedBy", "ReviewedAt").
+		Where("id = ?", assessment.ID).
+		Updates(assessment).Error
+}
+
+func (r *AssessmentRepositoryImpl) FindByPaymentID(ctx context.Context, paymentID string) (*domain.RiskAssessment, error) {
+	var assessment domain.RiskAssessment
+	if err := r.db.WithContext(ctx).Scopes(tenant.Scope(ctx)).Order("created_at DESC").First(&assessment, "payment_id = ?", paymentID).Error; err != nil {
+		return nil, err
+	}
+
+	email, err := r.cipher.Decrypt(assessment.Email)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decrypt email of risk assessment %s: %w", assessment.ID, err)
+	}
+	assessment.Email = email
+	return &assessment, nil
+}
+
+// CountSince counts across every merchant on purpose: the same card or
+// email hammering several merchants is still a velocity signal.
+func (r *AssessmentRepositoryImpl) CountSince(ctx context.Context, field, value string, since time.Time) (int64, error) {
+	column, ok := velocityColumns[field]
+	if !ok {
+		return 0, fmt.Errorf("unknown velocity field %q", field)
+	}
+	if field == "email" {
+		value = r.cipher.BlindIndex(value)
+	}
+
+	var count int64
+	err := r.db.WithContext(ctx).Model(&domain.RiskAssessment{}).
+		Where(column+" = ? AND created_at >= ?", value, since).
+		Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,25 @@
+package router
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/williamkoller/payment-system/internal/middleware"
+	ratelimit "github.com/williamkoller/payment-system/internal/ratelimit/domain"
+	"github.com/williamkoller/payment-system/internal/risk/application"
+	"github.com/williamkoller/payment-system/internal/risk/interfaces"
+	"github.com/williamkoller/payment-system/internal/risk/repository"
+	"github.com/williamkoller/payment-system/pkg/auth"
+	"gorm.io/gorm"
+)
+
+func NewEngine(db *gorm.DB, rulesFile string) (*application.Engine, error) {
+	cfg, err := application.LoadRulesConfig(rulesFile)
+	if err != nil {
+		return nil, err
+	}
+	return application.NewEngine(cfg, repository.NewAssessmentRepository(db))
+}
+
+func SetupRouter(e *gin.Engine, engine *application.Engine, authn gin.HandlerFunc, limits *middleware.RateLimits) {
+	handler := interfaces.NewRiskHandler(engine)
+	e.GET("/payments/:payment_id/risk", limits.PerIP(), authn, middleware.RequireScope(auth.ScopePaymentsRead), limits.PerClient(ratelimit.ClassRead), handler.GetAssessment)
+}
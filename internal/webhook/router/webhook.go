@@ -3,20 +3,30 @@ package router
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/williamkoller/payment-system/config"
-	"github.com/williamkoller/payment-system/internal/payment/repository"
+	"github.com/williamkoller/payment-system/internal/payment/application"
+	paymentRouter "github.com/williamkoller/payment-system/internal/payment/router"
 	"github.com/williamkoller/payment-system/internal/webhook/stripe"
 	"gorm.io/gorm"
 )
 
-func SetupWebhookRouter(e *gin.Engine, db *gorm.DB) {
+// SetupWebhookRouter mounts the platform endpoint and, for merchants with
+// their own Stripe account, /webhook/stripe/:merchant_id. Status changes
+// are published to events, which may be nil.
+func SetupWebhookRouter(e *gin.Engine, db *gorm.DB, merchants stripe.WebhookSecrets, events *application.StatusBroker) {
 	cfg, err := config.LoadConfiguration()
 	if err != nil {
 		panic("cannot load configuration: " + err.Error())
 	}
 
-	repo := repository.NewPaymentRepository(db)
+	repo, err := paymentRouter.NewPaymentRepository(db, cfg.PaymentStore)
+	if err != nil {
+		panic("cannot create payment repository: " + err.Error())
+	}
 	processor := stripe.NewStripeProcessor(repo)
+	processor.Events = events
 	handler := stripe.NewStripeWebhookHandler(cfg.Stripe.StripeWebhook, processor)
+	handler.Merchants = merchants
 
 	e.POST("/webhook/stripe", handler.Handle)
+	e.POST("/webhook/stripe/:merchant_id", handler.HandleMerchant)
 }
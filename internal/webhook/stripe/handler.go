@@ -1,6 +1,7 @@
 package stripe
 
 import (
+	"context"
 	"encoding/json"
 	"io"
 	"net/http"
@@ -8,19 +9,58 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/stripe/stripe-go"
 	"github.com/stripe/stripe-go/webhook"
+	"github.com/williamkoller/payment-system/internal/metrics"
+	"github.com/williamkoller/payment-system/internal/payment/domain"
+	"github.com/williamkoller/payment-system/pkg/auth"
 	"github.com/williamkoller/payment-system/pkg/logger"
+	"github.com/williamkoller/payment-system/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// WebhookSecrets looks up the signing secret of a merchant's own Stripe
+// account.
+type WebhookSecrets interface {
+	StripeWebhookSecret(ctx context.Context, merchantID string) (string, error)
+}
+
 type StripeWebhookHandler struct {
 	secret    string
 	processor *StripeProcessor
+	// Merchants is optional; without it only the platform endpoint works.
+	Merchants WebhookSecrets
 }
 
 func NewStripeWebhookHandler(secret string, processor *StripeProcessor) *StripeWebhookHandler {
 	return &StripeWebhookHandler{secret: secret, processor: processor}
 }
 
+// Handle receives the platform account's webhooks.
 func (h *StripeWebhookHandler) Handle(c *gin.Context) {
+	h.process(c.Request.Context(), c, h.secret)
+}
+
+// HandleMerchant receives webhooks of a merchant's own Stripe account. They
+// are verified with that merchant's secret and may only touch its payments.
+func (h *StripeWebhookHandler) HandleMerchant(c *gin.Context) {
+	merchantID := c.Param("merchant_id")
+
+	var secret string
+	var err error
+	if h.Merchants != nil {
+		secret, err = h.Merchants.StripeWebhookSecret(c.Request.Context(), merchantID)
+	}
+	if err != nil || secret == "" {
+		logger.Default().Errorw("no webhook secret for merchant", "merchant_id", merchantID, "err", err)
+		metrics.WebhookInvalidSignature()
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	h.process(auth.ForMerchant(c.Request.Context(), merchantID), c, secret)
+}
+
+func (h *StripeWebhookHandler) process(ctx context.Context, c *gin.Context, secret string) {
 	const MaxBodyBytes = int64(65536)
 	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, MaxBodyBytes)
 
@@ -32,36 +72,61 @@ func (h *StripeWebhookHandler) Handle(c *gin.Context) {
 	}
 
 	sigHeader := c.GetHeader("Stripe-Signature")
-	event, err := webhook.ConstructEvent(payload, sigHeader, h.secret)
+	event, err := webhook.ConstructEvent(payload, sigHeader, secret)
 	if err != nil {
 		logger.Default().Errorw("invalid webhook signature", "err", err)
+		metrics.WebhookInvalidSignature()
 		c.Status(http.StatusBadRequest)
 		return
 	}
 
 	logger.Default().Infow("received stripe webhook event", "type", event.Type)
 
+	ctx, span := tracing.Start(ctx, "StripeWebhook "+event.Type, trace.WithAttributes(
+		attribute.String("stripe.event_id", event.ID),
+		attribute.String("stripe.event_type", event.Type),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	var handle func(context.Context, *stripe.PaymentIntent) error
 	switch event.Type {
 	case "payment_intent.succeeded":
-		var pi stripe.PaymentIntent
-		if err := json.Unmarshal(event.Data.Raw, &pi); err != nil {
-			logger.Default().Errorw("failed unmarshal payment_intent.succeeded", "err", err)
-		} else {
-			if err := h.processor.HandleSucceeded(&pi); err != nil {
-				logger.Default().Errorw("processor handle succeeded error", "err", err)
-			}
-		}
+		handle = h.processor.HandleSucceeded
+	case "payment_intent.amount_capturable_updated":
+		handle = h.processor.HandleAuthorized
 	case "payment_intent.payment_failed":
-		var pi stripe.PaymentIntent
-		if err := json.Unmarshal(event.Data.Raw, &pi); err != nil {
-			logger.Default().Errorw("failed unmarshal payment_intent.payment_failed", "err", err)
-		} else {
-			if err := h.processor.HandleFailed(&pi); err != nil {
-				logger.Default().Errorw("processor handle failed error", "err", err)
-			}
-		}
+		handle = h.processor.HandleFailed
 	default:
 		logger.Default().Infow("unhandled stripe event", "type", event.Type)
+		metrics.WebhookIgnored(event.Type)
+		c.Status(http.StatusOK)
+		return
+	}
+
+	var pi stripe.PaymentIntent
+	if err = json.Unmarshal(event.Data.Raw, &pi); err != nil {
+		logger.Default().Errorw("failed unmarshal "+event.Type, "err", err)
+	} else {
+		// request_id is the id of the API request that created the intent,
+		// linking this delivery back to the original payment request.
+		originRequestID := pi.Metadata["request_id"]
+		span.SetAttributes(attribute.String("payment.origin_request_id", originRequestID))
+		logger.Default().Infow("processing stripe webhook event",
+			"type", event.Type,
+			"payment_intent", pi.ID,
+			"payment_id", pi.Metadata["payment_id"],
+			"origin_request_id", originRequestID,
+		)
+
+		if err = handle(domain.WithSource(ctx, domain.SourceWebhook), &pi); err != nil {
+			logger.Default().Errorw("processor error", "type", event.Type, "err", err)
+		}
+	}
+
+	if err != nil {
+		metrics.WebhookFailed(event.Type)
+	} else {
+		metrics.WebhookProcessed(event.Type)
 	}
 
 	c.Status(http.StatusOK)
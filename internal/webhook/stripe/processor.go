@@ -1,41 +1,76 @@
 package stripe
 
 import (
+	"context"
 	"errors"
+	"time"
 
 	"github.com/stripe/stripe-go"
 	"github.com/williamkoller/payment-system/internal/payment/application"
+	"github.com/williamkoller/payment-system/internal/payment/domain"
 )
 
 type StripeProcessor struct {
 	paymentRepo application.PaymentRepository
+	// Events is optional; it is told about the status changes webhooks
+	// make.
+	Events *application.StatusBroker
 }
 
 func NewStripeProcessor(paymentRepo application.PaymentRepository) *StripeProcessor {
-	return &StripeProcessor{paymentRepo}
+	return &StripeProcessor{paymentRepo: paymentRepo}
 }
 
-func (p *StripeProcessor) HandleSucceeded(pi *stripe.PaymentIntent) error {
+func (p *StripeProcessor) HandleSucceeded(ctx context.Context, pi *stripe.PaymentIntent) error {
 	if pi == nil {
 		return errors.New("nil payment")
 	}
 
-	payment, err := p.paymentRepo.FindByStripeID(pi.ID)
+	payment, err := p.paymentRepo.FindByStripeID(ctx, pi.ID)
 	if err != nil {
 		return err
 	}
 	payment.Complete()
-	return p.paymentRepo.Update(payment)
+	return application.UpdateAndPublish(ctx, p.paymentRepo, p.Events, payment)
 }
 
-func (p *StripeProcessor) HandleFailed(pi *stripe.PaymentIntent) error {
+// HandleAuthorized completes payments whose authorization finished
+// asynchronously, e.g. after a 3D Secure challenge.
+func (p *StripeProcessor) HandleAuthorized(ctx context.Context, pi *stripe.PaymentIntent) error {
 	if pi == nil {
 		return errors.New("nil PaymentIntent")
 	}
-	payment, err := p.paymentRepo.FindByStripeID(pi.ID)
+	payment, err := p.paymentRepo.FindByStripeID(ctx, pi.ID)
+	if err != nil {
+		return err
+	}
+	if payment.Status != domain.StatusPending {
+		return nil
+	}
+	authorizedAt := time.Now()
+	if pi.Created > 0 {
+		authorizedAt = time.Unix(pi.Created, 0)
+	}
+	payment.Complete()
+	payment.SetAuthorizationExpiresAt(authorizedAt)
+	return application.UpdateAndPublish(ctx, p.paymentRepo, p.Events, payment)
+}
+
+func (p *StripeProcessor) HandleFailed(ctx context.Context, pi *stripe.PaymentIntent) error {
+	if pi == nil {
+		return errors.New("nil PaymentIntent")
+	}
+	payment, err := p.paymentRepo.FindByStripeID(ctx, pi.ID)
 	if err != nil {
 		return err
 	}
 	payment.Fail()
-	return p.paymentRepo.Update(payment)
+	if e := pi.LastPaymentError; e != nil {
+		code := string(e.DeclineCode)
+		if code == "" {
+			code = string(e.Code)
+		}
+		payment.Explain(e.Msg, code)
+	}
+	return application.UpdateAndPublish(ctx, p.paymentRepo, p.Events, payment)
 }
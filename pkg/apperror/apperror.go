@@ -0,0 +1,92 @@
+// Package apperror defines the typed errors use cases return. Kind decides
+// the HTTP status; Code is a stable, machine-readable identifier clients can
+// switch on and never changes once published (see docs/errors.md).
+package apperror
+
+import "errors"
+
+type Kind string
+
+const (
+	KindValidation          Kind = "validation"
+	KindUnauthenticated     Kind = "unauthenticated"
+	KindForbidden           Kind = "forbidden"
+	KindNotFound            Kind = "not_found"
+	KindNotAcceptable       Kind = "not_acceptable"
+	KindInvalidTransition   Kind = "invalid_transition"
+	KindIdempotencyConflict Kind = "idempotency_conflict"
+	KindUnprocessable       Kind = "unprocessable"
+	KindDeclined            Kind = "declined"
+	KindRateLimited         Kind = "rate_limited"
+	KindGateway             Kind = "gateway"
+	KindGatewayUnavailable  Kind = "gateway_unavailable"
+	KindInternal            Kind = "internal"
+)
+
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	// DeclineCode explains a KindDeclined error, e.g. the issuer's decline
+	// code or the block list that matched.
+	DeclineCode string
+	Err         error
+}
+
+func New(kind Kind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is matches errors by Code, so package-level sentinels keep working with
+// errors.Is after Wrap or WithDecline.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// Wrap returns a copy of e caused by err.
+func (e *Error) Wrap(err error) *Error {
+	c := *e
+	c.Err = err
+	return &c
+}
+
+// WithDecline returns a copy of e carrying declineCode.
+func (e *Error) WithDecline(declineCode string) *Error {
+	c := *e
+	c.DeclineCode = declineCode
+	return &c
+}
+
+// WithMessage returns a copy of e with a more specific message.
+func (e *Error) WithMessage(message string) *Error {
+	c := *e
+	c.Message = message
+	return &c
+}
+
+// Validation wraps a request binding or validation failure.
+func Validation(err error) *Error {
+	return &Error{Kind: KindValidation, Code: "validation_failed", Message: "request validation failed", Err: err}
+}
+
+// As returns the outermost *Error in err's chain, or a KindInternal error
+// wrapping err when there is none.
+func As(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return &Error{Kind: KindInternal, Code: "internal_error", Message: "internal server error", Err: err}
+}
@@ -0,0 +1,75 @@
+// Package auth carries the authenticated caller of a request through
+// context.Context, so use cases can see who is acting without depending on
+// the HTTP layer.
+package auth
+
+import (
+	"context"
+	"slices"
+)
+
+type Scope string
+
+const (
+	ScopePaymentsRead  Scope = "payments:read"
+	ScopePaymentsWrite Scope = "payments:write"
+	ScopeRefundsWrite  Scope = "refunds:write"
+	ScopeReviewsWrite  Scope = "reviews:write"
+	// ScopeAdmin grants every other merchant scope as well.
+	ScopeAdmin Scope = "admin"
+	// ScopePlatform is for the operators of the platform rather than a
+	// merchant: it manages merchants, their API keys, the block/allow lists
+	// and reconciliation. Keys granted it belong to no merchant, and admin
+	// does not imply it.
+	ScopePlatform Scope = "platform"
+)
+
+// Scopes lists every scope a key can be granted.
+var Scopes = []Scope{ScopePaymentsRead, ScopePaymentsWrite, ScopeRefundsWrite, ScopeReviewsWrite, ScopeAdmin, ScopePlatform}
+
+func (s Scope) Valid() bool {
+	return slices.Contains(Scopes, s)
+}
+
+// Principal is the caller an API key resolved to.
+type Principal struct {
+	KeyID      string
+	MerchantID string
+	Live       bool
+	Scopes     []Scope
+}
+
+func (p *Principal) HasScope(scope Scope) bool {
+	if slices.Contains(p.Scopes, scope) {
+		return true
+	}
+	return scope != ScopePlatform && slices.Contains(p.Scopes, ScopeAdmin)
+}
+
+type contextKey struct{}
+
+func NewContext(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, p)
+}
+
+// FromContext returns the principal stored in ctx, or nil for
+// unauthenticated requests and background work.
+func FromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(contextKey{}).(*Principal)
+	return p
+}
+
+// MerchantID returns the merchant ctx acts for, or "" when it is not scoped
+// to one: background workers, or every request with AUTH_ENABLED=false.
+func MerchantID(ctx context.Context) string {
+	if p := FromContext(ctx); p != nil {
+		return p.MerchantID
+	}
+	return ""
+}
+
+// ForMerchant scopes ctx to merchantID without an API key, for work done on
+// a merchant's behalf such as processing its Stripe webhooks.
+func ForMerchant(ctx context.Context, merchantID string) context.Context {
+	return NewContext(ctx, &Principal{MerchantID: merchantID})
+}
@@ -0,0 +1,239 @@
+// Package client is a Go client for the payment API.
+//
+// It sends every payment it creates with an Idempotency-Key, retries
+// requests that failed with a 5xx, a 429 or a network error, and returns
+// API errors as *Error. It does not import the service's internal
+// packages, so it can be vendored on its own.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/williamkoller/payment-system/pkg/ulid"
+)
+
+const (
+	DefaultMaxRetries = 3
+	DefaultMinBackoff = 500 * time.Millisecond
+	DefaultMaxBackoff = 8 * time.Second
+
+	requestIDHeader      = "X-Request-ID"
+	idempotencyKeyHeader = "Idempotency-Key"
+
+	// maxErrorBody bounds how much of an error response is read.
+	maxErrorBody = 1 << 20
+)
+
+type Client struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+	maxRetries int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+}
+
+type Option func(*Client)
+
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) { c.httpClient = httpClient }
+}
+
+// WithMaxRetries sets how many times a failed request is retried; 0
+// disables retries.
+func WithMaxRetries(n int) Option {
+	return func(c *Client) { c.maxRetries = n }
+}
+
+// WithBackoff sets the delay before the first retry and the cap the
+// doubling delay does not grow past.
+func WithBackoff(min, max time.Duration) Option {
+	return func(c *Client) { c.minBackoff, c.maxBackoff = min, max }
+}
+
+// New returns a client for the API at baseURL, e.g. https://pay.example.com,
+// authenticating with apiKey.
+func New(baseURL, apiKey string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+		httpClient: http.DefaultClient,
+		maxRetries: DefaultMaxRetries,
+		minBackoff: DefaultMinBackoff,
+		maxBackoff: DefaultMaxBackoff,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+type request struct {
+	method         string
+	path           string
+	query          url.Values
+	body           any
+	idempotencyKey string
+	// header is sent on top of the defaults, e.g. to ask for another
+	// format than JSON.
+	header http.Header
+}
+
+// do sends r and decodes the response into out. Every attempt carries the
+// same X-Request-ID and Idempotency-Key: the API derives the gateway's
+// idempotency key from the request id, so a retried capture or refund is
+// not applied twice.
+func (c *Client) do(ctx context.Context, r request, out any) error {
+	resp, requestID, err := c.open(ctx, r)
+	if err != nil {
+		return err
+	}
+	return decode(resp, requestID, out)
+}
+
+// stream sends r like do, but hands back the body of a successful response
+// for the caller to read as it arrives and close.
+func (c *Client) stream(ctx context.Context, r request) (io.ReadCloser, error) {
+	resp, requestID, err := c.open(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		defer resp.Body.Close()
+		return nil, newError(resp, requestID)
+	}
+	return resp.Body, nil
+}
+
+// open sends r, retrying as do describes, and returns the first response
+// that is not retried along with the request id all attempts carried.
+func (c *Client) open(ctx context.Context, r request) (*http.Response, string, error) {
+	var body []byte
+	if r.body != nil {
+		var err error
+		if body, err = json.Marshal(r.body); err != nil {
+			return nil, "", fmt.Errorf("client: encode request: %w", err)
+		}
+	}
+	requestID := ulid.NewULID()
+
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, r, body, requestID)
+		if err != nil {
+			if ctx.Err() != nil || attempt >= c.maxRetries {
+				return nil, "", err
+			}
+			if err := c.wait(ctx, attempt, 0); err != nil {
+				return nil, "", err
+			}
+			continue
+		}
+
+		if retryable(resp.StatusCode) && attempt < c.maxRetries {
+			retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"))
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxErrorBody))
+			resp.Body.Close()
+			if err := c.wait(ctx, attempt, retryAfter); err != nil {
+				return nil, "", err
+			}
+			continue
+		}
+
+		return resp, requestID, nil
+	}
+}
+
+func (c *Client) send(ctx context.Context, r request, body []byte, requestID string) (*http.Response, error) {
+	target := c.baseURL + r.path
+	if len(r.query) > 0 {
+		target += "?" + r.query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, r.method, target, reader)
+	if err != nil {
+		return nil, fmt.Errorf("client: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set(requestIDHeader, requestID)
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if r.idempotencyKey != "" {
+		req.Header.Set(idempotencyKeyHeader, r.idempotencyKey)
+	}
+	for key, values := range r.header {
+		req.Header[key] = values
+	}
+	return c.httpClient.Do(req)
+}
+
+// wait sleeps before retry attempt+1: an exponential backoff with jitter,
+// or what the server asked for in Retry-After if that is longer.
+func (c *Client) wait(ctx context.Context, attempt int, retryAfter time.Duration) error {
+	delay := c.minBackoff << attempt
+	if delay > c.maxBackoff || delay <= 0 {
+		delay = c.maxBackoff
+	}
+	if delay > 0 {
+		delay = delay/2 + rand.N(delay/2+1)
+	}
+	if retryAfter > delay {
+		delay = retryAfter
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func retryable(status int) bool {
+	return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
+
+// parseRetryAfter reads a Retry-After given in seconds, which is the only
+// form the API sends.
+func parseRetryAfter(value string) time.Duration {
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+func decode(resp *http.Response, requestID string, out any) error {
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return newError(resp, requestID)
+	}
+	if out == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("client: decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,634 @@
+package client_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stripe/stripe-go"
+	apikey "github.com/williamkoller/payment-system/internal/apikey/application"
+	auditApplication "github.com/williamkoller/payment-system/internal/audit/application"
+	auditDomain "github.com/williamkoller/payment-system/internal/audit/domain"
+	auditRouter "github.com/williamkoller/payment-system/internal/audit/router"
+	batchApplication "github.com/williamkoller/payment-system/internal/batch/application"
+	batchDomain "github.com/williamkoller/payment-system/internal/batch/domain"
+	batchRouter "github.com/williamkoller/payment-system/internal/batch/router"
+	fxApplication "github.com/williamkoller/payment-system/internal/fx/application"
+	fxDomain "github.com/williamkoller/payment-system/internal/fx/domain"
+	fxInfra "github.com/williamkoller/payment-system/internal/fx/infra"
+	fxRouter "github.com/williamkoller/payment-system/internal/fx/router"
+	"github.com/williamkoller/payment-system/internal/middleware"
+	"github.com/williamkoller/payment-system/internal/payment/application"
+	"github.com/williamkoller/payment-system/internal/payment/domain"
+	"github.com/williamkoller/payment-system/internal/payment/infra"
+	paymentRouter "github.com/williamkoller/payment-system/internal/payment/router"
+	riskApplication "github.com/williamkoller/payment-system/internal/risk/application"
+	riskDomain "github.com/williamkoller/payment-system/internal/risk/domain"
+	riskRouter "github.com/williamkoller/payment-system/internal/risk/router"
+	"github.com/williamkoller/payment-system/pkg/auth"
+	"github.com/williamkoller/payment-system/pkg/client"
+	"github.com/williamkoller/payment-system/pkg/logger"
+	"github.com/williamkoller/payment-system/pkg/ulid"
+	"gorm.io/gorm"
+)
+
+const apiKey = "sk_test_admin"
+
+type fakePayments struct {
+	mu       sync.Mutex
+	payments map[string]*domain.Payment
+	order    []string
+	changes  map[string][]*domain.StatusChange
+}
+
+func (f *fakePayments) Save(ctx context.Context, p *domain.Payment) (*domain.Payment, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.payments[p.ID] = p
+	f.order = append(f.order, p.ID)
+	f.saveChanges(ctx, p)
+	return p, nil
+}
+
+// saveChanges records the payment's timeline like the real repository.
+func (f *fakePayments) saveChanges(ctx context.Context, p *domain.Payment) {
+	for _, c := range p.PendingStatusChanges() {
+		c.ID = ulid.NewULID()
+		c.Source = domain.SourceFromContext(ctx)
+		f.changes[p.ID] = append(f.changes[p.ID], c)
+	}
+	p.StatusChangesSaved()
+}
+
+func (f *fakePayments) FindByID(_ context.Context, id string) (*domain.Payment, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if p, ok := f.payments[id]; ok {
+		return p, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f *fakePayments) FindAll(context.Context) ([]*domain.Payment, error) { return nil, nil }
+func (f *fakePayments) Remove(context.Context, string) error               { return nil }
+
+func (f *fakePayments) Update(ctx context.Context, p *domain.Payment) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.payments[p.ID] = p
+	f.saveChanges(ctx, p)
+	return nil
+}
+
+func (f *fakePayments) FindByStripeID(context.Context, string) (*domain.Payment, error) {
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f *fakePayments) FindByIdempotencyKey(_ context.Context, key string) (*domain.Payment, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, p := range f.payments {
+		if p.IdempotencyKey == key {
+			return p, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// List returns payments newest first, paged like the real repository.
+func (f *fakePayments) List(_ context.Context, filter domain.PaymentFilter) ([]*domain.Payment, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var page []*domain.Payment
+	skipping := filter.StartingAfter != ""
+	for i := len(f.order) - 1; i >= 0; i-- {
+		id := f.order[i]
+		if skipping {
+			skipping = id != filter.StartingAfter
+			continue
+		}
+		if filter.Limit > 0 && len(page) == filter.Limit {
+			break
+		}
+		page = append(page, f.payments[id])
+	}
+	return page, nil
+}
+
+func (f *fakePayments) Each(ctx context.Context, filter domain.PaymentFilter, fn func(*domain.Payment) error) error {
+	payments, _ := f.List(ctx, filter)
+	for _, p := range payments {
+		if err := fn(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *fakePayments) FindStatusChanges(_ context.Context, paymentID string) ([]*domain.StatusChange, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.changes[paymentID]), nil
+}
+
+func (f *fakePayments) SaveFailedAttempt(context.Context, *domain.FailedAttempt) error {
+	return nil
+}
+
+func (f *fakePayments) FindFailedAttempts(context.Context, string) ([]*domain.FailedAttempt, error) {
+	return nil, nil
+}
+
+type fakeGateway struct {
+	intents atomic.Int32
+	err     error
+}
+
+func (f *fakeGateway) CreatePaymentIntent(_ context.Context, input infra.PaymentIntentInput) (*stripe.PaymentIntent, error) {
+	f.intents.Add(1)
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &stripe.PaymentIntent{ID: "pi_" + input.PaymentID, Status: stripe.PaymentIntentStatusRequiresCapture}, nil
+}
+
+func (f *fakeGateway) Card(context.Context) (infra.Card, error) { return infra.Card{}, nil }
+func (f *fakeGateway) Capture(context.Context, string) error    { return nil }
+func (f *fakeGateway) Cancel(context.Context, string) error     { return nil }
+func (f *fakeGateway) Refund(context.Context, string, int64) error {
+	return nil
+}
+
+type fakeAuditLog struct {
+	mu      sync.Mutex
+	entries []*auditDomain.Entry
+}
+
+func (f *fakeAuditLog) Append(_ context.Context, entry *auditDomain.Entry) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	entry.Seq = int64(len(f.entries) + 1)
+	f.entries = append(f.entries, entry)
+	return nil
+}
+
+func (f *fakeAuditLog) FindByResource(_ context.Context, resourceType, resourceID string) ([]*auditDomain.Entry, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var found []*auditDomain.Entry
+	for _, e := range f.entries {
+		if e.ResourceType == resourceType && e.ResourceID == resourceID {
+			found = append(found, e)
+		}
+	}
+	return found, nil
+}
+
+func (f *fakeAuditLog) FindAfter(context.Context, int64, int) ([]*auditDomain.Entry, error) {
+	return nil, nil
+}
+
+type fakeAssessments struct {
+	mu          sync.Mutex
+	assessments map[string]*riskDomain.RiskAssessment
+}
+
+func (f *fakeAssessments) CountSince(context.Context, string, string, time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (f *fakeAssessments) Save(_ context.Context, a *riskDomain.RiskAssessment) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.assessments[a.PaymentID] = a
+	return nil
+}
+
+func (f *fakeAssessments) Update(ctx context.Context, a *riskDomain.RiskAssessment) error {
+	return f.Save(ctx, a)
+}
+
+func (f *fakeAssessments) FindByPaymentID(_ context.Context, paymentID string) (*riskDomain.RiskAssessment, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if a, ok := f.assessments[paymentID]; ok {
+		return a, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// fakeBatches queues batches without running them; no processor claims
+// their items.
+type fakeBatches struct {
+	mu      sync.Mutex
+	batches map[string]*batchDomain.Batch
+}
+
+func (f *fakeBatches) Create(_ context.Context, batch *batchDomain.Batch) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.batches[batch.ID] = batch
+	return nil
+}
+
+func (f *fakeBatches) FindByID(_ context.Context, id string) (*batchDomain.Batch, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if batch, ok := f.batches[id]; ok {
+		return batch, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f *fakeBatches) Claim(context.Context, time.Time, time.Time, int) ([]*batchDomain.Item, error) {
+	return nil, nil
+}
+
+func (f *fakeBatches) Save(context.Context, *batchDomain.Item) error { return nil }
+
+type fakeQuotes struct {
+	mu     sync.Mutex
+	quotes map[string]*fxDomain.FxQuote
+}
+
+func (f *fakeQuotes) Save(_ context.Context, quote *fxDomain.FxQuote) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.quotes[quote.ID] = quote
+	return nil
+}
+
+func (f *fakeQuotes) FindByID(_ context.Context, id string) (*fxDomain.FxQuote, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if quote, ok := f.quotes[id]; ok {
+		return quote, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+type fakeReports struct{}
+
+func (fakeReports) SettlementTotals(context.Context, time.Time, time.Time) ([]fxDomain.SettlementTotal, error) {
+	return []fxDomain.SettlementTotal{{Status: "CAPTURED", SettlementCurrency: "USD", Count: 2, Amount: 2500}}, nil
+}
+
+type fakeAuthenticator struct{}
+
+func (fakeAuthenticator) Authenticate(_ context.Context, key string) (*auth.Principal, error) {
+	if key != apiKey {
+		return nil, apikey.ErrInvalidAPIKey
+	}
+	return &auth.Principal{KeyID: "k1", Scopes: []auth.Scope{auth.ScopeAdmin}}, nil
+}
+
+// newServer serves the real payment, audit, risk, batch and fx routes over
+// gateway and in-memory repositories. wrap, when set, sits in front of the
+// router to simulate failures.
+func newServer(t *testing.T, gateway *fakeGateway, wrap func(http.Handler) http.Handler) *httptest.Server {
+	t.Helper()
+	require.NoError(t, logger.InitLogger("dev"))
+	gin.SetMode(gin.TestMode)
+
+	audit := auditApplication.NewAuditService(&fakeAuditLog{})
+	risk, err := riskApplication.NewEngine(riskApplication.DefaultRulesConfig(), &fakeAssessments{assessments: map[string]*riskDomain.RiskAssessment{}})
+	require.NoError(t, err)
+	events := application.NewStatusBroker(0, 0)
+	events.HeartbeatInterval = 10 * time.Millisecond
+	t.Cleanup(events.Close)
+
+	usecase := application.NewPaymentUseCase(&fakePayments{payments: map[string]*domain.Payment{}, changes: map[string][]*domain.StatusChange{}}, gateway)
+	usecase.Audit = audit
+	usecase.Risk = risk
+	usecase.Events = events
+
+	rates := fxInfra.NewStaticRateProvider(map[string]string{"EUR/USD": "1.1"})
+	quotes := fxApplication.NewQuoteService(rates, &fakeQuotes{quotes: map[string]*fxDomain.FxQuote{}}, "USD", time.Minute)
+
+	authn := middleware.Auth(fakeAuthenticator{})
+	e := gin.New()
+	middleware.Middlewares(e)
+	paymentRouter.SetupRouter(e, usecase, authn, nil)
+	auditRouter.SetupRouter(e, audit, authn, nil)
+	riskRouter.SetupRouter(e, risk, authn, nil)
+	batchRouter.SetupRouter(e, batchApplication.NewBatchService(&fakeBatches{batches: map[string]*batchDomain.Batch{}}), authn, nil)
+	fxRouter.SetupRouter(e, quotes, fxApplication.NewReportService(fakeReports{}), authn, nil)
+
+	var handler http.Handler = e
+	if wrap != nil {
+		handler = wrap(e)
+	}
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newClient(server *httptest.Server, key string) *client.Client {
+	return client.New(server.URL, key, client.WithBackoff(time.Millisecond, 5*time.Millisecond))
+}
+
+var charge = client.CreatePaymentParams{
+	Amount:        1000,
+	Currency:      "USD",
+	Email:         "user@example.com",
+	PaymentMethod: "pm_card_visa",
+}
+
+func TestClient_CreatePayment_RetryKeepsIdempotencyKey(t *testing.T) {
+	gateway := &fakeGateway{}
+	var keys, requestIDs []string
+	server := newServer(t, gateway, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			keys = append(keys, r.Header.Get("Idempotency-Key"))
+			requestIDs = append(requestIDs, r.Header.Get("X-Request-ID"))
+			if len(keys) == 1 {
+				// The payment is created but the response is lost.
+				next.ServeHTTP(httptest.NewRecorder(), r)
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+
+	payment, err := newClient(server, apiKey).CreatePayment(context.Background(), charge)
+
+	require.NoError(t, err)
+	assert.Equal(t, client.StatusCompleted, payment.Status)
+	assert.Equal(t, int32(1), gateway.intents.Load(), "the retry must not charge again")
+	require.Len(t, keys, 2)
+	assert.NotEmpty(t, keys[0])
+	assert.Equal(t, keys[0], keys[1])
+	assert.Equal(t, requestIDs[0], requestIDs[1])
+	assert.Equal(t, keys[0], payment.IdempotencyKey)
+}
+
+func TestClient_RetriesUntilMaxRetries(t *testing.T) {
+	var attempts atomic.Int32
+	server := newServer(t, &fakeGateway{}, func(http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts.Add(1)
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"status":429,"title":"Too Many Requests","code":"rate_limited","request_id":"req_1"}`))
+		})
+	})
+	c := client.New(server.URL, apiKey, client.WithMaxRetries(2), client.WithBackoff(time.Millisecond, time.Millisecond))
+
+	_, err := c.GetPayment(context.Background(), "pay_1")
+
+	assert.ErrorIs(t, err, client.ErrRateLimited)
+	assert.Equal(t, int32(3), attempts.Load())
+}
+
+func TestClient_TypedErrors(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("not found", func(t *testing.T) {
+		_, err := newClient(newServer(t, &fakeGateway{}, nil), apiKey).GetPayment(ctx, "missing")
+
+		var apiErr *client.Error
+		require.ErrorAs(t, err, &apiErr)
+		assert.ErrorIs(t, err, client.ErrPaymentNotFound)
+		assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+		assert.NotEmpty(t, apiErr.RequestID)
+	})
+
+	t.Run("invalid api key", func(t *testing.T) {
+		_, err := newClient(newServer(t, &fakeGateway{}, nil), "sk_test_wrong").GetPayment(ctx, "pay_1")
+
+		assert.ErrorIs(t, err, client.ErrInvalidAPIKey)
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		_, err := newClient(newServer(t, &fakeGateway{}, nil), apiKey).CreatePayment(ctx, client.CreatePaymentParams{Amount: 1000})
+
+		var apiErr *client.Error
+		require.ErrorAs(t, err, &apiErr)
+		assert.ErrorIs(t, err, client.ErrValidation)
+		assert.NotEmpty(t, apiErr.Errors)
+	})
+
+	t.Run("card declined", func(t *testing.T) {
+		gateway := &fakeGateway{err: &stripe.Error{Type: stripe.ErrorTypeCard, DeclineCode: stripe.DeclineCodeInsufficientFunds}}
+		_, err := newClient(newServer(t, gateway, nil), apiKey).CreatePayment(ctx, charge)
+
+		var apiErr *client.Error
+		require.ErrorAs(t, err, &apiErr)
+		assert.ErrorIs(t, err, client.ErrCardDeclined)
+		assert.Equal(t, "insufficient_funds", apiErr.DeclineCode)
+		assert.NotEmpty(t, apiErr.PaymentID)
+		assert.Equal(t, client.StatusFailed, apiErr.PaymentStatus)
+	})
+
+	t.Run("idempotency key reused", func(t *testing.T) {
+		c := newClient(newServer(t, &fakeGateway{}, nil), apiKey)
+		first := charge
+		first.IdempotencyKey = "order_42"
+		_, err := c.CreatePayment(ctx, first)
+		require.NoError(t, err)
+
+		second := first
+		second.Amount = 2000
+		_, err = c.CreatePayment(ctx, second)
+
+		assert.ErrorIs(t, err, client.ErrIdempotencyKeyReused)
+	})
+
+	t.Run("already captured", func(t *testing.T) {
+		c := newClient(newServer(t, &fakeGateway{}, nil), apiKey)
+		payment, err := c.CreatePayment(ctx, charge)
+		require.NoError(t, err)
+		captured, err := c.Capture(ctx, payment.ID)
+		require.NoError(t, err)
+		assert.Equal(t, client.StatusCaptured, captured.Status)
+
+		_, err = c.Cancel(ctx, payment.ID)
+
+		assert.ErrorIs(t, err, client.ErrAlreadyCaptured)
+		assert.NotErrorIs(t, err, client.ErrAlreadyCanceled)
+	})
+}
+
+func TestClient_ListPayments_Pages(t *testing.T) {
+	var lists atomic.Int32
+	server := newServer(t, &fakeGateway{}, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet && r.URL.Path == "/payments/" {
+				lists.Add(1)
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+	c := newClient(server, apiKey)
+	ctx := context.Background()
+
+	created := make(map[string]bool)
+	for amount := int64(1000); amount < 1005; amount++ {
+		params := charge
+		params.Amount = amount
+		payment, err := c.CreatePayment(ctx, params)
+		require.NoError(t, err)
+		created[payment.ID] = true
+	}
+
+	listed := make(map[string]bool)
+	it := c.ListPayments(ctx, client.ListPaymentsParams{PageSize: 2})
+	for it.Next() {
+		listed[it.Payment().ID] = true
+	}
+
+	require.NoError(t, it.Err())
+	assert.Equal(t, created, listed)
+	assert.Equal(t, int32(3), lists.Load())
+}
+
+func TestClient_AuditAndRisk(t *testing.T) {
+	c := newClient(newServer(t, &fakeGateway{}, nil), apiKey)
+	ctx := context.Background()
+	payment, err := c.CreatePayment(ctx, charge)
+	require.NoError(t, err)
+
+	entries, err := c.Audit(ctx, payment.ID)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "payment.create", entries[0].Action)
+	assert.Equal(t, payment.ID, entries[0].ResourceID)
+	assert.Equal(t, "k1", entries[0].APIKeyID)
+
+	assessment, err := c.RiskAssessment(ctx, payment.ID)
+	require.NoError(t, err)
+	assert.Equal(t, payment.ID, assessment.PaymentID)
+	assert.Equal(t, client.RiskAllow, assessment.Decision)
+
+	_, err = c.RiskAssessment(ctx, "missing")
+	assert.ErrorIs(t, err, client.ErrRiskAssessmentNotFound)
+}
+
+func TestClient_WatchStatus_Resumes(t *testing.T) {
+	c := newClient(newServer(t, &fakeGateway{}, nil), apiKey)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	payment, err := c.CreatePayment(ctx, charge)
+	require.NoError(t, err)
+	timeline, err := c.Timeline(ctx, payment.ID)
+	require.NoError(t, err)
+
+	stream, err := c.WatchStatus(ctx, payment.ID, "")
+	require.NoError(t, err)
+	for _, want := range timeline {
+		require.True(t, stream.Next(), stream.Err())
+		assert.Equal(t, want.To, stream.Change().To)
+	}
+	_, err = c.Capture(ctx, payment.ID)
+	require.NoError(t, err)
+	require.True(t, stream.Next(), stream.Err())
+	assert.Equal(t, client.StatusCaptured, stream.Change().To)
+	require.NoError(t, stream.Close())
+
+	resumed, err := c.WatchStatus(ctx, payment.ID, stream.LastEventID())
+	require.NoError(t, err)
+	defer resumed.Close()
+	_, err = c.Refund(ctx, payment.ID, 500)
+	require.NoError(t, err)
+	require.True(t, resumed.Next(), resumed.Err())
+	assert.Equal(t, client.StatusCaptured, resumed.Change().From, "the resumed stream starts after the last change seen")
+	assert.Equal(t, client.StatusRefund, resumed.Change().To)
+}
+
+func TestClient_ExportPayments(t *testing.T) {
+	c := newClient(newServer(t, &fakeGateway{}, nil), apiKey)
+	ctx := context.Background()
+
+	created := make(map[string]bool)
+	for amount := int64(1000); amount < 1003; amount++ {
+		params := charge
+		params.Amount = amount
+		payment, err := c.CreatePayment(ctx, params)
+		require.NoError(t, err)
+		created[payment.ID] = true
+	}
+
+	rows, err := c.ExportPayments(ctx, client.ExportParams{Columns: []string{"id", "status"}})
+	require.NoError(t, err)
+	defer rows.Close()
+	exported := make(map[string]bool)
+	for rows.Next() {
+		assert.Len(t, rows.Row(), 2)
+		assert.Equal(t, string(client.StatusCompleted), rows.Row()["status"])
+		exported[rows.Row()["id"]] = true
+	}
+	require.NoError(t, rows.Err())
+	assert.Equal(t, created, exported)
+
+	_, err = c.ExportPayments(ctx, client.ExportParams{Columns: []string{"card_number"}})
+	var apiErr *client.Error
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, "unknown_export_column", apiErr.Code)
+}
+
+func TestClient_Batches(t *testing.T) {
+	c := newClient(newServer(t, &fakeGateway{}, nil), apiKey)
+	ctx := context.Background()
+	payment, err := c.CreatePayment(ctx, charge)
+	require.NoError(t, err)
+
+	batch, err := c.CreateBatch(ctx, []client.BatchOperation{{PaymentID: payment.ID, Operation: client.OperationCapture}})
+	require.NoError(t, err)
+	assert.Equal(t, client.BatchPending, batch.Status)
+	assert.Equal(t, 1, batch.Total)
+	require.Len(t, batch.Items, 1)
+	assert.Equal(t, client.ItemPending, batch.Items[0].Status)
+
+	found, err := c.GetBatch(ctx, batch.ID)
+	require.NoError(t, err)
+	assert.Equal(t, batch.ID, found.ID)
+	assert.Equal(t, payment.ID, found.Items[0].PaymentID)
+
+	_, err = c.GetBatch(ctx, "missing")
+	assert.ErrorIs(t, err, client.ErrBatchNotFound)
+}
+
+func TestClient_Fx(t *testing.T) {
+	var reportQuery string
+	server := newServer(t, &fakeGateway{}, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/reports/settlement" {
+				reportQuery = r.URL.RawQuery
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+	c := newClient(server, apiKey)
+	ctx := context.Background()
+
+	quote, err := c.CreateQuote(ctx, "EUR")
+	require.NoError(t, err)
+	assert.Equal(t, "EUR", quote.From)
+	assert.Equal(t, "USD", quote.To)
+	assert.Equal(t, "1.1", quote.Rate)
+
+	found, err := c.GetQuote(ctx, quote.ID)
+	require.NoError(t, err)
+	assert.Equal(t, quote.ID, found.ID)
+	_, err = c.GetQuote(ctx, "missing")
+	assert.ErrorIs(t, err, client.ErrFxQuoteNotFound)
+
+	from := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	totals, err := c.SettlementReport(ctx, from, from.AddDate(0, 0, 1))
+	require.NoError(t, err)
+	assert.Equal(t, "from=2026-10-01&to=2026-10-02", reportQuery)
+	require.Len(t, totals, 1)
+	assert.Equal(t, client.StatusCaptured, totals[0].Status)
+	assert.Equal(t, int64(2500), totals[0].Amount)
+	assert.Equal(t, "$25.00", totals[0].DisplayAmount)
+}
@@ -0,0 +1,104 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Error is a failed API call. The API answers errors with RFC 7807 problem
+// details; Code is its stable machine-readable code (see docs/errors.md).
+// Responses that are not problem details, e.g. from a proxy, leave Code
+// empty.
+type Error struct {
+	StatusCode  int          `json:"status"`
+	Code        string       `json:"code"`
+	Title       string       `json:"title"`
+	Detail      string       `json:"detail"`
+	RequestID   string       `json:"request_id"`
+	DeclineCode string       `json:"decline_code"`
+	Errors      []FieldError `json:"errors"`
+	// PaymentID and PaymentStatus name the payment a failed create or
+	// operation left behind, e.g. the FAILED payment of a declined card.
+	PaymentID     string        `json:"payment_id"`
+	PaymentStatus PaymentStatus `json:"payment_status"`
+}
+
+type FieldError struct {
+	Field string `json:"field"`
+	Rule  string `json:"rule"`
+}
+
+func (e *Error) Error() string {
+	msg := e.Detail
+	if msg == "" {
+		msg = e.Title
+	}
+	if msg == "" {
+		msg = http.StatusText(e.StatusCode)
+	}
+	if e.Code == "" {
+		return fmt.Sprintf("payment api: %s (status %d)", msg, e.StatusCode)
+	}
+	return fmt.Sprintf("payment api: %s: %s (status %d)", e.Code, msg, e.StatusCode)
+}
+
+// Is matches errors by code, so errors.Is(err, client.ErrCardDeclined)
+// holds for any declined payment.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code != "" && t.Code == e.Code
+}
+
+var (
+	ErrValidation                = &Error{Code: "validation_failed"}
+	ErrInvalidAmount             = &Error{Code: "invalid_amount"}
+	ErrAmountOutOfRange          = &Error{Code: "amount_out_of_range"}
+	ErrInvalidCurrency           = &Error{Code: "invalid_currency"}
+	ErrMissingAPIKey             = &Error{Code: "missing_api_key"}
+	ErrInvalidAPIKey             = &Error{Code: "invalid_api_key"}
+	ErrCardDeclined              = &Error{Code: "card_declined"}
+	ErrBlockedByRisk             = &Error{Code: "blocked_by_risk"}
+	ErrBlocklisted               = &Error{Code: "blocklisted"}
+	ErrInsufficientScope         = &Error{Code: "insufficient_scope"}
+	ErrMerchantDisabled          = &Error{Code: "merchant_disabled"}
+	ErrPaymentNotFound           = &Error{Code: "payment_not_found"}
+	ErrAlreadyCaptured           = &Error{Code: "payment_already_captured"}
+	ErrAlreadyCanceled           = &Error{Code: "payment_already_canceled"}
+	ErrNotInReview               = &Error{Code: "payment_not_in_review"}
+	ErrNotCaptured               = &Error{Code: "payment_not_captured"}
+	ErrNotAtGateway              = &Error{Code: "payment_not_at_gateway"}
+	ErrAlreadyFailed             = &Error{Code: "payment_already_failed"}
+	ErrIdempotencyConflict       = &Error{Code: "idempotency_conflict"}
+	ErrIdempotencyKeyReused      = &Error{Code: "idempotency_key_reused"}
+	ErrFxQuoteRejected           = &Error{Code: "fx_quote_rejected"}
+	ErrFxQuoteExpired            = &Error{Code: "fx_quote_expired"}
+	ErrFxQuoteNotFound           = &Error{Code: "fx_quote_not_found"}
+	ErrRiskAssessmentNotFound    = &Error{Code: "risk_assessment_not_found"}
+	ErrBatchNotFound             = &Error{Code: "batch_not_found"}
+	ErrTooManyStreams            = &Error{Code: "too_many_streams"}
+	ErrMerchantLimitExceeded     = &Error{Code: "merchant_limit_exceeded"}
+	ErrRateLimited               = &Error{Code: "rate_limited"}
+	ErrTooManyConcurrentRequests = &Error{Code: "too_many_concurrent_requests"}
+	ErrInternal                  = &Error{Code: "internal_error"}
+	ErrGateway                   = &Error{Code: "gateway_error"}
+	ErrGatewayUnavailable        = &Error{Code: "gateway_unavailable"}
+)
+
+// newError reads the problem details of resp. The request id falls back to
+// the one the client sent, which the API echoes and logs.
+func newError(resp *http.Response, requestID string) *Error {
+	e := &Error{}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	_ = json.Unmarshal(body, e)
+
+	e.StatusCode = resp.StatusCode
+	if e.RequestID == "" {
+		e.RequestID = resp.Header.Get(requestIDHeader)
+	}
+	if e.RequestID == "" {
+		e.RequestID = requestID
+	}
+	return e
+}
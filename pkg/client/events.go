@@ -0,0 +1,119 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// statusEvent is the server-sent event type of status changes.
+const statusEvent = "status"
+
+// maxEventSize bounds one line of the event stream.
+const maxEventSize = 1 << 20
+
+// WatchStatus opens the payment's event stream. It starts with the
+// payment's timeline after lastEventID, or the whole timeline when it is
+// empty, then delivers changes as they happen:
+//
+//	stream, err := c.WatchStatus(ctx, paymentID, "")
+//	if err != nil {
+//		return err
+//	}
+//	defer stream.Close()
+//	for stream.Next() {
+//		change := stream.Change()
+//	}
+//
+// The stream ends when ctx is done, when the server drops it or on
+// error. Pass LastEventID to a new WatchStatus to resume where it ended.
+func (c *Client) WatchStatus(ctx context.Context, paymentID, lastEventID string) (*StatusStream, error) {
+	header := http.Header{"Accept": {"text/event-stream"}}
+	if lastEventID != "" {
+		header.Set("Last-Event-ID", lastEventID)
+	}
+	body, err := c.stream(ctx, request{method: http.MethodGet, path: paymentPath(paymentID, "/events"), header: header})
+	if err != nil {
+		return nil, err
+	}
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(nil, maxEventSize)
+	return &StatusStream{body: body, scanner: scanner, lastEventID: lastEventID}, nil
+}
+
+type StatusStream struct {
+	body    io.ReadCloser
+	scanner *bufio.Scanner
+
+	current     *StatusChange
+	lastEventID string
+	err         error
+}
+
+// Next waits for the next status change. It returns false once the stream
+// has ended.
+func (s *StatusStream) Next() bool {
+	if s.err != nil {
+		return false
+	}
+
+	var id, event string
+	var data []string
+	for s.scanner.Scan() {
+		line := s.scanner.Text()
+		if line == "" {
+			if event == statusEvent && len(data) > 0 {
+				var change StatusChange
+				if err := json.Unmarshal([]byte(strings.Join(data, "\n")), &change); err != nil {
+					s.err = fmt.Errorf("client: decode event: %w", err)
+					return false
+				}
+				s.current, s.lastEventID = &change, id
+				return true
+			}
+			id, event, data = "", "", nil
+			continue
+		}
+		// Lines starting with a colon are comments, e.g. heartbeats.
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "id":
+			id = value
+		case "event":
+			event = value
+		case "data":
+			data = append(data, value)
+		}
+	}
+	s.err = s.scanner.Err()
+	return false
+}
+
+func (s *StatusStream) Change() *StatusChange {
+	return s.current
+}
+
+// LastEventID is the id of the last change Next returned, or the id the
+// stream was opened with if there was none.
+func (s *StatusStream) LastEventID() string {
+	return s.lastEventID
+}
+
+// Err returns the error that ended the stream, nil if the server closed
+// it.
+func (s *StatusStream) Err() error {
+	return s.err
+}
+
+func (s *StatusStream) Close() error {
+	return s.body.Close()
+}
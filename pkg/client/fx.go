@@ -0,0 +1,61 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Quote fixes the rate a payment in From is settled at in To until
+// ExpiresAt. Pass its id as CreatePaymentParams.FxQuoteID.
+type Quote struct {
+	ID        string    `json:"id"`
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Rate      string    `json:"rate"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SettlementTotal sums the payments of one status settled in one
+// currency.
+type SettlementTotal struct {
+	Status             PaymentStatus `json:"status"`
+	SettlementCurrency string        `json:"settlement_currency"`
+	Count              int64         `json:"count"`
+	Amount             int64         `json:"amount"`
+	DisplayAmount      string        `json:"display_amount"`
+}
+
+// CreateQuote quotes the rate from currency to the settlement currency.
+func (c *Client) CreateQuote(ctx context.Context, currency string) (*Quote, error) {
+	body := struct {
+		Currency string `json:"currency"`
+	}{currency}
+	return c.quote(ctx, http.MethodPost, "/fx/quotes", body)
+}
+
+func (c *Client) GetQuote(ctx context.Context, quoteID string) (*Quote, error) {
+	return c.quote(ctx, http.MethodGet, "/fx/quotes/"+url.PathEscape(quoteID), nil)
+}
+
+func (c *Client) quote(ctx context.Context, method, path string, body any) (*Quote, error) {
+	var quote Quote
+	if err := c.do(ctx, request{method: method, path: path, body: body}, &quote); err != nil {
+		return nil, err
+	}
+	return &quote, nil
+}
+
+// SettlementReport totals the payments created from the day of from up to,
+// but not including, the day of to. Both are taken as UTC dates.
+func (c *Client) SettlementReport(ctx context.Context, from, to time.Time) ([]*SettlementTotal, error) {
+	query := url.Values{
+		"from": {from.UTC().Format(time.DateOnly)},
+		"to":   {to.UTC().Format(time.DateOnly)},
+	}
+	var totals []*SettlementTotal
+	err := c.do(ctx, request{method: http.MethodGet, path: "/reports/settlement", query: query}, &totals)
+	return totals, err
+}
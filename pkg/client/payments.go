@@ -0,0 +1,306 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/williamkoller/payment-system/pkg/ulid"
+)
+
+type PaymentStatus string
+
+const (
+	StatusPending   PaymentStatus = "PENDING"
+	StatusCompleted PaymentStatus = "COMPLETED"
+	StatusFailed    PaymentStatus = "FAILED"
+	StatusCanceled  PaymentStatus = "CANCELED"
+	StatusCaptured  PaymentStatus = "CAPTURED"
+	StatusRefund    PaymentStatus = "REFUND"
+	StatusReview    PaymentStatus = "REVIEW"
+)
+
+type Settlement struct {
+	Amount        int64  `json:"amount"`
+	Currency      string `json:"currency"`
+	DisplayAmount string `json:"display_amount"`
+	FxRate        string `json:"fx_rate"`
+	FxQuoteID     string `json:"fx_quote_id,omitempty"`
+}
+
+type Payment struct {
+	ID                     string        `json:"id"`
+	MerchantID             string        `json:"merchant_id,omitempty"`
+	Amount                 int64         `json:"amount"`
+	Currency               string        `json:"currency"`
+	DisplayAmount          string        `json:"display_amount"`
+	Status                 PaymentStatus `json:"status"`
+	Email                  string        `json:"email"`
+	StripeID               string        `json:"stripe_id"`
+	PaymentMethod          string        `json:"payment_method"`
+	IdempotencyKey         string        `json:"idempotency_key"`
+	Settlement             *Settlement   `json:"settlement,omitempty"`
+	AuthorizationExpiresAt *time.Time    `json:"authorization_expires_at,omitempty"`
+	CreatedAt              time.Time     `json:"created_at"`
+	UpdatedAt              time.Time     `json:"updated_at"`
+}
+
+type StatusChange struct {
+	From      PaymentStatus `json:"from"`
+	To        PaymentStatus `json:"to"`
+	Source    string        `json:"source"`
+	Reason    string        `json:"reason,omitempty"`
+	ErrorCode string        `json:"error_code,omitempty"`
+	At        time.Time     `json:"at"`
+}
+
+type FailedAttempt struct {
+	Operation string    `json:"operation"`
+	ErrorCode string    `json:"error_code"`
+	Message   string    `json:"message"`
+	At        time.Time `json:"at"`
+}
+
+// AuditEntry is one entry of the audit log. Before, After and Details
+// are left as JSON since their shape depends on the action.
+type AuditEntry struct {
+	ID           string          `json:"id"`
+	Seq          int64           `json:"seq"`
+	Action       string          `json:"action"`
+	Outcome      string          `json:"outcome"`
+	ErrorCode    string          `json:"error_code,omitempty"`
+	Actor        string          `json:"actor"`
+	APIKeyID     string          `json:"api_key_id,omitempty"`
+	IP           string          `json:"ip,omitempty"`
+	RequestID    string          `json:"request_id,omitempty"`
+	ResourceType string          `json:"resource_type"`
+	ResourceID   string          `json:"resource_id"`
+	Before       json.RawMessage `json:"before,omitempty"`
+	After        json.RawMessage `json:"after,omitempty"`
+	Details      json.RawMessage `json:"details,omitempty"`
+	CreatedAt    time.Time       `json:"created_at"`
+	Hash         string          `json:"hash"`
+	PrevHash     string          `json:"prev_hash"`
+}
+
+type RiskDecision string
+
+const (
+	RiskAllow      RiskDecision = "ALLOW"
+	RiskRequire3DS RiskDecision = "REQUIRE_3DS"
+	RiskReview     RiskDecision = "REVIEW"
+	RiskBlock      RiskDecision = "BLOCK"
+)
+
+type RuleMatch struct {
+	Rule   string `json:"rule"`
+	Score  int    `json:"score"`
+	Reason string `json:"reason"`
+}
+
+type RiskAssessment struct {
+	ID           string       `json:"id"`
+	PaymentID    string       `json:"payment_id"`
+	Score        int          `json:"score"`
+	Decision     RiskDecision `json:"decision"`
+	MatchedRules []RuleMatch  `json:"matched_rules"`
+	ReviewedBy   string       `json:"reviewed_by,omitempty"`
+	ReviewedAt   *time.Time   `json:"reviewed_at,omitempty"`
+	CreatedAt    time.Time    `json:"created_at"`
+}
+
+type CreatePaymentParams struct {
+	Amount        int64  `json:"amount"`
+	Currency      string `json:"currency"`
+	Email         string `json:"email"`
+	PaymentMethod string `json:"payment_method"`
+	FxQuoteID     string `json:"fx_quote_id,omitempty"`
+	// IdempotencyKey is generated when empty. Set it to make a create safe
+	// to repeat across processes, e.g. to the id of the order it pays for.
+	IdempotencyKey string `json:"-"`
+}
+
+// CreatePayment charges the customer. A payment held for review comes back
+// with StatusReview; a declined or blocked one as an *Error naming the
+// FAILED payment.
+func (c *Client) CreatePayment(ctx context.Context, params CreatePaymentParams) (*Payment, error) {
+	key := params.IdempotencyKey
+	if key == "" {
+		key = ulid.NewULID()
+	}
+	var payment Payment
+	err := c.do(ctx, request{method: http.MethodPost, path: "/payments/", body: params, idempotencyKey: key}, &payment)
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+func (c *Client) GetPayment(ctx context.Context, paymentID string) (*Payment, error) {
+	return c.payment(ctx, http.MethodGet, paymentPath(paymentID, ""), nil)
+}
+
+// Timeline returns the payment's status changes, oldest first.
+func (c *Client) Timeline(ctx context.Context, paymentID string) ([]*StatusChange, error) {
+	var changes []*StatusChange
+	err := c.do(ctx, request{method: http.MethodGet, path: paymentPath(paymentID, "/timeline")}, &changes)
+	return changes, err
+}
+
+// FailedAttempts returns the captures, cancels and refunds the gateway
+// rejected for the payment.
+func (c *Client) FailedAttempts(ctx context.Context, paymentID string) ([]*FailedAttempt, error) {
+	var attempts []*FailedAttempt
+	err := c.do(ctx, request{method: http.MethodGet, path: paymentPath(paymentID, "/attempts")}, &attempts)
+	return attempts, err
+}
+
+// Audit returns the audit log entries of the payment, oldest first.
+func (c *Client) Audit(ctx context.Context, paymentID string) ([]*AuditEntry, error) {
+	var entries []*AuditEntry
+	err := c.do(ctx, request{method: http.MethodGet, path: paymentPath(paymentID, "/audit")}, &entries)
+	return entries, err
+}
+
+// RiskAssessment returns how the risk rules scored the payment. Payments
+// created while risk checks were off have none and return
+// ErrRiskAssessmentNotFound.
+func (c *Client) RiskAssessment(ctx context.Context, paymentID string) (*RiskAssessment, error) {
+	var assessment RiskAssessment
+	if err := c.do(ctx, request{method: http.MethodGet, path: paymentPath(paymentID, "/risk")}, &assessment); err != nil {
+		return nil, err
+	}
+	return &assessment, nil
+}
+
+func (c *Client) Capture(ctx context.Context, paymentID string) (*Payment, error) {
+	return c.payment(ctx, http.MethodPost, paymentPath(paymentID, "/capture"), nil)
+}
+
+func (c *Client) Cancel(ctx context.Context, paymentID string) (*Payment, error) {
+	return c.payment(ctx, http.MethodPost, paymentPath(paymentID, "/cancel"), nil)
+}
+
+// Refund refunds amount, in minor units, of a captured payment.
+func (c *Client) Refund(ctx context.Context, paymentID string, amount int64) (*Payment, error) {
+	body := struct {
+		Amount int64 `json:"amount"`
+	}{amount}
+	return c.payment(ctx, http.MethodPost, paymentPath(paymentID, "/refund"), body)
+}
+
+// ApproveReview sends a payment held for review to the gateway. It needs
+// a key with reviews:write; the review is recorded under that key.
+func (c *Client) ApproveReview(ctx context.Context, paymentID string) (*Payment, error) {
+	return c.payment(ctx, http.MethodPost, paymentPath(paymentID, "/review/approve"), nil)
+}
+
+// RejectReview fails a payment held for review. It needs a key with
+// reviews:write.
+func (c *Client) RejectReview(ctx context.Context, paymentID string) (*Payment, error) {
+	return c.payment(ctx, http.MethodPost, paymentPath(paymentID, "/review/reject"), nil)
+}
+
+func (c *Client) payment(ctx context.Context, method, path string, body any) (*Payment, error) {
+	var payment Payment
+	if err := c.do(ctx, request{method: method, path: path, body: body}, &payment); err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+func paymentPath(paymentID, suffix string) string {
+	return "/payments/" + url.PathEscape(paymentID) + suffix
+}
+
+// DefaultPageSize is how many payments ListPayments fetches per request
+// when PageSize is not set.
+const DefaultPageSize = 100
+
+type ListPaymentsParams struct {
+	// ExpiringBefore lists only authorizations that lapse before it,
+	// soonest first. Otherwise payments are listed newest first.
+	ExpiringBefore time.Time
+	PageSize       int
+}
+
+// ListPayments returns an iterator over the payments matching params. It
+// fetches a page at a time as the iterator advances.
+func (c *Client) ListPayments(ctx context.Context, params ListPaymentsParams) *PaymentIterator {
+	if params.PageSize <= 0 {
+		params.PageSize = DefaultPageSize
+	}
+	return &PaymentIterator{ctx: ctx, client: c, params: params}
+}
+
+// PaymentIterator walks the pages of a listing:
+//
+//	it := c.ListPayments(ctx, client.ListPaymentsParams{})
+//	for it.Next() {
+//		p := it.Payment()
+//	}
+//	if err := it.Err(); err != nil {
+//		return err
+//	}
+type PaymentIterator struct {
+	ctx    context.Context
+	client *Client
+	params ListPaymentsParams
+
+	page    []*Payment
+	current *Payment
+	last    string
+	done    bool
+	err     error
+}
+
+// Next advances to the next payment, fetching the next page when the
+// current one is used up. It returns false at the end or on error.
+func (it *PaymentIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	if len(it.page) == 0 {
+		if it.done {
+			return false
+		}
+		if it.err = it.fetch(); it.err != nil || len(it.page) == 0 {
+			return false
+		}
+	}
+	it.current, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+func (it *PaymentIterator) Payment() *Payment {
+	return it.current
+}
+
+func (it *PaymentIterator) Err() error {
+	return it.err
+}
+
+func (it *PaymentIterator) fetch() error {
+	query := url.Values{"limit": {strconv.Itoa(it.params.PageSize)}}
+	if !it.params.ExpiringBefore.IsZero() {
+		query.Set("expiring_before", it.params.ExpiringBefore.Format(time.RFC3339))
+	}
+	if it.last != "" {
+		query.Set("starting_after", it.last)
+	}
+
+	var page []*Payment
+	if err := it.client.do(it.ctx, request{method: http.MethodGet, path: "/payments/", query: query}, &page); err != nil {
+		return err
+	}
+	// A short page is the last one.
+	it.done = len(page) < it.params.PageSize
+	if len(page) > 0 {
+		it.last = page[len(page)-1].ID
+	}
+	it.page = page
+	return nil
+}
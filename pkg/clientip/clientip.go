@@ -0,0 +1,18 @@
+// Package clientip carries the caller's IP address in a context, so code
+// below the HTTP layer can record it.
+package clientip
+
+import "context"
+
+type contextKey struct{}
+
+func NewContext(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, contextKey{}, ip)
+}
+
+// FromContext returns the client IP stored in ctx, or "" outside of a
+// request.
+func FromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(contextKey{}).(string)
+	return ip
+}
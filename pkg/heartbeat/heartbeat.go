@@ -0,0 +1,26 @@
+package heartbeat
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Heartbeat records when a background loop last went round. The zero value
+// is ready to use.
+type Heartbeat struct {
+	last atomic.Int64
+}
+
+func (h *Heartbeat) Beat() {
+	h.last.Store(time.Now().UnixNano())
+}
+
+// Last returns the time of the latest beat, or the zero time if the loop
+// never started.
+func (h *Heartbeat) Last() time.Time {
+	n := h.last.Load()
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
@@ -18,15 +18,16 @@ var (
 func InitLogger(mode string) error {
 	once.Do(func() {
 		var zapLogger *zap.Logger
+		redact := zap.WrapCore(newRedactingCore)
 
 		switch mode {
 		case "dev":
-			zapLogger, initErr = zap.NewDevelopment()
+			zapLogger, initErr = zap.NewDevelopment(redact)
 		default:
 			cfg := zap.NewProductionConfig()
 			cfg.OutputPaths = []string{"stdout"} // Logs go to stdout
 			cfg.ErrorOutputPaths = []string{"stderr"}
-			zapLogger, initErr = cfg.Build()
+			zapLogger, initErr = cfg.Build(redact)
 		}
 
 		if initErr != nil {
@@ -62,6 +63,12 @@ func Info(msg string, fields ...interface{}) {
 	}
 }
 
+func Warn(msg string, fields ...interface{}) {
+	if logger != nil {
+		logger.Warnw(msg, fields...)
+	}
+}
+
 func Error(msg string, fields ...interface{}) {
 	if logger != nil {
 		logger.Errorw(msg, fields...)
@@ -0,0 +1,95 @@
+package logger
+
+import (
+	"fmt"
+	"regexp"
+
+	"go.uber.org/zap/zapcore"
+)
+
+var redactions = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9\-]+(\.[A-Za-z0-9\-]+)*\.[A-Za-z]{2,}`), "[REDACTED_EMAIL]"},
+	{regexp.MustCompile(`\b(sk|rk|pk)_(live|test)_[A-Za-z0-9_\-]+`), "${1}_${2}_[REDACTED]"},
+	{regexp.MustCompile(`\bwhsec_[A-Za-z0-9]+`), "whsec_[REDACTED]"},
+	{regexp.MustCompile(`(?i)\bBearer\s+[^\s"',]+`), "Bearer [REDACTED]"},
+}
+
+// Redact masks emails, Stripe and API keys, webhook secrets and bearer
+// tokens in s.
+func Redact(s string) string {
+	for _, r := range redactions {
+		s = r.pattern.ReplaceAllString(s, r.replacement)
+	}
+	return s
+}
+
+// redactingCore scrubs every message and field before it is encoded, so
+// secrets and PII that reach a log call, typically inside an error from
+// the database or Stripe, never reach the output.
+type redactingCore struct {
+	zapcore.Core
+}
+
+func newRedactingCore(core zapcore.Core) zapcore.Core {
+	return &redactingCore{Core: core}
+}
+
+func (c *redactingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactingCore{Core: c.Core.With(redactFields(fields))}
+}
+
+func (c *redactingCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c *redactingCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	entry.Message = Redact(entry.Message)
+	return c.Core.Write(entry, redactFields(fields))
+}
+
+// redactFields rewrites fields whose text changes once redacted as plain
+// string fields; every other field is left untouched.
+func redactFields(fields []zapcore.Field) []zapcore.Field {
+	out, copied := fields, false
+	for i, f := range fields {
+		text, ok := fieldText(f)
+		if !ok {
+			continue
+		}
+		redacted := Redact(text)
+		if redacted == text {
+			continue
+		}
+		if !copied {
+			out, copied = append([]zapcore.Field(nil), fields...), true
+		}
+		out[i] = zapcore.Field{Key: f.Key, Type: zapcore.StringType, String: redacted}
+	}
+	return out
+}
+
+func fieldText(f zapcore.Field) (string, bool) {
+	switch f.Type {
+	case zapcore.StringType:
+		return f.String, true
+	case zapcore.ErrorType:
+		if err, ok := f.Interface.(error); ok && err != nil {
+			return err.Error(), true
+		}
+	case zapcore.StringerType:
+		if s, ok := f.Interface.(fmt.Stringer); ok && s != nil {
+			return s.String(), true
+		}
+	case zapcore.ReflectType:
+		if f.Interface != nil {
+			return fmt.Sprintf("%+v", f.Interface), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,44 @@
+package logger
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestRedact(t *testing.T) {
+	tests := map[string]string{
+		"user@example.com":                       "[REDACTED_EMAIL]",
+		"Key (email)=(a.b+c@mail.example.co.uk)": "Key (email)=([REDACTED_EMAIL])",
+		"invalid key sk_live_abcDEF123_-x":       "invalid key sk_live_[REDACTED]",
+		"secret whsec_abc123":                    "secret whsec_[REDACTED]",
+		"Authorization: Bearer sk_test_abc":      "Authorization: Bearer [REDACTED]",
+		"payment 01HX captured":                  "payment 01HX captured",
+	}
+	for in, want := range tests {
+		assert.Equal(t, want, Redact(in), in)
+	}
+}
+
+func TestRedactingCore(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	log := zap.New(newRedactingCore(core)).Sugar().With("customer", "user@example.com")
+
+	log.Errorw("cannot save user@example.com",
+		"err", errors.New(`duplicate key: Key (email)=(user@example.com)`),
+		"key", "sk_test_123",
+		"status", 409,
+	)
+
+	entry := logs.All()[0]
+	assert.Equal(t, "cannot save [REDACTED_EMAIL]", entry.Message)
+	fields := entry.ContextMap()
+	assert.Equal(t, "[REDACTED_EMAIL]", fields["customer"])
+	assert.Equal(t, "duplicate key: Key (email)=([REDACTED_EMAIL])", fields["err"])
+	assert.Equal(t, "sk_test_[REDACTED]", fields["key"])
+	assert.EqualValues(t, 409, fields["status"])
+}
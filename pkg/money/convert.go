@@ -0,0 +1,50 @@
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// ParseRate parses a decimal exchange rate such as "5.4231". Rates are kept
+// as strings at the edges and as big.Rat while converting so that no
+// precision is lost to float64.
+func ParseRate(rate string) (*big.Rat, error) {
+	r, ok := new(big.Rat).SetString(rate)
+	if !ok {
+		return nil, fmt.Errorf("invalid exchange rate: %q", rate)
+	}
+	if r.Sign() <= 0 {
+		return nil, errors.New("exchange rate must be greater than zero")
+	}
+	return r, nil
+}
+
+// Convert converts m into currency to at the given rate (units of to per
+// unit of m's currency), accounting for the exponent of both currencies and
+// rounding half away from zero to the nearest minor unit.
+func Convert(m Money, to Currency, rate *big.Rat) Money {
+	v := new(big.Rat).SetInt64(m.Amount)
+	v.Mul(v, rate)
+	v.Mul(v, new(big.Rat).SetInt64(to.Factor()))
+	v.Quo(v, new(big.Rat).SetInt64(m.Currency.Factor()))
+
+	return Money{Amount: roundHalfAway(v), Currency: to}
+}
+
+func roundHalfAway(v *big.Rat) int64 {
+	num := new(big.Int).Set(v.Num())
+	den := v.Denom()
+
+	neg := num.Sign() < 0
+	num.Abs(num)
+
+	q, r := new(big.Int).QuoRem(num, den, new(big.Int))
+	if new(big.Int).Mul(r, big.NewInt(2)).Cmp(den) >= 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	if neg {
+		q.Neg(q)
+	}
+	return q.Int64()
+}
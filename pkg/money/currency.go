@@ -0,0 +1,76 @@
+package money
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Currency describes an ISO 4217 currency and the charge limits we accept
+// for it. Amounts are always expressed in minor units (10^Exponent per
+// major unit), so zero-decimal currencies such as JPY have Exponent 0.
+type Currency struct {
+	Code      string
+	Numeric   string
+	Exponent  int
+	Symbol    string
+	MinAmount int64
+	MaxAmount int64
+}
+
+// defaultMaxAmount mirrors the gateway's eight-digit ceiling on amounts.
+const defaultMaxAmount int64 = 99_999_999
+
+var currencies = map[string]Currency{
+	"AUD": {Code: "AUD", Numeric: "036", Exponent: 2, Symbol: "A$", MinAmount: 50, MaxAmount: defaultMaxAmount},
+	"BRL": {Code: "BRL", Numeric: "986", Exponent: 2, Symbol: "R$", MinAmount: 50, MaxAmount: defaultMaxAmount},
+	"CAD": {Code: "CAD", Numeric: "124", Exponent: 2, Symbol: "CA$", MinAmount: 50, MaxAmount: defaultMaxAmount},
+	"CHF": {Code: "CHF", Numeric: "756", Exponent: 2, Symbol: "CHF ", MinAmount: 50, MaxAmount: defaultMaxAmount},
+	"CLP": {Code: "CLP", Numeric: "152", Exponent: 0, Symbol: "CLP$", MinAmount: 500, MaxAmount: defaultMaxAmount},
+	"CNY": {Code: "CNY", Numeric: "156", Exponent: 2, Symbol: "CN¥", MinAmount: 400, MaxAmount: defaultMaxAmount},
+	"COP": {Code: "COP", Numeric: "170", Exponent: 2, Symbol: "COL$", MinAmount: 200000, MaxAmount: defaultMaxAmount},
+	"CZK": {Code: "CZK", Numeric: "203", Exponent: 2, Symbol: "Kč ", MinAmount: 1500, MaxAmount: defaultMaxAmount},
+	"DKK": {Code: "DKK", Numeric: "208", Exponent: 2, Symbol: "kr ", MinAmount: 250, MaxAmount: defaultMaxAmount},
+	"EUR": {Code: "EUR", Numeric: "978", Exponent: 2, Symbol: "€", MinAmount: 50, MaxAmount: defaultMaxAmount},
+	"GBP": {Code: "GBP", Numeric: "826", Exponent: 2, Symbol: "£", MinAmount: 30, MaxAmount: defaultMaxAmount},
+	"HKD": {Code: "HKD", Numeric: "344", Exponent: 2, Symbol: "HK$", MinAmount: 400, MaxAmount: defaultMaxAmount},
+	"HUF": {Code: "HUF", Numeric: "348", Exponent: 2, Symbol: "Ft ", MinAmount: 17500, MaxAmount: defaultMaxAmount},
+	"INR": {Code: "INR", Numeric: "356", Exponent: 2, Symbol: "₹", MinAmount: 50, MaxAmount: defaultMaxAmount},
+	"JPY": {Code: "JPY", Numeric: "392", Exponent: 0, Symbol: "¥", MinAmount: 50, MaxAmount: defaultMaxAmount},
+	"KRW": {Code: "KRW", Numeric: "410", Exponent: 0, Symbol: "₩", MinAmount: 500, MaxAmount: defaultMaxAmount},
+	"MXN": {Code: "MXN", Numeric: "484", Exponent: 2, Symbol: "MX$", MinAmount: 1000, MaxAmount: defaultMaxAmount},
+	"NOK": {Code: "NOK", Numeric: "578", Exponent: 2, Symbol: "kr ", MinAmount: 300, MaxAmount: defaultMaxAmount},
+	"NZD": {Code: "NZD", Numeric: "554", Exponent: 2, Symbol: "NZ$", MinAmount: 50, MaxAmount: defaultMaxAmount},
+	"PLN": {Code: "PLN", Numeric: "985", Exponent: 2, Symbol: "zł ", MinAmount: 200, MaxAmount: defaultMaxAmount},
+	"SEK": {Code: "SEK", Numeric: "752", Exponent: 2, Symbol: "kr ", MinAmount: 300, MaxAmount: defaultMaxAmount},
+	"SGD": {Code: "SGD", Numeric: "702", Exponent: 2, Symbol: "S$", MinAmount: 50, MaxAmount: defaultMaxAmount},
+	"USD": {Code: "USD", Numeric: "840", Exponent: 2, Symbol: "$", MinAmount: 50, MaxAmount: defaultMaxAmount},
+	"VND": {Code: "VND", Numeric: "704", Exponent: 0, Symbol: "₫", MinAmount: 10000, MaxAmount: defaultMaxAmount},
+}
+
+// LookupCurrency returns the currency for an ISO 4217 alphabetic code,
+// ignoring case and surrounding whitespace.
+func LookupCurrency(code string) (Currency, error) {
+	c, ok := currencies[strings.ToUpper(strings.TrimSpace(code))]
+	if !ok {
+		return Currency{}, fmt.Errorf("unsupported currency: %q", code)
+	}
+	return c, nil
+}
+
+func IsSupported(code string) bool {
+	_, err := LookupCurrency(code)
+	return err == nil
+}
+
+func (c Currency) IsZeroDecimal() bool {
+	return c.Exponent == 0
+}
+
+// Factor is the number of minor units in one major unit.
+func (c Currency) Factor() int64 {
+	f := int64(1)
+	for i := 0; i < c.Exponent; i++ {
+		f *= 10
+	}
+	return f
+}
@@ -0,0 +1,83 @@
+package money
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrAmountTooSmall = errors.New("amount is below the minimum charge for this currency")
+	ErrAmountTooLarge = errors.New("amount is above the maximum charge for this currency")
+)
+
+// Money is an amount in minor units of a given currency.
+type Money struct {
+	Amount   int64
+	Currency Currency
+}
+
+func New(amount int64, code string) (Money, error) {
+	currency, err := LookupCurrency(code)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{Amount: amount, Currency: currency}, nil
+}
+
+// NewCharge is New plus the per-currency charge limits.
+func NewCharge(amount int64, code string) (Money, error) {
+	m, err := New(amount, code)
+	if err != nil {
+		return Money{}, err
+	}
+	if err := m.ValidateCharge(); err != nil {
+		return Money{}, err
+	}
+	return m, nil
+}
+
+func (m Money) ValidateCharge() error {
+	if m.Amount < m.Currency.MinAmount {
+		return fmt.Errorf("%w: %s < %s", ErrAmountTooSmall, m.Format(), Money{m.Currency.MinAmount, m.Currency}.Format())
+	}
+	if m.Amount > m.Currency.MaxAmount {
+		return fmt.Errorf("%w: %s > %s", ErrAmountTooLarge, m.Format(), Money{m.Currency.MaxAmount, m.Currency}.Format())
+	}
+	return nil
+}
+
+// Decimal renders the amount in major units without a symbol, e.g. "10.50"
+// for 1050 USD and "1050" for 1050 JPY.
+func (m Money) Decimal() string {
+	amount := m.Amount
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	if m.Currency.IsZeroDecimal() {
+		return sign + strconv.FormatInt(amount, 10)
+	}
+
+	factor := m.Currency.Factor()
+	frac := strconv.FormatInt(amount%factor, 10)
+	frac = strings.Repeat("0", m.Currency.Exponent-len(frac)) + frac
+	return fmt.Sprintf("%s%d.%s", sign, amount/factor, frac)
+}
+
+// Format renders the amount for display, e.g. "$10.50" or "¥1050".
+func (m Money) Format() string {
+	decimal := m.Decimal()
+	if strings.HasPrefix(decimal, "-") {
+		return "-" + m.Currency.Symbol + decimal[1:]
+	}
+	return m.Currency.Symbol + decimal
+}
+
+// String renders the amount with its ISO code, e.g. "10.50 USD".
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency.Code
+}
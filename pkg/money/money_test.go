@@ -0,0 +1,93 @@
+package money_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/williamkoller/payment-system/pkg/money"
+)
+
+func TestLookupCurrency(t *testing.T) {
+	c, err := money.LookupCurrency(" usd ")
+	assert.NoError(t, err)
+	assert.Equal(t, "USD", c.Code)
+	assert.Equal(t, 2, c.Exponent)
+	assert.False(t, c.IsZeroDecimal())
+
+	jpy, err := money.LookupCurrency("JPY")
+	assert.NoError(t, err)
+	assert.True(t, jpy.IsZeroDecimal())
+	assert.Equal(t, int64(1), jpy.Factor())
+
+	_, err = money.LookupCurrency("XXX")
+	assert.Error(t, err)
+}
+
+func TestMoney_Format(t *testing.T) {
+	cases := []struct {
+		amount   int64
+		currency string
+		decimal  string
+		display  string
+	}{
+		{1050, "USD", "10.50", "$10.50"},
+		{5, "BRL", "0.05", "R$0.05"},
+		{1050, "JPY", "1050", "¥1050"},
+		{-250, "EUR", "-2.50", "-€2.50"},
+		{10000, "KRW", "10000", "₩10000"},
+	}
+
+	for _, tc := range cases {
+		m, err := money.New(tc.amount, tc.currency)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.decimal, m.Decimal())
+		assert.Equal(t, tc.display, m.Format())
+		assert.Equal(t, tc.decimal+" "+tc.currency, m.String())
+	}
+}
+
+func TestNewCharge_Limits(t *testing.T) {
+	_, err := money.NewCharge(49, "USD")
+	assert.True(t, errors.Is(err, money.ErrAmountTooSmall))
+
+	_, err = money.NewCharge(100_000_000, "JPY")
+	assert.True(t, errors.Is(err, money.ErrAmountTooLarge))
+
+	m, err := money.NewCharge(50, "jpy")
+	assert.NoError(t, err)
+	assert.Equal(t, "JPY", m.Currency.Code)
+}
+
+func TestConvert(t *testing.T) {
+	cases := []struct {
+		amount int64
+		from   string
+		to     string
+		rate   string
+		want   int64
+	}{
+		{1000, "USD", "BRL", "5.4231", 5423},
+		{1000, "USD", "JPY", "149.555", 1496},
+		{1000, "JPY", "USD", "0.0067", 670},
+		{1, "EUR", "BRL", "0.5", 1},
+	}
+
+	for _, tc := range cases {
+		m, err := money.New(tc.amount, tc.from)
+		assert.NoError(t, err)
+		to, err := money.LookupCurrency(tc.to)
+		assert.NoError(t, err)
+		rate, err := money.ParseRate(tc.rate)
+		assert.NoError(t, err)
+
+		got := money.Convert(m, to, rate)
+		assert.Equal(t, tc.want, got.Amount, "%d %s -> %s @ %s", tc.amount, tc.from, tc.to, tc.rate)
+		assert.Equal(t, tc.to, got.Currency.Code)
+	}
+
+	_, err := money.ParseRate("-1")
+	assert.Error(t, err)
+	_, err = money.ParseRate("abc")
+	assert.Error(t, err)
+}
@@ -0,0 +1,128 @@
+package pii
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// KeyFile is a KeyProvider backed by a local JSON file:
+//
+//	{
+//	  "current": "2026-10",
+//	  "keys": {"2026-01": "<base64>", "2026-10": "<base64>"},
+//	  "index_key": "<base64>"
+//	}
+//
+// Rotating means adding a key and pointing "current" at it; older keys
+// must stay in the file until `pii rotate` has re-encrypted their values.
+type KeyFile struct {
+	current  string
+	keys     map[string][]byte
+	indexKey []byte
+}
+
+type keyFileJSON struct {
+	Current  string            `json:"current"`
+	Keys     map[string]string `json:"keys"`
+	IndexKey string            `json:"index_key"`
+}
+
+func LoadKeyFile(path string) (*KeyFile, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc keyFileJSON
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("pii: invalid key file %s: %w", path, err)
+	}
+
+	kf := &KeyFile{current: doc.Current, keys: make(map[string][]byte, len(doc.Keys))}
+	for id, encoded := range doc.Keys {
+		if id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("pii: invalid key id %q", id)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("pii: key %q must be 32 bytes of base64", id)
+		}
+		kf.keys[id] = key
+	}
+	if _, ok := kf.keys[kf.current]; !ok {
+		return nil, fmt.Errorf("pii: current key %q is not in the key file", kf.current)
+	}
+
+	kf.indexKey, err = base64.StdEncoding.DecodeString(doc.IndexKey)
+	if err != nil || len(kf.indexKey) < 32 {
+		return nil, errors.New("pii: index_key must be at least 32 bytes of base64")
+	}
+
+	return kf, nil
+}
+
+// AddKeyFile generates a key named id, makes it current and writes the
+// file, creating it (with a fresh index key) if it does not exist.
+func AddKeyFile(path, id string) error {
+	if id == "" || strings.Contains(id, ":") {
+		return fmt.Errorf("pii: invalid key id %q", id)
+	}
+
+	doc := keyFileJSON{Keys: map[string]string{}}
+	raw, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(raw, &doc); err != nil {
+			return fmt.Errorf("pii: invalid key file %s: %w", path, err)
+		}
+	case errors.Is(err, os.ErrNotExist):
+		if doc.IndexKey, err = randomKey(); err != nil {
+			return err
+		}
+	default:
+		return err
+	}
+
+	if _, ok := doc.Keys[id]; ok {
+		return fmt.Errorf("pii: key %q already exists", id)
+	}
+	if doc.Keys[id], err = randomKey(); err != nil {
+		return err
+	}
+	doc.Current = id
+
+	out, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(out, '\n'), 0o600)
+}
+
+func (k *KeyFile) CurrentKeyID() string {
+	return k.current
+}
+
+func (k *KeyFile) Key(id string) ([]byte, error) {
+	key, ok := k.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, id)
+	}
+	return key, nil
+}
+
+func (k *KeyFile) IndexKey() []byte {
+	return k.indexKey
+}
+
+func randomKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
@@ -0,0 +1,222 @@
+// Package pii encrypts personal data at rest with envelope encryption and
+// derives blind indexes for the lookups that still need equality.
+//
+// Every value gets its own random data key. The value is sealed with that
+// key (AES-256-GCM), and the data key is wrapped by a key-encryption key
+// from a KeyProvider. The id of the key-encryption key prefixes the stored
+// value, so keys can be rotated while old values stay readable.
+package pii
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// prefix marks encrypted values. Values without it are legacy plain text,
+// which Decrypt passes through until they are re-encrypted.
+const prefix = "pii:"
+
+var (
+	ErrMalformed  = errors.New("pii: malformed encrypted value")
+	ErrUnknownKey = errors.New("pii: unknown key id")
+	ErrNoKeys     = errors.New("pii: value is encrypted but no key provider is configured")
+)
+
+// KeyProvider supplies key-encryption keys and the blind index key.
+type KeyProvider interface {
+	// CurrentKeyID names the key new values are encrypted under.
+	CurrentKeyID() string
+	// Key returns the 32-byte key-encryption key with the given id.
+	Key(id string) ([]byte, error)
+	// IndexKey returns the HMAC key of blind indexes. Changing it changes
+	// every index, so it is not rotated with the encryption keys.
+	IndexKey() []byte
+}
+
+// Cipher encrypts and indexes PII. A nil *Cipher stores values in plain
+// text and indexes them with an unkeyed SHA-256, which keeps local setups
+// working without a key file.
+type Cipher struct {
+	keys KeyProvider
+}
+
+func New(keys KeyProvider) *Cipher {
+	return &Cipher{keys: keys}
+}
+
+var defaultCipher atomic.Pointer[Cipher]
+
+// SetDefault installs the process-wide cipher. It is meant to be called
+// during start-up, before repositories are created.
+func SetDefault(c *Cipher) {
+	defaultCipher.Store(c)
+}
+
+// Default returns the process-wide cipher, nil until SetDefault is called.
+func Default() *Cipher {
+	return defaultCipher.Load()
+}
+
+// Encrypt seals plaintext under the current key as
+// "pii:<key id>:<wrapped data key>:<sealed value>". The empty string stays
+// empty.
+func (c *Cipher) Encrypt(plaintext string) (string, error) {
+	if c == nil || plaintext == "" {
+		return plaintext, nil
+	}
+
+	keyID := c.keys.CurrentKeyID()
+	kek, err := c.keys.Key(keyID)
+	if err != nil {
+		return "", err
+	}
+
+	dek := make([]byte, 32)
+	if _, err := rand.Read(dek); err != nil {
+		return "", err
+	}
+	wrapped, err := seal(kek, dek, []byte(keyID))
+	if err != nil {
+		return "", err
+	}
+	sealed, err := seal(dek, []byte(plaintext), []byte(keyID))
+	if err != nil {
+		return "", err
+	}
+
+	return prefix + keyID + ":" + encode(wrapped) + ":" + encode(sealed), nil
+}
+
+// Decrypt opens a value sealed by Encrypt under any key the provider still
+// has. Plain text values are returned unchanged.
+func (c *Cipher) Decrypt(value string) (string, error) {
+	rest, ok := strings.CutPrefix(value, prefix)
+	if !ok {
+		return value, nil
+	}
+	if c == nil {
+		return "", ErrNoKeys
+	}
+
+	parts := strings.Split(rest, ":")
+	if len(parts) != 3 {
+		return "", ErrMalformed
+	}
+	keyID := parts[0]
+	wrapped, err := decode(parts[1])
+	if err != nil {
+		return "", ErrMalformed
+	}
+	sealed, err := decode(parts[2])
+	if err != nil {
+		return "", ErrMalformed
+	}
+
+	kek, err := c.keys.Key(keyID)
+	if err != nil {
+		return "", err
+	}
+	dek, err := open(kek, wrapped, []byte(keyID))
+	if err != nil {
+		return "", fmt.Errorf("pii: cannot unwrap data key: %w", err)
+	}
+	plaintext, err := open(dek, sealed, []byte(keyID))
+	if err != nil {
+		return "", fmt.Errorf("pii: cannot decrypt value: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// NeedsRotation reports whether value is plain text or encrypted under a
+// key other than the current one.
+func (c *Cipher) NeedsRotation(value string) bool {
+	if c == nil || value == "" {
+		return false
+	}
+	return KeyID(value) != c.keys.CurrentKeyID()
+}
+
+// CurrentPrefix is the prefix shared by every value encrypted under the
+// current key, for finding the rows that still need rotating.
+func (c *Cipher) CurrentPrefix() string {
+	if c == nil {
+		return ""
+	}
+	return prefix + c.keys.CurrentKeyID() + ":"
+}
+
+// BlindIndex returns a deterministic digest of the normalized value
+// (trimmed, lower-cased), so equal emails can be found without decrypting.
+func (c *Cipher) BlindIndex(value string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return ""
+	}
+	if c == nil {
+		sum := sha256.Sum256([]byte(value))
+		return hex.EncodeToString(sum[:])
+	}
+	mac := hmac.New(sha256.New, c.keys.IndexKey())
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// KeyID returns the id of the key value was encrypted under, or "" for
+// plain text.
+func KeyID(value string) string {
+	rest, ok := strings.CutPrefix(value, prefix)
+	if !ok {
+		return ""
+	}
+	id, _, _ := strings.Cut(rest, ":")
+	return id
+}
+
+func seal(key, plaintext, additionalData []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func open(key, sealed, additionalData []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrMalformed
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, additionalData)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decode(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(s)
+}
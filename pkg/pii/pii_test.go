@@ -0,0 +1,85 @@
+package pii_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/williamkoller/payment-system/pkg/pii"
+)
+
+func loadKeys(t *testing.T, path string) *pii.Cipher {
+	t.Helper()
+	keys, err := pii.LoadKeyFile(path)
+	require.NoError(t, err)
+	return pii.New(keys)
+}
+
+func TestCipher_EncryptDecrypt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pii-keys.json")
+	require.NoError(t, pii.AddKeyFile(path, "k1"))
+	c := loadKeys(t, path)
+
+	sealed, err := c.Encrypt("User@Example.com")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(sealed, "pii:k1:"))
+	assert.NotContains(t, sealed, "Example")
+
+	again, err := c.Encrypt("User@Example.com")
+	require.NoError(t, err)
+	assert.NotEqual(t, sealed, again, "every value gets its own data key and nonce")
+
+	plaintext, err := c.Decrypt(sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "User@Example.com", plaintext)
+
+	plaintext, err = c.Decrypt("legacy@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "legacy@example.com", plaintext, "plain text passes through")
+
+	_, err = c.Decrypt(sealed[:len(sealed)-4] + "AAAA")
+	assert.Error(t, err)
+
+	_, err = (*pii.Cipher)(nil).Decrypt(sealed)
+	assert.ErrorIs(t, err, pii.ErrNoKeys)
+}
+
+func TestCipher_Rotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pii-keys.json")
+	require.NoError(t, pii.AddKeyFile(path, "k1"))
+	old := loadKeys(t, path)
+	sealed, err := old.Encrypt("user@example.com")
+	require.NoError(t, err)
+
+	require.NoError(t, pii.AddKeyFile(path, "k2"))
+	c := loadKeys(t, path)
+
+	assert.True(t, c.NeedsRotation(sealed))
+	assert.Equal(t, "k1", pii.KeyID(sealed))
+
+	plaintext, err := c.Decrypt(sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "user@example.com", plaintext, "old keys stay readable")
+
+	resealed, err := c.Encrypt(plaintext)
+	require.NoError(t, err)
+	assert.False(t, c.NeedsRotation(resealed))
+
+	assert.Equal(t, old.BlindIndex("user@example.com"), c.BlindIndex(" USER@example.com "),
+		"the index key survives rotation and values are normalized")
+
+	assert.Error(t, pii.AddKeyFile(path, "k2"))
+}
+
+func TestCipher_BlindIndex(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pii-keys.json")
+	require.NoError(t, pii.AddKeyFile(path, "k1"))
+	c := loadKeys(t, path)
+
+	var plain *pii.Cipher
+	assert.NotEqual(t, plain.BlindIndex("user@example.com"), c.BlindIndex("user@example.com"), "the keyed index differs from the unkeyed one")
+	assert.NotEqual(t, c.BlindIndex("a@example.com"), c.BlindIndex("b@example.com"))
+	assert.Empty(t, c.BlindIndex(""))
+}
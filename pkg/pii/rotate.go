@@ -0,0 +1,63 @@
+package pii
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// Column names an encrypted column and the blind index kept beside it.
+type Column struct {
+	Table string
+	Value string
+	Index string
+}
+
+type rotationRow struct {
+	ID    string
+	Value string
+}
+
+// Rotate re-encrypts, in batches, every value of column that is plain text
+// or sealed under an older key, refreshing its blind index. It returns the
+// number of rows rewritten.
+func (c *Cipher) Rotate(ctx context.Context, db *gorm.DB, column Column, batchSize int) (int64, error) {
+	if c == nil {
+		return 0, ErrNoKeys
+	}
+
+	var total int64
+	for {
+		var rows []rotationRow
+		err := db.WithContext(ctx).Table(column.Table).
+			Select("id, "+column.Value+" AS value").
+			Where(column.Value+" <> '' AND "+column.Value+" NOT LIKE ?", c.CurrentPrefix()+"%").
+			Order("id").
+			Limit(batchSize).
+			Scan(&rows).Error
+		if err != nil {
+			return total, err
+		}
+		if len(rows) == 0 {
+			return total, nil
+		}
+
+		for _, row := range rows {
+			plaintext, err := c.Decrypt(row.Value)
+			if err != nil {
+				return total, err
+			}
+			sealed, err := c.Encrypt(plaintext)
+			if err != nil {
+				return total, err
+			}
+			err = db.WithContext(ctx).Table(column.Table).
+				Where("id = ?", row.ID).
+				Updates(map[string]any{column.Value: sealed, column.Index: c.BlindIndex(plaintext)}).Error
+			if err != nil {
+				return total, err
+			}
+			total++
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package requestid
+
+import (
+	"context"
+
+	"github.com/williamkoller/payment-system/pkg/ulid"
+)
+
+// Header carries the request id in both directions.
+const Header = "X-Request-ID"
+
+const maxLength = 128
+
+type contextKey struct{}
+
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request id stored in ctx, or "" when there is
+// none, e.g. in background workers.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// Resolve returns inbound when it is usable as a request id and a fresh
+// ULID otherwise. Inbound ids are echoed into logs and Stripe metadata, so
+// anything long or containing non-printable ASCII is replaced.
+func Resolve(inbound string) string {
+	if inbound == "" || len(inbound) > maxLength {
+		return ulid.NewULID()
+	}
+	for i := 0; i < len(inbound); i++ {
+		if inbound[i] < 0x21 || inbound[i] > 0x7e {
+			return ulid.NewULID()
+		}
+	}
+	return inbound
+}
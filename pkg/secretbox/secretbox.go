@@ -0,0 +1,82 @@
+// Package secretbox encrypts secrets at rest with AES-256-GCM.
+package secretbox
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// version prefixes every sealed value so the format or key can change
+// later without ambiguity.
+const version = "v1:"
+
+var ErrMalformed = errors.New("secretbox: malformed sealed value")
+
+type Box struct {
+	aead cipher.AEAD
+}
+
+// New returns a Box for a 32-byte key.
+func New(key []byte) (*Box, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("secretbox: key must be 32 bytes, got %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Box{aead: aead}, nil
+}
+
+// NewFromBase64 decodes a standard base64 key, as generated by
+// `openssl rand -base64 32`.
+func NewFromBase64(key string) (*Box, error) {
+	raw, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, fmt.Errorf("secretbox: invalid base64 key: %w", err)
+	}
+	return New(raw)
+}
+
+// Seal encrypts plaintext with a random nonce. The empty string seals to
+// the empty string so optional secrets stay optional.
+func (b *Box) Seal(plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := b.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return version + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+func (b *Box) Open(sealed string) (string, error) {
+	if sealed == "" {
+		return "", nil
+	}
+	encoded, ok := strings.CutPrefix(sealed, version)
+	if !ok {
+		return "", ErrMalformed
+	}
+	raw, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil || len(raw) < b.aead.NonceSize() {
+		return "", ErrMalformed
+	}
+	nonce, ciphertext := raw[:b.aead.NonceSize()], raw[b.aead.NonceSize():]
+	plaintext, err := b.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", fmt.Errorf("secretbox: cannot decrypt: %w", err)
+	}
+	return string(plaintext), nil
+}
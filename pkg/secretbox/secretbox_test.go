@@ -0,0 +1,50 @@
+package secretbox_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/williamkoller/payment-system/pkg/secretbox"
+)
+
+func TestBox_SealOpen(t *testing.T) {
+	box, err := secretbox.New(bytes.Repeat([]byte{7}, 32))
+	require.NoError(t, err)
+
+	sealed, err := box.Seal("sk_live_secret")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(sealed, "v1:"))
+	assert.NotContains(t, sealed, "sk_live_secret")
+
+	again, err := box.Seal("sk_live_secret")
+	require.NoError(t, err)
+	assert.NotEqual(t, sealed, again, "every seal uses a fresh nonce")
+
+	opened, err := box.Open(sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "sk_live_secret", opened)
+
+	empty, err := box.Seal("")
+	require.NoError(t, err)
+	assert.Empty(t, empty)
+}
+
+func TestBox_OpenRejectsOtherKeyAndGarbage(t *testing.T) {
+	box, _ := secretbox.New(bytes.Repeat([]byte{7}, 32))
+	other, _ := secretbox.New(bytes.Repeat([]byte{8}, 32))
+
+	sealed, err := box.Seal("whsec_123")
+	require.NoError(t, err)
+
+	_, err = other.Open(sealed)
+	assert.Error(t, err)
+
+	_, err = box.Open("plaintext")
+	assert.ErrorIs(t, err, secretbox.ErrMalformed)
+
+	_, err = secretbox.New([]byte("short"))
+	assert.Error(t, err)
+}
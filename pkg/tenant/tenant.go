@@ -0,0 +1,20 @@
+// Package tenant keeps repositories from reading across merchants.
+package tenant
+
+import (
+	"context"
+
+	"github.com/williamkoller/payment-system/pkg/auth"
+	"gorm.io/gorm"
+)
+
+// Scope is a GORM scope restricting a query to the merchant ctx acts for.
+// It leaves the query unscoped when ctx carries no merchant.
+func Scope(ctx context.Context) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if merchantID := auth.MerchantID(ctx); merchantID != "" {
+			return db.Where("merchant_id = ?", merchantID)
+		}
+		return db
+	}
+}
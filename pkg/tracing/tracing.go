@@ -0,0 +1,106 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/williamkoller/payment-system"
+
+var serviceName = "payment-system"
+
+// Init installs the global tracer provider and the W3C trace context
+// propagator. exporter is "otlp", "stdout" or "none"; with "none" spans are
+// still created, so trace ids reach the logs, but nothing is exported. The
+// returned function flushes pending spans and must be called on shutdown.
+func Init(ctx context.Context, exporter, service string, sampleRatio float64) (func(context.Context) error, error) {
+	if service != "" {
+		serviceName = service
+	}
+
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(serviceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("cannot build tracing resource: %w", err)
+	}
+
+	options := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+	}
+
+	switch exporter {
+	case "otlp":
+		exp, err := otlptracegrpc.New(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("cannot create otlp exporter: %w", err)
+		}
+		options = append(options, sdktrace.WithBatcher(exp))
+	case "stdout":
+		exp, err := stdouttrace.New(stdouttrace.WithPrettyPrint())
+		if err != nil {
+			return nil, fmt.Errorf("cannot create stdout exporter: %w", err)
+		}
+		options = append(options, sdktrace.WithBatcher(exp))
+	case "none", "":
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", exporter)
+	}
+
+	provider := sdktrace.NewTracerProvider(options...)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+func ServiceName() string {
+	return serviceName
+}
+
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Start opens a span named name as a child of the span in ctx.
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, opts...)
+}
+
+// End records err, if any, on span and ends it. Use it as
+//
+//	ctx, span := tracing.Start(ctx, "PaymentUseCase.Capture")
+//	defer func() { tracing.End(span, err) }()
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// TraceID returns the hex trace id of the span in ctx, or "" when there is
+// none.
+func TraceID(ctx context.Context) string {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.HasTraceID() {
+		return ""
+	}
+	return sc.TraceID().String()
+}
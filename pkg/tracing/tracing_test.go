@@ -0,0 +1,39 @@
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/williamkoller/payment-system/pkg/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+func TestInit_PropagatesInboundTraceParent(t *testing.T) {
+	shutdown, err := tracing.Init(context.Background(), "none", "payment-system-test", 1)
+	require.NoError(t, err)
+	defer func() { _ = shutdown(context.Background()) }()
+
+	header := http.Header{}
+	header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx := otel.GetTextMapPropagator().Extract(context.Background(), propagation.HeaderCarrier(header))
+
+	ctx, span := tracing.Start(ctx, "child")
+	tracing.End(span, errors.New("boom"))
+
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", tracing.TraceID(ctx))
+	assert.Equal(t, "payment-system-test", tracing.ServiceName())
+}
+
+func TestInit_UnknownExporter(t *testing.T) {
+	_, err := tracing.Init(context.Background(), "zipkin", "", 1)
+	assert.Error(t, err)
+}
+
+func TestTraceID_NoSpan(t *testing.T) {
+	assert.Empty(t, tracing.TraceID(context.Background()))
+}